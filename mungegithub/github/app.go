@@ -0,0 +1,195 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// machineManPreview is needed for the GitHub App endpoints.
+	machineManPreview = "application/vnd.github.machine-man-preview+json"
+	defaultBaseURL    = "https://api.github.com/"
+)
+
+// AppTokenSource is an oauth2.TokenSource of installation tokens for a GitHub
+// App, for authenticating as the app instead of with a personal access
+// token. Installation tokens expire after an hour; wrap the source in
+// oauth2.ReuseTokenSource to get a new one only when needed.
+type AppTokenSource struct {
+	AppID int
+	Key   *rsa.PrivateKey
+	// InstallationID is the app's installation to act as. If 0, it is
+	// looked up for Org/Project.
+	InstallationID int
+	Org, Project   string
+	// Repositories, if set, limit the tokens to these repositories of the
+	// installation.
+	Repositories []string
+
+	// BaseURL of the API, defaults to https://api.github.com/.
+	BaseURL string
+	// Client talks to github to get tokens. It must not use the tokens
+	// itself. Defaults to http.DefaultClient.
+	Client *http.Client
+
+	lock sync.Mutex
+	now  func() time.Time
+}
+
+// LoadAppKey reads the PEM-encoded private key of a GitHub App.
+func LoadAppKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %v", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing app key %v: %v", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("app key %v is not an RSA key", path)
+	}
+	return key, nil
+}
+
+// jwt returns a token which authenticates as the app itself, good for a few
+// minutes.
+func (a *AppTokenSource) jwt() (string, error) {
+	now := time.Now()
+	if a.now != nil {
+		now = a.now()
+	}
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]int64{
+		// Allow for clock drift.
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": int64(a.AppID),
+	})
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// call makes a request as the app.
+func (a *AppTokenSource) call(method, path string, in, out interface{}) error {
+	jwt, err := a.jwt()
+	if err != nil {
+		return err
+	}
+	base := a.BaseURL
+	if base == "" {
+		base = defaultBaseURL
+	}
+	var body *bytes.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(base, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", machineManPreview)
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%v %v: %v: %s", method, path, resp.Status, data)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Token implements oauth2.TokenSource.
+func (a *AppTokenSource) Token() (*oauth2.Token, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.InstallationID == 0 {
+		installation := struct {
+			ID int `json:"id"`
+		}{}
+		if err := a.call("GET", fmt.Sprintf("/repos/%v/%v/installation", a.Org, a.Project), nil, &installation); err != nil {
+			return nil, fmt.Errorf("error looking up the app installation on %v/%v: %v", a.Org, a.Project, err)
+		}
+		a.InstallationID = installation.ID
+		glog.Infof("Acting as installation %d of app %d", a.InstallationID, a.AppID)
+	}
+	var in interface{}
+	if len(a.Repositories) > 0 {
+		in = map[string][]string{"repositories": a.Repositories}
+	}
+	token := struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{}
+	if err := a.call("POST", fmt.Sprintf("/app/installations/%d/access_tokens", a.InstallationID), in, &token); err != nil {
+		return nil, fmt.Errorf("error getting an installation token: %v", err)
+	}
+	glog.V(2).Infof("Got an installation token valid until %v", token.ExpiresAt)
+	return &oauth2.Token{
+		AccessToken: token.Token,
+		// Refresh a little early, so requests in flight don't fail.
+		Expiry: token.ExpiresAt.Add(-time.Minute),
+	}, nil
+}
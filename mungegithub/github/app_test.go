@@ -0,0 +1,97 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2016, 7, 1, 12, 0, 0, 0, time.UTC)
+	checkJWT := func(r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			t.Fatalf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+			t.Errorf("invalid signature: %v", err)
+		}
+		data, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		claims := map[string]int64{}
+		json.Unmarshal(data, &claims)
+		if claims["iss"] != 42 || claims["exp"] != now.Add(9*time.Minute).Unix() {
+			t.Errorf("unexpected claims %v", claims)
+		}
+	}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/repos/o/r/installation", func(w http.ResponseWriter, r *http.Request) {
+		checkJWT(r)
+		w.Write([]byte(`{"id": 7}`))
+	})
+	var requested map[string][]string
+	mux.HandleFunc("/app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		checkJWT(r)
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &requested)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "v1.abc", "expires_at": "2016-07-01T13:00:00Z"}`))
+	})
+
+	ts := &AppTokenSource{
+		AppID:        42,
+		Key:          key,
+		Org:          "o",
+		Project:      "r",
+		Repositories: []string{"r"},
+		BaseURL:      server.URL,
+		now:          func() time.Time { return now },
+	}
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token.AccessToken != "v1.abc" || !token.Expiry.Equal(now.Add(59*time.Minute)) {
+		t.Errorf("unexpected token %+v", token)
+	}
+	if ts.InstallationID != 7 {
+		t.Errorf("expected installation 7, got %v", ts.InstallationID)
+	}
+	if !reflect.DeepEqual(requested, map[string][]string{"repositories": {"r"}}) {
+		t.Errorf("unexpected token request %v", requested)
+	}
+}
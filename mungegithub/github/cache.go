@@ -0,0 +1,213 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// httpCache is the client side HTTP cache of a Config, which every munger
+// using the config shares. Responses are revalidated with github by their
+// ETag, which doesn't count against the rate limit. Up to size responses
+// are kept in memory, evicting the least recently used ones first; with a
+// dir, responses are written there as well, so that they survive restarts.
+type httpCache struct {
+	size int
+	dir  string
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	// order has the most recently used entries first.
+	order *list.List
+}
+
+type cacheEntry struct {
+	key  string
+	resp []byte
+}
+
+// newHTTPCache constructs a cache of `size` responses (unbounded if 0),
+// kept in `dir` as well if it isn't empty.
+func newHTTPCache(size int, dir string) (*httpCache, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &httpCache{
+		size:    size,
+		dir:     dir,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}, nil
+}
+
+// Get implements httpcache.Cache.
+func (c *httpCache) Get(key string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*cacheEntry).resp, true
+	}
+	if c.dir == "" {
+		return nil, false
+	}
+	resp, err := ioutil.ReadFile(c.file(key))
+	if err != nil {
+		return nil, false
+	}
+	c.add(key, resp)
+	return resp, true
+}
+
+// Set implements httpcache.Cache.
+func (c *httpCache) Set(key string, resp []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.add(key, resp)
+	if c.dir == "" {
+		return
+	}
+	path := c.file(key)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, resp, 0644); err != nil {
+		glog.Warningf("Unable to write %v to the HTTP cache: %v", key, err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		glog.Warningf("Unable to write %v to the HTTP cache: %v", key, err)
+	}
+}
+
+// Delete implements httpcache.Cache.
+func (c *httpCache) Delete(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.delete(key)
+}
+
+// invalidate deletes the cached responses whose URL path matches.
+func (c *httpCache) invalidate(match func(path string) bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key := range c.entries {
+		if u, err := url.Parse(key); err == nil && match(u.Path) {
+			c.delete(key)
+		}
+	}
+}
+
+// add puts `resp` in memory, evicting the least recently used responses if
+// there are too many. Must hold the lock.
+func (c *httpCache) add(key string, resp []byte) {
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*cacheEntry).resp = resp
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, resp: resp})
+	for c.size > 0 && c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// delete forgets the response for `key`, on disk as well. Must hold the
+// lock.
+func (c *httpCache) delete(key string) {
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+	if c.dir != "" {
+		os.Remove(c.file(key))
+	}
+}
+
+// file is where the response for `key` is kept on disk.
+func (c *httpCache) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+var (
+	// issueRE matches the paths of an issue or pull request and of
+	// everything about it, e.g. its comments or labels.
+	issueRE = regexp.MustCompile(`/repos/([^/]+)/([^/]+)/(issues|pulls)/(\d+)(/|$)`)
+	// commentRE matches the paths of a single comment, which don't say
+	// which issue it is on.
+	commentRE = regexp.MustCompile(`/repos/([^/]+)/([^/]+)/issues/comments/`)
+	// commentsRE matches the paths of the comments of an issue.
+	commentsRE = regexp.MustCompile(`/repos/([^/]+)/([^/]+)/issues/\d+/comments$`)
+)
+
+// issueCacheRoundTripper lets what was fetched about an issue less than
+// maxAge ago be answered by the cache without asking github, so that mungers
+// looking at the same issue in one loop don't fetch it again each. Anything
+// changed about an issue through the config drops it from the cache.
+type issueCacheRoundTripper struct {
+	cache    *httpCache
+	maxAge   time.Duration
+	delegate http.RoundTripper
+}
+
+func (r *issueCacheRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	delegate := r.delegate
+	if delegate == nil {
+		delegate = http.DefaultTransport
+	}
+	if req.Method == "GET" || req.Method == "HEAD" {
+		if m := issueRE.FindStringSubmatch(req.URL.Path); r.maxAge > 0 && m != nil && m[3] == "issues" {
+			req.Header.Set("Cache-Control", fmt.Sprintf("max-age=%d", int(r.maxAge/time.Second)))
+		}
+		return delegate.RoundTrip(req)
+	}
+	resp, err := delegate.RoundTrip(req)
+	r.invalidate(req.URL.Path)
+	return resp, err
+}
+
+// invalidate drops what the cache has about the issue changed by a request
+// for `path`.
+func (r *issueCacheRoundTripper) invalidate(path string) {
+	if m := issueRE.FindStringSubmatch(path); m != nil {
+		r.cache.invalidate(func(p string) bool {
+			c := issueRE.FindStringSubmatch(p)
+			return c != nil && c[1] == m[1] && c[2] == m[2] && c[4] == m[4]
+		})
+	} else if m := commentRE.FindStringSubmatch(path); m != nil {
+		r.cache.invalidate(func(p string) bool {
+			c := commentsRE.FindStringSubmatch(p)
+			return c != nil && c[1] == m[1] && c[2] == m[2]
+		})
+	}
+}
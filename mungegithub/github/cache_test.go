@@ -0,0 +1,148 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/gregjones/httpcache"
+)
+
+func TestHTTPCacheEviction(t *testing.T) {
+	c, _ := newHTTPCache(2, "")
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Get("a")
+	c.Set("c", []byte("3"))
+	for key, expected := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(key); ok != expected {
+			t.Errorf("expected %q cached: %v, got %v", key, expected, ok)
+		}
+	}
+}
+
+func TestHTTPCacheDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "http-cache")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	c, _ := newHTTPCache(1, dir)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Set("c", []byte("3"))
+	c.Delete("c")
+
+	restarted, err := newHTTPCache(1, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for key, expected := range map[string]string{"a": "1", "b": "2", "c": ""} {
+		if resp, _ := restarted.Get(key); string(resp) != expected {
+			t.Errorf("expected %q for %q, got %q", expected, key, resp)
+		}
+	}
+}
+
+func TestIssueCache(t *testing.T) {
+	requests := map[string]int{}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serve := func(path, etag, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			requests[r.Method+" "+r.URL.Path]++
+			if r.Method != "GET" {
+				w.Write([]byte(body))
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", "private, max-age=60")
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Write([]byte(body))
+		})
+	}
+	serve("/repos/o/r/issues/1", `"1"`, `{"number": 1}`)
+	serve("/repos/o/r/issues/1/labels", `"l"`, `[]`)
+	serve("/repos/o/r/issues/2", `"2"`, `{"number": 2}`)
+
+	tests := []struct {
+		name     string
+		maxAge   time.Duration
+		expected map[string]int
+	}{
+		{
+			name:   "revalidated",
+			maxAge: 0,
+			expected: map[string]int{
+				"GET /repos/o/r/issues/1":         3,
+				"GET /repos/o/r/issues/2":         2,
+				"POST /repos/o/r/issues/1/labels": 1,
+			},
+		},
+		{
+			name:   "fresh until changed",
+			maxAge: time.Minute,
+			expected: map[string]int{
+				"GET /repos/o/r/issues/1":         2,
+				"GET /repos/o/r/issues/2":         1,
+				"POST /repos/o/r/issues/1/labels": 1,
+			},
+		},
+	}
+	for _, test := range tests {
+		for k := range requests {
+			delete(requests, k)
+		}
+		cache, _ := newHTTPCache(0, "")
+		transport := httpcache.NewTransport(cache)
+		transport.Transport = http.DefaultTransport
+		client := github.NewClient(&http.Client{Transport: &zeroCacheRoundTripper{
+			delegate: &issueCacheRoundTripper{cache: cache, maxAge: test.maxAge, delegate: transport},
+		}})
+		client.BaseURL, _ = client.BaseURL.Parse(server.URL + "/")
+		config := &Config{Org: "o", Project: "r"}
+		config.SetClient(client)
+
+		for i := 0; i < 2; i++ {
+			for _, n := range []int{1, 2} {
+				if _, err := config.GetObject(n); err != nil {
+					t.Fatalf("%v: unexpected error: %v", test.name, err)
+				}
+			}
+		}
+		if _, _, err := client.Issues.AddLabelsToIssue("o", "r", 1, []string{"foo"}); err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		if _, err := config.GetObject(1); err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		if fmt.Sprint(requests) != fmt.Sprint(test.expected) {
+			t.Errorf("%v: expected requests %v, got %v", test.name, test.expected, requests)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// enterpriseAPIPath is where the REST API of GitHub Enterprise Server lives.
+const enterpriseAPIPath = "/api/v3/"
+
+// baseTransport is what the config talks to github with, before the cache
+// and authentication: through Proxy (or the proxy of the environment, e.g.
+// HTTPS_PROXY), trusting the certificates in CABundle if set.
+func (config *Config) baseTransport() (*http.Transport, error) {
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if config.Proxy != "" {
+		u, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %v", config.Proxy, err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	if config.CABundle != "" {
+		data, err := ioutil.ReadFile(config.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %v", config.CABundle)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return t, nil
+}
+
+// apiURLs returns the URLs of the API and of uploads, for BaseURL and
+// UploadURL. On GitHub Enterprise Server (a BaseURL ending in /api/v3/),
+// uploads default to /api/uploads/ of the same host.
+func (config *Config) apiURLs() (base, upload *url.URL, err error) {
+	if config.BaseURL == "" {
+		return nil, nil, nil
+	}
+	if base, err = url.Parse(strings.TrimRight(config.BaseURL, "/") + "/"); err != nil {
+		return nil, nil, fmt.Errorf("invalid API URL %q: %v", config.BaseURL, err)
+	}
+	switch {
+	case config.UploadURL != "":
+		if upload, err = url.Parse(strings.TrimRight(config.UploadURL, "/") + "/"); err != nil {
+			return nil, nil, fmt.Errorf("invalid upload URL %q: %v", config.UploadURL, err)
+		}
+	case strings.HasSuffix(base.Path, enterpriseAPIPath):
+		u := *base
+		u.Path = strings.TrimSuffix(base.Path, enterpriseAPIPath) + "/api/uploads/"
+		upload = &u
+	default:
+		upload = base
+	}
+	return base, upload, nil
+}
+
+// WebHost is the host issues are linked on: github.com, or the host of the
+// GitHub Enterprise Server at BaseURL.
+func (config *Config) WebHost() string {
+	base, _, err := config.apiURLs()
+	if err != nil || base == nil {
+		return "github.com"
+	}
+	if strings.HasSuffix(base.Path, enterpriseAPIPath) {
+		return base.Host
+	}
+	return strings.TrimPrefix(base.Host, "api.")
+}
+
+// graphQLURL is where GraphQL queries go: next to the REST API on
+// github.com, and to /api/graphql on GitHub Enterprise Server.
+func graphQLURL(base *url.URL) string {
+	if base != nil && strings.HasSuffix(base.Path, enterpriseAPIPath) {
+		u := *base
+		u.Path = strings.TrimSuffix(base.Path, enterpriseAPIPath) + "/api/graphql"
+		return u.String()
+	}
+	return "graphql"
+}
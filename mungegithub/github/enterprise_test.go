@@ -0,0 +1,146 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestAPIURLs(t *testing.T) {
+	tests := []struct {
+		base, upload             string
+		expectedBase, expectedUp string
+		expectedGraphQL          string
+		expectedWebHost          string
+	}{
+		{
+			base:            "https://ghe.example.com/api/v3",
+			expectedBase:    "https://ghe.example.com/api/v3/",
+			expectedUp:      "https://ghe.example.com/api/uploads/",
+			expectedGraphQL: "https://ghe.example.com/api/graphql",
+			expectedWebHost: "ghe.example.com",
+		},
+		{
+			base:            "https://ghe.example.com/api/v3/",
+			upload:          "https://uploads.example.com",
+			expectedBase:    "https://ghe.example.com/api/v3/",
+			expectedUp:      "https://uploads.example.com/",
+			expectedGraphQL: "https://ghe.example.com/api/graphql",
+			expectedWebHost: "ghe.example.com",
+		},
+		{
+			base:            "https://api.github.com/",
+			expectedBase:    "https://api.github.com/",
+			expectedUp:      "https://api.github.com/",
+			expectedGraphQL: "graphql",
+			expectedWebHost: "github.com",
+		},
+	}
+	for _, test := range tests {
+		config := &Config{BaseURL: test.base, UploadURL: test.upload}
+		base, upload, err := config.apiURLs()
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.base, err)
+			continue
+		}
+		if base.String() != test.expectedBase || upload.String() != test.expectedUp {
+			t.Errorf("%v: expected %v and %v, got %v and %v", test.base, test.expectedBase, test.expectedUp, base, upload)
+		}
+		if got := graphQLURL(base); got != test.expectedGraphQL {
+			t.Errorf("%v: expected GraphQL at %v, got %v", test.base, test.expectedGraphQL, got)
+		}
+		if got := config.WebHost(); got != test.expectedWebHost {
+			t.Errorf("%v: expected issues on %v, got %v", test.base, test.expectedWebHost, got)
+		}
+	}
+	if base, _, err := (&Config{}).apiURLs(); base != nil || err != nil {
+		t.Errorf("expected github.com by default, got %v %v", base, err)
+	}
+	if host := (&Config{}).WebHost(); host != "github.com" {
+		t.Errorf("expected issues on github.com by default, got %v", host)
+	}
+}
+
+func serveEnterprise(t *testing.T, mux *http.ServeMux) {
+	mux.HandleFunc("/api/v3/repos/o/r/issues/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"number": 1, "title": "TestFoo"}`))
+	})
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"repository": {"i1": {"number": 1, "title": "TestFoo", "state": "OPEN"}}}}`))
+	})
+}
+
+func TestEnterpriseCABundle(t *testing.T) {
+	mux := http.NewServeMux()
+	serveEnterprise(t, mux)
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	bundle, err := ioutil.TempFile("", "ca-bundle")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(bundle.Name())
+	pem.Encode(bundle, &pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]})
+	bundle.Close()
+
+	untrusted := &Config{Org: "o", Project: "r", BaseURL: server.URL + "/api/v3/"}
+	if err := untrusted.PreExecute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := untrusted.GetObject(1); err == nil {
+		t.Errorf("expected the server's certificate not to be trusted without the bundle")
+	}
+
+	config := &Config{Org: "o", Project: "r", BaseURL: server.URL + "/api/v3/", CABundle: bundle.Name()}
+	if err := config.PreExecute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj, err := config.GetObject(1); err != nil || *obj.Issue.Title != "TestFoo" {
+		t.Errorf("unexpected object %v: %v", obj, err)
+	}
+	if objs, _, err := config.GetObjects([]int{1}, 1); err != nil || len(objs) != 1 {
+		t.Errorf("expected GraphQL queries to go to /api/graphql, got %v: %v", objs, err)
+	}
+}
+
+func TestEnterpriseProxy(t *testing.T) {
+	hosts := []string{}
+	mux := http.NewServeMux()
+	serveEnterprise(t, mux)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.URL.Host)
+		mux.ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+
+	config := &Config{Org: "o", Project: "r", BaseURL: "http://ghe.invalid/api/v3/", Proxy: proxy.URL}
+	if err := config.PreExecute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := config.GetObject(1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0] != "ghe.invalid" {
+		t.Errorf("expected the request to go through the proxy, got %v", hosts)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	goflag "flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"net/http"
@@ -37,6 +38,7 @@ import (
 	"github.com/google/go-github/github"
 	"github.com/gregjones/httpcache"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 )
 
@@ -103,7 +105,9 @@ func (c *callLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, er
 	resp, err := c.delegate.RoundTrip(req)
 	c.Lock()
 	defer c.Unlock()
-	if resp != nil {
+	// The search API has its own, much smaller, rate limit. Don't let its
+	// headers convince us we're out of regular tokens.
+	if resp != nil && !strings.HasPrefix(req.URL.Path, "/search/") {
 		if remaining := resp.Header.Get(headerRateRemaining); remaining != "" {
 			c.remaining, _ = strconv.Atoi(remaining)
 		}
@@ -139,11 +143,60 @@ func (r *zeroCacheRoundTripper) RoundTrip(req *http.Request) (*http.Response, er
 	return delegate.RoundTrip(req)
 }
 
+// contextRoundTripper cancels requests when ctx is done, or when they take
+// longer than timeout (if non-zero).
+type contextRoundTripper struct {
+	ctx      context.Context
+	timeout  time.Duration
+	delegate http.RoundTripper
+}
+
+func (r *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if r.timeout > 0 {
+		ctx, cancel = context.WithTimeout(r.ctx, r.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(r.ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		cancel()
+		return nil, err
+	}
+	// Request.Cancel rather than Request.WithContext, which needs go1.7.
+	// RoundTrippers must not modify the request, so make a copy.
+	clone := *req
+	clone.Cancel = ctx.Done()
+	resp, err := r.delegate.RoundTrip(&clone)
+	if err != nil {
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	// The body is still to be read; cancel once it's closed.
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
 // Config is how we are configured to talk to github and provides access
 // methods for doing so.
 type Config struct {
 	client   *github.Client
 	apiLimit *callLimitRoundTripper
+	// transport is what client talks to github with.
+	transport http.RoundTripper
 	Org      string
 	Project  string
 
@@ -153,6 +206,25 @@ type Config struct {
 	Token     string
 	TokenFile string
 
+	// AppID and AppKeyFile, if set, authenticate as an installation of a
+	// GitHub App instead of with Token, see AppTokenSource.
+	AppID           int
+	AppKeyFile      string
+	AppInstallation int
+	AppRepoScoped   bool
+
+	// BaseURL and UploadURL, if set, are where the API is instead of
+	// github.com, e.g. https://ghe.example.com/api/v3/ for GitHub
+	// Enterprise Server. UploadURL is derived from BaseURL if unset.
+	BaseURL   string
+	UploadURL string
+	// CABundle, if set, is a PEM file of the certificates to trust
+	// instead of the system's, e.g. a company's internal CA.
+	CABundle string
+	// Proxy, if set, is the proxy to talk to github through. Otherwise the
+	// proxy of the environment (HTTPS_PROXY) is used.
+	Proxy string
+
 	Address string // if a munger runs a web server, where it should live
 	WWWRoot string
 
@@ -166,10 +238,19 @@ type Config struct {
 	PendingWaitTime *time.Duration
 
 	useMemoryCache bool
+	// httpCacheSize, httpCacheDir and httpCacheMaxAge configure the cache,
+	// see httpCache and issueCacheRoundTripper.
+	httpCacheSize   int
+	httpCacheDir    string
+	httpCacheMaxAge time.Duration
 
 	// When we clear analytics we store the last values here
 	lastAnalytics analytics
-	analytics     analytics
+	// A pointer, so that copies made by WithContext count towards it.
+	analytics *analytics
+
+	// mirror, if set, gets the changes instead, see MirrorTo.
+	mirror *mirror
 }
 
 type analytic struct {
@@ -217,6 +298,24 @@ type analytics struct {
 	GetUser           analytic
 	SetMilestone      analytic
 	ListMilestones    analytic
+	ListLabels        analytic
+	DeleteLabel       analytic
+	EditComment       analytic
+	SearchIssues      analytic
+	ListAdvisories    analytic
+	CreateAdvisory    analytic
+	ReopenIssue       analytic
+	EditIssue         analytic
+	ListProjects      analytic
+	ListColumns       analytic
+	CreateCard        analytic
+	MoveCard          analytic
+	GetBranch         analytic
+	CreateLabel       analytic
+	AddReaction       analytic
+	LockIssue         analytic
+	CreateGist        analytic
+	GraphQL           analytic
 }
 
 func (a analytics) print() {
@@ -249,6 +348,24 @@ func (a analytics) print() {
 	fmt.Fprintf(w, "GetUser\t%d\t\n", a.GetUser.Count)
 	fmt.Fprintf(w, "SetMilestone\t%d\t\n", a.SetMilestone.Count)
 	fmt.Fprintf(w, "ListMilestones\t%d\t\n", a.ListMilestones.Count)
+	fmt.Fprintf(w, "ListLabels\t%d\t\n", a.ListLabels.Count)
+	fmt.Fprintf(w, "DeleteLabel\t%d\t\n", a.DeleteLabel.Count)
+	fmt.Fprintf(w, "EditComment\t%d\t\n", a.EditComment.Count)
+	fmt.Fprintf(w, "SearchIssues\t%d\t\n", a.SearchIssues.Count)
+	fmt.Fprintf(w, "ListAdvisories\t%d\t\n", a.ListAdvisories.Count)
+	fmt.Fprintf(w, "CreateAdvisory\t%d\t\n", a.CreateAdvisory.Count)
+	fmt.Fprintf(w, "ReopenIssue\t%d\t\n", a.ReopenIssue.Count)
+	fmt.Fprintf(w, "EditIssue\t%d\t\n", a.EditIssue.Count)
+	fmt.Fprintf(w, "ListProjects\t%d\t\n", a.ListProjects.Count)
+	fmt.Fprintf(w, "ListColumns\t%d\t\n", a.ListColumns.Count)
+	fmt.Fprintf(w, "CreateCard\t%d\t\n", a.CreateCard.Count)
+	fmt.Fprintf(w, "MoveCard\t%d\t\n", a.MoveCard.Count)
+	fmt.Fprintf(w, "GetBranch\t%d\t\n", a.GetBranch.Count)
+	fmt.Fprintf(w, "CreateLabel\t%d\t\n", a.CreateLabel.Count)
+	fmt.Fprintf(w, "AddReaction\t%d\t\n", a.AddReaction.Count)
+	fmt.Fprintf(w, "LockIssue\t%d\t\n", a.LockIssue.Count)
+	fmt.Fprintf(w, "CreateGist\t%d\t\n", a.CreateGist.Count)
+	fmt.Fprintf(w, "GraphQL\t%d\t\n", a.GraphQL.Count)
 	w.Flush()
 	glog.V(2).Infof("\n%v", buf)
 }
@@ -295,10 +412,21 @@ func TestObject(config *Config, issue *github.Issue, pr *github.PullRequest, com
 func (config *Config) AddRootFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().StringVar(&config.Token, "token", "", "The OAuth Token to use for requests.")
 	cmd.PersistentFlags().StringVar(&config.TokenFile, "token-file", "", "The file containing the OAuth Token to use for requests.")
+	cmd.PersistentFlags().IntVar(&config.AppID, "app-id", 0, "If set, authenticate as an installation of this GitHub App instead of with --token, using --app-key-file")
+	cmd.PersistentFlags().StringVar(&config.AppKeyFile, "app-key-file", "", "The file containing the PEM private key of --app-id")
+	cmd.PersistentFlags().IntVar(&config.AppInstallation, "app-installation", 0, "The installation of --app-id to act as. If unset, the installation on --organization/--project is used")
+	cmd.PersistentFlags().BoolVar(&config.AppRepoScoped, "app-repo-scoped", false, "If true, limit the tokens of --app-id to --project, even if the installation covers more repos")
+	cmd.PersistentFlags().StringVar(&config.BaseURL, "github-url", "", "If set, the URL of the GitHub API, e.g. https://ghe.example.com/api/v3/ for GitHub Enterprise Server")
+	cmd.PersistentFlags().StringVar(&config.UploadURL, "github-upload-url", "", "If set, the URL of the GitHub upload API. Defaults to /api/uploads/ next to a --github-url ending in /api/v3/")
+	cmd.PersistentFlags().StringVar(&config.CABundle, "github-ca-bundle", "", "If set, a PEM file of the CA certificates to trust instead of the system's when talking to github")
+	cmd.PersistentFlags().StringVar(&config.Proxy, "github-proxy", "", "If set, the proxy to talk to github through, e.g. http://proxy.example.com:3128. Defaults to HTTPS_PROXY")
 	cmd.PersistentFlags().IntVar(&config.MinPRNumber, "min-pr-number", 0, "The minimum PR to start with")
 	cmd.PersistentFlags().IntVar(&config.MaxPRNumber, "max-pr-number", maxInt, "The maximum PR to start with")
 	cmd.PersistentFlags().BoolVar(&config.DryRun, "dry-run", true, "If true, don't actually merge anything")
 	cmd.PersistentFlags().BoolVar(&config.useMemoryCache, "use-http-cache", true, "If true, use a client side HTTP cache for API requests.")
+	cmd.PersistentFlags().IntVar(&config.httpCacheSize, "http-cache-size", 20000, "How many responses the HTTP cache keeps in memory, least recently used ones are evicted first. 0 is unbounded")
+	cmd.PersistentFlags().StringVar(&config.httpCacheDir, "http-cache-dir", "", "If set, the HTTP cache keeps responses in this directory as well, so that they are revalidated by ETag instead of fetched again after a restart")
+	cmd.PersistentFlags().DurationVar(&config.httpCacheMaxAge, "http-cache-max-age", 0, "If set, issues and their comments fetched less than this long ago are answered by the HTTP cache without asking github. Changes made through the bot drop an issue from the cache")
 	cmd.PersistentFlags().StringVar(&config.Org, "organization", "kubernetes", "The github organization to scan")
 	cmd.PersistentFlags().StringVar(&config.Project, "project", "kubernetes", "The github project to scan")
 	cmd.PersistentFlags().StringVar(&config.state, "state", "open", "State of PRs to process: 'open', 'all', etc")
@@ -330,31 +458,72 @@ func (config *Config) PreExecute() error {
 	// We need to get our Transport/RoundTripper in order based on arguments
 	//    oauth2 Transport // if we have an auth token
 	//    zeroCacheRoundTripper // if we are using the cache want faster timeouts
+	//    issueCacheRoundTripper // if we are using the cache
 	//    webCacheRoundTripper // if we are using the cache
 	//    callLimitRoundTripper ** always
-	//    [http.DefaultTransport] ** always implicit
+	//    baseTransport ** always, with --github-proxy and --github-ca-bundle
 
 	var transport http.RoundTripper
 
+	base, err := config.baseTransport()
+	if err != nil {
+		return err
+	}
+	baseURL, uploadURL, err := config.apiURLs()
+	if err != nil {
+		return err
+	}
 	callLimitTransport := &callLimitRoundTripper{
 		remaining: tokenLimit + 500, // put in 500 so we at least have a couple to check our real limits
 		resetTime: time.Now().Add(1 * time.Minute),
+		delegate:  base,
 	}
 	config.apiLimit = callLimitTransport
 	transport = callLimitTransport
 
 	if config.useMemoryCache {
-		t := httpcache.NewMemoryCacheTransport()
+		cache, err := newHTTPCache(config.httpCacheSize, config.httpCacheDir)
+		if err != nil {
+			return fmt.Errorf("error setting up the HTTP cache: %v", err)
+		}
+		t := httpcache.NewTransport(cache)
 		t.Transport = transport
 
 		zeroCacheTransport := &zeroCacheRoundTripper{
-			delegate: t,
+			delegate: &issueCacheRoundTripper{
+				cache:    cache,
+				maxAge:   config.httpCacheMaxAge,
+				delegate: t,
+			},
 		}
 
 		transport = zeroCacheTransport
 	}
 
-	if len(token) > 0 {
+	if config.AppID != 0 {
+		key, err := LoadAppKey(config.AppKeyFile)
+		if err != nil {
+			return fmt.Errorf("error reading --app-key-file: %v", err)
+		}
+		ts := &AppTokenSource{
+			AppID:          config.AppID,
+			Key:            key,
+			InstallationID: config.AppInstallation,
+			Org:            config.Org,
+			Project:        config.Project,
+			Client:         &http.Client{Transport: base},
+		}
+		if baseURL != nil {
+			ts.BaseURL = baseURL.String()
+		}
+		if config.AppRepoScoped {
+			ts.Repositories = []string{config.Project}
+		}
+		transport = &oauth2.Transport{
+			Base:   transport,
+			Source: oauth2.ReuseTokenSource(nil, ts),
+		}
+	} else if len(token) > 0 {
 		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 		transport = &oauth2.Transport{
 			Base:   transport,
@@ -365,7 +534,11 @@ func (config *Config) PreExecute() error {
 	client := &http.Client{
 		Transport: transport,
 	}
+	config.transport = transport
 	config.client = github.NewClient(client)
+	if baseURL != nil {
+		config.client.BaseURL, config.client.UploadURL = baseURL, uploadURL
+	}
 	config.ResetAPICount()
 	return nil
 }
@@ -387,6 +560,17 @@ func (config *Config) GetDebugStats() DebugStats {
 	return d
 }
 
+// APIRemaining returns how many API calls github last said we have left. It
+// returns false if we aren't tracking the rate limit, e.g. in tests.
+func (config *Config) APIRemaining() (int, bool) {
+	if config.apiLimit == nil {
+		return 0, false
+	}
+	config.apiLimit.Lock()
+	defer config.apiLimit.Unlock()
+	return config.apiLimit.remaining, true
+}
+
 func (config *Config) serveDebugStats(res http.ResponseWriter, req *http.Request) {
 	stats := config.GetDebugStats()
 	b, err := json.Marshal(stats)
@@ -415,18 +599,24 @@ func (config *Config) NextExpectedUpdate(t time.Time) {
 // ResetAPICount will both reset the counters of how many api calls have been
 // made but will also print the information from the last run.
 func (config *Config) ResetAPICount() {
+	if config.analytics == nil {
+		config.analytics = &analytics{}
+	}
 	since := time.Since(config.analytics.lastAPIReset)
 	config.analytics.apiPerSec = float64(config.analytics.apiCount) / since.Seconds()
-	config.lastAnalytics = config.analytics
+	config.lastAnalytics = *config.analytics
 	config.analytics.print()
 
-	config.analytics = analytics{}
+	*config.analytics = analytics{}
 	config.analytics.lastAPIReset = time.Now()
 }
 
 // SetClient should ONLY be used by testing. Normal commands should use PreExecute()
 func (config *Config) SetClient(client *github.Client) {
 	config.client = client
+	if config.analytics == nil {
+		config.analytics = &analytics{}
+	}
 }
 
 func (config *Config) getPR(num int) (*github.PullRequest, error) {
@@ -482,8 +672,364 @@ func (config *Config) ListMilestones(state string) []github.Milestone {
 	return milestones
 }
 
+// ListLabels returns all of the labels defined in the repo
+func (config *Config) ListLabels() ([]github.Label, error) {
+	page := 1
+	var result []github.Label
+	for {
+		glog.V(4).Infof("Fetching page %d of labels", page)
+		listOpts := &github.ListOptions{PerPage: 100, Page: page}
+		labels, response, err := config.client.Issues.ListLabels(config.Org, config.Project, listOpts)
+		config.analytics.ListLabels.Call(config, response)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, labels...)
+		if response.LastPage == 0 || response.LastPage <= page {
+			break
+		}
+		page++
+	}
+	return result, nil
+}
+
+// CreateLabel adds the label `name` to the repo, with `color` (like
+// "ededed") and `description`, which the vendored client doesn't know about.
+func (config *Config) CreateLabel(name, color, description string) error {
+	if config.mirror != nil {
+		return config.mirror.staging.CreateLabel(name, color, description)
+	}
+	config.analytics.CreateLabel.Call(config, nil)
+	glog.Infof("Creating label %q", name)
+	if config.DryRun {
+		return nil
+	}
+	u := fmt.Sprintf("repos/%v/%v/labels", config.Org, config.Project)
+	req, err := config.client.NewRequest("POST", u, map[string]string{
+		"name":        name,
+		"color":       color,
+		"description": description,
+	})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.symmetra-preview+json")
+	if _, err := config.client.Do(req, nil); err != nil {
+		glog.Errorf("Error creating label %q: %v", name, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteLabel removes the label `name` from the repo, and thus from every
+// issue which has it applied
+func (config *Config) DeleteLabel(name string) error {
+	if config.mirror != nil {
+		return config.mirror.staging.DeleteLabel(name)
+	}
+	config.analytics.DeleteLabel.Call(config, nil)
+	glog.Infof("Deleting label %q", name)
+	if config.DryRun {
+		return nil
+	}
+	if _, err := config.client.Issues.DeleteLabel(config.Org, config.Project, name); err != nil {
+		glog.Errorf("Error deleting label %q: %v", name, err)
+		return err
+	}
+	return nil
+}
+
+// SearchIssues returns all issues (and PRs) matching the github search `query`
+func (config *Config) SearchIssues(query string) ([]github.Issue, error) {
+	page := 1
+	var result []github.Issue
+	for {
+		glog.V(4).Infof("Fetching page %d of issues matching %q", page, query)
+		searchOpts := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 100, Page: page}}
+		issues, response, err := config.client.Search.Issues(query, searchOpts)
+		config.analytics.SearchIssues.Call(config, response)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, issues.Issues...)
+		if response.LastPage == 0 || response.LastPage <= page {
+			break
+		}
+		page++
+	}
+	if config.mirror != nil {
+		return config.searchFiled(query, result)
+	}
+	return result, nil
+}
+
+// ListCommitsBetween returns the commits on `branch` committed between
+// `since` and `until`, newest first.
+func (config *Config) ListCommitsBetween(branch string, since, until time.Time) ([]github.RepositoryCommit, error) {
+	page := 1
+	var result []github.RepositoryCommit
+	for {
+		glog.V(4).Infof("Fetching page %d of commits on %v", page, branch)
+		opts := &github.CommitsListOptions{SHA: branch, Since: since, Until: until, ListOptions: github.ListOptions{PerPage: 100, Page: page}}
+		commits, response, err := config.client.Repositories.ListCommits(config.Org, config.Project, opts)
+		config.analytics.ListCommits.Call(config, response)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, commits...)
+		if response.LastPage == 0 || response.LastPage <= page {
+			break
+		}
+		page++
+	}
+	return result, nil
+}
+
+// ListFileCommits returns the commits on `branch` which touched `path` since
+// `since`, newest first.
+func (config *Config) ListFileCommits(branch, path string, since time.Time) ([]github.RepositoryCommit, error) {
+	page := 1
+	var result []github.RepositoryCommit
+	for {
+		glog.V(4).Infof("Fetching page %d of commits to %v on %v", page, path, branch)
+		opts := &github.CommitsListOptions{SHA: branch, Path: path, Since: since, ListOptions: github.ListOptions{PerPage: 100, Page: page}}
+		commits, response, err := config.client.Repositories.ListCommits(config.Org, config.Project, opts)
+		config.analytics.ListCommits.Call(config, response)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, commits...)
+		if response.LastPage == 0 || response.LastPage <= page {
+			break
+		}
+		page++
+	}
+	return result, nil
+}
+
+// WithContext returns a copy of the config whose requests are canceled when
+// ctx is done. If timeout is non-zero, it is the deadline for each request.
+func (config *Config) WithContext(ctx context.Context, timeout time.Duration) *Config {
+	c := *config
+	transport := config.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	client := github.NewClient(&http.Client{
+		Transport: &contextRoundTripper{ctx: ctx, timeout: timeout, delegate: transport},
+	})
+	client.BaseURL = config.client.BaseURL
+	client.UploadURL = config.client.UploadURL
+	client.UserAgent = config.client.UserAgent
+	c.client = client
+	if config.mirror != nil {
+		c.mirror = &mirror{staging: config.mirror.staging.WithContext(ctx, timeout), mirrorState: config.mirror.mirrorState}
+	}
+	return &c
+}
+
+// ForRepo returns a copy of the config which talks to `org`/`project` with
+// the same client and settings.
+func (config *Config) ForRepo(org, project string) *Config {
+	c := *config
+	c.Org = org
+	c.Project = project
+	return &c
+}
+
+// SecurityAdvisory is a github repository security advisory. The vendored
+// client doesn't know about them.
+type SecurityAdvisory struct {
+	GHSAID      *string `json:"ghsa_id,omitempty"`
+	Summary     *string `json:"summary,omitempty"`
+	Description *string `json:"description,omitempty"`
+	State       *string `json:"state,omitempty"`
+	HTMLURL     *string `json:"html_url,omitempty"`
+}
+
+// ListSecurityAdvisories returns the repository's security advisories in
+// `state` (e.g. "draft"), or in any state if `state` is empty.
+func (config *Config) ListSecurityAdvisories(state string) ([]SecurityAdvisory, error) {
+	page := 1
+	var result []SecurityAdvisory
+	for {
+		glog.V(4).Infof("Fetching page %d of security advisories", page)
+		u := fmt.Sprintf("repos/%v/%v/security-advisories?per_page=100&page=%d", config.Org, config.Project, page)
+		if state != "" {
+			u += "&state=" + state
+		}
+		req, err := config.client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		advisories := []SecurityAdvisory{}
+		response, err := config.client.Do(req, &advisories)
+		config.analytics.ListAdvisories.Call(config, response)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, advisories...)
+		if response.LastPage == 0 || response.LastPage <= page {
+			break
+		}
+		page++
+	}
+	return result, nil
+}
+
+// CreateSecurityAdvisory files a draft security advisory, which only the
+// repository's admins and security managers can see.
+func (config *Config) CreateSecurityAdvisory(summary, description string) (*SecurityAdvisory, error) {
+	if config.mirror != nil {
+		return config.mirror.staging.CreateSecurityAdvisory(summary, description)
+	}
+	config.analytics.CreateAdvisory.Call(config, nil)
+	glog.Infof("Creating draft security advisory %q", summary)
+	if config.DryRun {
+		return nil, fmt.Errorf("can't make security advisories in dry-run mode")
+	}
+	u := fmt.Sprintf("repos/%v/%v/security-advisories", config.Org, config.Project)
+	req, err := config.client.NewRequest("POST", u, &SecurityAdvisory{
+		Summary:     &summary,
+		Description: &description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	advisory := &SecurityAdvisory{}
+	if _, err := config.client.Do(req, advisory); err != nil {
+		glog.Errorf("createSecurityAdvisory: %v", err)
+		return nil, err
+	}
+	return advisory, nil
+}
+
+// projectsPreview is the media type of the (preview) projects API.
+const projectsPreview = "application/vnd.github.inertia-preview+json"
+
+// Project is a github project board. The vendored client doesn't know about
+// them, nor about ProjectColumn and ProjectCard.
+type Project struct {
+	ID   *int    `json:"id,omitempty"`
+	Name *string `json:"name,omitempty"`
+}
+
+// ProjectColumn is a column of a project board.
+type ProjectColumn struct {
+	ID   *int    `json:"id,omitempty"`
+	Name *string `json:"name,omitempty"`
+}
+
+// ProjectCard is a card on a project board.
+type ProjectCard struct {
+	ID *int `json:"id,omitempty"`
+}
+
+// ListProjects returns the repository's project boards.
+func (config *Config) ListProjects() ([]Project, error) {
+	u := fmt.Sprintf("repos/%v/%v/projects?per_page=100", config.Org, config.Project)
+	req, err := config.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", projectsPreview)
+	projects := []Project{}
+	response, err := config.client.Do(req, &projects)
+	config.analytics.ListProjects.Call(config, response)
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// ListProjectColumns returns the columns of project board `id`.
+func (config *Config) ListProjectColumns(id int) ([]ProjectColumn, error) {
+	req, err := config.client.NewRequest("GET", fmt.Sprintf("projects/%d/columns?per_page=100", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", projectsPreview)
+	columns := []ProjectColumn{}
+	response, err := config.client.Do(req, &columns)
+	config.analytics.ListColumns.Call(config, response)
+	if err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// AddToProject puts a card for the issue in project column `column`.
+func (obj *MungeObject) AddToProject(column int) (*ProjectCard, error) {
+	config := obj.config
+	prNum := *obj.Issue.Number
+	config.analytics.CreateCard.Call(config, nil)
+	glog.Infof("Adding %d to project column %d", prNum, column)
+	if config.readOnly() {
+		return nil, fmt.Errorf("can't make project cards in dry-run or mirror mode")
+	}
+	// Cards refer to the issue's id, which the vendored client drops.
+	req, err := config.client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/issues/%d", config.Org, config.Project, prNum), nil)
+	if err != nil {
+		return nil, err
+	}
+	issue := struct {
+		ID *int `json:"id,omitempty"`
+	}{}
+	response, err := config.client.Do(req, &issue)
+	config.analytics.GetIssue.Call(config, response)
+	if err != nil {
+		return nil, err
+	}
+	if issue.ID == nil {
+		return nil, fmt.Errorf("github returned no id for issue %d", prNum)
+	}
+	req, err = config.client.NewRequest("POST", fmt.Sprintf("projects/columns/%d/cards", column), map[string]interface{}{
+		"content_id":   *issue.ID,
+		"content_type": "Issue",
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", projectsPreview)
+	card := &ProjectCard{}
+	if _, err := config.client.Do(req, card); err != nil {
+		glog.Errorf("Failed to add %d to project column %d: %v", prNum, column, err)
+		return nil, err
+	}
+	return card, nil
+}
+
+// MoveProjectCard moves card `card` to the top of project column `column`.
+func (config *Config) MoveProjectCard(card, column int) error {
+	config.analytics.MoveCard.Call(config, nil)
+	glog.Infof("Moving project card %d to column %d", card, column)
+	if config.readOnly() {
+		return nil
+	}
+	req, err := config.client.NewRequest("POST", fmt.Sprintf("projects/columns/cards/%d/moves", card), map[string]interface{}{
+		"position":  "top",
+		"column_id": column,
+	})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", projectsPreview)
+	if _, err := config.client.Do(req, nil); err != nil {
+		glog.Errorf("Failed to move project card %d to column %d: %v", card, column, err)
+		return err
+	}
+	return nil
+}
+
 // GetObject will return an object (with only the issue filled in)
 func (config *Config) GetObject(num int) (*MungeObject, error) {
+	if config.mirror != nil && config.filed(num) {
+		issue, err := config.mirror.staging.getIssue(num - stagedNumberBase)
+		if err != nil {
+			return nil, err
+		}
+		return config.IssueObject(staged(issue)), nil
+	}
 	issue, err := config.getIssue(num)
 	if err != nil {
 		return nil, err
@@ -493,6 +1039,11 @@ func (config *Config) GetObject(num int) (*MungeObject, error) {
 		Issue:       issue,
 		Annotations: map[string]string{},
 	}
+	if config.mirror != nil {
+		if err := config.overlayMirror(obj); err != nil {
+			return nil, err
+		}
+	}
 	return obj, nil
 }
 
@@ -501,6 +1052,9 @@ func (config *Config) NewIssue(title, body string, labels []string) (*MungeObjec
 	if config.DryRun {
 		return nil, fmt.Errorf("can't make issues in dry-run mode")
 	}
+	if config.mirror != nil {
+		return config.fileInStaging(title, body, labels)
+	}
 	issue, resp, err := config.client.Issues.Create(config.Org, config.Project, &github.IssueRequest{
 		Title:  &title,
 		Body:   &body,
@@ -669,6 +1223,9 @@ func (obj *MungeObject) AddLabels(labels []string) error {
 		}
 		obj.Issue.Labels = append(obj.Issue.Labels, label)
 	}
+	if config.mirror != nil {
+		return obj.inMirror(func(m *MungeObject) error { return m.AddLabels(labels) })
+	}
 	if _, _, err := config.client.Issues.AddLabelsToIssue(config.Org, config.Project, prNum, labels); err != nil {
 		glog.Errorf("Failed to set labels %v for %d: %v", labels, prNum, err)
 		return err
@@ -702,6 +1259,14 @@ func (obj *MungeObject) RemoveLabel(label string) error {
 	if config.DryRun {
 		return nil
 	}
+	if config.mirror != nil {
+		return obj.inMirror(func(m *MungeObject) error {
+			if !m.HasLabel(label) {
+				return nil
+			}
+			return m.RemoveLabel(label)
+		})
+	}
 	if _, err := config.client.Issues.RemoveLabelForIssue(config.Org, config.Project, prNum, label); err != nil {
 		glog.Errorf("Failed to remove %v from issue %d: %v", label, prNum, err)
 		return err
@@ -744,6 +1309,9 @@ func (obj *MungeObject) GetSHAFromRef(ref string) (sha string, ok bool) {
 
 // SetMilestone will set the milestone to the value specified
 func (obj *MungeObject) SetMilestone(title string) error {
+	if obj.config.mirror != nil {
+		return obj.inMirror(func(m *MungeObject) error { return m.SetMilestone(title) })
+	}
 	milestones := obj.config.ListMilestones("all")
 
 	var milestone *github.Milestone
@@ -776,6 +1344,27 @@ func (obj *MungeObject) SetMilestone(title string) error {
 	return nil
 }
 
+// EditBody will replace the body of the issue with `body`
+func (obj *MungeObject) EditBody(body string) error {
+	config := obj.config
+	config.analytics.EditIssue.Call(config, nil)
+	glog.Infof("Editing the body of issue #%d", *obj.Issue.Number)
+	if config.mirror != nil {
+		mirrored := config.mirrorBody(*obj.Issue.Number, body)
+		if err := obj.inMirror(func(m *MungeObject) error { return m.EditBody(mirrored) }); err != nil {
+			return err
+		}
+	} else if !config.DryRun {
+		request := &github.IssueRequest{Body: &body}
+		if _, _, err := config.client.Issues.Edit(config.Org, config.Project, *obj.Issue.Number, request); err != nil {
+			glog.Errorf("Failed to edit the body of issue %d: %v", *obj.Issue.Number, err)
+			return err
+		}
+	}
+	obj.Issue.Body = &body
+	return nil
+}
+
 // ReleaseMilestone returns the name of the 'release' milestone or an empty string
 // if none found. Release milestones are determined by the format "vX.Y"
 func (obj *MungeObject) ReleaseMilestone() string {
@@ -1026,7 +1615,7 @@ func (obj *MungeObject) SetStatus(state, url, description, context string) error
 	ref := *pr.Head.SHA
 	glog.Infof("PR %d setting %q Github status to %q", *obj.Issue.Number, context, description)
 	config.analytics.SetStatus.Call(config, nil)
-	if config.DryRun {
+	if config.readOnly() {
 		return nil
 	}
 	_, _, err = config.client.Repositories.CreateStatus(config.Org, config.Project, ref, status)
@@ -1036,6 +1625,60 @@ func (obj *MungeObject) SetStatus(state, url, description, context string) error
 	return err
 }
 
+// SetBranchStatus sets a github status on the commit at the head of
+// `branch`, e.g. to publish a bot's own health.
+func (config *Config) SetBranchStatus(branch, state, url, description, context string) error {
+	b, response, err := config.client.Repositories.GetBranch(config.Org, config.Project, branch)
+	config.analytics.GetBranch.Call(config, response)
+	if err != nil {
+		return err
+	}
+	if b.Commit == nil || b.Commit.SHA == nil {
+		return fmt.Errorf("github returned no commit for branch %v", branch)
+	}
+	ref := *b.Commit.SHA
+	glog.Infof("Setting %q Github status of %v to %q", context, branch, description)
+	config.analytics.SetStatus.Call(config, nil)
+	if config.readOnly() {
+		return nil
+	}
+	_, _, err = config.client.Repositories.CreateStatus(config.Org, config.Project, ref, &github.RepoStatus{
+		State:       &state,
+		TargetURL:   &url,
+		Description: &description,
+		Context:     &context,
+	})
+	if err != nil {
+		glog.Errorf("Unable to set status. Branch %v Ref: %q: %v", branch, ref, err)
+	}
+	return err
+}
+
+// CreateGist creates a secret gist of a single file, and returns its URL.
+func (config *Config) CreateGist(description, filename, content string) (string, error) {
+	glog.Infof("Creating gist %q", description)
+	config.analytics.CreateGist.Call(config, nil)
+	if config.DryRun {
+		return "", nil
+	}
+	public := false
+	gist, _, err := config.client.Gists.Create(&github.Gist{
+		Description: &description,
+		Public:      &public,
+		Files: map[github.GistFilename]github.GistFile{
+			github.GistFilename(filename): {Content: &content},
+		},
+	})
+	if err != nil {
+		glog.Errorf("Unable to create gist %q: %v", description, err)
+		return "", err
+	}
+	if gist.HTMLURL == nil {
+		return "", nil
+	}
+	return *gist.HTMLURL, nil
+}
+
 // GetStatus returns the actual requested status, or nil if not found
 func (obj *MungeObject) GetStatus(context string) *github.RepoStatus {
 	combinedStatus := obj.getCombinedStatus()
@@ -1215,6 +1858,9 @@ func (obj *MungeObject) GetPR() (*github.PullRequest, error) {
 
 // AssignPR will assign `prNum` to the `owner` where the `owner` is asignee's github login
 func (obj *MungeObject) AssignPR(owner string) error {
+	if obj.config.mirror != nil {
+		return obj.inMirror(func(m *MungeObject) error { return m.AssignPR(owner) })
+	}
 	config := obj.config
 	prNum := *obj.Issue.Number
 	assignee := &github.IssueRequest{Assignee: &owner}
@@ -1232,20 +1878,108 @@ func (obj *MungeObject) AssignPR(owner string) error {
 
 // CloseIssuef will close the given issue with a message
 func (obj *MungeObject) CloseIssuef(format string, args ...interface{}) error {
-	config := obj.config
 	msg := fmt.Sprintf(format, args...)
 	if err := obj.WriteComment(msg); err != nil {
 		return fmt.Errorf("failed to write comment to %v: %q: %v", *obj.Issue.Number, msg, err)
 	}
+	glog.Infof("Closing issue #%d: %v", *obj.Issue.Number, msg)
+	return obj.CloseIssue()
+}
+
+// AddReaction reacts to the issue with `content`, e.g. "eyes". Reacting
+// twice with the same content does nothing.
+func (obj *MungeObject) AddReaction(content string) error {
+	if obj.config.mirror != nil {
+		return obj.inMirror(func(m *MungeObject) error { return m.AddReaction(content) })
+	}
+	config := obj.config
+	prNum := *obj.Issue.Number
+	config.analytics.AddReaction.Call(config, nil)
+	glog.Infof("Reacting to %d with %q", prNum, content)
+	if config.DryRun {
+		return nil
+	}
+	u := fmt.Sprintf("repos/%v/%v/issues/%d/reactions", config.Org, config.Project, prNum)
+	req, err := config.client.NewRequest("POST", u, map[string]string{"content": content})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.squirrel-girl-preview")
+	if _, err := config.client.Do(req, nil); err != nil {
+		glog.Errorf("Failed to react to %d with %q: %v", prNum, content, err)
+		return err
+	}
+	return nil
+}
+
+// LockIssue locks the issue's conversation, so only collaborators can
+// comment. `reason` is one of github's lock reasons, e.g. "resolved".
+func (obj *MungeObject) LockIssue(reason string) error {
+	return obj.setLocked("PUT", map[string]string{"lock_reason": reason})
+}
+
+// UnlockIssue unlocks the issue's conversation.
+func (obj *MungeObject) UnlockIssue() error {
+	return obj.setLocked("DELETE", nil)
+}
+
+func (obj *MungeObject) setLocked(method string, body interface{}) error {
+	if obj.config.mirror != nil {
+		return obj.inMirror(func(m *MungeObject) error { return m.setLocked(method, body) })
+	}
+	config := obj.config
+	prNum := *obj.Issue.Number
+	config.analytics.LockIssue.Call(config, nil)
+	if config.DryRun {
+		return nil
+	}
+	u := fmt.Sprintf("repos/%v/%v/issues/%d/lock", config.Org, config.Project, prNum)
+	req, err := config.client.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	// Lock reasons are a preview.
+	req.Header.Set("Accept", "application/vnd.github.sailor-v-preview+json")
+	if _, err := config.client.Do(req, nil); err != nil {
+		glog.Errorf("Failed to change the lock of %d: %v", prNum, err)
+		return err
+	}
+	return nil
+}
+
+// CloseIssue will close the given issue without leaving a comment
+func (obj *MungeObject) CloseIssue() error {
+	if obj.config.mirror != nil {
+		return obj.inMirror(func(m *MungeObject) error { return m.CloseIssue() })
+	}
+	config := obj.config
 	closed := "closed"
 	state := &github.IssueRequest{State: &closed}
 	config.analytics.CloseIssue.Call(config, nil)
-	glog.Infof("Closing issue #%d: %v", *obj.Issue.Number, msg)
 	if config.DryRun {
 		return nil
 	}
 	if _, _, err := config.client.Issues.Edit(config.Org, config.Project, *obj.Issue.Number, state); err != nil {
-		glog.Errorf("Error closing issue #%d: %v: %v", *obj.Issue.Number, msg, err)
+		glog.Errorf("Error closing issue #%d: %v", *obj.Issue.Number, err)
+		return err
+	}
+	return nil
+}
+
+// ReopenIssue will reopen the given issue
+func (obj *MungeObject) ReopenIssue() error {
+	if obj.config.mirror != nil {
+		return obj.inMirror(func(m *MungeObject) error { return m.ReopenIssue() })
+	}
+	config := obj.config
+	open := "open"
+	state := &github.IssueRequest{State: &open}
+	config.analytics.ReopenIssue.Call(config, nil)
+	if config.DryRun {
+		return nil
+	}
+	if _, _, err := config.client.Issues.Edit(config.Org, config.Project, *obj.Issue.Number, state); err != nil {
+		glog.Errorf("Error reopening issue #%d: %v", *obj.Issue.Number, err)
 		return err
 	}
 	return nil
@@ -1260,7 +1994,7 @@ func (obj *MungeObject) ClosePR() error {
 	}
 	config.analytics.ClosePR.Call(config, nil)
 	glog.Infof("Closing PR# %d", *pr.Number)
-	if config.DryRun {
+	if config.readOnly() {
 		return nil
 	}
 	state := "closed"
@@ -1283,7 +2017,7 @@ func (obj *MungeObject) OpenPR(numTries int) error {
 	}
 	config.analytics.OpenPR.Call(config, nil)
 	glog.Infof("Opening PR# %d", *pr.Number)
-	if config.DryRun {
+	if config.readOnly() {
 		return nil
 	}
 	state := "open"
@@ -1353,7 +2087,7 @@ func (obj *MungeObject) MergePR(who string) error {
 	prNum := *obj.Issue.Number
 	config.analytics.Merge.Call(config, nil)
 	glog.Infof("Merging PR# %d", prNum)
-	if config.DryRun {
+	if config.readOnly() {
 		return nil
 	}
 	mergeBody := fmt.Sprintf("Automatic merge from %s", who)
@@ -1429,15 +2163,32 @@ func (obj *MungeObject) GetPRFixesList() []int {
 
 // ListComments returns all comments for the issue/PR in question
 func (obj *MungeObject) ListComments() ([]github.IssueComment, error) {
-	config := obj.config
-	issueNum := *obj.Issue.Number
-	allComments := []github.IssueComment{}
-
 	if obj.comments != nil {
 		return obj.comments, nil
 	}
+	allComments, err := obj.listComments(&github.IssueListCommentsOptions{})
+	if err != nil {
+		return nil, err
+	}
+	obj.comments = allComments
+	return allComments, nil
+}
 
-	listOpts := &github.IssueListCommentsOptions{}
+// ListCommentsSince returns only the comments for the issue/PR in question
+// which were created or edited at or after `since`. Unlike ListComments the
+// result is not cached in the object.
+func (obj *MungeObject) ListCommentsSince(since time.Time) ([]github.IssueComment, error) {
+	return obj.listComments(&github.IssueListCommentsOptions{Since: since})
+}
+
+func (obj *MungeObject) listComments(listOpts *github.IssueListCommentsOptions) ([]github.IssueComment, error) {
+	config := obj.config
+	issueNum := *obj.Issue.Number
+	mirrorOpts := *listOpts
+	if config.mirror != nil && config.filed(issueNum) {
+		return config.mirrorComments(issueNum, mirrorOpts)
+	}
+	allComments := []github.IssueComment{}
 
 	page := 1
 	for {
@@ -1454,12 +2205,21 @@ func (obj *MungeObject) ListComments() ([]github.IssueComment, error) {
 		}
 		page++
 	}
-	obj.comments = allComments
+	if config.mirror != nil {
+		mirrored, err := config.mirrorComments(issueNum, mirrorOpts)
+		if err != nil {
+			return nil, err
+		}
+		allComments = append(allComments, mirrored...)
+	}
 	return allComments, nil
 }
 
 // WriteComment will send the `msg` as a comment to the specified PR
 func (obj *MungeObject) WriteComment(msg string) error {
+	if obj.config.mirror != nil {
+		return obj.inMirror(func(m *MungeObject) error { return m.WriteComment(msg) })
+	}
 	config := obj.config
 	prNum := *obj.Issue.Number
 	config.analytics.CreateComment.Call(config, nil)
@@ -1474,6 +2234,90 @@ func (obj *MungeObject) WriteComment(msg string) error {
 	return nil
 }
 
+// EditComment will change the body of the specified comment to `msg`
+func (obj *MungeObject) EditComment(comment *github.IssueComment, msg string) error {
+	config := obj.config
+	prNum := *obj.Issue.Number
+	config.analytics.EditComment.Call(config, nil)
+	if comment.ID == nil {
+		err := fmt.Errorf("Found a comment with nil id for Issue %d", prNum)
+		glog.Errorf("Found a comment with nil id for Issue %d", prNum)
+		return err
+	}
+	glog.Infof("Editing comment %d in %d to %q", *comment.ID, prNum, msg)
+	for i := range obj.comments {
+		if c := &obj.comments[i]; c.ID != nil && *c.ID == *comment.ID {
+			c.Body = &msg
+		}
+	}
+	if config.DryRun {
+		return nil
+	}
+	if config.mirror != nil {
+		if !config.mirroredComment(comment) {
+			glog.Infof("Not editing comment %d outside of the staging repo", *comment.ID)
+			return nil
+		}
+		return obj.inMirror(func(m *MungeObject) error { return m.EditComment(comment, msg) })
+	}
+	if _, _, err := config.client.Issues.EditComment(config.Org, config.Project, *comment.ID, &github.IssueComment{Body: &msg}); err != nil {
+		glog.Errorf("Error editing comment: %v", err)
+		return err
+	}
+	return nil
+}
+
+// stickyMarker is hidden in every sticky comment about `topic`, so we can find
+// it again.
+func stickyMarker(topic string) string {
+	return fmt.Sprintf("<!-- sticky-comment: %s -->", topic)
+}
+
+// FindStickyComment returns the sticky comment about `topic`, or nil if there
+// is none.
+func (obj *MungeObject) FindStickyComment(topic string) (*github.IssueComment, error) {
+	comments, err := obj.ListComments()
+	if err != nil {
+		return nil, err
+	}
+	marker := stickyMarker(topic)
+	for i := range comments {
+		c := comments[i]
+		if c.Body != nil && strings.Contains(*c.Body, marker) {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// StickyComment makes sure there is exactly one comment about `topic` and that
+// it says `msg`. If there already is one it is edited in place (and only if
+// the text changed) instead of a new comment being written, so people
+// watching the issue aren't notified over and over again.
+func (obj *MungeObject) StickyComment(topic, msg string) error {
+	body := msg + "\n\n" + stickyMarker(topic)
+	existing, err := obj.FindStickyComment(topic)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return obj.WriteComment(body)
+	}
+	if *existing.Body == body {
+		return nil
+	}
+	return obj.EditComment(existing, body)
+}
+
+// DeleteStickyComment removes the sticky comment about `topic`, if any.
+func (obj *MungeObject) DeleteStickyComment(topic string) error {
+	existing, err := obj.FindStickyComment(topic)
+	if err != nil || existing == nil {
+		return err
+	}
+	return obj.DeleteComment(existing)
+}
+
 // DeleteComment will remove the specified comment
 func (obj *MungeObject) DeleteComment(comment *github.IssueComment) error {
 	config := obj.config
@@ -1511,6 +2355,13 @@ func (obj *MungeObject) DeleteComment(comment *github.IssueComment) error {
 	if config.DryRun {
 		return nil
 	}
+	if config.mirror != nil {
+		if !config.mirroredComment(comment) {
+			glog.Infof("Not deleting comment %d outside of the staging repo", *comment.ID)
+			return nil
+		}
+		return obj.inMirror(func(m *MungeObject) error { return m.DeleteComment(comment) })
+	}
 	if _, err := config.client.Issues.DeleteComment(config.Org, config.Project, *comment.ID); err != nil {
 		glog.Errorf("Error removing comment: %v", err)
 		return err
@@ -1676,5 +2527,48 @@ func (config *Config) ListAllIssues(listOpts *github.IssueListByRepoOptions) ([]
 		}
 		page++
 	}
+	if config.mirror != nil {
+		return config.listFiled(listOpts, allIssues)
+	}
 	return allIssues, nil
 }
+
+// IssueObject returns an object for `issue`, e.g. one listed with
+// ListAllIssues, without fetching it again.
+func (config *Config) IssueObject(issue *github.Issue) *MungeObject {
+	return &MungeObject{
+		config:      config,
+		Issue:       issue,
+		Annotations: map[string]string{},
+	}
+}
+
+// ListRepoComments returns the comments on all issues and PRs of the repo
+// which were created or edited at or after `since`, oldest first.
+func (config *Config) ListRepoComments(since time.Time) ([]github.IssueComment, error) {
+	allComments := []github.IssueComment{}
+	page := 1
+	for {
+		glog.V(4).Infof("Fetching page %d of comments since %v", page, since)
+		listOpts := &github.IssueListCommentsOptions{
+			Sort:        "updated",
+			Direction:   "asc",
+			Since:       since,
+			ListOptions: github.ListOptions{PerPage: 100, Page: page},
+		}
+		comments, response, err := config.client.Issues.ListComments(config.Org, config.Project, 0, listOpts)
+		config.analytics.ListComments.Call(config, response)
+		if err != nil {
+			return nil, err
+		}
+		allComments = append(allComments, comments...)
+		if response.LastPage == 0 || response.LastPage <= page {
+			break
+		}
+		page++
+	}
+	if config.mirror != nil {
+		return config.listFiledComments(since, allComments)
+	}
+	return allComments, nil
+}
@@ -27,6 +27,7 @@ import (
 	github_test "k8s.io/contrib/mungegithub/github/testing"
 
 	"github.com/google/go-github/github"
+	"golang.org/x/net/context"
 )
 
 func stringPtr(val string) *string     { return &val }
@@ -183,12 +184,12 @@ func TestForEachIssueDo(t *testing.T) {
 	for i, test := range tests {
 		client, server, mux := github_test.InitServer(t, nil, nil, nil, nil, nil, nil)
 		config := &Config{
-			client:      client,
 			Org:         "foo",
 			Project:     "bar",
 			MinPRNumber: 5,
 			MaxPRNumber: 15,
 		}
+		config.SetClient(client)
 		count := 0
 		mux.HandleFunc("/repos/foo/bar/issues", func(w http.ResponseWriter, r *http.Request) {
 			if r.Method != "GET" {
@@ -535,3 +536,105 @@ this pr Fixes #23 and FIXES #45 but not fixxx #99`,
 		server.Close()
 	}
 }
+
+func TestStickyComment(t *testing.T) {
+	marker := "\n\n" + stickyMarker("topic")
+	tests := []struct {
+		comments []github.IssueComment
+		msg      string
+		created  bool
+		edited   bool
+	}{
+		{
+			comments: []github.IssueComment{},
+			msg:      "hello",
+			created:  true,
+		},
+		{
+			comments: []github.IssueComment{
+				github_test.Comment(1, "bot", time.Unix(0, 0), "unrelated"),
+			},
+			msg:     "hello",
+			created: true,
+		},
+		{
+			comments: []github.IssueComment{
+				github_test.Comment(1, "bot", time.Unix(0, 0), "hello"+marker),
+			},
+			msg: "hello",
+		},
+		{
+			comments: []github.IssueComment{
+				github_test.Comment(1, "bot", time.Unix(0, 0), "unrelated"),
+				github_test.Comment(2, "bot", time.Unix(0, 0), "hello"+marker),
+			},
+			msg:    "goodbye",
+			edited: true,
+		},
+	}
+	for testNum, test := range tests {
+		client, server, mux := github_test.InitServer(t, github_test.Issue("", 1, nil, false), nil, nil, nil, nil, nil)
+		config := &Config{}
+		config.Org = "o"
+		config.Project = "r"
+		config.SetClient(client)
+		created, edited := false, false
+		mux.HandleFunc("/repos/o/r/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				created = true
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("{}"))
+				return
+			}
+			data, err := json.Marshal(test.comments)
+			if err != nil {
+				t.Errorf("%d: %v", testNum, err)
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(data)
+		})
+		mux.HandleFunc("/repos/o/r/issues/comments/2", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "PATCH" {
+				t.Errorf("%d: unexpected method %s", testNum, r.Method)
+			}
+			edited = true
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("{}"))
+		})
+
+		obj, err := config.GetObject(1)
+		if err != nil {
+			t.Fatalf("%d: unable to get issue: %v", testNum, err)
+		}
+		if err := obj.StickyComment("topic", test.msg); err != nil {
+			t.Errorf("%d: unexpected error: %v", testNum, err)
+		}
+		if created != test.created || edited != test.edited {
+			t.Errorf("%d: expected created=%v edited=%v, got created=%v edited=%v", testNum, test.created, test.edited, created, edited)
+		}
+		server.Close()
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	client, server, _ := github_test.InitServer(t, github_test.Issue("", 1, nil, false), nil, nil, nil, nil, nil)
+	defer server.Close()
+	config := &Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	withCtx := config.WithContext(ctx, time.Minute)
+	if _, err := withCtx.GetObject(1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cancel()
+	if _, err := withCtx.GetObject(1); err == nil {
+		t.Errorf("expected an error after cancelation")
+	}
+	if _, err := config.GetObject(1); err != nil {
+		t.Errorf("cancelation should not affect the original config: %v", err)
+	}
+	if config.analytics.GetIssue.Count != 3 {
+		t.Errorf("expected 3 calls to be counted, got %d", config.analytics.GetIssue.Count)
+	}
+}
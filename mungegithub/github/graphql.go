@@ -0,0 +1,144 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/go-github/github"
+)
+
+// graphQLIssue is what GetObjects asks for about every issue.
+type graphQLIssue struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	State     string    `json:"state"`
+	Body      string    `json:"body"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Author    *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Assignees struct {
+		Nodes []struct {
+			Login string `json:"login"`
+		} `json:"nodes"`
+	} `json:"assignees"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Comments struct {
+		TotalCount int `json:"totalCount"`
+		Nodes      []struct {
+			Body string `json:"body"`
+		} `json:"nodes"`
+	} `json:"comments"`
+}
+
+// issue converts to what the REST API returns.
+func (g *graphQLIssue) issue() *github.Issue {
+	state := strings.ToLower(g.State)
+	issue := &github.Issue{
+		Number:    &g.Number,
+		Title:     &g.Title,
+		State:     &state,
+		Body:      &g.Body,
+		UpdatedAt: &g.UpdatedAt,
+	}
+	if g.Author != nil {
+		issue.User = &github.User{Login: &g.Author.Login}
+	}
+	if len(g.Assignees.Nodes) > 0 {
+		issue.Assignee = &github.User{Login: &g.Assignees.Nodes[0].Login}
+	}
+	for i := range g.Labels.Nodes {
+		issue.Labels = append(issue.Labels, github.Label{Name: &g.Labels.Nodes[i].Name})
+	}
+	return issue
+}
+
+// RecentComments are the last comments of an issue, see GetObjects.
+type RecentComments struct {
+	Bodies []string
+	// Complete is true if Bodies are all of the issue's comments.
+	Complete bool
+}
+
+// GetObjects fetches the issues `nums`, with the bodies of their last
+// `comments` comments, in a single GraphQL query instead of a REST call or
+// more each. Numbers which aren't issues (e.g. pull requests) are left out.
+func (config *Config) GetObjects(nums []int, comments int) (map[int]*MungeObject, map[int]RecentComments, error) {
+	if config.mirror != nil {
+		return config.getFiledObjects(nums, comments)
+	}
+	fields := fmt.Sprintf("number title state body updatedAt author { login } assignees(first: 1) { nodes { login } } labels(first: 100) { nodes { name } } comments(last: %d) { totalCount nodes { body } }", comments)
+	aliases := []string{}
+	for _, n := range nums {
+		aliases = append(aliases, fmt.Sprintf("i%d: issue(number: %d) { %v }", n, n, fields))
+	}
+	query := fmt.Sprintf("query($owner: String!, $name: String!) { repository(owner: $owner, name: $name) { %v } }", strings.Join(aliases, " "))
+
+	req, err := config.client.NewRequest("POST", graphQLURL(config.client.BaseURL), map[string]interface{}{
+		"query":     query,
+		"variables": map[string]string{"owner": config.Org, "name": config.Project},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	result := struct {
+		Data struct {
+			Repository map[string]*graphQLIssue `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	response, err := config.client.Do(req, &result)
+	config.analytics.GraphQL.Call(config, response)
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.Data.Repository == nil && len(result.Errors) > 0 {
+		return nil, nil, fmt.Errorf("graphql query failed: %v", result.Errors[0].Message)
+	}
+	for _, e := range result.Errors {
+		// e.g. numbers which are pull requests
+		glog.V(2).Infof("Partial GraphQL result for issues %v: %v", nums, e.Message)
+	}
+	objs := map[int]*MungeObject{}
+	recent := map[int]RecentComments{}
+	for _, g := range result.Data.Repository {
+		if g == nil {
+			continue
+		}
+		objs[g.Number] = &MungeObject{
+			config:      config,
+			Issue:       g.issue(),
+			Annotations: map[string]string{},
+		}
+		r := RecentComments{Complete: g.Comments.TotalCount <= len(g.Comments.Nodes)}
+		for _, c := range g.Comments.Nodes {
+			r.Bodies = append(r.Bodies, c.Body)
+		}
+		recent[g.Number] = r
+	}
+	return objs, recent, nil
+}
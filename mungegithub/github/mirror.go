@@ -0,0 +1,434 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/go-github/github"
+)
+
+// stagedNumberBase is added to the numbers of the issues filed in the staging
+// repo, so that they don't hide the repo's issues with the same numbers.
+const stagedNumberBase = 1 << 30
+
+// mirror sends the changes a Config makes to a staging repo, see MirrorTo.
+type mirror struct {
+	// staging is replaced in the copies WithContext makes, the state is
+	// shared.
+	staging *Config
+	*mirrorState
+}
+
+type mirrorState struct {
+	path string
+	lock sync.Mutex
+	// Mirrors maps issues ("org/project#N") to the staging issues which
+	// mirror them.
+	Mirrors map[string]int `json:"mirrors"`
+	// Filed are the issues of the staging repo ("org/project#N") which
+	// were filed there instead of in the config's repo. They are read as
+	// the config's issue N+stagedNumberBase.
+	Filed map[string]bool `json:"staged"`
+	// Legacy are the issues filed in the staging repo recorded before
+	// Filed, as "org/project#N" of the config's repo.
+	Legacy map[string]bool `json:"filed,omitempty"`
+	// comments are the ids of the comments listed from mirror issues.
+	comments map[int]bool
+}
+
+// MirrorTo makes the config leave its repo alone, so changes to the mungers
+// can be tried out against real issues: issues are still read from the repo,
+// but every change to one of them is made to a mirror issue in the `staging`
+// repo instead, which is filed the first time the issue is changed, and which
+// its comments are listed with. New issues are filed in the staging repo, and
+// are read from there as if they were in the repo, numbered past the repo's
+// issues so as not to hide any (see stagedNumberBase). Changes which can't be
+// mirrored, like statuses, project cards and merges, are skipped as in
+// dry-run mode.
+//
+// The staging repo should be private: mentions and references made there
+// would reach the people and issues of the repo otherwise. If `path` is set,
+// which issues are mirrored or filed where is kept there across restarts.
+func (config *Config) MirrorTo(staging *Config, path string) error {
+	state := &mirrorState{
+		path:     path,
+		Mirrors:  map[string]int{},
+		Filed:    map[string]bool{},
+		comments: map[int]bool{},
+	}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, state); err != nil {
+				return fmt.Errorf("error parsing mirror state %v: %v", path, err)
+			}
+		}
+	}
+	m := &mirror{staging: staging, mirrorState: state}
+	for key := range state.Legacy {
+		if i := strings.LastIndex(key, "#"); i >= 0 {
+			if num, err := strconv.Atoi(key[i+1:]); err == nil {
+				state.Filed[m.stagingKey(num)] = true
+			}
+		}
+	}
+	state.Legacy = nil
+	config.mirror = m
+	return nil
+}
+
+// readOnly is true if the config may not change what it can't mirror.
+func (config *Config) readOnly() bool {
+	return config.DryRun || config.mirror != nil
+}
+
+func (config *Config) issueKey(num int) string {
+	return fmt.Sprintf("%v/%v#%d", config.Org, config.Project, num)
+}
+
+// stagingKey identifies issue `num` of the staging repo.
+func (m *mirror) stagingKey(num int) string {
+	return fmt.Sprintf("%v/%v#%d", m.staging.Org, m.staging.Project, num)
+}
+
+// filed returns true if `num` is an issue filed in the staging repo, as the
+// config numbers it.
+func (config *Config) filed(num int) bool {
+	return num > stagedNumberBase && config.filedInStaging(num-stagedNumberBase)
+}
+
+// filedInStaging returns true if issue `num` of the staging repo was filed
+// there instead of in the config's repo.
+func (config *Config) filedInStaging(num int) bool {
+	m := config.mirror
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.Filed[m.stagingKey(num)]
+}
+
+// staged returns a copy of `issue`, filed in the staging repo, numbered as
+// the config's issues which are filed there are.
+func staged(issue *github.Issue) *github.Issue {
+	filed := *issue
+	num := *issue.Number + stagedNumberBase
+	filed.Number = &num
+	return &filed
+}
+
+// mirrorIssue returns the number of the issue which mirrors `num`, or 0. An
+// issue filed in the staging repo is its own mirror.
+func (config *Config) mirrorIssue(num int) int {
+	if config.filed(num) {
+		return num - stagedNumberBase
+	}
+	m := config.mirror
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.Mirrors[config.issueKey(num)]
+}
+
+// remember records `update` and saves the state. Must not hold the lock.
+func (m *mirror) remember(update func(*mirrorState)) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	update(m.mirrorState)
+	if m.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m.mirrorState, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// fileInStaging files a new issue in the staging repo instead of the
+// config's, see NewIssue.
+func (config *Config) fileInStaging(title, body string, labels []string) (*MungeObject, error) {
+	obj, err := config.mirror.staging.NewIssue(title, body, labels)
+	if err != nil {
+		return nil, err
+	}
+	key := config.mirror.stagingKey(*obj.Issue.Number)
+	filed := config.IssueObject(staged(obj.Issue))
+	glog.Infof("Filed %v in the staging repo, as %v", key, config.issueKey(*filed.Issue.Number))
+	return filed, config.mirror.remember(func(s *mirrorState) { s.Filed[key] = true })
+}
+
+// mirrorBody returns the body of the issue which mirrors `num` when that has
+// `body`. It starts with where it comes from, in a code span so the mirror
+// doesn't show up on the issue. Issues filed in the staging repo don't come
+// from anywhere.
+func (config *Config) mirrorBody(num int, body string) string {
+	if config.filed(num) {
+		return body
+	}
+	return fmt.Sprintf("Mirrors `%v`.\n\n%v", config.issueKey(num), body)
+}
+
+// inMirror calls `change` with the issue which mirrors the object, filing it
+// first if there is none yet.
+func (obj *MungeObject) inMirror(change func(*MungeObject) error) error {
+	config := obj.config
+	staging := config.mirror.staging
+	num := *obj.Issue.Number
+	if m := config.mirrorIssue(num); m != 0 {
+		mirrored, err := staging.GetObject(m)
+		if err != nil {
+			return err
+		}
+		return change(mirrored)
+	}
+
+	key := config.issueKey(num)
+	title, body := "", ""
+	if obj.Issue.Title != nil {
+		title = *obj.Issue.Title
+	}
+	if obj.Issue.Body != nil {
+		body = *obj.Issue.Body
+	}
+	labels := []string{}
+	for _, l := range obj.Issue.Labels {
+		if l.Name != nil {
+			labels = append(labels, *l.Name)
+		}
+	}
+	mirrored, err := staging.NewIssue(fmt.Sprintf("[%v] %v", key, title), config.mirrorBody(num, body), labels)
+	if err != nil {
+		return err
+	}
+	glog.Infof("Mirroring %v as %d in the staging repo", key, *mirrored.Issue.Number)
+	if err := config.mirror.remember(func(s *mirrorState) { s.Mirrors[key] = *mirrored.Issue.Number }); err != nil {
+		return err
+	}
+	if obj.Issue.State != nil && *obj.Issue.State == "closed" {
+		if err := mirrored.CloseIssue(); err != nil {
+			return err
+		}
+	}
+	return change(mirrored)
+}
+
+// mirroredComment returns true if `comment` was listed from a mirror issue,
+// and can only be changed there.
+func (config *Config) mirroredComment(comment *github.IssueComment) bool {
+	m := config.mirror
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return comment.ID != nil && m.comments[*comment.ID]
+}
+
+// mirrorComments lists the comments of the issue which mirrors `num`, if any.
+func (config *Config) mirrorComments(num int, listOpts github.IssueListCommentsOptions) ([]github.IssueComment, error) {
+	m := config.mirrorIssue(num)
+	if m == 0 {
+		return nil, nil
+	}
+	comments, err := config.mirror.staging.IssueObject(&github.Issue{Number: &m}).listComments(&listOpts)
+	if err != nil {
+		return nil, err
+	}
+	config.mirror.lock.Lock()
+	defer config.mirror.lock.Unlock()
+	for _, c := range comments {
+		if c.ID != nil {
+			config.mirror.comments[*c.ID] = true
+		}
+	}
+	return comments, nil
+}
+
+// mirrorIssueURLRE gets the issue number out of a comment's issue_url.
+var mirrorIssueURLRE = regexp.MustCompile(`/issues/(\d+)$`)
+
+// filedComment returns `c`, a comment of the staging repo, as a comment on
+// the config's issue if it is on an issue filed in the staging repo.
+func (config *Config) filedComment(c github.IssueComment) (github.IssueComment, bool) {
+	if c.IssueURL == nil {
+		return c, false
+	}
+	m := mirrorIssueURLRE.FindStringSubmatch(*c.IssueURL)
+	if m == nil {
+		return c, false
+	}
+	num, _ := strconv.Atoi(m[1])
+	if !config.filedInStaging(num) {
+		return c, false
+	}
+	staging := config.mirror.staging
+	url := strings.Replace(*c.IssueURL,
+		fmt.Sprintf("/repos/%v/%v/issues/%d", staging.Org, staging.Project, num),
+		fmt.Sprintf("/repos/%v/%v/issues/%d", config.Org, config.Project, num+stagedNumberBase), 1)
+	c.IssueURL = &url
+	return c, true
+}
+
+// searchFiled adds what `query` finds among the issues filed in the staging
+// repo to `issues`.
+func (config *Config) searchFiled(query string, issues []github.Issue) ([]github.Issue, error) {
+	result := issues
+	repo := fmt.Sprintf("repo:%v/%v", config.Org, config.Project)
+	if !strings.Contains(query, repo) {
+		return result, nil
+	}
+	staging := config.mirror.staging
+	filed, err := staging.SearchIssues(strings.Replace(query, repo, fmt.Sprintf("repo:%v/%v", staging.Org, staging.Project), -1))
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range filed {
+		if issue.Number != nil && config.filedInStaging(*issue.Number) {
+			result = append(result, *staged(&issue))
+		}
+	}
+	return result, nil
+}
+
+// listFiled adds the issues filed in the staging repo which match `listOpts`
+// to `issues`.
+func (config *Config) listFiled(listOpts *github.IssueListByRepoOptions, issues []*github.Issue) ([]*github.Issue, error) {
+	filed, err := config.mirror.staging.ListAllIssues(listOpts)
+	if err != nil {
+		return nil, err
+	}
+	result := issues
+	for _, issue := range filed {
+		if config.filedInStaging(*issue.Number) {
+			result = append(result, staged(issue))
+		}
+	}
+	return result, nil
+}
+
+// listFiledComments adds the comments since `since` on the issues filed in
+// the staging repo to `comments`.
+func (config *Config) listFiledComments(since time.Time, comments []github.IssueComment) ([]github.IssueComment, error) {
+	filed, err := config.mirror.staging.ListRepoComments(since)
+	if err != nil {
+		return nil, err
+	}
+	result := comments
+	for _, c := range filed {
+		if c, ok := config.filedComment(c); ok {
+			config.mirror.lock.Lock()
+			if c.ID != nil {
+				config.mirror.comments[*c.ID] = true
+			}
+			config.mirror.lock.Unlock()
+			result = append(result, c)
+		}
+	}
+	sort.Stable(byCommentUpdate(result))
+	return result, nil
+}
+
+type byCommentUpdate []github.IssueComment
+
+func (b byCommentUpdate) Len() int      { return len(b) }
+func (b byCommentUpdate) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byCommentUpdate) Less(i, j int) bool {
+	return b[i].UpdatedAt != nil && b[j].UpdatedAt != nil && b[i].UpdatedAt.Before(*b[j].UpdatedAt)
+}
+
+// getFiledObjects is GetObjects for a config with a mirror: the issues filed
+// in the staging repo are fetched from there.
+func (config *Config) getFiledObjects(nums []int, comments int) (map[int]*MungeObject, map[int]RecentComments, error) {
+	filed, others := []int{}, []int{}
+	for _, n := range nums {
+		if config.filed(n) {
+			filed = append(filed, n)
+		} else {
+			others = append(others, n)
+		}
+	}
+	direct := *config
+	direct.mirror = nil
+	objs, recent, err := direct.GetObjects(others, comments)
+	if err != nil {
+		return nil, nil, err
+	}
+	for n, obj := range objs {
+		obj.config = config
+		if config.mirrorIssue(n) == 0 {
+			continue
+		}
+		if err := config.overlayMirror(obj); err != nil {
+			return nil, nil, err
+		}
+		// The comments of the mirror issue weren't fetched.
+		recent[n] = RecentComments{Bodies: recent[n].Bodies}
+	}
+	if len(filed) == 0 {
+		return objs, recent, nil
+	}
+	stagingNums := []int{}
+	for _, n := range filed {
+		stagingNums = append(stagingNums, n-stagedNumberBase)
+	}
+	stagedObjs, stagedRecent, err := config.mirror.staging.GetObjects(stagingNums, comments)
+	if err != nil {
+		return nil, nil, err
+	}
+	for n, obj := range stagedObjs {
+		objs[n+stagedNumberBase] = config.IssueObject(staged(obj.Issue))
+		recent[n+stagedNumberBase] = stagedRecent[n]
+	}
+	return objs, recent, nil
+}
+
+// overlayMirror makes the object look as if the changes made to its mirror
+// issue, if it has one, had been made to it: it gets the mirror's state,
+// labels and body, and its update time if that is later.
+func (config *Config) overlayMirror(obj *MungeObject) error {
+	m := config.mirrorIssue(*obj.Issue.Number)
+	if m == 0 {
+		return nil
+	}
+	mirrored, err := config.mirror.staging.getIssue(m)
+	if err != nil {
+		return err
+	}
+	obj.Issue.State = mirrored.State
+	obj.Issue.Labels = mirrored.Labels
+	if mirrored.Body != nil {
+		body := strings.TrimPrefix(*mirrored.Body, config.mirrorBody(*obj.Issue.Number, ""))
+		obj.Issue.Body = &body
+	}
+	if mirrored.UpdatedAt != nil && (obj.Issue.UpdatedAt == nil || mirrored.UpdatedAt.After(*obj.Issue.UpdatedAt)) {
+		obj.Issue.UpdatedAt = mirrored.UpdatedAt
+	}
+	return nil
+}
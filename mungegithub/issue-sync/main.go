@@ -0,0 +1,530 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	gosync "sync"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/contrib/mungegithub/mungers/sync"
+	utilflag "k8s.io/kubernetes/pkg/util/flag"
+)
+
+// issue-sync files deduplicated issues for sources described in JSON (see
+// sync.JSONSource), for CI pipelines which aren't part of mungegithub. It
+// syncs the sources it's given and exits, or keeps accepting sources over
+// http (--listen) or from Pub/Sub (--pubsub-subscription).
+type options struct {
+	sources   string
+	namespace string
+	labels    []string
+	metadata  string
+	templates string
+	auditLog  string
+	logFormat string
+	normalize string
+	quiet     string
+	plan      bool
+	reconcile string
+	redact    string
+	idMatch   string
+	redactLog bool
+	lockAfter time.Duration
+	muteLabel string
+	histogram int
+	fanOut    bool
+
+	historyCSV      string
+	historyCSVMax   int64
+	historyBigQuery string
+
+	ccAuthors      int
+	ccAuthorsSince time.Duration
+
+	attachDest   string
+	attachDir    string
+	attachInline int
+
+	tenants       string
+	defaultTenant string
+
+	mirrorTo    string
+	upstream    string
+	mirrorState string
+
+	listen       string
+	subscription string
+	schedule     string
+	sourceFlags  string
+	tokenFile    string
+	webhookFile  string
+	syncInterval time.Duration
+	stopTimeout  time.Duration
+
+	retest     bool
+	retestCmd  string
+	maxRetests int
+}
+
+// readSources reads the sources from stdin if `path` is "-", from every
+// *.json file in `path` if it's a directory, or else from the file `path`.
+func readSources(path string) ([]sync.IssueSource, error) {
+	var files []string
+	if path == "-" {
+		files = []string{"-"}
+	} else if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	} else {
+		files = []string{path}
+	}
+
+	sources := []sync.IssueSource{}
+	for _, f := range files {
+		var r io.Reader = os.Stdin
+		if f != "-" {
+			file, err := os.Open(f)
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			r = file
+		}
+		parsed, err := sync.ReadJSONSources(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading sources from %v: %v", f, err)
+		}
+		for _, s := range parsed {
+			sources = append(sources, s)
+		}
+	}
+	return sources, nil
+}
+
+func run(config *github.Config, o *options) error {
+	err := config.PreExecute()
+	if err != nil {
+		return err
+	}
+	if o.mirrorTo != "" {
+		parts := strings.Split(o.mirrorTo, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("--mirror-to must be org/project, not %q", o.mirrorTo)
+		}
+		if err := config.MirrorTo(config.ForRepo(parts[0], parts[1]), o.mirrorState); err != nil {
+			return err
+		}
+	}
+	logger, err := sync.NewLogger(o.logFormat)
+	if err != nil {
+		return err
+	}
+	var audit *sync.AuditLog
+	if o.auditLog != "" {
+		if audit, err = sync.NewAuditLog(o.auditLog); err != nil {
+			return err
+		}
+		defer audit.Close()
+	}
+	var history sync.HistoryExporter
+	if o.historyCSV != "" && o.historyBigQuery != "" {
+		return fmt.Errorf("--history-csv and --history-bigquery can't be used together")
+	} else if o.historyCSV != "" {
+		csv, err := sync.NewCSVExporter(o.historyCSV)
+		if err != nil {
+			return err
+		}
+		csv.MaxBytes = o.historyCSVMax
+		history = csv
+	} else if o.historyBigQuery != "" {
+		if history, err = sync.NewBigQueryExporter(o.historyBigQuery, nil); err != nil {
+			return err
+		}
+	}
+	if history != nil {
+		defer func() {
+			if err := history.Close(); err != nil {
+				glog.Errorf("Unable to export the last of the history: %v", err)
+			}
+		}()
+	}
+
+	// opts are the settings of every syncer.
+	opts := []sync.Option{
+		sync.WithLogger(logger),
+		sync.WithAudit(audit),
+		sync.WithHistory(history),
+		sync.WithMuteLabel(o.muteLabel),
+		sync.WithHistogram(o.histogram),
+		sync.WithOwnerFanOut(o.fanOut),
+	}
+	if o.quiet != "" {
+		quiet, err := sync.LoadQuietHours(o.quiet)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, sync.WithQuietHours(quiet))
+	}
+	if o.redact != "" {
+		redactor, err := sync.LoadRedactor(o.redact)
+		if err != nil {
+			return err
+		}
+		redactor.ReportOnly = redactor.ReportOnly || o.redactLog
+		opts = append(opts, sync.WithRedactor(redactor))
+	}
+	if o.ccAuthors > 0 {
+		authors := sync.NewRecentAuthors(config)
+		authors.Max = o.ccAuthors
+		authors.Window = o.ccAuthorsSince
+		opts = append(opts, sync.WithStages(authors.Stage()))
+	}
+	if o.lockAfter > 0 {
+		locking := sync.NewClosedLocking()
+		locking.After = o.lockAfter
+		opts = append(opts, sync.WithLocking(locking))
+	}
+	if o.attachDest != "" {
+		var store sync.ArtifactStore = &sync.BucketStore{Dest: o.attachDest}
+		if o.attachDest == "gist" {
+			store = &sync.GistStore{Config: config}
+		}
+		uploads := sync.NewAttachmentUploads(store)
+		uploads.InlineLimit = o.attachInline
+		uploads.Dir = o.attachDir
+		opts = append(opts, sync.WithUploads(uploads))
+	}
+	if o.retest {
+		opts = append(opts, sync.WithRetest(&sync.RetestPolicy{Command: o.retestCmd, MaxPerPR: o.maxRetests}))
+	}
+
+	// health maps /healthz paths to the syncers they report on.
+	health := map[string]*sync.IssueSyncer{}
+	// webhook, if set, keeps the finder up to date, see --webhook-secret-file.
+	var webhook *sync.WebhookReceiver
+	var syncer sync.Syncer
+	if o.tenants != "" {
+		if o.upstream != "" {
+			return fmt.Errorf("--upstream can't be used with --tenants")
+		}
+		multi, err := sync.LoadTenants(o.tenants, config, opts...)
+		if err != nil {
+			return err
+		}
+		multi.Default = o.defaultTenant
+		for _, name := range multi.Tenants() {
+			health["/healthz/"+name] = multi.Tenant(name).Syncer
+		}
+		syncer = multi
+	} else {
+		labels := []string{}
+		for _, l := range o.labels {
+			labels = append(labels, sync.Namespaced(o.namespace, l))
+		}
+		finder := sync.NewSearchFinder(config, labels)
+		var normalizer *sync.TitleNormalizer
+		if o.normalize == "default" {
+			normalizer = sync.DefaultTitleNormalizer()
+		} else if o.normalize != "" {
+			if normalizer, err = sync.LoadTitleNormalizer(o.normalize); err != nil {
+				return err
+			}
+		}
+		finder.Normalizer = normalizer
+		var upstream *github.Config
+		if o.upstream != "" {
+			parts := strings.Split(o.upstream, "/")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return fmt.Errorf("--upstream must be org/project, not %q", o.upstream)
+			}
+			upstream = config.ForRepo(parts[0], parts[1])
+		}
+		s, err := sync.New(append([]sync.Option{
+			sync.WithRepo(config),
+			sync.WithFinder(finder),
+			sync.WithNormalizer(normalizer),
+			sync.WithNamespace(o.namespace),
+			sync.WithPersistence(o.metadata),
+			sync.WithIDMatching(sync.IDMatching(o.idMatch)),
+			sync.WithTemplatesFile(o.templates),
+			sync.WithUpstream(upstream),
+		}, opts...)...)
+		if err != nil {
+			return err
+		}
+		health["/healthz"] = s
+		syncer = s
+		if o.webhookFile != "" {
+			secret, err := ioutil.ReadFile(o.webhookFile)
+			if err != nil {
+				return fmt.Errorf("error reading --webhook-secret-file: %v", err)
+			}
+			webhook = sync.NewWebhookReceiver(bytes.TrimSpace(secret), finder)
+			webhook.Syncer = s
+			webhook.Logger = logger
+		}
+	}
+
+	if o.listen != "" && o.subscription != "" {
+		return fmt.Errorf("--listen and --pubsub-subscription can't be used together")
+	}
+	if o.webhookFile != "" && (o.listen == "" || o.tenants != "") {
+		return fmt.Errorf("--webhook-secret-file needs --listen, and can't be used with --tenants")
+	}
+	if o.plan && (o.listen != "" || o.subscription != "" || o.tenants != "") {
+		return fmt.Errorf("--plan only works with --sources")
+	}
+	if o.reconcile != "" && (o.listen != "" || o.subscription != "" || o.schedule != "" || o.tenants != "" || o.plan) {
+		return fmt.Errorf("--reconcile only works with --sources")
+	}
+	if o.reconcile != "" && o.reconcile != "report" && o.reconcile != "fix" {
+		return fmt.Errorf("--reconcile must be report or fix, not %q", o.reconcile)
+	}
+	if o.sourceFlags != "" && o.schedule == "" {
+		return fmt.Errorf("--source-flags only works with --schedule")
+	}
+	if o.schedule != "" && (o.listen != "" || o.subscription != "" || o.plan) {
+		return fmt.Errorf("--schedule can't be used with --listen, --pubsub-subscription or --plan")
+	}
+	for _, s := range health {
+		if err := s.WarmUp(context.Background()); err != nil {
+			logger.Warningf("Unable to warm up, the first sync will be slower: %v", err)
+		}
+	}
+	stopOnSignal(health, o.stopTimeout)
+	if o.listen != "" {
+		return serve(syncer, health, webhook, logger, o)
+	}
+	if o.schedule != "" {
+		scheduler := sync.NewScheduler(syncer)
+		scheduler.Logger = logger
+		if err := sync.LoadSchedule(scheduler, o.schedule); err != nil {
+			return err
+		}
+		if o.sourceFlags != "" {
+			flags, err := sync.LoadFeatureFlags(o.sourceFlags)
+			if err != nil {
+				return err
+			}
+			flags.Logger = logger
+			scheduler.Flags = flags
+		}
+		glog.Infof("Syncing sources on the schedule of %v", o.schedule)
+		scheduler.Run(context.Background())
+		return nil
+	}
+	if o.subscription != "" {
+		glog.Infof("Syncing sources from %v", o.subscription)
+		adapter := sync.NewQueueAdapter(sync.NewPubSubQueue(o.subscription, nil), syncer)
+		adapter.Logger = logger
+		adapter.Run(context.Background(), o.syncInterval)
+		return nil
+	}
+	sources, err := readSources(o.sources)
+	if err != nil {
+		return err
+	}
+	if o.plan {
+		plan, err := health["/healthz"].Plan(context.Background(), sources)
+		if err != nil {
+			return err
+		}
+		fmt.Print(plan)
+		return nil
+	}
+	if o.reconcile != "" {
+		report, err := health["/healthz"].Reconcile(context.Background(), sources, o.reconcile == "fix")
+		if report != nil {
+			fmt.Print(report)
+		}
+		return err
+	}
+	glog.Infof("Syncing %d sources", len(sources))
+	results, err := syncer.SyncAll(context.Background(), sources)
+	glog.Infof("Sync results: %v", summarize(results))
+	return err
+}
+
+// stopOnSignal stops the syncers gracefully once we're asked to terminate,
+// reports the sources they left unsynced, and exits.
+func stopOnSignal(health map[string]*sync.IssueSyncer, timeout time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		sig := <-signals
+		glog.Infof("Got %v, stopping once the sources being synced are done", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		code := 0
+		lock := gosync.Mutex{}
+		wg := gosync.WaitGroup{}
+		for path, s := range health {
+			wg.Add(1)
+			go func(path string, s *sync.IssueSyncer) {
+				defer wg.Done()
+				unfinished, err := s.Stop(ctx)
+				lock.Lock()
+				defer lock.Unlock()
+				if err != nil {
+					glog.Errorf("Unable to stop the syncer of %v cleanly: %v", path, err)
+					code = 1
+				}
+				if len(unfinished) > 0 {
+					glog.Warningf("The syncer of %v left %d sources unsynced: %v", path, len(unfinished), strings.Join(unfinished, ", "))
+				}
+			}(path, s)
+		}
+		wg.Wait()
+		glog.Flush()
+		os.Exit(code)
+	}()
+}
+
+// summarize counts results by what was done, and failures by kind.
+func summarize(results []sync.SyncResult) string {
+	counts := map[string]int{}
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			kind := "other"
+			if k := sync.ErrorKind(r.Err); k != nil {
+				kind = k.Error()
+			}
+			counts["failed ("+kind+")"]++
+		case r.Deferred:
+			counts["deferred"]++
+		case r.Skipped != "":
+			counts["skipped"]++
+		default:
+			counts[string(r.Action)]++
+		}
+	}
+	out := []string{}
+	for what, n := range counts {
+		out = append(out, fmt.Sprintf("%d %v", n, what))
+	}
+	sort.Strings(out)
+	return strings.Join(out, ", ")
+}
+
+// serve accepts sources on /sources (and webhook deliveries on /webhook) and
+// syncs them until we're killed. The state of the syncers is served on
+// /status, next to /healthz.
+func serve(syncer sync.Syncer, health map[string]*sync.IssueSyncer, webhook *sync.WebhookReceiver, logger sync.Logger, o *options) error {
+	data, err := ioutil.ReadFile(o.tokenFile)
+	if err != nil {
+		return fmt.Errorf("error reading --token-file: %v", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return fmt.Errorf("--token-file %v is empty", o.tokenFile)
+	}
+	ingester := sync.NewIngester(syncer, token)
+	ingester.Logger = logger
+	http.Handle("/sources", ingester)
+	for path, s := range health {
+		http.Handle(path, sync.NewHealthReporter(s))
+		status := sync.NewStatusPage(s)
+		status.Ingester = ingester
+		http.Handle(strings.Replace(path, "/healthz", "/status", 1), status)
+	}
+	if webhook != nil {
+		http.Handle("/webhook", webhook)
+	}
+	go ingester.Run(context.Background(), o.syncInterval)
+	glog.Infof("Accepting sources on %v", o.listen)
+	return http.ListenAndServe(o.listen, nil)
+}
+
+func main() {
+	config := &github.Config{}
+	o := &options{}
+	root := &cobra.Command{
+		Use:   filepath.Base(os.Args[0]),
+		Short: "Files deduplicated github issues for sources described in JSON",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return run(config, o)
+		},
+	}
+	root.SetGlobalNormalizationFunc(utilflag.WordSepNormalizeFunc)
+	config.AddRootFlags(root)
+	root.Flags().StringVar(&o.sources, "sources", "-", "A JSON file of sources, a directory of them, or - for stdin. Each holds a source or a list of them, like {\"title\": ..., \"id\": ..., \"body\": ..., \"labels\": [...]}")
+	root.Flags().StringVar(&o.namespace, "namespace", "", "If set, a prefix for the titles and labels of the issues, to keep them apart from those of other syncers")
+	root.Flags().StringSliceVar(&o.labels, "label", []string{}, "Only issues with all of these labels are considered when looking for existing issues")
+	root.Flags().StringVar(&o.normalize, "title-normalization", "", "If set, how titles are normalized into the keys issues are found by: default (lowercase, without timestamps, IDs, run numbers and node names) or a yaml file of regexp rules")
+	root.Flags().StringVar(&o.quiet, "quiet-hours", "", "If set, a yaml file of weekly windows (and a freeze file to watch) during which sources are held instead of synced; with --listen or --pubsub-subscription they are synced once the quiet hours are over")
+	root.Flags().BoolVar(&o.plan, "plan", false, "If true, print what syncing --sources would do (issues to file, comment on or close as duplicates) instead of doing it")
+	root.Flags().StringVar(&o.reconcile, "reconcile", "", "If set, compare --sources, taken to be all of the active sources, with the open issues the syncer filed (see --metadata) instead of syncing them, and print the drift: report only lists it, fix also closes issues without an active source, files active sources without an issue and adds missing labels")
+	root.Flags().StringVar(&o.idMatch, "id-matching", string(sync.IDMatchExact), "How sources are recognized in issues: exact (their marker, or their ID as a whole word, for issues from before markers), marker (only their marker) or substring (their ID anywhere, as before markers)")
+	root.Flags().StringVar(&o.redact, "redaction-rules", "", "If set, a yaml file of regexp rules for what to redact from the bodies of sources before they are posted, e.g. IP addresses, tokens and internal hostnames")
+	root.Flags().BoolVar(&o.redactLog, "redaction-report-only", false, "If true, only log what --redaction-rules would redact, to try them out")
+	root.Flags().StringVar(&o.muteLabel, "mute-label", sync.DefaultMuteLabel, "The label humans put on an issue to silence it: while it has the label, new occurrences are only counted (see --metadata) instead of commented. Empty turns muting off")
+	root.Flags().IntVar(&o.ccAuthors, "cc-recent-authors", 0, "If set, new issues of sources which name the file they failed in (\"file\") cc up to this many people who recently committed to it in --organization/--project")
+	root.Flags().DurationVar(&o.ccAuthorsSince, "cc-recent-authors-window", 90*24*time.Hour, "With --cc-recent-authors, how far back commits count")
+	root.Flags().IntVar(&o.histogram, "histogram-days", 0, "If set, keep a sparkline of the occurrences per day over this many days (at most 35) in the body of issues, counted in --metadata")
+	root.Flags().BoolVar(&o.fanOut, "fan-out-owners", false, "If set, sources with more than one owner (\"owners\": [\"sig/node\", \"sig/storage\"]) get a tracking issue and a linked issue per owner, instead of a single issue with all of the owner labels")
+	root.Flags().DurationVar(&o.lockAfter, "lock-closed-after", 0, "If set, how long after closing an issue as a duplicate it is locked, with a pointer to the issue it duplicates (see --metadata)")
+	root.Flags().StringVar(&o.attachDest, "attachments-dest", "", "If set, where the attachments of sources (\"attachments\": [{\"name\": ..., \"content\" or \"path\": ...}]) too big to include in issues are uploaded and linked from: a gs:// URL, or gist")
+	root.Flags().StringVar(&o.attachDir, "attachments-dir", "", "With --attachments-dest, the directory attachment paths are read from. Without it, only attachments with content are used")
+	root.Flags().IntVar(&o.attachInline, "attachments-inline-limit", 4<<10, "With --attachments-dest, the size (in bytes) up to which attachments are included in issues instead of uploaded")
+	root.Flags().StringVar(&o.templates, "templates", "", "If set, a yaml file with the texts the syncer writes (duplicate, recurrence, staleClose, lock, escalation, sloBreach and a footer added to everything) and the bot's name and help URL to sign with. Tenants have their own, see --tenants")
+	root.Flags().StringVar(&o.metadata, "metadata", "", "If set, a file in which to remember the issues filed, across runs")
+	root.Flags().StringVar(&o.auditLog, "audit-log", "", "If set, a file to which every change made on github is appended")
+	root.Flags().StringVar(&o.historyCSV, "history-csv", "", "If set, a directory to which every change made on github and every occurrence synced is written, in a CSV file a day, for analyses outside of github")
+	root.Flags().Int64Var(&o.historyCSVMax, "history-csv-max-bytes", 100<<20, "With --history-csv, the size (in bytes) after which a day's file is continued in another")
+	root.Flags().StringVar(&o.historyBigQuery, "history-bigquery", "", "If set, a BigQuery table (project:dataset.table) into which every change made on github and every occurrence synced is streamed, for analyses outside of github. Not with --history-csv")
+	root.Flags().StringVar(&o.tenants, "tenants", "", "If set, a yaml file of tenants, each with its own repo, labels, templates, caps and escalation policy; sources pick theirs with \"tenant\". Replaces --namespace, --label and --metadata")
+	root.Flags().StringVar(&o.defaultTenant, "default-tenant", "", "With --tenants, the tenant of sources which don't name one")
+	root.Flags().StringVar(&o.mirrorTo, "mirror-to", "", "If set, a private staging repo (org/project) to which every change is made instead: issues are still read from --organization/--project, but changed through mirror issues filed in the staging repo, and new issues are filed there, to try the syncer out against real issues")
+	root.Flags().StringVar(&o.mirrorState, "mirror-state", "", "With --mirror-to, a file in which to remember which issues are mirrored where, across runs")
+	root.Flags().StringVar(&o.upstream, "upstream", "", "If set, the repo (org/project) --organization/--project is a fork of: sources with an open issue there, found by sync key, are recorded on it instead of filed again. Not with --tenants")
+	root.Flags().StringVar(&o.listen, "listen", "", "If set, an address (e.g. :8080) on which to accept sources POSTed to /sources, instead of reading --sources. The state of the syncer is served on /status")
+	root.Flags().StringVar(&o.tokenFile, "token-file", "", "With --listen, a file holding the token clients must send as \"Authorization: Bearer <token>\"")
+	root.Flags().StringVar(&o.webhookFile, "webhook-secret-file", "", "With --listen, a file with the secret of a github webhook for issues and issue comments, whose deliveries are accepted on /webhook so that changes to issues are noticed right away")
+	root.Flags().StringVar(&o.subscription, "pubsub-subscription", "", "If set, a Pub/Sub subscription (projects/<project>/subscriptions/<name>) from which to keep syncing sources, instead of reading --sources. Messages are acked once synced")
+	root.Flags().StringVar(&o.schedule, "schedule", "", "If set, a yaml file of commands which print sources as JSON, each with how often to run it (e.g. flakes: {every: 10m, command: [./find-flakes.sh]}), to keep syncing their sources instead of reading --sources")
+	root.Flags().StringVar(&o.sourceFlags, "source-flags", "", "With --schedule, a yaml file of flags per command, read again whenever it changes: enabled (false stops running it), labels (replacing those of its sources), maxSources (synced per run) and dryRun (log what syncing would do instead)")
+	root.Flags().DurationVar(&o.syncInterval, "sync-interval", time.Minute, "With --listen or --pubsub-subscription, how often to sync the sources received")
+	root.Flags().DurationVar(&o.stopTimeout, "stop-timeout", 25*time.Second, "On SIGTERM, how long to wait for the sources being synced to be done before exiting anyway")
+	root.Flags().BoolVar(&o.retest, "retest", false, "If true, rerun the jobs of pull requests which failed with a flake (sources with \"pr\" and \"context\"), and report how the rerun went on the flake's issue")
+	root.Flags().StringVar(&o.retestCmd, "retest-command", sync.DefaultRetestCommand, "With --retest, what to comment on pull requests to rerun their jobs")
+	root.Flags().IntVar(&o.maxRetests, "max-retests", 1, "With --retest, how many times a pull request may be retested")
+	root.Flags().StringVar(&o.logFormat, "log-format", sync.LogFormatText, "How to log: text or json")
+	if err := root.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
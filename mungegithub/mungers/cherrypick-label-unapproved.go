@@ -18,6 +18,7 @@ package mungers
 
 import (
 	"fmt"
+	"strings"
 
 	"k8s.io/contrib/mungegithub/features"
 	"k8s.io/contrib/mungegithub/github"
@@ -83,14 +84,14 @@ func (LabelUnapprovedPicks) Munge(obj *github.MungeObject) {
 
 	obj.AddLabel(doNotMergeLabel)
 
-	obj.WriteComment(labelUnapprovedBody)
+	obj.StickyComment(labelUnapprovedPicksName, labelUnapprovedBody)
 }
 
 func (LabelUnapprovedPicks) isStaleComment(obj *github.MungeObject, comment githubapi.IssueComment) bool {
 	if !mergeBotComment(comment) {
 		return false
 	}
-	if *comment.Body != labelUnapprovedBody {
+	if !strings.HasPrefix(*comment.Body, labelUnapprovedBody) {
 		return false
 	}
 	stale := obj.HasLabel(cpApprovedLabel)
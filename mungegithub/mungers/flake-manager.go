@@ -17,8 +17,15 @@ limitations under the License.
 package mungers
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"k8s.io/contrib/mungegithub/features"
 	"k8s.io/contrib/mungegithub/github"
@@ -26,10 +33,14 @@ import (
 	"k8s.io/contrib/mungegithub/mungers/sync"
 	"k8s.io/contrib/test-utils/utils"
 
-	// "github.com/golang/glog"
+	"github.com/golang/glog"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
+// flakeSourceFlags is the entry of --flake-source-flags for flakes.
+const flakeSourceFlags = "flakes"
+
 // issueFinder finds an issue for a given key.
 type issueFinder interface {
 	AllIssuesForKey(key string) []int
@@ -45,6 +56,81 @@ type FlakeManager struct {
 	googleGCSBucketUtils *utils.Utils
 
 	syncer *sync.IssueSyncer
+	// ctx is canceled when we are asked to shut down, once the syncer has
+	// stopped or stopTimeout has passed, see drainOnSignal.
+	ctx         context.Context
+	cancel      context.CancelFunc
+	stopTimeout time.Duration
+	// busy is held while syncing, so that shutdown can wait for it.
+	busy         chan struct{}
+	callTimeout  time.Duration
+	editBody     bool
+	taskList     bool
+	suspects     time.Duration
+	lockAfter    time.Duration
+	maxCreates   int
+	maxOpen      int
+	minAPIBudget int
+
+	syncRetries    int
+	syncRetryDelay time.Duration
+
+	ownershipExporter *sync.OwnershipExporter
+	ownershipDest     string
+	teamPaths         []string
+
+	calendarPath string
+	normalizer   string
+
+	reportDest   string
+	reportPeriod time.Duration
+	reportTop    int
+	nextReport   time.Time
+
+	metadataPath   string
+	escalationPath string
+	escalation     *sync.EscalationPolicy
+	sloPath        string
+	slo            *sync.SLOPolicy
+
+	linkRelated bool
+	logFormat   string
+	auditPath   string
+	templates   string
+
+	health       *sync.HealthReporter
+	healthRepo   string
+	healthBranch string
+	healthMaxAge time.Duration
+
+	reopenWithin time.Duration
+	reactAfter   int
+	muteLabel    string
+	histogram    int
+	taxonomyPath string
+	minResync    time.Duration
+	reopen       bool
+	triageQuiet  time.Duration
+	maxComments  int
+	bulkComments int
+	quietHours   string
+	// sourceFlags, read from sourceFlagsPath, can turn flake syncing off,
+	// relabel, cap or dry-run it without a restart.
+	sourceFlags     *sync.FeatureFlags
+	sourceFlagsPath string
+	// webhookSecret is the file with the secret of the issue webhook.
+	webhookSecret string
+
+	// board is set from flags, and used if it names a project.
+	board sync.ProjectBoard
+
+	lifecycle   *sync.Lifecycle
+	staleAfter  time.Duration
+	rottenAfter time.Duration
+	closeAfter  time.Duration
+
+	// If true, search github for issues until the issue-cacher has synced.
+	searchFallback bool
 }
 
 func init() {
@@ -75,8 +161,185 @@ func (p *FlakeManager) Initialize(config *github.Config, features *features.Feat
 		return fmt.Errorf("submit-queue not found")
 	}
 	p.config = config
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.busy = make(chan struct{}, 1)
 	p.googleGCSBucketUtils = utils.NewUtils(utils.KubekinsBucket, utils.LogDir)
-	p.syncer = sync.NewIssueSyncer(config, p.finder)
+	var normalizer *sync.TitleNormalizer
+	switch p.normalizer {
+	case "":
+	case "default":
+		normalizer = sync.DefaultTitleNormalizer()
+	default:
+		var err error
+		if normalizer, err = sync.LoadTitleNormalizer(p.normalizer); err != nil {
+			return err
+		}
+	}
+	p.finder.(*IssueCacher).Normalizer = normalizer
+	var finder sync.IssueFinder = p.finder
+	if p.searchFallback {
+		search := sync.NewSearchFinder(config, []string{sync.Namespaced(p.finder.(*IssueCacher).Namespace, "kind/flake")})
+		search.Normalizer = normalizer
+		finder = &sync.FallbackFinder{
+			Primary:  p.finder,
+			Fallback: search,
+		}
+	}
+	logger, err := sync.NewLogger(p.logFormat)
+	if err != nil {
+		return err
+	}
+	backoff := sync.DefaultBackoff
+	backoff.Steps = p.syncRetries
+	backoff.Initial = p.syncRetryDelay
+	opts := []sync.Option{
+		sync.WithRepo(config),
+		sync.WithFinder(finder),
+		sync.WithNormalizer(normalizer),
+		sync.WithNamespace(p.finder.(*IssueCacher).Namespace),
+		sync.WithLogger(logger),
+		sync.WithCreationBudget(p.maxCreates, p.maxOpen),
+		sync.WithAPIBudget(p.minAPIBudget),
+		sync.WithPersistence(p.metadataPath),
+		sync.WithTemplatesFile(p.templates),
+		sync.WithBackoff(backoff),
+		sync.WithCallTimeout(p.callTimeout),
+		sync.WithEditBody(p.editBody),
+		sync.WithCommentLimits(p.minResync, p.maxComments, p.reactAfter),
+		sync.WithBulkComments(p.bulkComments),
+		sync.WithMuteLabel(p.muteLabel),
+		sync.WithHistogram(p.histogram),
+	}
+	if p.taskList {
+		opts = append(opts, sync.WithTaskList(sync.NewTaskList()))
+	}
+	if p.suspects > 0 {
+		suspects := sync.NewSuspectMerges()
+		suspects.Window = p.suspects
+		opts = append(opts, sync.WithSuspects(suspects))
+	}
+	if p.lockAfter > 0 {
+		locking := sync.NewClosedLocking()
+		locking.After = p.lockAfter
+		opts = append(opts, sync.WithLocking(locking))
+	}
+	if p.calendarPath != "" {
+		calendar, err := sync.LoadCalendar(p.calendarPath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, sync.WithCalendar(calendar))
+	}
+	if p.auditPath != "" {
+		audit, err := sync.NewAuditLog(p.auditPath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, sync.WithAudit(audit))
+	}
+	if p.taxonomyPath != "" {
+		taxonomy, err := sync.LoadLabelTaxonomy(p.taxonomyPath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, sync.WithTaxonomy(taxonomy))
+	}
+	if p.reopenWithin > 0 {
+		match := sync.NewClosedMatch()
+		match.Within = p.reopenWithin
+		match.Reopen = p.reopen
+		match.Bots.Insert(botName, jenkinsBotName)
+		opts = append(opts, sync.WithRecentlyClosed(match))
+	}
+	if p.board.Project != "" {
+		opts = append(opts, sync.WithBoard(&p.board))
+	}
+	if p.quietHours != "" {
+		quiet, err := sync.LoadQuietHours(p.quietHours)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, sync.WithQuietHours(quiet))
+	}
+	if p.sourceFlagsPath != "" {
+		flags, err := sync.LoadFeatureFlags(p.sourceFlagsPath)
+		if err != nil {
+			return err
+		}
+		p.sourceFlags = flags
+	}
+	if p.triageQuiet > 0 {
+		triage := sync.NewTriageSignals()
+		triage.QuietAfter = p.triageQuiet
+		triage.Bots.Insert(botName, jenkinsBotName)
+		opts = append(opts, sync.WithTriage(triage))
+	}
+	if p.linkRelated {
+		opts = append(opts, sync.WithRelated(sync.NewRelatedIssues()))
+	}
+	if p.syncer, err = sync.New(opts...); err != nil {
+		return err
+	}
+	p.health = sync.NewHealthReporter(p.syncer)
+	p.health.MaxAge = p.healthMaxAge
+	p.health.Branch = p.healthBranch
+	if p.healthRepo != "" {
+		parts := strings.SplitN(p.healthRepo, "/", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid --flake-sync-health-repo %q, expected org/project", p.healthRepo)
+		}
+		p.health.Status = config.ForRepo(parts[0], parts[1])
+	}
+	if len(config.Address) > 0 {
+		http.Handle("/healthz", p.health)
+		http.Handle("/flake-status", sync.NewStatusPage(p.syncer))
+	}
+	if p.webhookSecret != "" {
+		secret, err := ioutil.ReadFile(p.webhookSecret)
+		if err != nil {
+			return fmt.Errorf("error reading --flake-webhook-secret-file: %v", err)
+		}
+		webhook := sync.NewWebhookReceiver(bytes.TrimSpace(secret), finder.(sync.IssueObserver))
+		webhook.Syncer = p.syncer
+		webhook.Logger = logger
+		http.Handle("/flake-webhook", webhook)
+	}
+	if p.closeAfter > 0 {
+		p.lifecycle = sync.NewLifecycle()
+		p.lifecycle.StaleAfter = p.staleAfter
+		p.lifecycle.RottenAfter = p.rottenAfter
+		p.lifecycle.CloseAfter = p.closeAfter
+		p.lifecycle.Bots.Insert(botName, jenkinsBotName)
+	}
+	if p.escalationPath != "" {
+		policy, err := sync.LoadEscalationPolicy(p.escalationPath)
+		if err != nil {
+			return err
+		}
+		p.escalation = policy
+	}
+	if p.sloPath != "" {
+		policy, err := sync.LoadSLOPolicy(p.sloPath)
+		if err != nil {
+			return err
+		}
+		p.slo = policy
+	}
+
+	if p.ownershipDest != "" {
+		p.ownershipExporter = sync.NewOwnershipExporter(config, sync.Namespaced(p.syncer.Namespace, "kind/flake"))
+		for _, tp := range p.teamPaths {
+			parts := strings.SplitN(tp, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid --flake-team-paths entry %q, expected label=path", tp)
+			}
+			p.ownershipExporter.TeamPaths[parts[0]] = parts[1]
+		}
+		if features.Repos != nil {
+			p.ownershipExporter.Owners = features.Repos.Assignees
+		}
+	}
+	go p.drainOnSignal()
 	return nil
 }
 
@@ -85,29 +348,202 @@ func (p *FlakeManager) EachLoop() error {
 	if p.sq.e2e == nil {
 		return fmt.Errorf("submit queue not initialized yet")
 	}
-	if !p.finder.Synced() {
+	if !p.searchFallback && !p.finder.Synced() {
 		return nil
 	}
+	p.busy <- struct{}{}
+	defer func() { <-p.busy }()
+
 	p.sq.e2e.GCSBasedStable()
+	sources := []sync.IssueSource{}
 	for _, f := range p.sq.e2e.Flakes() {
-		p.syncFlake(f)
+		sources = append(sources, p.flakeSource(f))
+	}
+	p.syncFlakes(sources)
+	if err := p.health.Publish(); err != nil {
+		glog.Errorf("Unable to publish flake syncing health: %v", err)
+	}
+	if p.escalation != nil {
+		if err := p.syncer.Escalate(p.ctx, p.escalation); err != nil {
+			glog.Errorf("Unable to escalate flake issues: %v", err)
+		}
+	}
+	if p.slo != nil {
+		if err := p.syncer.TrackSLOs(p.ctx, p.slo); err != nil {
+			glog.Errorf("Unable to track the SLOs of flake issues: %v", err)
+		}
+	}
+	if p.lifecycle != nil {
+		if err := p.syncer.UpdateLifecycle(p.ctx, p.lifecycle); err != nil {
+			glog.Errorf("Unable to update the lifecycle of flake issues: %v", err)
+		}
+	}
+	// Escalating and the lifecycle change the metadata after SyncAll
+	// persisted it.
+	if err := p.syncer.Store.Flush(); err != nil {
+		glog.Errorf("Unable to persist the flake issue metadata: %v", err)
+	}
+	if p.ownershipExporter != nil {
+		if err := p.ownershipExporter.Export(p.ownershipDest); err != nil {
+			glog.Errorf("Unable to export flake issue ownership: %v", err)
+		}
+	}
+	if p.reportDest != "" && !time.Now().Before(p.nextReport) {
+		end := time.Now().UTC().Truncate(24 * time.Hour)
+		report := sync.NewFlakeReport(p.syncer.Store, end, p.reportPeriod, p.reportTop)
+		if err := p.syncer.PublishReport(p.ctx, report, p.reportDest); err != nil {
+			glog.Errorf("Unable to publish the flake report: %v", err)
+		} else {
+			p.nextReport = end.Add(p.reportPeriod)
+		}
 	}
 	return nil
 }
 
+// syncFlakes syncs `sources` as the flags of flakeSourceFlags in
+// --flake-source-flags have it: not at all, relabeled or capped, or only
+// planned.
+func (p *FlakeManager) syncFlakes(sources []sync.IssueSource) {
+	flags := p.sourceFlags.For(flakeSourceFlags)
+	if !flags.IsEnabled() {
+		glog.Infof("Flake syncing is turned off by %v, not syncing %d flakes", p.sourceFlagsPath, len(sources))
+		return
+	}
+	flagged := flags.Apply(sources)
+	if len(flagged) < len(sources) {
+		glog.Warningf("Syncing %d of %d flakes, the most allowed per loop", len(flagged), len(sources))
+	}
+	if flags.DryRun {
+		plan, err := p.syncer.Plan(p.ctx, flagged)
+		if err != nil {
+			glog.Errorf("Unable to plan syncing flakes: %v", err)
+			return
+		}
+		glog.Infof("Dry run, syncing flakes would do:\n%v", plan)
+		return
+	}
+	if _, err := p.syncer.SyncAll(p.ctx, flagged); err != nil {
+		glog.Errorf("Unable to sync all flakes: %v", err)
+	}
+}
+
+// drainOnSignal stops the syncer when we are asked to shut down, so that
+// the source being synced is finished and the metadata, audit log and history
+// are persisted, waits for the loop in progress to wrap up, and then dies of
+// the signal after all. github calls in flight are only canceled once the
+// syncer has stopped, or --flake-sync-stop-timeout has passed.
+func (p *FlakeManager) drainOnSignal() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGTERM, os.Interrupt)
+	sig := <-c
+	glog.Infof("Got %v, stopping flake issue syncing once the flake being synced is done", sig)
+	ctx, cancel := context.WithTimeout(context.Background(), p.stopTimeout)
+	unfinished, err := p.syncer.Stop(ctx)
+	cancel()
+	if err != nil {
+		glog.Errorf("Unable to stop the flake issue syncer cleanly: %v", err)
+	}
+	if len(unfinished) > 0 {
+		glog.Warningf("The flake issue syncer left %d flakes unsynced: %v", len(unfinished), strings.Join(unfinished, ", "))
+	}
+	p.cancel()
+	p.busy <- struct{}{}
+	glog.Flush()
+	signal.Stop(c)
+	syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+}
+
 // AddFlags will add any request flags to the cobra `cmd`
-func (p *FlakeManager) AddFlags(cmd *cobra.Command, config *github.Config) {}
+func (p *FlakeManager) AddFlags(cmd *cobra.Command, config *github.Config) {
+	cmd.Flags().IntVar(&p.syncRetries, "flake-sync-retries", sync.DefaultBackoff.Steps, "How many times to try a github call when filing flake issues before giving up until the next loop")
+	cmd.Flags().DurationVar(&p.syncRetryDelay, "flake-sync-retry-delay", sync.DefaultBackoff.Initial, "How long to wait before the first retry of a failed github call; doubled for every further retry")
+	cmd.Flags().DurationVar(&p.callTimeout, "flake-sync-call-timeout", time.Minute, "How long a github request made while filing flake issues may take; 0 for no limit")
+	cmd.Flags().DurationVar(&p.stopTimeout, "flake-sync-stop-timeout", 25*time.Second, "On SIGTERM, how long to wait for the flake being synced to be done before canceling syncing")
+	cmd.Flags().IntVar(&p.maxCreates, "flake-sync-max-creates", 20, "The most flake issues to file in one sync cycle; 0 for no limit")
+	cmd.Flags().IntVar(&p.maxOpen, "flake-sync-max-open", 500, "Stop filing flake issues while this many of the ones we filed are open (see --flake-sync-metadata); 0 for no limit")
+	cmd.Flags().IntVar(&p.minAPIBudget, "flake-sync-min-api-budget", 0, "While fewer github API calls than this remain, only sync flake issues for broken jobs; 0 to always sync everything")
+	cmd.Flags().StringVar(&p.board.Project, "flake-project", "", "If set, the name of a project board on which flake issues get a card")
+	cmd.Flags().StringVar(&p.board.NewColumn, "flake-project-new-column", "To Triage", "The --flake-project column in which cards for new flake issues go")
+	cmd.Flags().StringVar(&p.board.EscalatedColumn, "flake-project-escalated-column", "", "If set, the --flake-project column to which cards are moved when their issue is escalated")
+	cmd.Flags().StringVar(&p.board.ClosedColumn, "flake-project-closed-column", "", "If set, the --flake-project column to which cards are moved when their issue is closed")
+	cmd.Flags().StringVar(&p.healthRepo, "flake-sync-health-repo", "", "If set, an org/project on which the health of flake issue syncing is published as a github status")
+	cmd.Flags().StringVar(&p.healthBranch, "flake-sync-health-branch", "master", "The branch of --flake-sync-health-repo whose head gets the status")
+	cmd.Flags().DurationVar(&p.healthMaxAge, "flake-sync-health-max-age", time.Hour, "How long flake issue syncing may go without syncing every flake before it is unhealthy, see /healthz")
+	cmd.Flags().DurationVar(&p.reopenWithin, "flake-reopen-within", 0, "If set, a flake whose issue was closed this recently gets a new issue linking to the old one, with a summary of how it was handled")
+	cmd.Flags().BoolVar(&p.reopen, "flake-reopen", false, "If true, reopen the issue closed within --flake-reopen-within instead of filing a new one")
+	cmd.Flags().DurationVar(&p.minResync, "flake-sync-min-interval", 0, "If set, the least time between two comments about new occurrences on a flake issue; occurrences in between are only counted (see --flake-sync-metadata)")
+	cmd.Flags().StringVar(&p.taxonomyPath, "flake-label-taxonomy", "", "If set, a yaml file of label aliases and of how to create missing labels; labels of flake issues which the repo doesn't have are then created or left out")
+	cmd.Flags().IntVar(&p.maxComments, "flake-sync-max-comments", 0, "If set, once the bot commented this often on a flake issue (see --flake-sync-metadata), it is closed and continued in a new issue")
+	cmd.Flags().StringVar(&p.sourceFlagsPath, "flake-source-flags", "", "If set, a yaml file of feature flags, read again whenever it changes, whose "+flakeSourceFlags+" entry can turn flake syncing off (enabled: false), replace the labels of flake issues (labels), cap the flakes synced per loop (maxSources) or log what syncing would do instead (dryRun)")
+	cmd.Flags().StringVar(&p.quietHours, "flake-sync-quiet-hours", "", "If set, a yaml file of weekly windows (and a freeze file to watch) during which flakes are held instead of synced, until the quiet hours are over")
+	cmd.Flags().IntVar(&p.bulkComments, "flake-sync-graphql-comments", 0, "If set, fetch the candidate issues for a flake, with this many of their most recent comments, in one GraphQL query instead of REST calls for each")
+	cmd.Flags().StringVar(&p.muteLabel, "flake-sync-mute-label", sync.DefaultMuteLabel, "The label humans put on a flake issue to silence it: while it has the label, new occurrences are only counted (see --flake-sync-metadata) instead of commented. Empty turns muting off")
+	cmd.Flags().IntVar(&p.histogram, "flake-sync-histogram-days", 0, "If set, keep a sparkline of the occurrences per day over this many days (at most 35) in the body of flake issues (see --flake-sync-metadata)")
+	cmd.Flags().IntVar(&p.reactAfter, "flake-sync-react-after", 0, "If set, once a flake issue has this many occurrences, new ones only get a reaction instead of a comment (see --flake-sync-metadata)")
+	cmd.Flags().DurationVar(&p.triageQuiet, "flake-triage-quiet", 0, "If set, while a flake issue is being triaged (it has an assignee or the triaged label, or someone commented after the bot) and until it has been quiet this long, new occurrences are recorded in its body instead of commented")
+	cmd.Flags().BoolVar(&p.editBody, "flake-sync-edit-body", false, "If true, keep a summary and a table of recent occurrences in the body of flake issues, instead of commenting for every occurrence")
+	cmd.Flags().BoolVar(&p.taskList, "flake-sync-task-list", false, "If true, add occurrences to a task list comment on flake issues instead of commenting for every occurrence. Unchecked occurrences are mentioned again daily")
+	cmd.Flags().DurationVar(&p.suspects, "flake-sync-suspect-window", 0, "If set, new flake issues list the merges to master in this window before the flake was synced, as suspects")
+	cmd.Flags().DurationVar(&p.lockAfter, "flake-sync-lock-closed-after", 0, "If set, how long (in business time, see --flake-calendar) after closing a flake issue as a duplicate or as stale it is locked, with a pointer to the issue to continue in (see --flake-sync-metadata)")
+	cmd.Flags().StringVar(&p.ownershipDest, "flake-ownership-export", "", "If set, a file or gs:// URL to which a JSON list of the owners of all open flake issues is written every loop")
+	cmd.Flags().BoolVar(&p.searchFallback, "flake-search-fallback", false, "If true, file flake issues right after a restart, using github search to find existing issues until the issue-cacher has seen every issue")
+	cmd.Flags().StringVar(&p.reportDest, "flake-report", "", "If set, where to publish a report of the top, new and resolved flakes every --flake-report-period (and after a restart): issue, gist, or a file or gs:// URL (HTML if it ends in .html, else Markdown). Requires --flake-sync-metadata")
+	cmd.Flags().DurationVar(&p.reportPeriod, "flake-report-period", 7*24*time.Hour, "The period covered by each --flake-report")
+	cmd.Flags().IntVar(&p.reportTop, "flake-report-top", 10, "How many of the flakes with the most occurrences --flake-report lists")
+	cmd.Flags().StringVar(&p.normalizer, "flake-title-normalization", "", "If set, how flake issue titles are normalized into the keys issues are found by: default (lowercase, without timestamps, IDs, run numbers and node names) or a yaml file of regexp rules. Changing it may file duplicates of existing issues")
+	cmd.Flags().StringVar(&p.calendarPath, "flake-calendar", "", "If set, a yaml file listing the weekend, holidays and freeze periods, which don't count for flake issue timers")
+	cmd.Flags().StringVar(&p.webhookSecret, "flake-webhook-secret-file", "", "If set, a file with the secret of a github webhook for issues and issue comments, whose deliveries are accepted on /flake-webhook (see --address) so that changes to flake issues are noticed right away")
+	cmd.Flags().StringVar(&p.metadataPath, "flake-sync-metadata", "", "If set, a file in which to keep track of the flake issues we filed across restarts")
+	cmd.Flags().StringVar(&p.escalationPath, "flake-escalation-config", "", "If set, a yaml file with the schedule by which untriaged flake issues are escalated, and the release tracking issues whose references raise their priority. Issue ages are counted in business days of --flake-calendar")
+	cmd.Flags().StringVar(&p.sloPath, "flake-slo-config", "", "If set, a yaml file of how soon flake issues with a priority label must be triaged. Their issues show a countdown and are escalated once overdue")
+	cmd.Flags().BoolVar(&p.linkRelated, "flake-link-related", false, "If true, comment on new flake issues with links to older issues about similar tests (requires --flake-sync-metadata to know about issues filed before a restart)")
+	cmd.Flags().DurationVar(&p.staleAfter, "flake-stale-after", 10*24*time.Hour, "How long (in business time, see --flake-calendar) a flake issue must be idle to be labeled lifecycle/stale")
+	cmd.Flags().DurationVar(&p.rottenAfter, "flake-rotten-after", 20*24*time.Hour, "How long a flake issue must be idle to be labeled lifecycle/rotten")
+	cmd.Flags().DurationVar(&p.closeAfter, "flake-close-after", 0, "If set, how long a flake issue must be idle to be closed. Enables lifecycle labels, which requires --flake-sync-metadata to survive restarts")
+	cmd.Flags().StringVar(&p.logFormat, "flake-sync-log-format", sync.LogFormatText, "How the flake issue syncer logs, tagging lines with the sync cycle, flake and issue: text (to the usual log) or json (one object per line on stderr)")
+	cmd.Flags().StringVar(&p.auditPath, "flake-sync-audit-log", "", "If set, a file to which every change the flake issue syncer makes on github is appended, see undo-flake-sync")
+	cmd.Flags().StringVar(&p.templates, "flake-comment-templates", "", "If set, a yaml file with templates for the comments the flake issue syncer writes (duplicate, recurrence, staleClose, lock, escalation, sloBreach and a footer added to everything) and the bot's name and help URL to sign with")
+	cmd.Flags().StringSliceVar(&p.teamPaths, "flake-team-paths", []string{}, "Comma separated list of label=path pairs. Owners of flake issues with the label are taken from the OWNERS files for the path (requires the gitrepos feature)")
+	p.addUndoCommand(cmd, config)
+}
+
+func (p *FlakeManager) addUndoCommand(root *cobra.Command, config *github.Config) {
+	var path, source string
+	undo := &cobra.Command{
+		Use:   "undo-flake-sync CYCLE",
+		Short: "Revert the changes the flake issue syncer made in the given sync cycle, as recorded in its audit log. With --dry-run only logs the changes",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 || path == "" {
+				return fmt.Errorf("usage: undo-flake-sync --audit-log=PATH CYCLE")
+			}
+			if err := config.PreExecute(); err != nil {
+				return err
+			}
+			entries, err := sync.ReadAuditLog(path, func(e sync.AuditEntry) bool {
+				return e.Cycle == args[0] && (source == "" || e.Source == source)
+			})
+			if err != nil {
+				return err
+			}
+			glog.Infof("Undoing %d changes made in cycle %v", len(entries), args[0])
+			return sync.Undo(config, entries)
+		},
+	}
+	undo.Flags().StringVar(&path, "audit-log", "", "The audit log written with --flake-sync-audit-log")
+	undo.Flags().StringVar(&source, "source", "", "If set, only undo changes made while syncing this source")
+	root.AddCommand(undo)
+}
 
 // Munge is unused by this munger.
 func (p *FlakeManager) Munge(obj *github.MungeObject) {}
 
-func (p *FlakeManager) syncFlake(f cache.Flake) error {
+func (p *FlakeManager) flakeSource(f cache.Flake) sync.IssueSource {
 	if p.isIndividualFlake(f) {
 		// Just an individual failure.
-		return p.syncer.Sync(&individualFlakeSource{f, p})
+		return &individualFlakeSource{f, p}
 	}
 
-	return p.syncer.Sync(&brokenJobSource{f.Result, p})
+	return &brokenJobSource{f.Result, p}
 }
 
 func (p *FlakeManager) isIndividualFlake(f cache.Flake) bool {
@@ -153,11 +589,13 @@ func (p *individualFlakeSource) ID() string {
 // Body implements IssueSource
 func (p *individualFlakeSource) Body(newIssue bool) string {
 	testName := string(p.flake.Test)
-	extraInfo := fmt.Sprintf("Failed: %v\n\n```\n%v\n```\n\n", testName, p.flake.Reason)
-	body := p.ID() + "\n" + extraInfo
+	body := sync.NewBodyBuilder().
+		Text("%v", p.ID()).
+		Text("Failed: %v", testName).
+		Log("output", p.flake.Reason)
 
 	if !newIssue {
-		return body
+		return body.String()
 	}
 
 	// If we're filing a new issue, reference previous issues if we know of any.
@@ -166,9 +604,9 @@ func (p *individualFlakeSource) Body(newIssue bool) string {
 		for _, i := range previousIssues {
 			s = append(s, fmt.Sprintf("#%v", i))
 		}
-		body = body + fmt.Sprintf("\nPrevious issues for this test: %v\n", strings.Join(s, " "))
+		body.Text("Previous issues for this test: %v", strings.Join(s, " "))
 	}
-	return body
+	return body.String()
 }
 
 // Labels implements IssueSource
@@ -207,11 +645,10 @@ func (p *brokenJobSource) Body(newIssue bool) string {
 	if p.result.Status == cache.ResultFailed {
 		return fmt.Sprintf("%v\nRun so broken it didn't make JUnit output!", p.ID())
 	}
-	body := fmt.Sprintf("%v\nMultiple broken tests:\n\n", p.ID())
+	body := sync.NewBodyBuilder().Text("%v\nMultiple broken tests:", p.ID())
 
-	sections := []string{}
 	for testName, reason := range p.result.Flakes {
-		text := fmt.Sprintf("Failed: %v\n\n```\n%v\n```\n", testName, reason)
+		body.Text("Failed: %v", testName).Log("output", reason)
 		// Reference previous issues if we know of any.
 		// (key must batch individualFlakeSource.Title()!)
 		if previousIssues := p.fm.finder.AllIssuesForKey(string(testName)); len(previousIssues) > 0 {
@@ -219,14 +656,19 @@ func (p *brokenJobSource) Body(newIssue bool) string {
 			for _, i := range previousIssues {
 				s = append(s, fmt.Sprintf("#%v", i))
 			}
-			text = text + fmt.Sprintf("Issues about this test specifically: %v\n", strings.Join(s, " "))
+			body.Text("Issues about this test specifically: %v", strings.Join(s, " "))
 		}
-		sections = append(sections, text)
 	}
-	return body + strings.Join(sections, "\n\n")
+	return body.String()
 }
 
 // Labels implements IssueSource
 func (p *brokenJobSource) Labels() []string {
 	return []string{"kind/flake", "team/test-infra"}
 }
+
+// Severity implements sync.SeveritySource: a whole job failing is worse than
+// a test flaking.
+func (p *brokenJobSource) Severity() sync.Severity {
+	return sync.SeverityHigh
+}
@@ -22,6 +22,7 @@ import (
 
 	"k8s.io/contrib/mungegithub/features"
 	"k8s.io/contrib/mungegithub/github"
+	issuesync "k8s.io/contrib/mungegithub/mungers/sync"
 	"k8s.io/kubernetes/pkg/util/sets"
 
 	"github.com/golang/glog"
@@ -34,6 +35,11 @@ type issueIndexKey string
 type issueList []int
 
 func (l *issueList) add(i int) {
+	for _, n := range *l {
+		if n == i {
+			return
+		}
+	}
 	*l = append(*l, i)
 	// Not efficient but we don't expect big lists.
 	sort.Ints([]int(*l))
@@ -50,12 +56,22 @@ type IssueCacher struct {
 	// TODO: this can easily be extended to keep multiple issues indexes if needed.
 	labelFilter sets.String
 
-	lock                                sync.RWMutex
-	index                               keyToIssueList
-	prevIndex                           keyToIssueList
+	lock      sync.RWMutex
+	index     keyToIssueList
+	prevIndex keyToIssueList
+	// syncKeys and prevSyncKeys index issues by their sync keys, see
+	// issuesync.KeyedSource.
+	syncKeys, prevSyncKeys              keyToIssueList
 	firstSyncStarted, firstSyncFinished bool
 
 	config *github.Config
+
+	// Namespace restricts the cache to issues filed by an IssueSyncer
+	// running with the same namespace.
+	Namespace string
+	// Normalizer, if set, turns titles into the keys issues are cached
+	// by, see IssueSyncer.Normalizer.
+	Normalizer *issuesync.TitleNormalizer
 }
 
 func init() {
@@ -70,9 +86,11 @@ func (p *IssueCacher) RequiredFeatures() []string { return []string{} }
 
 // Initialize will initialize the munger
 func (p *IssueCacher) Initialize(config *github.Config, features *features.Features) error {
-	p.labelFilter = sets.NewString("kind/flake")
+	p.labelFilter = sets.NewString(issuesync.Namespaced(p.Namespace, "kind/flake"))
 	p.index = keyToIssueList{}
 	p.prevIndex = keyToIssueList{}
+	p.syncKeys = keyToIssueList{}
+	p.prevSyncKeys = keyToIssueList{}
 	p.config = config
 	return nil
 }
@@ -84,6 +102,8 @@ func (p *IssueCacher) EachLoop() error {
 		defer p.lock.Unlock()
 		p.prevIndex = p.index
 		p.index = keyToIssueList{}
+		p.prevSyncKeys = p.syncKeys
+		p.syncKeys = keyToIssueList{}
 		if !p.firstSyncStarted {
 			p.firstSyncStarted = true
 		} else if !p.firstSyncFinished {
@@ -95,7 +115,24 @@ func (p *IssueCacher) EachLoop() error {
 }
 
 // AddFlags will add any request flags to the cobra `cmd`
-func (p *IssueCacher) AddFlags(cmd *cobra.Command, config *github.Config) {}
+func (p *IssueCacher) AddFlags(cmd *cobra.Command, config *github.Config) {
+	cmd.PersistentFlags().StringVar(&p.Namespace, "issue-namespace", "", "If set (e.g. 'staging/'), issues, labels and comments filed for flakes are prefixed with this namespace and issues outside of it are ignored. Useful for experiments against a real repo.")
+	p.addCleanupNamespaceCommand(cmd, config)
+}
+
+func (p *IssueCacher) addCleanupNamespaceCommand(root *cobra.Command, config *github.Config) {
+	cleanup := &cobra.Command{
+		Use:   "cleanup-issue-namespace",
+		Short: "Close all issues and delete all labels in --issue-namespace",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := config.PreExecute(); err != nil {
+				return err
+			}
+			return issuesync.CleanupNamespace(config, p.Namespace)
+		},
+	}
+	root.AddCommand(cleanup)
+}
 
 func (p *IssueCacher) findClosedIssues() {
 	issues, err := p.config.ListAllIssues(&githubapi.IssueListByRepoOptions{
@@ -126,15 +163,37 @@ func (p *IssueCacher) Munge(obj *github.MungeObject) {
 	}
 
 	p.addNumberToKey(key, *obj.Issue.Number)
+	if obj.Issue.Body != nil {
+		for _, k := range issuesync.SyncKeys(*obj.Issue.Body) {
+			p.addNumberToSyncKey(issueIndexKey(k), *obj.Issue.Number)
+		}
+	}
+}
+
+// Observe implements issuesync.IssueObserver: issues which changed are
+// cached right away, instead of on the next munge loop.
+func (p *IssueCacher) Observe(issue *githubapi.Issue) {
+	p.Munge(&github.MungeObject{Issue: issue})
 }
 
 func (p *IssueCacher) addNumberToKey(key issueIndexKey, issueNumber int) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	if l, ok := p.index[key]; ok {
+	p.index.add(key, issueNumber)
+}
+
+func (p *IssueCacher) addNumberToSyncKey(key issueIndexKey, issueNumber int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.syncKeys.add(key, issueNumber)
+}
+
+// add must be called with the lock held.
+func (m keyToIssueList) add(key issueIndexKey, issueNumber int) {
+	if l, ok := m[key]; ok {
 		l.add(issueNumber)
 	} else {
-		p.index[key] = &issueList{issueNumber}
+		m[key] = &issueList{issueNumber}
 	}
 }
 
@@ -142,8 +201,7 @@ func (p *IssueCacher) keyFromIssue(obj *github.MungeObject) (issueIndexKey, bool
 	if obj.Issue == nil || obj.Issue.Title == nil {
 		return "", false
 	}
-	// Currently, just use the issue title directly.
-	return issueIndexKey(*obj.Issue.Title), true
+	return issueIndexKey(p.Normalizer.Normalize(*obj.Issue.Title)), true
 }
 
 // AllIssuesForKey returns all known issues matching the key, oldest first.
@@ -151,6 +209,7 @@ func (p *IssueCacher) AllIssuesForKey(key string) []int {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 	got := sets.NewInt()
+	key = p.Normalizer.Normalize(key)
 	if n, ok := p.index[issueIndexKey(key)]; ok {
 		got.Insert([]int(*n)...)
 	}
@@ -162,10 +221,29 @@ func (p *IssueCacher) AllIssuesForKey(key string) []int {
 	return out
 }
 
+// IssuesForSyncKey returns all known issues which embed the sync key, oldest
+// first.
+func (p *IssueCacher) IssuesForSyncKey(key string) ([]int, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	got := sets.NewInt()
+	for _, index := range []keyToIssueList{p.syncKeys, p.prevSyncKeys} {
+		if n, ok := index[issueIndexKey(key)]; ok {
+			got.Insert([]int(*n)...)
+		}
+	}
+	return got.List(), nil
+}
+
+// CreatedSyncKey adds the sync key of an issue we just filed to the cache.
+func (p *IssueCacher) CreatedSyncKey(key string, number int) {
+	p.addNumberToSyncKey(issueIndexKey(key), number)
+}
+
 // Created adds this entry to the cache, in case you try to access it again
 // before another complete pass is made.
 func (p *IssueCacher) Created(key string, number int) {
-	p.addNumberToKey(issueIndexKey(key), number)
+	p.addNumberToKey(issueIndexKey(p.Normalizer.Normalize(key)), number)
 }
 
 // Synced returns true if we've made at least one complete pass through all
@@ -17,6 +17,8 @@ limitations under the License.
 package mungers
 
 import (
+	"strings"
+
 	"k8s.io/contrib/mungegithub/features"
 	"k8s.io/contrib/mungegithub/github"
 
@@ -26,6 +28,7 @@ import (
 )
 
 const (
+	lgtmAfterCommit = "lgtm-after-commit"
 	lgtmRemovedBody = "PR changed after LGTM, removing LGTM."
 )
 
@@ -40,7 +43,7 @@ func init() {
 }
 
 // Name is the name usable in --pr-mungers
-func (LGTMAfterCommitMunger) Name() string { return "lgtm-after-commit" }
+func (LGTMAfterCommitMunger) Name() string { return lgtmAfterCommit }
 
 // RequiredFeatures is a slice of 'features' that must be provided
 func (LGTMAfterCommitMunger) RequiredFeatures() []string { return []string{} }
@@ -76,7 +79,7 @@ func (LGTMAfterCommitMunger) Munge(obj *github.MungeObject) {
 
 	if lastModified.After(*lgtmTime) {
 		glog.Infof("PR: %d lgtm:%s  lastModified:%s", *obj.Issue.Number, lgtmTime.String(), lastModified.String())
-		if err := obj.WriteComment(lgtmRemovedBody); err != nil {
+		if err := obj.StickyComment(lgtmAfterCommit, lgtmRemovedBody); err != nil {
 			return
 		}
 		obj.RemoveLabel(lgtmLabel)
@@ -87,7 +90,7 @@ func (LGTMAfterCommitMunger) isStaleComment(obj *github.MungeObject, comment git
 	if !mergeBotComment(comment) {
 		return false
 	}
-	if *comment.Body != lgtmRemovedBody {
+	if !strings.HasPrefix(*comment.Body, lgtmRemovedBody) {
 		return false
 	}
 	if !obj.HasLabel("lgtm") {
@@ -97,7 +100,12 @@ func (LGTMAfterCommitMunger) isStaleComment(obj *github.MungeObject, comment git
 	if lgtmTime == nil {
 		return false
 	}
-	stale := lgtmTime.After(*comment.CreatedAt)
+	// The comment is edited in place, see StickyComment.
+	updated := comment.CreatedAt
+	if comment.UpdatedAt != nil {
+		updated = comment.UpdatedAt
+	}
+	stale := lgtmTime.After(*updated)
 	if stale {
 		glog.V(6).Infof("Found stale LGTMAfterCommitMunger comment")
 	}
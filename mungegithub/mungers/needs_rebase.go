@@ -83,7 +83,7 @@ func (NeedsRebaseMunger) Munge(obj *github.MungeObject) {
 		obj.AddLabels([]string{needsRebaseLabel})
 
 		body := fmt.Sprintf("@%s PR needs rebase", *obj.Issue.User.Login)
-		if err := obj.WriteComment(body); err != nil {
+		if err := obj.StickyComment(needsRebase, body); err != nil {
 			return
 		}
 	}
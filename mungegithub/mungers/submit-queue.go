@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -795,7 +796,7 @@ func (sq *SubmitQueue) validForMerge(obj *github.MungeObject) bool {
 	if !obj.HasLabel(okToMergeLabel) && !userSet.Has(*obj.Issue.User.Login) {
 		if !obj.HasLabel(needsOKToMergeLabel) {
 			obj.AddLabels([]string{needsOKToMergeLabel})
-			obj.WriteComment(notInWhitelistBody)
+			obj.StickyComment(needsOKToMergeLabel, notInWhitelistBody)
 		}
 		sq.SetMergeStatus(obj, needsok)
 		return false
@@ -1231,7 +1232,7 @@ func (sq *SubmitQueue) isStaleWhitelistComment(obj *github.MungeObject, comment
 	if !mergeBotComment(comment) {
 		return false
 	}
-	if *comment.Body != notInWhitelistBody {
+	if !strings.HasPrefix(*comment.Body, notInWhitelistBody) {
 		return false
 	}
 	stale := obj.HasLabel(okToMergeLabel)
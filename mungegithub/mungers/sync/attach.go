@@ -0,0 +1,198 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// Attachment is a file a source refers to, e.g. a build log or junit
+// results, which is too big to be part of its Body.
+type Attachment struct {
+	// Name is what the attachment is called in the issue.
+	Name string `json:"name"`
+	// Content is the attachment, or else it is read from Path, see
+	// AttachmentUploads.Dir.
+	Content string `json:"content,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+// AttachmentSource is an IssueSource with attachments.
+type AttachmentSource interface {
+	IssueSource
+	Attachments() []Attachment
+}
+
+// ArtifactStore is where attachments are uploaded to.
+type ArtifactStore interface {
+	// Upload stores `data` as `name` and returns the URL to link to.
+	Upload(ctx context.Context, name string, data []byte) (string, error)
+}
+
+// BucketStore uploads attachments under a gs:// URL with gsutil, or writes
+// them under a local directory.
+type BucketStore struct {
+	Dest string
+	// BaseURL is where Dest is served from. For gs://<bucket>/<prefix> it
+	// defaults to https://storage.googleapis.com/<bucket>/<prefix>.
+	BaseURL string
+}
+
+// Upload implements ArtifactStore.
+func (b *BucketStore) Upload(ctx context.Context, name string, data []byte) (string, error) {
+	dest := strings.TrimSuffix(b.Dest, "/") + "/" + name
+	if !strings.HasPrefix(dest, "gs://") {
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return "", err
+		}
+	}
+	if err := writeDest(dest, data); err != nil {
+		return "", err
+	}
+	base := b.BaseURL
+	if base == "" && strings.HasPrefix(b.Dest, "gs://") {
+		base = "https://storage.googleapis.com/" + strings.TrimPrefix(b.Dest, "gs://")
+	}
+	if base == "" {
+		return dest, nil
+	}
+	return strings.TrimSuffix(base, "/") + "/" + name, nil
+}
+
+// GistStore uploads every attachment as a secret gist.
+type GistStore struct {
+	Config *github.Config
+}
+
+// Upload implements ArtifactStore.
+func (g *GistStore) Upload(ctx context.Context, name string, data []byte) (string, error) {
+	return g.Config.CreateGist(name, path.Base(name), string(data))
+}
+
+// AttachmentUploads decides what happens to the attachments of sources (see
+// AttachmentSource): small ones are included in the issue, collapsed, and
+// the others uploaded to Store and linked. Like bodies, attachments are
+// redacted first (see IssueSyncer.Redactor). Sensitive sources' attachments
+// are never uploaded.
+type AttachmentUploads struct {
+	Store ArtifactStore
+	// InlineLimit is the size up to which attachments are included in
+	// the issue instead of uploaded.
+	InlineLimit int
+	// Dir, if set, is where attachments with a Path are read from. Paths
+	// outside of it are refused, and without it only attachments with
+	// Content are used: sources may come from anyone who can POST them.
+	Dir string
+}
+
+// NewAttachmentUploads returns AttachmentUploads to `store`, which inline
+// attachments of up to 4KB.
+func NewAttachmentUploads(store ArtifactStore) *AttachmentUploads {
+	return &AttachmentUploads{Store: store, InlineLimit: 4 << 10}
+}
+
+// read returns the content of `a`.
+func (u *AttachmentUploads) read(a Attachment) ([]byte, error) {
+	if a.Path == "" || a.Content != "" {
+		return []byte(a.Content), nil
+	}
+	if u.Dir == "" {
+		return nil, fmt.Errorf("attachment paths aren't allowed without a directory to read them from")
+	}
+	dir, err := filepath.Abs(u.Dir)
+	if err != nil {
+		return nil, err
+	}
+	p, err := filepath.Abs(filepath.Join(dir, a.Path))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(p, dir+string(filepath.Separator)) {
+		return nil, fmt.Errorf("attachment path %q is outside of %v", a.Path, u.Dir)
+	}
+	return ioutil.ReadFile(p)
+}
+
+// attach uploads the attachments of `source`, if it has any, and remembers
+// what sourceBody adds about them. Attachments which can't be read or
+// uploaded are mentioned as such; they don't fail the sync.
+func (s *IssueSyncer) attach(ctx context.Context, source IssueSource) {
+	a, ok := source.(AttachmentSource)
+	if !ok || s.Uploads == nil || isSensitive(source) {
+		return
+	}
+	if _, ok := s.attached[source.ID()]; ok {
+		return
+	}
+	lines := []string{}
+	for _, attachment := range a.Attachments() {
+		name := path.Base(attachment.Name)
+		if name == "." || name == "/" {
+			name = path.Base(attachment.Path)
+		}
+		log := s.logger().With("attachment", name)
+		data, err := s.Uploads.read(attachment)
+		if err != nil {
+			log.Errorf("Unable to read attachment: %v", err)
+			lines = append(lines, fmt.Sprintf("- %v (unavailable)", name))
+			continue
+		}
+		data = []byte(s.redact(source, string(data)))
+		if len(data) <= s.Uploads.InlineLimit {
+			lines = append(lines, fmt.Sprintf("<details><summary>%v</summary>\n\n```\n%v\n```\n</details>", name, strings.TrimRight(string(data), "\n")))
+			continue
+		}
+		var url string
+		err = s.retry(ctx, fmt.Sprintf("uploading attachment %v", name), func() (err error) {
+			url, err = s.Uploads.Store.Upload(ctx, redact(source.ID())+"/"+name, data)
+			return err
+		})
+		if err != nil {
+			log.Errorf("Unable to upload attachment: %v", err)
+			lines = append(lines, fmt.Sprintf("- %v (%v, upload failed)", name, byteSize(len(data))))
+			continue
+		}
+		metrics.Add("attachmentsUploaded", 1)
+		lines = append(lines, fmt.Sprintf("- [%v](%v) (%v)", name, url, byteSize(len(data))))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	if s.attached == nil {
+		s.attached = map[string]string{}
+	}
+	s.attached[source.ID()] = "Attachments:\n\n" + strings.Join(lines, "\n")
+}
+
+// byteSize formats `n` bytes for people.
+func byteSize(n int) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestAttachments(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	dir, err := ioutil.TempDir("", "attachments")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	workspace := filepath.Join(dir, "workspace")
+	os.MkdirAll(workspace, 0755)
+	ioutil.WriteFile(filepath.Join(workspace, "junit.xml"), []byte("<testsuite/>"), 0644)
+
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.Redactor, _ = NewRedactor(DefaultRedactionRules)
+	s.Uploads = NewAttachmentUploads(&BucketStore{Dest: filepath.Join(dir, "bucket"), BaseURL: "https://artifacts/flakes"})
+	s.Uploads.InlineLimit = 100
+	s.Uploads.Dir = workspace
+
+	log := strings.Repeat("connecting to 10.0.0.1\n", 10)
+	source := &JSONSource{Key: "TestFoo", Ref: "foo-1", Details: "Failed", Files: []Attachment{
+		{Name: "build-log.txt", Content: log},
+		{Name: "junit.xml", Path: "junit.xml"},
+		{Name: "passwd", Path: "../../../etc/passwd"},
+	}}
+	if err := s.Sync(context.Background(), source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := *tracker.Issues()[0].Body
+	name := redact("foo-1") + "/build-log.txt"
+	for _, expected := range []string{
+		"- [build-log.txt](https://artifacts/flakes/" + name + ") (",
+		"<details><summary>junit.xml</summary>\n\n```\n<testsuite/>\n```\n</details>",
+		"- passwd (unavailable)",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected the body to contain %q, got %q", expected, body)
+		}
+	}
+	uploaded, err := ioutil.ReadFile(filepath.Join(dir, "bucket", name))
+	if err != nil {
+		t.Fatalf("expected the build log to be uploaded: %v", err)
+	}
+	if strings.Contains(string(uploaded), "10.0.0.1") || !strings.Contains(string(uploaded), "connecting to") {
+		t.Errorf("expected the uploaded build log to be redacted, got %q", uploaded)
+	}
+}
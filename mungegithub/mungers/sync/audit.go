@@ -0,0 +1,296 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// Audit actions, one for every kind of mutation the syncer makes.
+const (
+	AuditCreate   = "create"
+	AuditComment  = "comment"
+	AuditClose    = "close"
+	AuditLabel    = "label"
+	AuditUnlabel  = "unlabel"
+	AuditAdvisory = "advisory"
+	AuditEdit     = "edit"
+	AuditReopen   = "reopen"
+	AuditReact    = "react"
+	AuditLock     = "lock"
+	// AuditEditComment is an edit of one of our comments, like a task
+	// list, rather than of the issue body.
+	AuditEditComment = "edit-comment"
+)
+
+// AuditEntry records one mutation made by the syncer.
+type AuditEntry struct {
+	Time time.Time
+	// Cycle and Source are what the syncer was doing, if anything. See
+	// IssueSyncer.SyncAll.
+	Cycle  string `json:",omitempty"`
+	Source string `json:",omitempty"`
+	Action string
+	// Repo is the "org/project" of Issue, if the syncer knew it: tenants
+	// (see MultiSyncer) may share an audit log.
+	Repo  string `json:",omitempty"`
+	Issue int    `json:",omitempty"`
+	// Detail is the comment body for AuditComment, the label for
+	// AuditLabel and AuditUnlabel, the reaction for AuditReact, the title
+	// for AuditCreate, the previous body for AuditEdit, and the previous
+	// body of comment Comment for AuditEditComment.
+	Detail  string `json:",omitempty"`
+	Comment int    `json:",omitempty"`
+}
+
+// AuditLog is an append-only file with a JSON AuditEntry per line.
+type AuditLog struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+// NewAuditLog opens the audit log at `path`, creating it if needed.
+func NewAuditLog(path string) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{file: file}, nil
+}
+
+// Record appends an entry to the log.
+func (a *AuditLog) Record(e AuditEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	_, err = a.file.Write(append(data, '\n'))
+	return err
+}
+
+// Sync commits what was recorded to disk.
+func (a *AuditLog) Sync() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.file.Sync()
+}
+
+// Close closes the log.
+func (a *AuditLog) Close() error {
+	return a.file.Close()
+}
+
+// ReadAuditLog returns the entries in the audit log at `path` for which
+// `match` returns true, oldest first. A nil `match` returns all entries.
+func ReadAuditLog(path string, match func(AuditEntry) bool) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	entries := []AuditEntry{}
+	scanner := bufio.NewScanner(file)
+	// Comments can be long.
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		e := AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+		}
+		if match == nil || match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// audit records a mutation, if the syncer has an audit log, and exports it,
+// if it has a history exporter.
+func (s *IssueSyncer) audit(action string, issue int, detail string) {
+	s.auditEntry(AuditEntry{Action: action, Issue: issue, Detail: detail})
+}
+
+// auditEntry is audit, for entries with more than a detail. Time, Cycle and
+// Source are filled in.
+func (s *IssueSyncer) auditEntry(e AuditEntry) {
+	e.Time, e.Cycle, e.Source = s.now(), s.cycle, s.source
+	if s.config != nil {
+		e.Repo = fmt.Sprintf("%v/%v", s.config.Org, s.config.Project)
+	}
+	s.export(HistoryRecord{Time: e.Time, Kind: HistoryAction, Issue: e.Issue, Action: e.Action, Detail: e.Detail})
+	if s.Audit == nil {
+		return
+	}
+	if err := s.Audit.Record(e); err != nil {
+		s.logger().With("issue", e.Issue).Errorf("Unable to record %v in the audit log: %v", e.Action, err)
+	}
+}
+
+// writeComment, closeIssue, addLabel and removeLabel make a mutation with
+// retries and audit it.
+
+func (s *IssueSyncer) writeComment(ctx context.Context, op string, obj *github.MungeObject, msg string) error {
+	if err := s.retry(ctx, op, func() error { return obj.WriteComment(msg) }); err != nil {
+		return err
+	}
+	s.audit(AuditComment, *obj.Issue.Number, msg)
+	return nil
+}
+
+func (s *IssueSyncer) closeIssue(ctx context.Context, op string, obj *github.MungeObject) error {
+	if err := s.retry(ctx, op, obj.CloseIssue); err != nil {
+		return err
+	}
+	s.audit(AuditClose, *obj.Issue.Number, "")
+	if s.Board != nil {
+		s.moveCard(ctx, *obj.Issue.Number, s.Board.ClosedColumn)
+	}
+	return nil
+}
+
+func (s *IssueSyncer) addLabel(ctx context.Context, obj *github.MungeObject, label string) error {
+	n := *obj.Issue.Number
+	if err := s.retry(ctx, fmt.Sprintf("adding %v to %v", label, n), func() error {
+		return obj.AddLabel(label)
+	}); err != nil {
+		return err
+	}
+	s.audit(AuditLabel, n, label)
+	return nil
+}
+
+func (s *IssueSyncer) removeLabel(ctx context.Context, obj *github.MungeObject, label string) error {
+	n := *obj.Issue.Number
+	if err := s.retry(ctx, fmt.Sprintf("removing %v from %v", label, n), func() error {
+		return obj.RemoveLabel(label)
+	}); err != nil {
+		return err
+	}
+	s.audit(AuditUnlabel, n, label)
+	return nil
+}
+
+// Undo reverts the mutations in `entries`, newest first: issues we created
+// are closed, our comments deleted, issues we closed reopened (and those we
+// reopened closed), edited bodies and comments restored, label changes
+// reversed and locks lifted. Advisories and reactions can't be undone and
+// are only logged. With config.DryRun nothing is changed, which makes it a
+// replay of what a sync run did.
+func Undo(config *github.Config, entries []AuditEntry) error {
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Action == AuditAdvisory {
+			glog.Warningf("Can't undo security advisory %q, delete it by hand", e.Detail)
+			continue
+		}
+		if e.Action == AuditReact {
+			glog.Warningf("Can't undo %q reaction on issue %v, remove it by hand", e.Detail, e.Issue)
+			continue
+		}
+		repo := config
+		if e.Repo != "" {
+			parts := strings.Split(e.Repo, "/")
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid repo %q of issue %v", e.Repo, e.Issue)
+			}
+			repo = config.ForRepo(parts[0], parts[1])
+		}
+		obj, err := repo.GetObject(e.Issue)
+		if err != nil {
+			return fmt.Errorf("error getting issue %v of %v/%v: %v", e.Issue, repo.Org, repo.Project, err)
+		}
+		glog.Infof("Undoing %v on issue %v of %v/%v (cycle %q, source %q)", e.Action, e.Issue, repo.Org, repo.Project, e.Cycle, e.Source)
+		switch e.Action {
+		case AuditCreate:
+			if obj.Issue.State != nil && *obj.Issue.State == "closed" {
+				continue
+			}
+			err = obj.CloseIssuef("This issue was filed by mistake; closing.")
+		case AuditComment:
+			err = undoComment(obj, e.Detail)
+		case AuditClose:
+			err = obj.ReopenIssue()
+		case AuditLock:
+			err = obj.UnlockIssue()
+		case AuditReopen:
+			if obj.Issue.State != nil && *obj.Issue.State == "closed" {
+				continue
+			}
+			err = obj.CloseIssue()
+		case AuditEdit:
+			err = obj.EditBody(e.Detail)
+		case AuditEditComment:
+			err = undoEditComment(obj, e.Comment, e.Detail)
+		case AuditLabel:
+			if obj.HasLabel(e.Detail) {
+				err = obj.RemoveLabel(e.Detail)
+			}
+		case AuditUnlabel:
+			if !obj.HasLabel(e.Detail) {
+				err = obj.AddLabel(e.Detail)
+			}
+		default:
+			err = fmt.Errorf("unknown action %q", e.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("error undoing %v on issue %v: %v", e.Action, e.Issue, err)
+		}
+	}
+	return nil
+}
+
+// undoEditComment restores the body of comment `id` to `body`.
+func undoEditComment(obj *github.MungeObject, id int, body string) error {
+	comments, err := obj.ListComments()
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		if c := &comments[i]; c.ID != nil && *c.ID == id {
+			return obj.EditComment(c, body)
+		}
+	}
+	glog.Warningf("Comment %v on issue %v is gone, can't restore it", id, *obj.Issue.Number)
+	return nil
+}
+
+// undoComment deletes the newest comment with exactly `body`.
+func undoComment(obj *github.MungeObject, body string) error {
+	comments, err := obj.ListComments()
+	if err != nil {
+		return err
+	}
+	for i := len(comments) - 1; i >= 0; i-- {
+		if c := comments[i]; c.Body != nil && *c.Body == body {
+			return obj.DeleteComment(&c)
+		}
+	}
+	glog.Warningf("Comment on issue %v is already gone: %q", *obj.Issue.Number, body)
+	return nil
+}
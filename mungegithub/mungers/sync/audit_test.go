@@ -0,0 +1,145 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	entries := []AuditEntry{
+		{Time: date("2016-07-01 12:00"), Cycle: "c1", Source: "a", Action: AuditCreate, Issue: 1, Detail: "title"},
+		{Time: date("2016-07-01 12:00"), Cycle: "c1", Source: "b", Action: AuditComment, Issue: 2, Detail: "multi\nline"},
+		{Time: date("2016-07-01 13:00"), Cycle: "c2", Source: "a", Action: AuditLabel, Issue: 1, Detail: "lifecycle/stale"},
+	}
+	for i := range entries {
+		// Reopening appends.
+		a, err := NewAuditLog(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := a.Record(entries[i]); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		a.Close()
+	}
+
+	got, err := ReadAuditLog(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("expected %v, got %v", entries, got)
+	}
+	got, err = ReadAuditLog(path, func(e AuditEntry) bool { return e.Cycle == "c1" })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, entries[:2]) {
+		t.Errorf("expected %v, got %v", entries[:2], got)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	issue := github_test.Issue("bot", 1, []string{"kind/flake", "lifecycle/stale"}, false)
+	state := "open"
+	issue.State = &state
+	client, server, mux := github_test.InitServer(t, issue, nil, nil, nil, nil, nil)
+	defer server.Close()
+	config := &github.Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+
+	calls := []string{}
+	mux.HandleFunc("/repos/o/r/issues/1/labels", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" labels")
+		w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("/repos/o/r/issues/1/labels/", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path[len("/repos/o/r/issues/1/"):])
+	})
+
+	// Another tenant's issue 1, in a repo of its own.
+	mux.HandleFunc("/repos/o/s/issues/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"number": 1, "state": "open", "labels": [{"name": "kind/bug"}]}`))
+	})
+	mux.HandleFunc("/repos/o/s/issues/1/labels/", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" o/s "+r.URL.Path[len("/repos/o/s/issues/1/"):])
+	})
+
+	err := Undo(config, []AuditEntry{
+		{Action: AuditUnlabel, Issue: 1, Detail: "lifecycle/active"},
+		{Action: AuditLabel, Repo: "o/r", Issue: 1, Detail: "lifecycle/stale"},
+		{Action: AuditLabel, Repo: "o/s", Issue: 1, Detail: "kind/bug"},
+		{Action: AuditAdvisory, Detail: "abc"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// The stale label is removed before the active one is restored.
+	expected := []string{"DELETE o/s labels/kind/bug", "DELETE labels/lifecycle/stale", "POST labels"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+}
+
+func TestUndoTaskList(t *testing.T) {
+	issue := github_test.Issue("bot", 1, []string{"kind/flake"}, false)
+	client, server, mux := github_test.InitServer(t, issue, nil, nil, nil, nil, nil)
+	defer server.Close()
+	config := &github.Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+
+	mux.HandleFunc("/repos/o/r/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 6, "body": "other"}, {"id": 7, "body": "- [ ] a\n- [ ] b"}]`))
+	})
+	edited := map[string]string{}
+	mux.HandleFunc("/repos/o/r/issues/comments/", func(w http.ResponseWriter, r *http.Request) {
+		c := map[string]string{}
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		edited[r.Method+" "+r.URL.Path] = c["body"]
+		w.Write([]byte(`{}`))
+	})
+
+	err := Undo(config, []AuditEntry{
+		{Action: AuditEditComment, Issue: 1, Comment: 7, Detail: "- [ ] a"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"PATCH /repos/o/r/issues/comments/7": "- [ ] a"}
+	if !reflect.DeepEqual(edited, expected) {
+		t.Errorf("expected the task list to be restored with %v, got %v", expected, edited)
+	}
+}
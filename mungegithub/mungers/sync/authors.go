@@ -0,0 +1,149 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// FileSource is an IssueSource which can name the file it failed in, e.g.
+// the file of a failing test, relative to the root of the repo.
+type FileSource interface {
+	IssueSource
+	File() string
+}
+
+// RecentAuthors cc's the people who recently committed to the file a source
+// failed in on its new issue, since they likely have the context to look
+// into it quickly.
+type RecentAuthors struct {
+	Config *github.Config
+	// Branch is where commits are looked at, "master" if empty.
+	Branch string
+	// Max is how many authors are cc'd, those with the most commits to the
+	// file first.
+	Max int
+	// Window is how far back commits count.
+	Window time.Duration
+	// Ignore are logins never cc'd, e.g. bots. So are logins ending in
+	// "[bot]".
+	Ignore []string
+
+	now func() time.Time
+}
+
+// NewRecentAuthors cc's the top 3 authors of the last 90 days' commits to
+// master.
+func NewRecentAuthors(config *github.Config) *RecentAuthors {
+	return &RecentAuthors{
+		Config: config,
+		Branch: "master",
+		Max:    3,
+		Window: 90 * 24 * time.Hour,
+		now:    time.Now,
+	}
+}
+
+// SetClock has the commit window end at `clock`'s time.
+func (a *RecentAuthors) SetClock(clock Clock) {
+	a.now = clock.Now
+}
+
+// Stage returns the stage which adds the cc to new issues. Sources which
+// don't name a file, or which are sensitive, aren't enriched, and neither
+// are those whose commits can't be listed: the issue matters more than the
+// cc.
+func (a *RecentAuthors) Stage() Stage {
+	return Stage{
+		Name:   "cc-recent-authors",
+		Before: PhaseMutate,
+		Run: func(ctx context.Context, st *SyncState) error {
+			source, ok := st.Source.(FileSource)
+			if !ok || st.Decision != DecisionCreate || isSensitive(st.Source) || source.File() == "" {
+				return nil
+			}
+			authors, err := a.authors(ctx, source.File())
+			if err != nil {
+				glog.Errorf("Unable to list the recent commits to %v: %v", source.File(), err)
+				return nil
+			}
+			if len(authors) == 0 {
+				return nil
+			}
+			mentions := []string{}
+			for _, login := range authors {
+				mentions = append(mentions, "@"+login)
+			}
+			st.Notes = append(st.Notes, fmt.Sprintf("cc %v, who recently changed `%v`", strings.Join(mentions, " "), source.File()))
+			return nil
+		},
+	}
+}
+
+// authors returns the logins with the most commits to `path` in the window,
+// at most Max of them.
+func (a *RecentAuthors) authors(ctx context.Context, path string) ([]string, error) {
+	branch := a.Branch
+	if branch == "" {
+		branch = "master"
+	}
+	commits, err := a.Config.WithContext(ctx, 0).ListFileCommits(branch, path, a.now().Add(-a.Window))
+	if err != nil {
+		return nil, err
+	}
+	ignore := sets.NewString(a.Ignore...)
+	counts := map[string]int{}
+	// Commits are newest first, so are ties.
+	order := []string{}
+	for _, c := range commits {
+		if c.Author == nil || c.Author.Login == nil {
+			// Not a github user.
+			continue
+		}
+		login := *c.Author.Login
+		if ignore.Has(login) || strings.HasSuffix(login, "[bot]") {
+			continue
+		}
+		if counts[login] == 0 {
+			order = append(order, login)
+		}
+		counts[login]++
+	}
+	sort.Stable(byCommits{logins: order, counts: counts})
+	if a.Max > 0 && len(order) > a.Max {
+		order = order[:a.Max]
+	}
+	return order, nil
+}
+
+// byCommits sorts logins by their number of commits, most first.
+type byCommits struct {
+	logins []string
+	counts map[string]int
+}
+
+func (b byCommits) Len() int           { return len(b.logins) }
+func (b byCommits) Less(i, j int) bool { return b.counts[b.logins[i]] > b.counts[b.logins[j]] }
+func (b byCommits) Swap(i, j int)      { b.logins[i], b.logins[j] = b.logins[j], b.logins[i] }
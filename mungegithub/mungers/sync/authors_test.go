@@ -0,0 +1,86 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestRecentAuthors(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	now := time.Now()
+	file := "test/e2e/foo_test.go"
+	// Oldest first, like they were committed.
+	for _, c := range []struct {
+		path, author string
+		age          time.Duration
+	}{
+		{file, "dave", 100 * 24 * time.Hour},
+		{file, "carol", 4 * time.Hour},
+		{file, "alice", 3 * time.Hour},
+		{file, "alice", 2 * time.Hour},
+		{file, "k8s-merge-robot", time.Hour},
+		{file, "renovate[bot]", time.Hour},
+		{"test/e2e/bar_test.go", "erin", time.Hour},
+		{file, "bob", time.Hour},
+	} {
+		tracker.AddCommit(c.path, c.author, now.Add(-c.age))
+	}
+
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	authors := NewRecentAuthors(tracker.Config())
+	authors.Max = 2
+	authors.Ignore = []string{"k8s-merge-robot"}
+	s.Stages = []Stage{authors.Stage()}
+
+	for _, source := range []*JSONSource{
+		{Key: "TestFoo", Ref: "foo-1", Path: file},
+		{Key: "TestFoo", Ref: "foo-2", Path: file},
+		{Key: "TestBar", Ref: "bar-1"},
+	} {
+		if err := s.Sync(context.Background(), source); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	cc := "cc @alice @bob, who recently changed `" + file + "`"
+	for _, issue := range tracker.Issues() {
+		body := *issue.Body
+		switch *issue.Title {
+		case "TestFoo":
+			if !strings.Contains(body, cc) {
+				t.Errorf("expected %q in the body, got %q", cc, body)
+			}
+			for _, c := range tracker.Comments(*issue.Number) {
+				if strings.Contains(c, "cc @") {
+					t.Errorf("expected only the new issue to cc, got comment %q", c)
+				}
+			}
+		case "TestBar":
+			if strings.Contains(body, "cc @") {
+				t.Errorf("expected no cc without a file, got %q", body)
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// ProjectBoard puts the issues the syncer files on a github project board,
+// and moves their cards along as the issues are escalated and closed.
+type ProjectBoard struct {
+	// Project is the name of one of the repository's project boards.
+	Project string
+	// NewColumn is where cards for new issues go, e.g. "To Triage".
+	NewColumn string
+	// EscalatedColumn and ClosedColumn, if set, are where cards are moved
+	// when their issue is escalated or closed.
+	EscalatedColumn string
+	ClosedColumn    string
+
+	// columns maps the project's column names to their ids, once looked up.
+	columns map[string]int
+}
+
+// column returns the id of column `name`, looking the board up the first
+// time it's needed.
+func (s *IssueSyncer) column(ctx context.Context, name string) (int, error) {
+	b := s.Board
+	if b.columns == nil {
+		columns, err := s.lookUpColumns(ctx)
+		if err != nil {
+			return 0, err
+		}
+		b.columns = columns
+	}
+	id, ok := b.columns[name]
+	if !ok {
+		return 0, fmt.Errorf("project %q has no column %q", b.Project, name)
+	}
+	return id, nil
+}
+
+func (s *IssueSyncer) lookUpColumns(ctx context.Context) (map[string]int, error) {
+	var projects []github.Project
+	err := s.retry(ctx, "listing projects", func() (err error) {
+		projects, err = s.client(ctx).ListProjects()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		if p.ID == nil || p.Name == nil || *p.Name != s.Board.Project {
+			continue
+		}
+		var columns []github.ProjectColumn
+		err := s.retry(ctx, fmt.Sprintf("listing columns of project %q", *p.Name), func() (err error) {
+			columns, err = s.client(ctx).ListProjectColumns(*p.ID)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids := map[string]int{}
+		for _, c := range columns {
+			if c.ID != nil && c.Name != nil {
+				ids[*c.Name] = *c.ID
+			}
+		}
+		return ids, nil
+	}
+	return nil, fmt.Errorf("no project named %q", s.Board.Project)
+}
+
+// addToBoard puts a card for a new issue in the board's NewColumn. Failures
+// are only logged: the issue is filed either way.
+func (s *IssueSyncer) addToBoard(ctx context.Context, obj *github.MungeObject) {
+	if s.Board == nil || s.Board.NewColumn == "" {
+		return
+	}
+	n := *obj.Issue.Number
+	log := s.logger().With("issue", n)
+	column, err := s.column(ctx, s.Board.NewColumn)
+	if err != nil {
+		log.Errorf("Unable to add issue to the project board: %v", err)
+		return
+	}
+	var card *github.ProjectCard
+	err = s.retry(ctx, fmt.Sprintf("adding %v to the project board", n), func() (err error) {
+		card, err = obj.AddToProject(column)
+		return err
+	})
+	if err != nil {
+		log.Errorf("Unable to add issue to the project board: %v", err)
+		return
+	}
+	if card.ID == nil {
+		return
+	}
+	if err := s.Store.Update(n, func(r *IssueRecord) {
+		r.ProjectCard = *card.ID
+		r.ProjectColumn = s.Board.NewColumn
+	}); err != nil {
+		log.Errorf("Unable to record project card: %v", err)
+	}
+}
+
+// moveCard moves the card of issue `n`, if it has one, to column `name`.
+// Failures are only logged.
+func (s *IssueSyncer) moveCard(ctx context.Context, n int, name string) {
+	if s.Board == nil || name == "" {
+		return
+	}
+	r, ok := s.Store.Get(n)
+	if !ok || r.ProjectCard == 0 || r.ProjectColumn == name {
+		return
+	}
+	log := s.logger().With("issue", n)
+	column, err := s.column(ctx, name)
+	if err != nil {
+		log.Errorf("Unable to move project card: %v", err)
+		return
+	}
+	err = s.retry(ctx, fmt.Sprintf("moving the project card of %v to %q", n, name), func() error {
+		return s.client(ctx).MoveProjectCard(r.ProjectCard, column)
+	})
+	if err != nil {
+		log.Errorf("Unable to move project card: %v", err)
+		return
+	}
+	if err := s.Store.Update(n, func(r *IssueRecord) { r.ProjectColumn = name }); err != nil {
+		log.Errorf("Unable to record project card: %v", err)
+	}
+}
+
+// noticeClosed records that issue `n` was closed, by us or someone else.
+func (s *IssueSyncer) noticeClosed(ctx context.Context, n int) error {
+	if s.Board != nil {
+		s.moveCard(ctx, n, s.Board.ClosedColumn)
+	}
+	return s.Store.Update(n, func(r *IssueRecord) {
+		if !r.Closed {
+			r.Closed, r.ClosedAt = true, s.now()
+		}
+	})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func TestNoticeClosedMovesCard(t *testing.T) {
+	client, server, mux := github_test.InitServer(t, github_test.Issue("bot", 1, nil, false), nil, nil, nil, nil, nil)
+	defer server.Close()
+	config := &github.Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+
+	mux.HandleFunc("/repos/o/r/projects", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 1, "name": "Other"}, {"id": 2, "name": "Flakes"}]`))
+	})
+	mux.HandleFunc("/projects/2/columns", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 10, "name": "To Triage"}, {"id": 11, "name": "Done"}]`))
+	})
+	moves := []map[string]interface{}{}
+	mux.HandleFunc("/projects/columns/cards/5/moves", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		move := map[string]interface{}{}
+		json.Unmarshal(body, &move)
+		moves = append(moves, move)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	})
+
+	s := NewIssueSyncer(config, nil)
+	s.Board = &ProjectBoard{Project: "Flakes", NewColumn: "To Triage", ClosedColumn: "Done"}
+	s.Store.Update(1, func(r *IssueRecord) {
+		r.ProjectCard = 5
+		r.ProjectColumn = "To Triage"
+	})
+	for i := 0; i < 2; i++ {
+		if err := s.noticeClosed(context.Background(), 1); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(moves) != 1 || moves[0]["column_id"] != float64(11) {
+		t.Errorf("expected the card to be moved to column 11 once, got %v", moves)
+	}
+	r, _ := s.Store.Get(1)
+	if !r.Closed || r.ProjectColumn != "Done" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+}
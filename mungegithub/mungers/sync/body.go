@@ -0,0 +1,197 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const (
+	// DefaultMaxLogLines is how many lines of a log BodyBuilder.Log shows,
+	// before folding the whole log into a details block.
+	DefaultMaxLogLines = 30
+	// DefaultMaxLogBytes is how much of a log BodyBuilder.Log keeps at
+	// most. github refuses bodies longer than 65536 characters.
+	DefaultMaxLogBytes = 20000
+)
+
+// BodyBuilder builds issue bodies out of paragraphs, tables, code blocks and
+// collapsible sections, for IssueSources which want consistent, readable
+// bodies. For example:
+//
+//	NewBodyBuilder().
+//		Text("Failed: %v", test).
+//		Log("build log", output).
+//		Links(Link{"artifacts", url}).
+//		String()
+type BodyBuilder struct {
+	// MaxLogLines and MaxLogBytes limit how much of a log Log shows and
+	// keeps.
+	MaxLogLines int
+	MaxLogBytes int
+
+	blocks []string
+}
+
+// NewBodyBuilder constructs an empty BodyBuilder.
+func NewBodyBuilder() *BodyBuilder {
+	return &BodyBuilder{
+		MaxLogLines: DefaultMaxLogLines,
+		MaxLogBytes: DefaultMaxLogBytes,
+	}
+}
+
+// Link is a link to e.g. a build artifact.
+type Link struct {
+	Text string
+	URL  string
+}
+
+func (l Link) String() string {
+	return fmt.Sprintf("[%v](%v)", strings.NewReplacer("[", `\[`, "]", `\]`).Replace(l.Text), l.URL)
+}
+
+// StatusEmoji returns the emoji for a github status `state`, e.g. ":x:" for
+// "failure".
+func StatusEmoji(state string) string {
+	switch state {
+	case "success":
+		return ":white_check_mark:"
+	case "failure":
+		return ":x:"
+	case "error":
+		return ":warning:"
+	case "pending":
+		return ":hourglass:"
+	}
+	return ":grey_question:"
+}
+
+// String returns the body.
+func (b *BodyBuilder) String() string {
+	return strings.Join(b.blocks, "\n\n")
+}
+
+func (b *BodyBuilder) add(block string) *BodyBuilder {
+	b.blocks = append(b.blocks, block)
+	return b
+}
+
+// Text adds a paragraph.
+func (b *BodyBuilder) Text(format string, args ...interface{}) *BodyBuilder {
+	return b.add(fmt.Sprintf(format, args...))
+}
+
+// Heading adds a heading.
+func (b *BodyBuilder) Heading(text string) *BodyBuilder {
+	return b.add("### " + text)
+}
+
+// Table adds a table with the `header` columns.
+func (b *BodyBuilder) Table(header []string, rows ...[]string) *BodyBuilder {
+	lines := []string{tableRow(header), "|" + strings.Repeat(" --- |", len(header))}
+	for _, row := range rows {
+		lines = append(lines, tableRow(row))
+	}
+	return b.add(strings.Join(lines, "\n"))
+}
+
+var tableCell = strings.NewReplacer("|", `\|`, "\r", "", "\n", "<br>")
+
+func tableRow(cells []string) string {
+	escaped := []string{}
+	for _, c := range cells {
+		escaped = append(escaped, tableCell.Replace(c))
+	}
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
+
+// Code adds a code block, highlighted as `lang` if set.
+func (b *BodyBuilder) Code(lang, text string) *BodyBuilder {
+	return b.add(codeBlock(lang, text))
+}
+
+// codeBlock fences `text` with more backticks than it contains in a row.
+func codeBlock(lang, text string) string {
+	longest, run := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := "```"
+	if longest >= len(fence) {
+		fence = strings.Repeat("`", longest+1)
+	}
+	return fmt.Sprintf("%v%v\n%v\n%v", fence, lang, strings.TrimRight(text, "\n"), fence)
+}
+
+// Details adds a section which is collapsed until clicked on. `content` is
+// markdown.
+func (b *BodyBuilder) Details(summary, content string) *BodyBuilder {
+	return b.add(details(summary, content))
+}
+
+func details(summary, content string) string {
+	return fmt.Sprintf("<details>\n<summary>%v</summary>\n\n%v\n\n</details>", html.EscapeString(summary), content)
+}
+
+// Log adds a log excerpt as a code block. Logs with more than MaxLogLines
+// lines only show the last ones, with the whole log (or its last
+// MaxLogBytes) folded into a details block.
+func (b *BodyBuilder) Log(name, text string) *BodyBuilder {
+	text = strings.TrimRight(text, "\n")
+	lines := strings.Split(text, "\n")
+	if len(lines) <= b.MaxLogLines && len(text) <= b.MaxLogBytes {
+		return b.Code("", text)
+	}
+	shown := lines
+	if len(shown) > b.MaxLogLines {
+		shown = shown[len(shown)-b.MaxLogLines:]
+	}
+	tail := strings.Join(shown, "\n")
+	if len(tail) > b.MaxLogBytes {
+		tail = tail[len(tail)-b.MaxLogBytes:]
+	}
+	full := text
+	if len(full) > b.MaxLogBytes {
+		full = full[len(full)-b.MaxLogBytes:]
+		if i := strings.Index(full, "\n"); i != -1 {
+			full = full[i+1:]
+		}
+		full = fmt.Sprintf("... (%d lines cut)\n%v", len(lines)-len(strings.Split(full, "\n")), full)
+	}
+	b.Text("Last %d of %d lines of the %v:", len(shown), len(lines), name)
+	b.Code("", tail)
+	return b.Details(fmt.Sprintf("Full %v", name), codeBlock("", full))
+}
+
+// Links adds a line of links, e.g. to a job's artifacts.
+func (b *BodyBuilder) Links(links ...Link) *BodyBuilder {
+	s := []string{}
+	for _, l := range links {
+		s = append(s, l.String())
+	}
+	return b.add(strings.Join(s, " · "))
+}
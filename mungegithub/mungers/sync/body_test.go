@@ -0,0 +1,70 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBodyBuilder(t *testing.T) {
+	got := NewBodyBuilder().
+		Heading("Summary").
+		Text("Failed %d times", 2).
+		Table([]string{"Job", "Result"}, []string{"e2e|gce", StatusEmoji("failure")}).
+		Code("go", "s := \"```\"").
+		Details("More <stuff>", "hidden").
+		Links(Link{Text: "log", URL: "http://ci/1/log"}, Link{Text: "junit", URL: "http://ci/1/junit.xml"}).
+		String()
+	expected := strings.Join([]string{
+		"### Summary",
+		"Failed 2 times",
+		"| Job | Result |\n| --- | --- |\n| e2e\\|gce | :x: |",
+		"````go\ns := \"```\"\n````",
+		"<details>\n<summary>More &lt;stuff&gt;</summary>\n\nhidden\n\n</details>",
+		"[log](http://ci/1/log) · [junit](http://ci/1/junit.xml)",
+	}, "\n\n")
+	if got != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, got)
+	}
+}
+
+func TestBodyBuilderLog(t *testing.T) {
+	b := NewBodyBuilder()
+	b.MaxLogLines = 2
+	b.MaxLogBytes = 12
+	tests := []struct {
+		name, log, expected string
+	}{
+		{
+			name:     "short",
+			log:      "a\nb\n",
+			expected: "```\na\nb\n```",
+		},
+		{
+			name:     "long",
+			log:      "line1\nline2\nline3\nline4",
+			expected: "Last 2 of 4 lines of the log:\n\n```\nline3\nline4\n```\n\n<details>\n<summary>Full log</summary>\n\n```\n... (2 lines cut)\nline3\nline4\n```\n\n</details>",
+		},
+	}
+	for _, test := range tests {
+		b.blocks = nil
+		if got := b.Log("log", test.log).String(); got != test.expected {
+			t.Errorf("%v: expected:\n%v\ngot:\n%v", test.name, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// BugzillaTracker is an IssueTracker filing bugs in a component of a
+// Bugzilla product, using the REST API. The labels of an issue are the
+// keywords of its bug, so they must be defined in Bugzilla; duplicates are
+// resolved as DUPLICATE of the bug they duplicate.
+type BugzillaTracker struct {
+	// url is the Bugzilla server, like "https://bugzilla.example.com".
+	url       string
+	product   string
+	component string
+	client    *http.Client
+
+	// Version is the version new bugs are filed against.
+	Version string
+	// APIKey, if set, is the API key requests are authenticated with.
+	APIKey string
+}
+
+// NewBugzillaTracker returns a BugzillaTracker filing bugs in `component`
+// of `product` on the Bugzilla server at `url`. A nil client is
+// http.DefaultClient.
+func NewBugzillaTracker(url, product, component string, client *http.Client) *BugzillaTracker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &BugzillaTracker{
+		url:       strings.TrimSuffix(url, "/"),
+		product:   product,
+		component: component,
+		client:    client,
+		Version:   "unspecified",
+	}
+}
+
+// bugzillaError is how Bugzilla reports a failed call, sometimes with a
+// 200 status.
+type bugzillaError struct {
+	Error   bool   `json:"error"`
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+// call sends `in`, if it isn't nil, to `path` with `method`, decoding the
+// answer into `out` if it isn't nil.
+func (b *BugzillaTracker) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var data []byte
+	if in != nil {
+		var err error
+		if data, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, b.url+"/rest"+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.APIKey != "" {
+		req.Header.Set("X-BUGZILLA-API-KEY", b.APIKey)
+	}
+	req.Cancel = ctx.Done()
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	op := fmt.Sprintf("bugzilla %v %v", method, strings.SplitN(path, "?", 2)[0])
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &TrackerError{Op: op, StatusCode: resp.StatusCode, Message: string(data)}
+	}
+	var e bugzillaError
+	if json.Unmarshal(data, &e) == nil && e.Error {
+		// The status says nothing about what went wrong, and a
+		// request Bugzilla rejected won't succeed the next time.
+		return &TrackerError{Op: op, StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("%v (code %d)", e.Message, e.Code)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Find implements IssueTracker. Bugzilla matches the summaries of bugs
+// which contain `title`.
+func (b *BugzillaTracker) Find(ctx context.Context, title string) ([]TrackedIssue, error) {
+	q := url.Values{}
+	q.Set("product", b.product)
+	q.Set("component", b.component)
+	q.Set("summary", title)
+	q.Set("include_fields", "id,summary,is_open")
+	found := struct {
+		Bugs []struct {
+			ID      int    `json:"id"`
+			Summary string `json:"summary"`
+			IsOpen  bool   `json:"is_open"`
+		} `json:"bugs"`
+	}{}
+	if err := b.call(ctx, "GET", "/bug?"+q.Encode(), nil, &found); err != nil {
+		return nil, err
+	}
+	issues := []TrackedIssue{}
+	for _, bug := range found.Bugs {
+		issues = append(issues, TrackedIssue{Number: bug.ID, Title: bug.Summary, Open: bug.IsOpen})
+	}
+	return issues, nil
+}
+
+// Comments implements IssueTracker. The description of a bug is its first
+// comment.
+func (b *BugzillaTracker) Comments(ctx context.Context, number int) ([]string, error) {
+	found := struct {
+		Bugs map[string]struct {
+			Comments []struct {
+				Text string `json:"text"`
+			} `json:"comments"`
+		} `json:"bugs"`
+	}{}
+	if err := b.call(ctx, "GET", fmt.Sprintf("/bug/%d/comment", number), nil, &found); err != nil {
+		return nil, err
+	}
+	comments := []string{}
+	for _, c := range found.Bugs[strconv.Itoa(number)].Comments {
+		comments = append(comments, c.Text)
+	}
+	return comments, nil
+}
+
+// Create implements IssueTracker.
+func (b *BugzillaTracker) Create(ctx context.Context, title, body string, labels []string) (int, error) {
+	in := map[string]interface{}{
+		"product":     b.product,
+		"component":   b.component,
+		"version":     b.Version,
+		"summary":     title,
+		"description": body,
+	}
+	if len(labels) > 0 {
+		in["keywords"] = labels
+	}
+	created := struct {
+		ID int `json:"id"`
+	}{}
+	if err := b.call(ctx, "POST", "/bug", in, &created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// Comment implements IssueTracker.
+func (b *BugzillaTracker) Comment(ctx context.Context, number int, body string) error {
+	return b.call(ctx, "POST", fmt.Sprintf("/bug/%d/comment", number), map[string]string{"comment": body}, nil)
+}
+
+// CloseDuplicate implements IssueTracker by resolving the bug as a
+// DUPLICATE, with `text` as the comment.
+func (b *BugzillaTracker) CloseDuplicate(ctx context.Context, number, of int, text string) error {
+	in := map[string]interface{}{
+		"status":     "RESOLVED",
+		"resolution": "DUPLICATE",
+		"dupe_of":    of,
+		"comment":    map[string]string{"body": text},
+	}
+	return b.call(ctx, "PUT", fmt.Sprintf("/bug/%d", number), in, nil)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+type fakeBug struct {
+	summary    string
+	keywords   []string
+	comments   []string
+	resolution string
+	dupeOf     int
+}
+
+// fakeBugzilla serves the parts of the Bugzilla REST API a BugzillaTracker
+// uses.
+type fakeBugzilla struct {
+	bugs []*fakeBug
+}
+
+var fakeBugzillaPath = regexp.MustCompile(`^/rest/bug/(\d+)(/comment)?$`)
+
+func (f *fakeBugzilla) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-BUGZILLA-API-KEY") != "key" {
+		// Bugzilla reports errors with a 200 status.
+		fmt.Fprint(w, `{"error":true,"message":"The API key you specified is invalid.","code":306}`)
+		return
+	}
+	in := map[string]interface{}{}
+	if r.Method != "GET" {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var out interface{}
+	m := fakeBugzillaPath.FindStringSubmatch(r.URL.Path)
+	switch {
+	case r.URL.Path == "/rest/bug" && r.Method == "GET":
+		q := r.URL.Query()
+		bugs := []map[string]interface{}{}
+		for i, b := range f.bugs {
+			if q.Get("product") == "Infra" && q.Get("component") == "CI" && strings.Contains(b.summary, q.Get("summary")) {
+				bugs = append(bugs, map[string]interface{}{"id": i + 1, "summary": b.summary, "is_open": b.resolution == ""})
+			}
+		}
+		out = map[string]interface{}{"bugs": bugs}
+	case r.URL.Path == "/rest/bug" && r.Method == "POST":
+		b := &fakeBug{summary: in["summary"].(string), comments: []string{in["description"].(string)}}
+		for _, k := range in["keywords"].([]interface{}) {
+			b.keywords = append(b.keywords, k.(string))
+		}
+		f.bugs = append(f.bugs, b)
+		out = map[string]int{"id": len(f.bugs)}
+	case m != nil && m[2] != "":
+		n, _ := strconv.Atoi(m[1])
+		b := f.bugs[n-1]
+		if r.Method == "POST" {
+			b.comments = append(b.comments, in["comment"].(string))
+			out = map[string]int{"id": 100}
+			break
+		}
+		comments := []map[string]string{}
+		for _, c := range b.comments {
+			comments = append(comments, map[string]string{"text": c})
+		}
+		out = map[string]interface{}{"bugs": map[string]interface{}{m[1]: map[string]interface{}{"comments": comments}}}
+	case m != nil && r.Method == "PUT":
+		n, _ := strconv.Atoi(m[1])
+		b := f.bugs[n-1]
+		b.resolution = in["resolution"].(string)
+		b.dupeOf = int(in["dupe_of"].(float64))
+		b.comments = append(b.comments, in["comment"].(map[string]interface{})["body"].(string))
+		out = map[string]interface{}{"bugs": []interface{}{}}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+func TestBugzillaTracker(t *testing.T) {
+	fake := &fakeBugzilla{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	tracker := NewBugzillaTracker(server.URL, "Infra", "CI", nil)
+	tracker.APIKey = "key"
+	s, err := NewTrackerSyncer(tracker, WithLabels("flake"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	first := &testSource{title: "TestFoo", id: "http://build/1"}
+	second := &testSource{title: "TestFoo", id: "http://build/2"}
+	results, err := s.SyncAll(ctx, []IssueSource{first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != DecisionCreate || results[0].Issue != 1 {
+		t.Fatalf("expected bug 1 to be filed, got %+v", results[0])
+	}
+	if e, a := []string{"kind/bug", "flake"}, fake.bugs[0].keywords; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected keywords %v, got %v", e, a)
+	}
+
+	// The source is found in the description by a new syncer.
+	s, err = NewTrackerSyncer(tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = s.SyncAll(ctx, []IssueSource{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != DecisionNone || results[1].Action != DecisionUpdate || results[1].Issue != 1 {
+		t.Errorf("expected only %v to be commented on bug 1, got %+v", second.id, results)
+	}
+	if c := fake.bugs[0].comments; len(c) != 2 || !strings.Contains(c[1], IDMarker(second.id)) {
+		t.Errorf("unexpected comments %q", c)
+	}
+
+	// A newer bug is resolved as a duplicate.
+	fake.bugs = append(fake.bugs, &fakeBug{summary: "TestFoo"})
+	results, err = s.SyncAll(ctx, []IssueSource{&testSource{title: "TestFoo", id: "http://build/3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dup := fake.bugs[1]; results[0].Issue != 1 || dup.resolution != "DUPLICATE" || dup.dupeOf != 1 {
+		t.Errorf("expected bug 2 to be resolved as a duplicate of 1, got %+v", results[0])
+	}
+
+	tracker.APIKey = "wrong"
+	_, err = tracker.Find(ctx, "TestFoo")
+	if e, ok := err.(*TrackerError); !ok || !strings.Contains(e.Message, "API key") || IsRetryable(err) {
+		t.Errorf("expected a permanent TrackerError for the rejected key, got %v", err)
+	}
+}
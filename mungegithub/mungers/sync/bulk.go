@@ -0,0 +1,79 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// maxBulkIssues is the most issues bulkFetch asks for in one query, which
+// keeps the queries well within github's limits on GraphQL nodes.
+const maxBulkIssues = 50
+
+// prefetchedComments are an issue's recent comments as of updatedAt.
+type prefetchedComments struct {
+	github.RecentComments
+	updatedAt time.Time
+}
+
+// bulkFetch gets the issues `numbers`, with their most recent comments, in
+// as few GraphQL queries as possible, see BulkComments. Issues it can't get
+// are left out, for the caller to get the usual way.
+func (s *IssueSyncer) bulkFetch(ctx context.Context, numbers []int) map[int]*github.MungeObject {
+	if s.BulkComments <= 0 || len(numbers) < 2 {
+		return nil
+	}
+	s.prefetched = map[int]prefetchedComments{}
+	out := map[int]*github.MungeObject{}
+	for start := 0; start < len(numbers); start += maxBulkIssues {
+		end := start + maxBulkIssues
+		if end > len(numbers) {
+			end = len(numbers)
+		}
+		var objs map[int]*github.MungeObject
+		var recent map[int]github.RecentComments
+		err := s.retry(ctx, fmt.Sprintf("getting issues %v", numbers[start:end]), func() (err error) {
+			objs, recent, err = s.client(ctx).GetObjects(numbers[start:end], s.BulkComments)
+			return err
+		})
+		if err != nil {
+			s.logger().Errorf("Unable to get issues in bulk, getting them one by one: %v", err)
+			continue
+		}
+		for n, obj := range objs {
+			out[n] = obj
+			if obj.Issue.UpdatedAt != nil {
+				s.prefetched[n] = prefetchedComments{RecentComments: recent[n], updatedAt: *obj.Issue.UpdatedAt}
+			}
+		}
+	}
+	return out
+}
+
+// prefetchedFor returns the comments bulkFetch got for `obj`, if they are
+// still current.
+func (s *IssueSyncer) prefetchedFor(obj *github.MungeObject) (prefetchedComments, bool) {
+	p, ok := s.prefetched[*obj.Issue.Number]
+	if !ok || obj.Issue.UpdatedAt == nil || !p.updatedAt.Equal(*obj.Issue.UpdatedAt) {
+		return prefetchedComments{}, false
+	}
+	return p, true
+}
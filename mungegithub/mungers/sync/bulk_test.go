@@ -0,0 +1,97 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+const bulkResponse = `{"data": {"repository": {
+	"i1": {"number": 1, "title": "TestFoo", "state": "OPEN", "body": "TestFoo failed", "updatedAt": "2016-07-01T12:00:00Z",
+		"comments": {"totalCount": 1, "nodes": [{"body": "Failed again in ref-1"}]}},
+	"i2": {"number": 2, "title": "TestFoo", "state": "CLOSED", "body": "TestFoo failed", "updatedAt": "2016-07-01T12:00:00Z",
+		"comments": {"totalCount": 5, "nodes": [{"body": "Failed again in ref-2"}]}},
+	"i3": null
+}}, "errors": [{"message": "Could not resolve to an Issue with the number of 3."}]}`
+
+func TestBulkFetch(t *testing.T) {
+	issue := github_test.Issue("bot", 3, nil, false)
+	state := "open"
+	issue.State = &state
+	client, server, mux := github_test.InitServer(t, issue, nil, nil, nil, nil, nil)
+	defer server.Close()
+	config := &github.Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+	queries := 0
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		w.Write([]byte(bulkResponse))
+	})
+	listed := map[int]int{}
+	for n := 1; n <= 3; n++ {
+		n := n
+		mux.HandleFunc(fmt.Sprintf("/repos/o/r/issues/%d/comments", n), func(w http.ResponseWriter, r *http.Request) {
+			listed[n]++
+			w.Write([]byte("[]"))
+		})
+	}
+
+	s := NewIssueSyncer(config, &keyFinder{titles: map[string][]int{"TestFoo": {1, 2, 3}}})
+	s.BulkComments = 10
+	tests := []struct {
+		ref      string
+		found    bool
+		expected map[int]int
+	}{
+		// Found in the recent comments of #2, #3 isn't an issue to
+		// GraphQL and has its comments listed instead.
+		{ref: "ref-2", found: true, expected: map[int]int{3: 1}},
+		// #1 has no further comments, but #2 does.
+		{ref: "ref-9", found: false, expected: map[int]int{2: 1, 3: 1}},
+	}
+	for _, test := range tests {
+		queries, listed = 0, map[int]int{}
+		found, open, closed, err := s.findPreviousIssues(context.Background(), &JSONSource{Key: "TestFoo", Ref: test.ref})
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.ref, err)
+			continue
+		}
+		if found != test.found {
+			t.Errorf("%v: expected found %v, got %v", test.ref, test.found, found)
+		}
+		if len(open) != 2 || len(closed) != 1 || *closed[0].Issue.Number != 2 {
+			t.Errorf("%v: expected #1 and #3 open and #2 closed, got %v open, %v closed", test.ref, len(open), len(closed))
+		}
+		if queries != 1 {
+			t.Errorf("%v: expected one query, got %v", test.ref, queries)
+		}
+		if len(listed) != len(test.expected) {
+			t.Errorf("%v: expected comments of %v to be listed, got %v", test.ref, test.expected, listed)
+		}
+		for n, count := range test.expected {
+			if listed[n] != count {
+				t.Errorf("%v: expected comments of %v to be listed, got %v", test.ref, test.expected, listed)
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+const dateFormat = "2006-01-02"
+
+// Period is a span of time, including Start and excluding End.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Calendar knows which time counts for timers like "no response in 3 days":
+// weekends, holidays and freeze periods don't. A nil *Calendar counts every
+// second of every day.
+type Calendar struct {
+	// Location is used to decide where days start and end. Defaults to UTC.
+	Location *time.Location
+	// Weekend lists the days of the week which are not business days.
+	Weekend []time.Weekday
+	// Holidays are dates (only the day matters) which are not business days.
+	Holidays []time.Time
+	// Freezes are periods during which the clock doesn't run, e.g. a
+	// holiday break or a code freeze.
+	Freezes []Period
+}
+
+// NewCalendar returns a calendar with Saturday and Sunday as the weekend.
+func NewCalendar() *Calendar {
+	return &Calendar{
+		Location: time.UTC,
+		Weekend:  []time.Weekday{time.Saturday, time.Sunday},
+	}
+}
+
+// calendarConfig is how calendars are written down, e.g.:
+//
+//	timezone: America/Los_Angeles
+//	weekend: [Saturday, Sunday]
+//	holidays: ["2016-12-25", "2017-01-01"]
+//	freezes:
+//	- start: "2016-12-19"
+//	  end: "2017-01-03"
+type calendarConfig struct {
+	Timezone string   `json:"timezone"`
+	Weekend  []string `json:"weekend"`
+	Holidays []string `json:"holidays"`
+	Freezes  []struct {
+		Start string `json:"start"`
+		End   string `json:"end"`
+	} `json:"freezes"`
+}
+
+// LoadCalendar reads a calendar from a yaml (or json) file.
+func LoadCalendar(path string) (*Calendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cfg := calendarConfig{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("error parsing calendar %v: %v", path, err)
+	}
+
+	c := NewCalendar()
+	if cfg.Timezone != "" {
+		if c.Location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Weekend != nil {
+		c.Weekend = []time.Weekday{}
+		for _, name := range cfg.Weekend {
+			day, ok := weekdays[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown weekday %q in %v", name, path)
+			}
+			c.Weekend = append(c.Weekend, day)
+		}
+	}
+	for _, h := range cfg.Holidays {
+		d, err := time.ParseInLocation(dateFormat, h, c.Location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday in %v: %v", path, err)
+		}
+		c.Holidays = append(c.Holidays, d)
+	}
+	for _, f := range cfg.Freezes {
+		start, err := time.ParseInLocation(dateFormat, f.Start, c.Location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid freeze in %v: %v", path, err)
+		}
+		end, err := time.ParseInLocation(dateFormat, f.End, c.Location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid freeze in %v: %v", path, err)
+		}
+		c.Freezes = append(c.Freezes, Period{Start: start, End: end})
+	}
+	return c, nil
+}
+
+var weekdays = map[string]time.Weekday{}
+
+func init() {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		weekdays[strings.ToLower(d.String())] = d
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// IsBusinessDay returns true if `t` falls on a business day.
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	if c == nil {
+		return true
+	}
+	t = t.In(c.Location)
+	for _, d := range c.Weekend {
+		if t.Weekday() == d {
+			return false
+		}
+	}
+	for _, h := range c.Holidays {
+		if sameDay(t, h.In(c.Location)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Frozen returns true if `t` is within a freeze period.
+func (c *Calendar) Frozen(t time.Time) bool {
+	if c == nil {
+		return false
+	}
+	for _, f := range c.Freezes {
+		if !t.Before(f.Start) && t.Before(f.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// Elapsed returns how much business time passed between `from` and `to`:
+// weekends, holidays and freezes are skipped.
+func (c *Calendar) Elapsed(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+	if c == nil {
+		return to.Sub(from)
+	}
+	var total time.Duration
+	for start := from.In(c.Location); start.Before(to); {
+		y, m, d := start.Date()
+		end := time.Date(y, m, d+1, 0, 0, 0, 0, c.Location)
+		if end.After(to) {
+			end = to
+		}
+		if c.IsBusinessDay(start) {
+			total += end.Sub(start) - c.frozen(start, end)
+		}
+		start = end
+	}
+	return total
+}
+
+// BusinessDays returns how many full business days passed between `from`
+// and `to`.
+func (c *Calendar) BusinessDays(from, to time.Time) int {
+	return int(c.Elapsed(from, to) / (24 * time.Hour))
+}
+
+// frozen returns how much of [start, end) is within freeze periods. Time
+// within overlapping freezes counts once.
+func (c *Calendar) frozen(start, end time.Time) time.Duration {
+	overlaps := []Period{}
+	for _, f := range c.Freezes {
+		s, e := f.Start, f.End
+		if s.Before(start) {
+			s = start
+		}
+		if e.After(end) {
+			e = end
+		}
+		if e.After(s) {
+			overlaps = append(overlaps, Period{Start: s, End: e})
+		}
+	}
+	sort.Sort(byStart(overlaps))
+	var total time.Duration
+	// counted is how far freezes were counted.
+	counted := start
+	for _, p := range overlaps {
+		if p.Start.Before(counted) {
+			p.Start = counted
+		}
+		if p.End.After(p.Start) {
+			total += p.End.Sub(p.Start)
+			counted = p.End
+		}
+	}
+	return total
+}
+
+type byStart []Period
+
+func (b byStart) Len() int           { return len(b) }
+func (b byStart) Less(i, j int) bool { return b[i].Start.Before(b[j].Start) }
+func (b byStart) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
@@ -0,0 +1,131 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCalendarElapsed(t *testing.T) {
+	c := NewCalendar()
+	c.Holidays = []time.Time{date("2016-07-04 00:00")}
+	c.Freezes = []Period{{Start: date("2016-12-19 00:00"), End: date("2017-01-03 00:00")}}
+	overlapping := NewCalendar()
+	overlapping.Freezes = []Period{
+		{Start: date("2016-12-26 00:00"), End: date("2017-01-04 00:00")},
+		{Start: date("2016-12-19 00:00"), End: date("2017-01-03 00:00")},
+		{Start: date("2016-12-20 00:00"), End: date("2016-12-21 00:00")},
+	}
+
+	tests := []struct {
+		name     string
+		cal      *Calendar
+		from, to string
+		expected time.Duration
+	}{
+		{
+			name:     "no calendar",
+			from:     "2016-07-01 12:00",
+			to:       "2016-07-05 12:00",
+			expected: 96 * time.Hour,
+		},
+		{
+			name:     "weekdays",
+			cal:      c,
+			from:     "2016-06-27 12:00",
+			to:       "2016-06-29 18:00",
+			expected: 54 * time.Hour,
+		},
+		{
+			name:     "weekend and holiday",
+			cal:      c,
+			from:     "2016-07-01 12:00",
+			to:       "2016-07-05 12:00",
+			expected: 24 * time.Hour,
+		},
+		{
+			name:     "freeze",
+			cal:      c,
+			from:     "2016-12-16 00:00",
+			to:       "2017-01-04 00:00",
+			expected: 48 * time.Hour,
+		},
+		{
+			name:     "overlapping freezes",
+			cal:      overlapping,
+			from:     "2016-12-16 00:00",
+			to:       "2017-01-05 00:00",
+			expected: 48 * time.Hour,
+		},
+		{
+			name:     "backwards",
+			cal:      c,
+			from:     "2016-07-05 12:00",
+			to:       "2016-07-01 12:00",
+			expected: 0,
+		},
+	}
+	for _, test := range tests {
+		if got := test.cal.Elapsed(date(test.from), date(test.to)); got != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestLoadCalendar(t *testing.T) {
+	file, err := ioutil.TempFile("", "calendar")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`
+weekend: [Friday, Saturday]
+holidays: ["2016-12-25"]
+freezes:
+- start: "2016-12-19"
+  end: "2017-01-03"
+`)
+	file.Close()
+
+	c, err := LoadCalendar(file.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.IsBusinessDay(date("2016-12-23 10:00")) {
+		t.Errorf("Friday should be part of the weekend")
+	}
+	if !c.IsBusinessDay(date("2016-12-18 10:00")) {
+		t.Errorf("Sunday should be a business day")
+	}
+	if c.IsBusinessDay(date("2016-12-25 10:00")) {
+		t.Errorf("holiday should not be a business day")
+	}
+	if !c.Frozen(date("2016-12-28 10:00")) || c.Frozen(date("2017-01-03 00:00")) {
+		t.Errorf("unexpected freeze: %v", c.Freezes)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock is where the syncer gets the time from, and waits with: for
+// backoff, resync intervals, staleness, quiet hours and polling. Tests use
+// a FakeClock, to move time along without sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock is the wall clock.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Rand is where jitter (see Backoff and Scheduler) comes from. A
+// *rand.Rand with a fixed seed makes it repeatable.
+type Rand interface {
+	Float64() float64
+}
+
+// globalRand is math/rand's shared source, which is safe to use from
+// several goroutines.
+type globalRand struct{}
+
+func (globalRand) Float64() float64 { return rand.Float64() }
+
+// FakeClock is a Clock which only moves when told to. Waits end once the
+// clock was moved past them.
+type FakeClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewFakeClock returns a FakeClock set to `now`.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements Clock.
+func (f *FakeClock) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.now
+}
+
+// After implements Clock.
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- f.now
+		return c
+	}
+	f.waiters = append(f.waiters, fakeWaiter{at: f.now.Add(d), c: c})
+	return c
+}
+
+// Advance moves the clock by `d`, ending the waits it moves past.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the clock to `t`, ending the waits it moves past.
+func (f *FakeClock) Set(t time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = t
+	waiting := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.at.After(t) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.c <- t
+	}
+	f.waiters = waiting
+}
+
+// Waiters returns how many waits haven't ended yet, so that tests can tell
+// when the code under test is waiting.
+func (f *FakeClock) Waiters() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.waiters)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(date("2016-07-01 12:00"))
+	soon, later := clock.After(time.Minute), clock.After(time.Hour)
+	if clock.Waiters() != 2 {
+		t.Errorf("expected 2 waiters, got %d", clock.Waiters())
+	}
+	clock.Advance(10 * time.Minute)
+	select {
+	case at := <-soon:
+		if !at.Equal(date("2016-07-01 12:10")) {
+			t.Errorf("unexpected time %v", at)
+		}
+	default:
+		t.Errorf("expected the short wait to be over")
+	}
+	select {
+	case <-later:
+		t.Errorf("expected the long wait to go on")
+	default:
+	}
+	if clock.Waiters() != 1 || !clock.Now().Equal(date("2016-07-01 12:10")) {
+		t.Errorf("unexpected clock state: %d waiters at %v", clock.Waiters(), clock.Now())
+	}
+}
+
+func newClockedSyncer(t *testing.T, tracker *synctesting.Tracker, clock Clock, opts ...Option) *IssueSyncer {
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s, err := New(append([]Option{WithRepo(tracker.Config()), WithFinder(finder), WithClock(clock)}, opts...)...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return s
+}
+
+func TestFakeClockBackoff(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	clock := NewFakeClock(date("2016-07-01 12:00"))
+	s := newClockedSyncer(t, tracker, clock, WithRand(rand.New(rand.NewSource(1))))
+	tracker.FailNext("POST", "/repos/o/r/issues", http.StatusServiceUnavailable, 2)
+
+	done := make(chan error)
+	go func() { done <- s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: "foo-1"}) }()
+	for retries := 0; retries < 2; retries++ {
+		for clock.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(s.Backoff.Cap)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tracker.OpenIssues("TestFoo")) != 1 {
+		t.Errorf("expected the issue to be filed after the retries")
+	}
+}
+
+func TestFakeClockStaleClose(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	clock := NewFakeClock(date("2016-07-01 12:00"))
+	s := newClockedSyncer(t, tracker, clock)
+	if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: "foo-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l := NewLifecycle()
+	l.Close = NoOccurrencesFor(14 * 24 * time.Hour)
+
+	clock.Advance(13 * 24 * time.Hour)
+	if err := s.UpdateLifecycle(context.Background(), l); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tracker.OpenIssues("TestFoo")) != 1 {
+		t.Errorf("expected the issue to stay open for 14 days")
+	}
+	clock.Advance(24 * time.Hour)
+	if err := s.UpdateLifecycle(context.Background(), l); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tracker.OpenIssues("TestFoo")) != 0 {
+		t.Errorf("expected the issue to be closed after 14 days")
+	}
+}
+
+func TestSortSources(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	s := newClockedSyncer(t, tracker, NewFakeClock(date("2016-07-01 12:00")), WithSortedSources(true))
+	if _, err := s.SyncAll(context.Background(), []IssueSource{
+		&JSONSource{Key: "TestBar", Ref: "b-1"},
+		&JSONSource{Key: "TestFoo", Ref: "a-1"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	foo, bar := tracker.OpenIssues("TestFoo"), tracker.OpenIssues("TestBar")
+	if len(foo) != 1 || len(bar) != 1 || foo[0] > bar[0] {
+		t.Errorf("expected a-1 to be filed before b-1, got %v and %v", foo, bar)
+	}
+}
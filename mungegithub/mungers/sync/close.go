@@ -0,0 +1,107 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// CloseCandidate is an open issue we filed, as Lifecycle sees it.
+type CloseCandidate struct {
+	Record IssueRecord
+	Issue  *github.MungeObject
+	// Idle is how long (in business time) it has been since the source
+	// last occurred or a human last commented.
+	Idle time.Duration
+	// SinceOccurrence is how long (in business time) it has been since the
+	// source last occurred.
+	SinceOccurrence time.Duration
+}
+
+// CloseCondition decides when Lifecycle closes issues, e.g. "no occurrences
+// for 14 days and the test was removed from the tree":
+//
+//	AllOf(NoOccurrencesFor(14*24*time.Hour), CloseConditionFunc(
+//		func(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+//			gone, err := testRemoved(c.Record.Title)
+//			return gone, "The test was removed.", err
+//		}))
+type CloseCondition interface {
+	// ShouldClose returns true if the issue should be closed, and why,
+	// for the closing comment. An empty reason comments with the
+	// StaleClose template.
+	ShouldClose(ctx context.Context, c *CloseCandidate) (bool, string, error)
+}
+
+// CloseConditionFunc is a function which is a CloseCondition.
+type CloseConditionFunc func(ctx context.Context, c *CloseCandidate) (bool, string, error)
+
+// ShouldClose implements CloseCondition.
+func (f CloseConditionFunc) ShouldClose(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+	return f(ctx, c)
+}
+
+// IdleFor closes issues which have been idle for `d`, with the StaleClose
+// template. It's Lifecycle's default, with CloseAfter.
+func IdleFor(d time.Duration) CloseCondition {
+	return CloseConditionFunc(func(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+		return c.Idle >= d, "", nil
+	})
+}
+
+// NoOccurrencesFor closes issues whose source hasn't occurred for `d`, even
+// if humans commented since.
+func NoOccurrencesFor(d time.Duration) CloseCondition {
+	return CloseConditionFunc(func(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+		return c.SinceOccurrence >= d, "", nil
+	})
+}
+
+// AllOf closes issues which all of `conditions` would close. The reasons
+// are joined.
+func AllOf(conditions ...CloseCondition) CloseCondition {
+	return CloseConditionFunc(func(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+		reasons := []string{}
+		for _, cond := range conditions {
+			shouldClose, reason, err := cond.ShouldClose(ctx, c)
+			if err != nil || !shouldClose {
+				return false, "", err
+			}
+			if reason != "" {
+				reasons = append(reasons, reason)
+			}
+		}
+		return len(conditions) > 0, strings.Join(reasons, " "), nil
+	})
+}
+
+// AnyOf closes issues which any of `conditions` would close, for its reason.
+func AnyOf(conditions ...CloseCondition) CloseCondition {
+	return CloseConditionFunc(func(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+		for _, cond := range conditions {
+			shouldClose, reason, err := cond.ShouldClose(ctx, c)
+			if err != nil || shouldClose {
+				return shouldClose, reason, err
+			}
+		}
+		return false, "", nil
+	})
+}
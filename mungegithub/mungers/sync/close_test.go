@@ -0,0 +1,107 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestCloseConditions(t *testing.T) {
+	yes := CloseConditionFunc(func(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+		return true, "Yes.", nil
+	})
+	no := CloseConditionFunc(func(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+		return false, "", nil
+	})
+	broken := CloseConditionFunc(func(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+		return false, "", fmt.Errorf("broken")
+	})
+	tests := []struct {
+		name      string
+		condition CloseCondition
+		close     bool
+		reason    string
+		err       bool
+	}{
+		{name: "idle", condition: IdleFor(10 * businessDay), close: true},
+		{name: "not idle", condition: IdleFor(30 * businessDay)},
+		{name: "no occurrences", condition: NoOccurrencesFor(20 * businessDay), close: true},
+		{name: "occurred", condition: NoOccurrencesFor(30 * businessDay)},
+		{name: "all", condition: AllOf(yes, IdleFor(10*businessDay), yes), close: true, reason: "Yes. Yes."},
+		{name: "not all", condition: AllOf(yes, no)},
+		{name: "none of all", condition: AllOf()},
+		{name: "any", condition: AnyOf(no, yes), close: true, reason: "Yes."},
+		{name: "not any", condition: AnyOf(no, no)},
+		{name: "error", condition: AllOf(yes, broken), err: true},
+	}
+	c := &CloseCandidate{Idle: 20 * businessDay, SinceOccurrence: 25 * businessDay}
+	for _, test := range tests {
+		shouldClose, reason, err := test.condition.ShouldClose(context.Background(), c)
+		if shouldClose != test.close || reason != test.reason || (err != nil) != test.err {
+			t.Errorf("%v: expected %v %q (error: %v), got %v %q %v", test.name, test.close, test.reason, test.err, shouldClose, reason, err)
+		}
+	}
+}
+
+func TestLifecycleCloseCondition(t *testing.T) {
+	removed := map[string]bool{"TestGone": true}
+	l := NewLifecycle()
+	l.Close = AllOf(NoOccurrencesFor(14*businessDay), CloseConditionFunc(func(ctx context.Context, c *CloseCandidate) (bool, string, error) {
+		return removed[c.Record.Title], "The test was removed from the tree.", nil
+	}))
+	tests := []struct {
+		title    string
+		occurred string
+		closed   bool
+		label    string
+	}{
+		{title: "TestGone", occurred: "2016-06-01 12:00", closed: true},
+		{title: "TestGone", occurred: "2016-07-01 12:00", label: "lifecycle/active"},
+		{title: "TestHere", occurred: "2016-04-01 12:00", label: "lifecycle/rotten"},
+	}
+	for _, test := range tests {
+		tracker := synctesting.NewTracker()
+		n := tracker.AddIssue(test.title, "details")
+		s := NewIssueSyncer(tracker.Config(), nil)
+		s.now = func() time.Time { return date("2016-07-05 12:00") }
+		s.Store.Update(n, func(r *IssueRecord) {
+			r.Title = test.title
+			r.Created = date("2016-03-01 12:00")
+			r.LastOccurrence = date(test.occurred)
+		})
+		if err := s.UpdateLifecycle(context.Background(), l); err != nil {
+			t.Errorf("%v since %v: unexpected error: %v", test.title, test.occurred, err)
+		}
+		issue := tracker.Issues()[0]
+		if closed := *issue.State == "closed"; closed != test.closed {
+			t.Errorf("%v since %v: expected closed %v, got %v", test.title, test.occurred, test.closed, closed)
+		}
+		if comments := tracker.Comments(n); test.closed && (len(comments) != 1 || !strings.HasPrefix(comments[0], "The test was removed from the tree.")) {
+			t.Errorf("%v since %v: expected the reason as comment, got %q", test.title, test.occurred, comments)
+		}
+		if test.label != "" && (len(issue.Labels) != 1 || *issue.Labels[0].Name != test.label) {
+			t.Errorf("%v since %v: expected %v, got %v", test.title, test.occurred, test.label, issue.Labels)
+		}
+		tracker.Close()
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	githubapi "github.com/google/go-github/github"
+)
+
+// listCommentsFunc lists the comments of an issue. A zero `since` means all
+// of them.
+type listCommentsFunc func(since time.Time) ([]githubapi.IssueComment, error)
+
+// commentCache remembers the comment bodies of every issue isRecorded has
+// looked at. Busy flake issues have hundreds of comments, and listing them
+// all on every pass is by far the most expensive thing the syncer does.
+//
+// An issue's updated_at changes whenever a comment is added or edited, so if
+// it hasn't moved since our last look we don't make any calls at all (and
+// fetching the issue itself is answered by the http cache with an ETag, which
+// doesn't count against our rate limit). If it has moved, only the comments
+// updated since the newest one we know about are listed.
+//
+// Deleted comments are not noticed, which at worst means we believe a source
+// is still recorded in an issue after a human removed our comment.
+type commentCache struct {
+	lock   sync.Mutex
+	issues map[int]*cachedComments
+}
+
+type cachedComments struct {
+	// updatedAt is the issue's updated_at when we last listed.
+	updatedAt time.Time
+	// newest is the most recent comment updated_at we've seen.
+	newest time.Time
+	// bodies maps comment IDs to their bodies.
+	bodies map[int]string
+}
+
+func newCommentCache() *commentCache {
+	return &commentCache{issues: map[int]*cachedComments{}}
+}
+
+// get returns the comment bodies of issue `number`, which was last updated
+// at `updatedAt`, calling `list` only if we have to.
+func (c *commentCache) get(number int, updatedAt *time.Time, list listCommentsFunc) ([]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cached, ok := c.issues[number]
+	if ok && updatedAt != nil && cached.updatedAt.Equal(*updatedAt) {
+		glog.V(4).Infof("Comments of issue %v unchanged since %v", number, cached.updatedAt)
+		return cached.list(), nil
+	}
+	if !ok {
+		cached = &cachedComments{bodies: map[int]string{}}
+	}
+	comments, err := list(cached.newest)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		if comment.ID == nil || comment.Body == nil {
+			continue
+		}
+		cached.bodies[*comment.ID] = *comment.Body
+		if comment.UpdatedAt != nil && comment.UpdatedAt.After(cached.newest) {
+			cached.newest = *comment.UpdatedAt
+		}
+	}
+	if updatedAt != nil {
+		cached.updatedAt = *updatedAt
+	}
+	c.issues[number] = cached
+	return cached.list(), nil
+}
+
+// prime caches `comments`, all the comments of issue `number` as of
+// `updatedAt`, e.g. from a listing of the repo's comments.
+func (c *commentCache) prime(number int, updatedAt time.Time, comments []githubapi.IssueComment) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cached := &cachedComments{updatedAt: updatedAt, bodies: map[int]string{}}
+	for _, comment := range comments {
+		if comment.ID == nil || comment.Body == nil {
+			continue
+		}
+		cached.bodies[*comment.ID] = *comment.Body
+		if comment.UpdatedAt != nil && comment.UpdatedAt.After(cached.newest) {
+			cached.newest = *comment.UpdatedAt
+		}
+	}
+	c.issues[number] = cached
+}
+
+func (c *cachedComments) list() []string {
+	out := make([]string, 0, len(c.bodies))
+	for _, body := range c.bodies {
+		out = append(out, body)
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	githubapi "github.com/google/go-github/github"
+)
+
+func comment(id int, body string, updated int64) githubapi.IssueComment {
+	t := time.Unix(updated, 0)
+	return githubapi.IssueComment{ID: &id, Body: &body, UpdatedAt: &t}
+}
+
+func TestCommentCache(t *testing.T) {
+	all := []githubapi.IssueComment{
+		comment(1, "a", 10),
+		comment(2, "b", 20),
+	}
+	var calls []time.Time
+	list := func(since time.Time) ([]githubapi.IssueComment, error) {
+		calls = append(calls, since)
+		out := []githubapi.IssueComment{}
+		for _, c := range all {
+			if !c.UpdatedAt.Before(since) {
+				out = append(out, c)
+			}
+		}
+		return out, nil
+	}
+
+	c := newCommentCache()
+	steps := []struct {
+		updatedAt int64
+		add       []githubapi.IssueComment
+		expected  []string
+		since     []time.Time
+	}{
+		{
+			updatedAt: 20,
+			expected:  []string{"a", "b"},
+			since:     []time.Time{{}},
+		},
+		{
+			// Nothing changed, nothing listed.
+			updatedAt: 20,
+			expected:  []string{"a", "b"},
+			since:     []time.Time{{}},
+		},
+		{
+			updatedAt: 30,
+			add:       []githubapi.IssueComment{comment(3, "c", 30), comment(1, "A", 30)},
+			expected:  []string{"A", "b", "c"},
+			since:     []time.Time{{}, time.Unix(20, 0)},
+		},
+	}
+	for i, step := range steps {
+		all = append(all, step.add...)
+		updatedAt := time.Unix(step.updatedAt, 0)
+		got, err := c.get(1, &updatedAt, list)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		sort.Strings(got)
+		if len(got) != len(step.expected) {
+			t.Errorf("%d: expected %v, got %v", i, step.expected, got)
+			continue
+		}
+		for j := range got {
+			if got[j] != step.expected[j] {
+				t.Errorf("%d: expected %v, got %v", i, step.expected, got)
+			}
+		}
+		if len(calls) != len(step.since) {
+			t.Errorf("%d: expected list calls %v, got %v", i, step.since, calls)
+			continue
+		}
+		for j := range calls {
+			if !calls[j].Equal(step.since[j]) {
+				t.Errorf("%d: expected list calls %v, got %v", i, step.since, calls)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// bodyHash is how what we wrote in an issue body is remembered, see
+// IssueRecord.BodyHash.
+func bodyHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// editBody replaces the body of `obj`, which was `old`, with `body`, and
+// remembers what we wrote to notice edits by others, see editConflict.
+func (s *IssueSyncer) editBody(ctx context.Context, op string, obj *github.MungeObject, old, body string) error {
+	n := *obj.Issue.Number
+	if err := s.retry(ctx, op, func() error {
+		return obj.EditBody(body)
+	}); err != nil {
+		return err
+	}
+	s.audit(AuditEdit, n, old)
+	s.rememberBody(n, body)
+	return nil
+}
+
+// rememberBody records that we wrote `body` in issue `n`.
+func (s *IssueSyncer) rememberBody(n int, body string) {
+	if err := s.Store.Update(n, func(r *IssueRecord) {
+		r.BodyHash = bodyHash(body)
+		r.SectionHash = bodyHash(syncSection(body))
+	}); err != nil {
+		s.logger().With("issue", n).Errorf("Unable to remember the body: %v", err)
+	}
+}
+
+// editConflict is what was found about edits by others before an occurrence
+// is recorded in an issue body.
+type editConflict int
+
+const (
+	// noConflict: nobody else edited the body since we last did, or we
+	// never did.
+	noConflict editConflict = iota
+	// conflictMerged: someone else edited the body, but not the sync
+	// section, so the section can be updated around their changes.
+	conflictMerged
+	// conflictSection: someone else edited the sync section, which we
+	// mustn't clobber.
+	conflictSection
+)
+
+// checkEdited compares `body`, freshly fetched from issue `n`, with what we
+// last wrote there.
+func (s *IssueSyncer) checkEdited(n int, body string) editConflict {
+	r, ok := s.Store.Get(n)
+	if !ok || r.BodyHash == "" || r.BodyHash == bodyHash(body) {
+		return noConflict
+	}
+	metrics.Add("editConflicts", 1)
+	if r.SectionHash != bodyHash(syncSection(body)) {
+		metrics.Add("editConflictsCommented", 1)
+		return conflictSection
+	}
+	metrics.Add("editConflictsMerged", 1)
+	return conflictMerged
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestEditConflicts(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.EditBody = true
+
+	sync := func(ref string) {
+		if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	body := func() string {
+		issues := tracker.Issues()
+		if len(issues) != 1 {
+			t.Fatalf("expected one issue, got %d", len(issues))
+		}
+		return *issues[0].Body
+	}
+	sync("foo-1")
+	sync("foo-2")
+	n := tracker.OpenIssues("TestFoo")[0]
+	counted := func(name string) string {
+		if v := metrics.Get(name); v != nil {
+			return v.String()
+		}
+		return "0"
+	}
+	merged, commented := counted("editConflictsMerged"), counted("editConflictsCommented")
+
+	// Edits outside of the sync section are kept.
+	tracker.EditBody(n, "Looks like a race in the kubelet.\n\n"+body())
+	sync("foo-3")
+	if b := body(); !strings.HasPrefix(b, "Looks like a race") || !recordedInSection(b, "foo-3") {
+		t.Errorf("expected the section to be updated around the human edit:\n%v", b)
+	}
+	if counted("editConflictsMerged") == merged {
+		t.Errorf("expected a merged conflict to be counted")
+	}
+
+	// Edits of the sync section are never clobbered.
+	edited := strings.Replace(body(), "`foo-3`", "`foo-3` (bad node)", 1)
+	tracker.EditBody(n, edited)
+	sync("foo-4")
+	if b := body(); b != edited {
+		t.Errorf("expected the human edit to be left alone, got:\n%v", b)
+	}
+	if comments := tracker.Comments(n); len(comments) != 1 || !strings.Contains(comments[0], "foo-4") {
+		t.Errorf("expected the occurrence to be commented instead, got %q", comments)
+	}
+	if counted("editConflictsCommented") == commented {
+		t.Errorf("expected a commented conflict to be counted")
+	}
+
+	// Without edits by others, the section is updated again.
+	tracker.EditBody(n, strings.Replace(body(), " (bad node)", "", 1))
+	sync("foo-5")
+	sync("foo-6")
+	if b := body(); !recordedInSection(b, "foo-6") {
+		t.Errorf("expected the section to be updated:\n%v", b)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// CulpritFinder guesses which pull request introduced the failure of a
+// source, e.g. the only one merged between the last good and the first bad
+// run of a job. Its author is told on the pull request, and the issue links
+// to it.
+type CulpritFinder interface {
+	// Culprit returns the number of the pull request, 0 if it can't tell.
+	Culprit(ctx context.Context, source IssueSource) (int, error)
+}
+
+// CulpritFunc is a function which is a CulpritFinder.
+type CulpritFunc func(ctx context.Context, source IssueSource) (int, error)
+
+// Culprit implements CulpritFinder.
+func (f CulpritFunc) Culprit(ctx context.Context, source IssueSource) (int, error) {
+	return f(ctx, source)
+}
+
+// blame comments on the culprit of `source`, which was synced to issue `n`,
+// and links it from the issue. Each culprit is only told once per issue.
+func (s *IssueSyncer) blame(ctx context.Context, n int, source IssueSource) error {
+	pr, err := s.Culprits.Culprit(ctx, source)
+	if err != nil || pr <= 0 {
+		return err
+	}
+	r, _ := s.Store.Get(n)
+	for _, c := range r.Culprits {
+		if c == pr {
+			return nil
+		}
+	}
+	var obj, issue *github.MungeObject
+	err = s.retry(ctx, fmt.Sprintf("getting objects for %v and %v", pr, n), func() (err error) {
+		if obj, err = s.client(ctx).GetObject(pr); err != nil {
+			return err
+		}
+		issue, err = s.client(ctx).GetObject(n)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.logger().With("issue", n).With("pr", pr).Infof("Telling the likely culprit")
+	msg := fmt.Sprintf("This pull request may have introduced #%d, %q failed since it was merged. Please take a look.", n, source.Title())
+	if err := s.writeComment(ctx, fmt.Sprintf("blaming %v for %v", pr, n), obj, s.text(msg)); err != nil {
+		return err
+	}
+	msg = fmt.Sprintf("This may have been introduced by #%d.", pr)
+	if err := s.writeComment(ctx, fmt.Sprintf("linking culprit %v from %v", pr, n), issue, s.text(msg)); err != nil {
+		return err
+	}
+	return s.Store.Update(n, func(r *IssueRecord) { r.Culprits = append(r.Culprits, pr) })
+}
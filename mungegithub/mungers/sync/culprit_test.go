@@ -0,0 +1,65 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestBlame(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	pr := tracker.AddIssue("Add a feature", "")
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	culprits := map[string]int{"run-1": pr, "run-2": pr, "run-3": 0}
+	s.Culprits = CulpritFunc(func(ctx context.Context, source IssueSource) (int, error) {
+		n, ok := culprits[source.ID()]
+		if !ok {
+			return 0, fmt.Errorf("no idea")
+		}
+		return n, nil
+	})
+	for _, ref := range []string{"run-1", "run-2", "run-3", "run-4"} {
+		if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref}); err != nil {
+			t.Fatalf("unexpected error syncing %v: %v", ref, err)
+		}
+	}
+
+	n := tracker.OpenIssues("TestFoo")[0]
+	if comments := tracker.Comments(pr); len(comments) != 1 || !strings.HasPrefix(comments[0], fmt.Sprintf("This pull request may have introduced #%d", n)) {
+		t.Errorf("expected the pull request to be told once, got %q", comments)
+	}
+	linked := 0
+	for _, c := range tracker.Comments(n) {
+		if strings.HasPrefix(c, fmt.Sprintf("This may have been introduced by #%d.", pr)) {
+			linked++
+		}
+	}
+	if linked != 1 {
+		t.Errorf("expected the issue to link the culprit once, got %q", tracker.Comments(n))
+	}
+	if r, _ := s.Store.Get(n); len(r.Culprits) != 1 || r.Culprits[0] != pr {
+		t.Errorf("expected the culprit recorded, got %v", r.Culprits)
+	}
+}
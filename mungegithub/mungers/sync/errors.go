@@ -0,0 +1,111 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	githubapi "github.com/google/go-github/github"
+)
+
+// The kinds of errors syncing can fail with. Errors carry more detail than
+// these; use ErrorKind to tell which kind an error is.
+var (
+	// ErrNotFound: an issue, comment or label we needed doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited: github told us to slow down.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrValidation: the source can't be synced as it is, or github
+	// rejected what we sent.
+	ErrValidation = errors.New("invalid")
+	// ErrTrackerUnavailable: github failed or couldn't be reached.
+	ErrTrackerUnavailable = errors.New("tracker unavailable")
+)
+
+// ValidationError is returned for a source which can't be synced as it is,
+// e.g. one without an ID. Its kind is ErrValidation.
+type ValidationError struct {
+	// Source is the source's ID, if it has one.
+	Source string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Source == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("source %v: %v", e.Source, e.Reason)
+}
+
+// StageError is returned when one of IssueSyncer.Stages failed.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %v failed: %v", e.Stage, e.Err)
+}
+
+// ErrorKind returns which of ErrNotFound, ErrRateLimited, ErrValidation and
+// ErrTrackerUnavailable err is, or nil if it is none of them.
+func ErrorKind(err error) error {
+	switch e := err.(type) {
+	case *APIError:
+		if e.Kind != nil {
+			return e.Kind
+		}
+		return kindOf(e.Err)
+	case *StageError:
+		return ErrorKind(e.Err)
+	case *ValidationError:
+		return ErrValidation
+	}
+	switch err {
+	case ErrNotFound, ErrRateLimited, ErrValidation, ErrTrackerUnavailable:
+		return err
+	}
+	return nil
+}
+
+// kindOf returns the kind of an error returned by a github call.
+func kindOf(err error) error {
+	if _, ok := err.(*url.Error); ok {
+		return ErrTrackerUnavailable
+	}
+	errResp, ok := err.(*githubapi.ErrorResponse)
+	if !ok || errResp.Response == nil {
+		return ErrorKind(err)
+	}
+	resp := errResp.Response
+	switch {
+	case resp.StatusCode >= 500:
+		return ErrTrackerUnavailable
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		return ErrRateLimited
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrNotFound
+	case resp.StatusCode == http.StatusUnprocessableEntity:
+		return ErrValidation
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "not found",
+			err:      &APIError{Op: "getting issue 1", Err: errorResponse(http.StatusNotFound, nil)},
+			expected: ErrNotFound,
+		},
+		{
+			name:     "rate limited",
+			err:      &APIError{Op: "creating issue", Err: errorResponse(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0"}), Retryable: true},
+			expected: ErrRateLimited,
+		},
+		{
+			name:     "search rate limited",
+			err:      &APIError{Op: "searching", Err: errors.New("search rate limit exceeded"), Retryable: true, Kind: ErrRateLimited},
+			expected: ErrRateLimited,
+		},
+		{
+			name:     "server error",
+			err:      &APIError{Op: "creating issue", Err: errorResponse(http.StatusBadGateway, nil), Retryable: true},
+			expected: ErrTrackerUnavailable,
+		},
+		{
+			name:     "network",
+			err:      &APIError{Op: "creating issue", Err: &url.Error{Op: "Post", URL: "https://api.github.com", Err: errors.New("connection reset")}},
+			expected: ErrTrackerUnavailable,
+		},
+		{
+			name:     "rejected",
+			err:      &APIError{Op: "creating issue", Err: errorResponse(http.StatusUnprocessableEntity, nil)},
+			expected: ErrValidation,
+		},
+		{
+			name:     "invalid source",
+			err:      (&JSONSource{Key: "TestFoo"}).Validate(),
+			expected: ErrValidation,
+		},
+		{
+			name:     "failed stage",
+			err:      &StageError{Stage: "enrich", Err: &APIError{Op: "getting issue 1", Err: errorResponse(http.StatusNotFound, nil)}},
+			expected: ErrNotFound,
+		},
+		{
+			name: "canceled",
+			err:  &APIError{Op: "creating issue", Err: context.Canceled, Retryable: true},
+		},
+		{
+			name: "other",
+			err:  fmt.Errorf("unknown decision %q", "foo"),
+		},
+	}
+	for _, test := range tests {
+		if got := ErrorKind(test.err); got != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestSyncAllResults(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.Backoff = Backoff{Steps: 1}
+
+	results, err := s.SyncAll(context.Background(), []IssueSource{
+		&JSONSource{Key: "TestFoo", Ref: "foo-1"},
+		&testSource{title: "CVE in foo", id: "http://report/1", sensitive: true},
+	})
+	if err == nil {
+		t.Errorf("expected the sensitive source to fail")
+	}
+	foo := tracker.OpenIssues("TestFoo")[0]
+	byID := map[string]SyncResult{}
+	for _, r := range results {
+		byID[r.Source] = r
+	}
+	if r := byID["foo-1"]; r.Action != DecisionCreate || r.Issue != foo || r.Err != nil {
+		t.Errorf("expected foo-1 to be filed as #%d, got %+v", foo, r)
+	}
+	if r := byID[redact("http://report/1")]; ErrorKind(r.Err) != ErrValidation {
+		t.Errorf("expected the sensitive source to be invalid without routing, got %+v", r)
+	}
+
+	tracker.FailNext("POST", "/repos/o/r/issues", http.StatusServiceUnavailable, 1)
+	results, err = s.SyncAll(context.Background(), []IssueSource{
+		&JSONSource{Key: "TestFoo", Ref: "foo-2"},
+		&JSONSource{Key: "TestBar", Ref: "bar-1"},
+	})
+	if err == nil {
+		t.Errorf("expected filing bar-1 to fail")
+	}
+	byID = map[string]SyncResult{}
+	for _, r := range results {
+		byID[r.Source] = r
+	}
+	if r := byID["foo-2"]; r.Action != DecisionUpdate || r.Issue != foo || r.Err != nil {
+		t.Errorf("expected foo-2 to be recorded on #%d, got %+v", foo, r)
+	}
+	if r := byID["bar-1"]; r.Action != DecisionCreate || r.Issue != 0 || ErrorKind(r.Err) != ErrTrackerUnavailable {
+		t.Errorf("expected filing bar-1 to fail with github unavailable, got %+v", r)
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// EscalationStep is one step of an escalation schedule.
+type EscalationStep struct {
+	// Days is how many business days (see Calendar) after it was filed
+	// an untriaged issue reaches this step.
+	Days int `json:"days"`
+	// PingOwners mentions the owners of the issue's team labels, see
+	// EscalationPolicy.Owners.
+	PingOwners bool `json:"pingOwners"`
+	// Ping is mentioned as well, e.g. "@kubernetes/test-infra-maintainers".
+	Ping string `json:"ping"`
+	// Priority, if set, replaces the issue's priority label.
+	Priority string `json:"priority"`
+}
+
+// EscalationPolicy decides what happens to issues we filed which nobody
+// triages. For example:
+//
+//	triageLabel: triaged
+//	owners:
+//	  sig/node: "@kubernetes/sig-node"
+//	schedules:
+//	  kind/flake:
+//	  - days: 3
+//	    pingOwners: true
+//	  - days: 7
+//	    priority: priority/P1
+//	    ping: "@kubernetes/test-infra-maintainers"
+type EscalationPolicy struct {
+	// TriageLabel marks an issue as triaged. So do an assignee and a
+	// comment starting with "/triage".
+	TriageLabel string `json:"triageLabel"`
+	// Owners maps team labels to who should be pinged for them.
+	Owners map[string]string `json:"owners"`
+	// Schedules maps labels to escalation steps, in order. An issue follows
+	// the schedule of the first of its labels which has one, or of the
+	// priority it inherited, see Inheritance.
+	Schedules map[string][]EscalationStep `json:"schedules"`
+	// Inheritance, if set, raises the priority of issues which release
+	// tracking issues reference before escalating.
+	Inheritance *PriorityInheritance `json:"inheritance"`
+}
+
+// LoadEscalationPolicy reads a policy from a yaml (or json) file.
+func LoadEscalationPolicy(path string) (*EscalationPolicy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	p := &EscalationPolicy{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(p); err != nil {
+		return nil, fmt.Errorf("error parsing escalation policy %v: %v", path, err)
+	}
+	if p.Inheritance != nil && p.Inheritance.Priority == "" {
+		return nil, fmt.Errorf("priority inheritance in %v has no priority", path)
+	}
+	return p, nil
+}
+
+func (p *EscalationPolicy) schedule(labels []string) []EscalationStep {
+	for _, l := range labels {
+		if steps, ok := p.Schedules[l]; ok {
+			return steps
+		}
+	}
+	return nil
+}
+
+// Escalate goes through every open issue in the store which hasn't been
+// triaged, and takes the escalation steps it has become due for.
+func (s *IssueSyncer) Escalate(ctx context.Context, policy *EscalationPolicy) error {
+	if policy.Inheritance != nil {
+		if err := s.InheritPriorities(ctx, policy.Inheritance); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.Store.List() {
+		if r.Closed || r.Triaged || r.Created.IsZero() {
+			continue
+		}
+		labels := r.Labels
+		if r.InheritedPriority != "" {
+			labels = append([]string{r.InheritedPriority}, labels...)
+		}
+		steps := policy.schedule(labels)
+		if r.Escalations >= len(steps) {
+			continue
+		}
+		if err := s.escalate(ctx, policy, r, steps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *IssueSyncer) escalate(ctx context.Context, policy *EscalationPolicy, r IssueRecord, steps []EscalationStep) error {
+	obj, err := s.getObject(ctx, r.Number)
+	if err != nil {
+		return err
+	}
+	if obj.Issue.State != nil && *obj.Issue.State == "closed" {
+		return s.noticeClosed(ctx, r.Number)
+	}
+	triaged, err := s.isTriaged(ctx, obj, policy)
+	if err != nil {
+		return err
+	}
+	if triaged {
+		s.logger().With("issue", r.Number).Debugf("Triaged, no need to escalate")
+		return s.Store.Update(r.Number, func(r *IssueRecord) { r.Triaged = true })
+	}
+
+	days := s.Calendar.BusinessDays(r.Created, s.now())
+	for i := r.Escalations; i < len(steps) && steps[i].Days <= days; i++ {
+		if err := s.escalationStep(ctx, obj, policy, steps[i], days); err != nil {
+			return err
+		}
+		if err := s.Store.Update(r.Number, func(r *IssueRecord) {
+			r.Escalations = i + 1
+			r.LastEscalation = s.now()
+		}); err != nil {
+			return err
+		}
+		if s.Board != nil {
+			s.moveCard(ctx, r.Number, s.Board.EscalatedColumn)
+		}
+	}
+	return nil
+}
+
+func (s *IssueSyncer) isTriaged(ctx context.Context, obj *github.MungeObject, policy *EscalationPolicy) (bool, error) {
+	if obj.Issue.Assignee != nil {
+		return true, nil
+	}
+	if policy.TriageLabel != "" && obj.HasLabel(policy.TriageLabel) {
+		return true, nil
+	}
+	comments, err := s.commentBodies(ctx, obj)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range comments {
+		if strings.HasPrefix(strings.TrimSpace(c), "/triage") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *IssueSyncer) escalationStep(ctx context.Context, obj *github.MungeObject, policy *EscalationPolicy, step EscalationStep, days int) error {
+	n := *obj.Issue.Number
+	mentions := []string{}
+	if step.PingOwners {
+		for _, l := range obj.Issue.Labels {
+			if l.Name == nil {
+				continue
+			}
+			if owner, ok := policy.Owners[*l.Name]; ok {
+				mentions = append(mentions, owner)
+			}
+		}
+	}
+	if step.Ping != "" {
+		mentions = append(mentions, step.Ping)
+	}
+
+	if step.Priority != "" && !obj.HasLabel(step.Priority) {
+		for _, l := range github.GetLabelsWithPrefix(obj.Issue.Labels, "priority/") {
+			if err := s.removeLabel(ctx, obj, l); err != nil {
+				return err
+			}
+		}
+		if err := s.addLabel(ctx, obj, step.Priority); err != nil {
+			return err
+		}
+	}
+
+	if len(mentions) == 0 && step.Priority == "" {
+		return nil
+	}
+	msg := s.escalationText(n, days, mentions, step.Priority)
+	s.logger().With("issue", n).Infof("Escalating: %v", msg)
+	return s.writeComment(ctx, fmt.Sprintf("escalating %v", n), obj, s.text(msg))
+}
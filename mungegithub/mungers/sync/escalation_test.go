@@ -0,0 +1,160 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func TestEscalate(t *testing.T) {
+	policy := &EscalationPolicy{
+		TriageLabel: "triaged",
+		Owners:      map[string]string{"sig/node": "@kubernetes/sig-node"},
+		Schedules: map[string][]EscalationStep{
+			"kind/flake": {
+				{Days: 3, PingOwners: true},
+				{Days: 7, Priority: "priority/P1", Ping: "@oncall"},
+				{Days: 20, Ping: "@everyone"},
+			},
+		},
+	}
+	tests := []struct {
+		name        string
+		labels      []string
+		comments    []string
+		created     string
+		escalations int
+		triaged     bool
+		expected    []string
+		addLabel    bool
+	}{
+		{
+			name:     "too new",
+			labels:   []string{"kind/flake", "sig/node"},
+			created:  "2016-07-04 12:00",
+			expected: []string{},
+		},
+		{
+			name:        "first step",
+			labels:      []string{"kind/flake", "sig/node"},
+			created:     "2016-06-30 12:00",
+			expected:    []string{"@kubernetes/sig-node This issue has not been triaged in 3 business days, can you please take a look?"},
+			escalations: 1,
+		},
+		{
+			name:    "two steps",
+			labels:  []string{"kind/flake", "sig/node", "priority/P3"},
+			created: "2016-06-24 12:00",
+			expected: []string{
+				"@kubernetes/sig-node This issue has not been triaged in 7 business days, can you please take a look?",
+				"@oncall This issue has not been triaged in 7 business days, can you please take a look? Raising the priority to `priority/P1`.",
+			},
+			escalations: 2,
+			addLabel:    true,
+		},
+		{
+			name:     "triage label",
+			labels:   []string{"kind/flake", "triaged"},
+			created:  "2016-06-24 12:00",
+			expected: []string{},
+			triaged:  true,
+		},
+		{
+			name:     "triage comment",
+			labels:   []string{"kind/flake"},
+			comments: []string{"/triage this is fine"},
+			created:  "2016-06-24 12:00",
+			expected: []string{},
+			triaged:  true,
+		},
+		{
+			name:     "no schedule",
+			labels:   []string{"kind/bug"},
+			created:  "2016-06-24 12:00",
+			expected: []string{},
+		},
+	}
+	for _, test := range tests {
+		client, server, mux := github_test.InitServer(t, github_test.Issue("bot", 1, test.labels, false), nil, nil, nil, nil, nil)
+		config := &github.Config{Org: "o", Project: "r"}
+		config.SetClient(client)
+
+		written := []string{}
+		mux.HandleFunc("/repos/o/r/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				body, _ := ioutil.ReadAll(r.Body)
+				c := struct{ Body string }{}
+				json.Unmarshal(body, &c)
+				written = append(written, c.Body)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("{}"))
+				return
+			}
+			comments := []interface{}{}
+			for i, c := range test.comments {
+				comments = append(comments, github_test.Comment(i+1, "alice", date("2016-07-01 12:00"), c))
+			}
+			data, _ := json.Marshal(comments)
+			w.Write(data)
+		})
+		addedLabel := false
+		mux.HandleFunc("/repos/o/r/issues/1/labels", func(w http.ResponseWriter, r *http.Request) {
+			addedLabel = true
+			w.Write([]byte("[]"))
+		})
+		mux.HandleFunc("/repos/o/r/issues/1/labels/", func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasSuffix(r.URL.Path, "priority/P3") {
+				t.Errorf("%v: unexpected label removal %v", test.name, r.URL.Path)
+			}
+		})
+
+		s := NewIssueSyncer(config, nil)
+		s.Calendar = NewCalendar()
+		s.now = func() time.Time { return date("2016-07-05 13:00") }
+		s.Store.Update(1, func(r *IssueRecord) {
+			r.Labels = test.labels
+			r.Created = date(test.created)
+		})
+		if err := s.Escalate(context.Background(), policy); err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		}
+		server.Close()
+
+		for i := range written {
+			written[i] = strings.TrimSpace(written[i])
+		}
+		if strings.Join(written, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%v: expected comments %q, got %q", test.name, test.expected, written)
+		}
+		if addedLabel != test.addLabel {
+			t.Errorf("%v: expected label added: %v, got %v", test.name, test.addLabel, addedLabel)
+		}
+		r, _ := s.Store.Get(1)
+		if r.Escalations != test.escalations || r.Triaged != test.triaged {
+			t.Errorf("%v: unexpected record %#v", test.name, r)
+		}
+	}
+}
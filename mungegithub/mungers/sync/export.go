@@ -0,0 +1,439 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// History record kinds.
+const (
+	// HistoryAction is a mutation the syncer made, see AuditEntry.
+	HistoryAction = "action"
+	// HistoryOccurrence is a source synced to an issue.
+	HistoryOccurrence = "occurrence"
+)
+
+// HistoryRecord is one row of sync history, for analyses (flake rates, time
+// to triage, how much the bot does) outside of github.
+type HistoryRecord struct {
+	Time time.Time
+	Kind string
+	// Repo is the "org/project" of Issue.
+	Repo   string
+	Cycle  string
+	Source string
+	Issue  int
+	// Action and Detail are those of the AuditEntry, for HistoryAction.
+	Action string
+	Detail string
+	// Occurrences is how many sources were synced to the issue so far, and
+	// Labels its labels, for HistoryOccurrence.
+	Occurrences int
+	Labels      []string
+}
+
+// historyColumns are the columns of exported history, in order.
+var historyColumns = []string{"time", "kind", "repo", "cycle", "source", "issue", "action", "detail", "occurrences", "labels"}
+
+// row returns the record's values, in historyColumns order.
+func (r HistoryRecord) row() []string {
+	return []string{
+		r.Time.UTC().Format(time.RFC3339),
+		r.Kind,
+		r.Repo,
+		r.Cycle,
+		r.Source,
+		strconv.Itoa(r.Issue),
+		r.Action,
+		r.Detail,
+		strconv.Itoa(r.Occurrences),
+		strings.Join(r.Labels, ","),
+	}
+}
+
+// HistoryExporter is where the syncer streams its history to, see
+// IssueSyncer.History.
+type HistoryExporter interface {
+	Export(r HistoryRecord) error
+	// Flush writes out what is buffered, see IssueSyncer.Stop.
+	Flush() error
+	// Close flushes and releases the exporter.
+	Close() error
+}
+
+// export hands a record to the history exporter, if the syncer has one.
+func (s *IssueSyncer) export(r HistoryRecord) {
+	if s.History == nil {
+		return
+	}
+	r.Repo = fmt.Sprintf("%v/%v", s.config.Org, s.config.Project)
+	r.Cycle, r.Source = s.cycle, s.source
+	if err := s.History.Export(r); err != nil {
+		metrics.Add("historyExportErrors", 1)
+		s.logger().With("issue", r.Issue).Errorf("Unable to export %v: %v", r.Kind, err)
+		return
+	}
+	metrics.Add("historyExported", 1)
+}
+
+// CSVExporter writes history to CSV files in a directory, one a day
+// (history-20160701.csv). A file which reaches MaxBytes is continued in the
+// next part (history-20160701.1.csv).
+type CSVExporter struct {
+	dir string
+	// MaxBytes, if positive, is how large a file may get.
+	MaxBytes int64
+
+	lock   sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+	day    string
+	part   int
+	size   int64
+}
+
+// NewCSVExporter writes history to files in `dir`, creating it if needed.
+func NewCSVExporter(dir string) (*CSVExporter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &CSVExporter{dir: dir}, nil
+}
+
+// Export implements HistoryExporter. Records are written right away; the day
+// of their Time picks the file.
+func (c *CSVExporter) Export(r HistoryRecord) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	day := r.Time.UTC().Format("20060102")
+	if c.file == nil || day != c.day || (c.MaxBytes > 0 && c.size >= c.MaxBytes) {
+		if err := c.rotate(day); err != nil {
+			return err
+		}
+	}
+	if err := c.writer.Write(r.row()); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	if err := c.writer.Error(); err != nil {
+		return err
+	}
+	info, err := c.file.Stat()
+	if err != nil {
+		return err
+	}
+	c.size = info.Size()
+	return nil
+}
+
+// rotate opens the file to write `day`'s records to: the first part of the
+// day which isn't full yet. Must hold the lock.
+func (c *CSVExporter) rotate(day string) error {
+	if c.file != nil {
+		if err := c.file.Close(); err != nil {
+			return err
+		}
+		c.file = nil
+	}
+	part := 0
+	if day == c.day {
+		part = c.part + 1
+	}
+	for ; ; part++ {
+		name := filepath.Join(c.dir, fmt.Sprintf("history-%v.csv", day))
+		if part > 0 {
+			name = filepath.Join(c.dir, fmt.Sprintf("history-%v.%d.csv", day, part))
+		}
+		info, statErr := os.Stat(name)
+		if statErr == nil && c.MaxBytes > 0 && info.Size() >= c.MaxBytes {
+			continue
+		} else if statErr != nil && !os.IsNotExist(statErr) {
+			return statErr
+		}
+		file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		c.file, c.writer, c.day, c.part, c.size = file, csv.NewWriter(file), day, part, 0
+		if statErr != nil {
+			// A new file, give it a header.
+			if err := c.writer.Write(historyColumns); err != nil {
+				return err
+			}
+			c.writer.Flush()
+			return c.writer.Error()
+		}
+		c.size = info.Size()
+		return nil
+	}
+}
+
+// Flush implements HistoryExporter: records are written right away, this
+// commits them to disk.
+func (c *CSVExporter) Flush() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	return c.file.Sync()
+}
+
+// Close implements HistoryExporter.
+func (c *CSVExporter) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+const (
+	bigQueryAPI = "https://www.googleapis.com/bigquery/v2/"
+	// bigQueryTimeout bounds every request, so that a hung endpoint only
+	// delays the history.
+	bigQueryTimeout = 30 * time.Second
+)
+
+// BigQueryExporter streams history into a BigQuery table, using the REST
+// API. The table needs a column of the same name for every field of
+// HistoryRecord: Time a TIMESTAMP, Issue and Occurrences INTEGERs, Labels a
+// REPEATED STRING and the others STRINGs.
+//
+// Records are buffered and sent in the background, so that syncing never
+// waits for BigQuery.
+type BigQueryExporter struct {
+	// table is like "projects/my-project/datasets/flakes/tables/history".
+	table  string
+	client *http.Client
+	// api is the BigQuery API endpoint, replaced in tests.
+	api string
+	// BatchSize is how many records are buffered before they are sent.
+	BatchSize int
+	// MaxPending, if positive, is how many records are kept while BigQuery
+	// can't be reached. Beyond that the oldest are dropped.
+	MaxPending int
+	// Logger is where failures to send records are logged.
+	Logger Logger
+
+	lock    sync.Mutex
+	pending []HistoryRecord
+	// sending is held while records are sent, to keep them in order.
+	sending sync.Mutex
+	// kick has the background loop send the pending records, until done
+	// is closed.
+	kick chan struct{}
+	done chan struct{}
+}
+
+// NewBigQueryExporter constructs a BigQueryExporter for `table`, given as
+// "project:dataset.table". A nil client authenticates as the service account
+// of the GCE instance we run on.
+func NewBigQueryExporter(table string, client *http.Client) (*BigQueryExporter, error) {
+	parts := strings.SplitN(table, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("BigQuery table must be project:dataset.table, not %q", table)
+	}
+	names := strings.Split(parts[1], ".")
+	if parts[0] == "" || len(names) != 2 || names[0] == "" || names[1] == "" {
+		return nil, fmt.Errorf("BigQuery table must be project:dataset.table, not %q", table)
+	}
+	if client == nil {
+		client = &http.Client{
+			Transport: &oauth2.Transport{Source: oauth2.ReuseTokenSource(nil, metadataTokenSource{})},
+			Timeout:   bigQueryTimeout,
+		}
+	}
+	b := &BigQueryExporter{
+		table:      fmt.Sprintf("projects/%v/datasets/%v/tables/%v", parts[0], names[0], names[1]),
+		client:     client,
+		api:        bigQueryAPI,
+		BatchSize:  100,
+		MaxPending: 10000,
+		Logger:     &textLogger{},
+		kick:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	go b.loop()
+	return b, nil
+}
+
+// Export implements HistoryExporter. Once BatchSize records are buffered,
+// they are sent in the background; if that fails they stay buffered for
+// the next try.
+func (b *BigQueryExporter) Export(r HistoryRecord) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.pending = append(b.pending, r)
+	b.trim()
+	if len(b.pending) >= b.BatchSize {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+			// Already kicked.
+		}
+	}
+	return nil
+}
+
+// trim drops the oldest records beyond MaxPending. Must hold the lock.
+func (b *BigQueryExporter) trim() {
+	if b.MaxPending <= 0 || len(b.pending) <= b.MaxPending {
+		return
+	}
+	dropped := len(b.pending) - b.MaxPending
+	metrics.Add("historyDropped", int64(dropped))
+	b.pending = append([]HistoryRecord{}, b.pending[dropped:]...)
+}
+
+func (b *BigQueryExporter) loop() {
+	for {
+		select {
+		case <-b.kick:
+			if err := b.send(); err != nil {
+				metrics.Add("historyExportErrors", 1)
+				b.Logger.Errorf("Unable to export the history: %v", err)
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// Flush implements HistoryExporter, sending the buffered records right away.
+func (b *BigQueryExporter) Flush() error {
+	return b.send()
+}
+
+// Close implements HistoryExporter.
+func (b *BigQueryExporter) Close() error {
+	close(b.done)
+	return b.send()
+}
+
+// send sends the buffered records. Those which BigQuery couldn't be asked
+// to insert are buffered again.
+func (b *BigQueryExporter) send() error {
+	b.sending.Lock()
+	defer b.sending.Unlock()
+	b.lock.Lock()
+	batch := b.pending
+	b.pending = nil
+	b.lock.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+	retry, err := b.insert(batch)
+	if err != nil && retry {
+		b.lock.Lock()
+		b.pending = append(batch, b.pending...)
+		b.trim()
+		b.lock.Unlock()
+	}
+	return err
+}
+
+type bigQueryRow struct {
+	// InsertID lets BigQuery drop rows which were sent twice.
+	InsertID string                 `json:"insertId"`
+	JSON     map[string]interface{} `json:"json"`
+}
+
+// insert sends `batch` to BigQuery. If it fails, retry says whether the
+// batch is worth sending again.
+func (b *BigQueryExporter) insert(batch []HistoryRecord) (retry bool, err error) {
+	rows := []bigQueryRow{}
+	for _, r := range batch {
+		labels := r.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		row := bigQueryRow{
+			JSON: map[string]interface{}{
+				"Time":        r.Time.UTC().Format(time.RFC3339Nano),
+				"Kind":        r.Kind,
+				"Repo":        r.Repo,
+				"Cycle":       r.Cycle,
+				"Source":      r.Source,
+				"Issue":       r.Issue,
+				"Action":      r.Action,
+				"Detail":      r.Detail,
+				"Occurrences": r.Occurrences,
+				"Labels":      labels,
+			},
+		}
+		data, err := json.Marshal(row.JSON)
+		if err != nil {
+			return false, err
+		}
+		row.InsertID = fmt.Sprintf("%x", sha256.Sum256(data))
+		rows = append(rows, row)
+	}
+	data, err := json.Marshal(map[string]interface{}{"rows": rows})
+	if err != nil {
+		return false, err
+	}
+	resp, err := b.client.Post(b.api+b.table+"/insertAll", "application/json", bytes.NewReader(data))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("bigquery insertAll on %v failed: %v: %s", b.table, resp.Status, body)
+	}
+	inserted := struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+	}{}
+	if err := json.Unmarshal(body, &inserted); err != nil {
+		return false, err
+	}
+	if len(inserted.InsertErrors) > 0 {
+		// The other rows of the batch may not have been inserted
+		// either, but sending them again would only fail the same way.
+		e := inserted.InsertErrors[0]
+		return false, fmt.Errorf("bigquery rejected %d rows on %v, e.g. row %d: %v", len(inserted.InsertErrors), b.table, e.Index, e.Errors)
+	}
+	return false, nil
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+type recordingExporter struct {
+	records []HistoryRecord
+	flushes int
+}
+
+func (r *recordingExporter) Export(h HistoryRecord) error {
+	r.records = append(r.records, h)
+	return nil
+}
+
+func (r *recordingExporter) Flush() error {
+	r.flushes++
+	return nil
+}
+
+func (r *recordingExporter) Close() error { return nil }
+
+func TestSyncHistory(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	history := &recordingExporter{}
+	s, err := New(WithRepo(tracker.Config()), WithFinder(finder), WithHistory(history))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, ref := range []string{"foo-1", "foo-2"} {
+		if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	kinds := map[string]int{}
+	for _, r := range history.records {
+		if r.Repo != "o/r" || r.Issue == 0 {
+			t.Errorf("unexpected record %+v", r)
+		}
+		kinds[r.Kind+"/"+r.Action]++
+	}
+	expected := map[string]int{
+		HistoryAction + "/" + AuditCreate:  1,
+		HistoryAction + "/" + AuditComment: 1,
+		HistoryOccurrence + "/":            2,
+	}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected %v, got %v", expected, kinds)
+	}
+	if last := history.records[len(history.records)-1]; last.Kind != HistoryOccurrence || last.Occurrences != 2 {
+		t.Errorf("expected the second occurrence last, got %+v", last)
+	}
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rows
+}
+
+func TestCSVExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	records := []HistoryRecord{
+		{Time: date("2016-07-01 12:00"), Kind: HistoryAction, Repo: "o/r", Issue: 1, Action: AuditCreate, Detail: "TestFoo"},
+		{Time: date("2016-07-01 12:00"), Kind: HistoryOccurrence, Repo: "o/r", Issue: 1, Occurrences: 1, Labels: []string{"kind/flake", "sig/node"}},
+		{Time: date("2016-07-01 13:00"), Kind: HistoryAction, Repo: "o/r", Issue: 1, Action: AuditComment, Detail: "multi\nline, \"quoted\""},
+		{Time: date("2016-07-02 09:00"), Kind: HistoryAction, Repo: "o/r", Issue: 1, Action: AuditClose},
+	}
+	c, err := NewCSVExporter(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Small enough for every file to take two records.
+	c.MaxBytes = 150
+	for _, r := range records[:3] {
+		if err := c.Export(r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	c.Close()
+	// Reopening carries on in the last file which isn't full.
+	if c, err = NewCSVExporter(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.MaxBytes = 150
+	if err := c.Export(records[3]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Close()
+
+	expected := map[string][]HistoryRecord{
+		"history-20160701.csv":   records[:2],
+		"history-20160701.1.csv": records[2:3],
+		"history-20160702.csv":   records[3:],
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != len(expected) {
+		t.Errorf("expected %d files, got %v", len(expected), files)
+	}
+	for name, records := range expected {
+		rows := readCSV(t, filepath.Join(dir, name))
+		want := [][]string{historyColumns}
+		for _, r := range records {
+			want = append(want, r.row())
+		}
+		if !reflect.DeepEqual(rows, want) {
+			t.Errorf("%v: expected %q, got %q", name, want, rows)
+		}
+	}
+}
+
+func TestBigQueryExporter(t *testing.T) {
+	lock := sync.Mutex{}
+	requests := []map[string][]bigQueryRow{}
+	paths := []string{}
+	// replies are the answers to the requests, in order; a status, or
+	// the insert errors.
+	replies := []interface{}{http.StatusOK, http.StatusInternalServerError, http.StatusOK, `[{"index": 0, "errors": [{"message": "no such field"}]}]`}
+	received := make(chan struct{}, len(replies))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		defer func() { received <- struct{}{} }()
+		paths = append(paths, r.URL.Path)
+		in := map[string][]bigQueryRow{}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		requests = append(requests, in)
+		switch reply := replies[len(requests)-1].(type) {
+		case int:
+			w.WriteHeader(reply)
+			w.Write([]byte(`{"kind": "bigquery#tableDataInsertAllResponse"}`))
+		case string:
+			w.Write([]byte(`{"insertErrors": ` + reply + `}`))
+		}
+	}))
+	defer server.Close()
+
+	if _, err := NewBigQueryExporter("project.dataset.table", http.DefaultClient); err == nil {
+		t.Errorf("expected an error for a table without a project")
+	}
+	b, err := NewBigQueryExporter("p:flakes.history", http.DefaultClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b.api = server.URL + "/"
+	b.BatchSize = 2
+	b.MaxPending = 3
+	export := func(occurrences ...int) {
+		for _, o := range occurrences {
+			r := HistoryRecord{Time: date("2016-07-01 12:00"), Kind: HistoryOccurrence, Repo: "o/r", Issue: 1, Occurrences: o}
+			if err := b.Export(r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+	sent := func(i int) []int {
+		lock.Lock()
+		defer lock.Unlock()
+		occurrences := []int{}
+		for _, row := range requests[i]["rows"] {
+			occurrences = append(occurrences, int(row.JSON["Occurrences"].(float64)))
+		}
+		return occurrences
+	}
+
+	// A full batch is sent in the background.
+	export(1, 2)
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected the batch to be sent")
+	}
+	if got := sent(0); !reflect.DeepEqual(got, []int{1, 2}) || paths[0] != "/projects/p/datasets/flakes/tables/history/insertAll" {
+		t.Errorf("expected a batch of 2 rows, got %v to %v", got, paths)
+	}
+	lock.Lock()
+	row := requests[0]["rows"][1]
+	if row.JSON["Time"] != "2016-07-01T12:00:00Z" || row.InsertID == requests[0]["rows"][0].InsertID {
+		t.Errorf("unexpected row %+v", row)
+	}
+	lock.Unlock()
+
+	// Records which couldn't be sent are kept, but only the newest
+	// MaxPending of them.
+	b.BatchSize = 10
+	export(3)
+	if err := b.Flush(); err == nil {
+		t.Errorf("expected the server error to fail the flush")
+	}
+	export(4, 5, 6)
+	if err := b.Flush(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := sent(2); !reflect.DeepEqual(got, []int{4, 5, 6}) {
+		t.Errorf("expected the oldest record to be dropped, got %v", got)
+	}
+
+	// Rejected records aren't.
+	export(7)
+	if err := b.Close(); err == nil {
+		t.Errorf("expected the rejected row to fail the flush")
+	}
+	if len(b.pending) != 0 {
+		t.Errorf("expected the rejected batch to be dropped, got %v", b.pending)
+	}
+}
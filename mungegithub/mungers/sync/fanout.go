@@ -0,0 +1,192 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// The tracking issue of a fanned out source starts with a list of its child
+// issues between these markers, see IssueSyncer.OwnerFanOut.
+const (
+	fanOutStart = "<!-- sync-fan-out-start -->"
+	fanOutEnd   = "<!-- sync-fan-out-end -->"
+)
+
+// OwnedSource is an IssueSource which more than one team may be responsible
+// for, e.g. a test failure implicating both sig/node and sig/storage. Owners
+// are labels. By default the issue for the source gets all of them; with
+// IssueSyncer.OwnerFanOut, each owner gets an issue of its own instead.
+type OwnedSource interface {
+	IssueSource
+	Owners() []string
+}
+
+// sourceOwners returns the owner labels of `source`, without duplicates, in
+// the order the source gave them.
+func sourceOwners(source IssueSource) []string {
+	o, ok := source.(OwnedSource)
+	if !ok {
+		return nil
+	}
+	seen := sets.NewString()
+	owners := []string{}
+	for _, owner := range o.Owners() {
+		if owner = strings.TrimSpace(owner); owner != "" && !seen.Has(owner) {
+			seen.Insert(owner)
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+// fansOut returns true if `source` gets a tracking issue and an issue per
+// owner.
+func (s *IssueSyncer) fansOut(source IssueSource) bool {
+	return s.OwnerFanOut && len(sourceOwners(source)) > 1 && !isSensitive(source) && groupKey(source) == ""
+}
+
+// trackingSource is a fanned out source, synced to its tracking issue, which
+// gets none of the owner labels: the child issues have them.
+type trackingSource struct {
+	IssueSource
+}
+
+func (t *trackingSource) Owners() []string { return nil }
+
+// childSource is a fanned out source, synced to the issue of one owner. It
+// has an ID of its own, so that it is recorded on every child issue.
+type childSource struct {
+	IssueSource
+	owner string
+	// parent is the tracking issue.
+	parent int
+}
+
+func (c *childSource) Title() string {
+	return fmt.Sprintf("%v [%v]", c.IssueSource.Title(), c.owner)
+}
+
+func (c *childSource) ID() string {
+	return c.owner + " " + c.IssueSource.ID()
+}
+
+func (c *childSource) Body(newIssue bool) string {
+	body := c.IssueSource.Body(newIssue)
+	if !newIssue {
+		return body
+	}
+	return fmt.Sprintf("This is the part of #%d for %v.\n\n%v", c.parent, c.owner, body)
+}
+
+func (c *childSource) Owners() []string { return []string{c.owner} }
+
+// fanOut syncs `source` to its tracking issue, and then to the child issue
+// of every owner. The result is about the tracking issue; the first error
+// syncing a child is returned if the tracking issue synced fine.
+func (s *IssueSyncer) fanOut(ctx context.Context, log Logger, source IssueSource) SyncResult {
+	result := s.syncWith(ctx, log, &trackingSource{source})
+	if result.Err != nil {
+		return result
+	}
+	parent := result.Issue
+	if parent == 0 {
+		// Already synced, but its children may not have been.
+		parent = s.fannedOut[source.ID()]
+	}
+	if parent == 0 {
+		return result
+	}
+	if s.fannedOut == nil {
+		s.fannedOut = map[string]int{}
+	}
+	s.fannedOut[source.ID()] = parent
+	for _, owner := range sourceOwners(source) {
+		child := s.syncWith(ctx, log, &childSource{source, owner, parent})
+		if child.Err != nil {
+			if result.Err == nil {
+				result.Err = child.Err
+			}
+			continue
+		}
+		if child.Issue == 0 {
+			continue
+		}
+		if err := s.Store.Update(parent, func(r *IssueRecord) {
+			if r.Children == nil {
+				r.Children = map[string]int{}
+			}
+			r.Children[owner] = child.Issue
+		}); err != nil {
+			log.With("issue", parent).Errorf("Unable to record child issue #%d: %v", child.Issue, err)
+		}
+		if err := s.Store.Update(child.Issue, func(r *IssueRecord) { r.Parent = parent }); err != nil {
+			log.With("issue", child.Issue).Errorf("Unable to record tracking issue #%d: %v", parent, err)
+		}
+	}
+	if err := s.updateFanOut(ctx, parent); err != nil {
+		log.With("issue", parent).Errorf("Unable to update the child issues of the tracking issue: %v", err)
+	}
+	return result
+}
+
+// fanOutList lists the child issues of a tracking issue, by owner.
+func fanOutList(children map[string]int) string {
+	owners := []string{}
+	for owner := range children {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	lines := []string{
+		fanOutStart,
+		fmt.Sprintf("This issue tracks the problem for %d owners:", len(owners)),
+		"",
+	}
+	for _, owner := range owners {
+		lines = append(lines, fmt.Sprintf("- %v: #%d", owner, children[owner]))
+	}
+	lines = append(lines, fanOutEnd)
+	return strings.Join(lines, "\n")
+}
+
+// updateFanOut rewrites the list of child issues of tracking issue `n` from
+// the store.
+func (s *IssueSyncer) updateFanOut(ctx context.Context, n int) error {
+	r, ok := s.Store.Get(n)
+	if !ok || len(r.Children) == 0 {
+		return nil
+	}
+	obj, err := s.getObject(ctx, n)
+	if err != nil {
+		return err
+	}
+	old := ""
+	if obj.Issue.Body != nil {
+		old = *obj.Issue.Body
+	}
+	body := replaceSection(old, fanOutStart, fanOutEnd, fanOutList(r.Children), false)
+	if body == old {
+		return nil
+	}
+	s.logger().With("issue", n).Infof("Updating the child issues of the tracking issue, it has %d", len(r.Children))
+	return s.editBody(ctx, fmt.Sprintf("editing tracking issue %v", n), obj, old, body)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestOwnerLabels(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+
+	source := &JSONSource{Key: "TestFoo", Ref: "foo-1", Tags: []string{"kind/flake", "sig/node"}, Scopes: []string{"sig/node", "sig/storage", "sig/node"}}
+	if err := s.Sync(context.Background(), source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	issues := tracker.Issues()
+	if len(issues) != 1 {
+		t.Fatalf("expected one issue, got %d", len(issues))
+	}
+	labels := []string{}
+	for _, l := range issues[0].Labels {
+		labels = append(labels, *l.Name)
+	}
+	if expected := []string{"kind/flake", "sig/node", "sig/storage"}; !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, labels)
+	}
+}
+
+func TestOwnerFanOut(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.OwnerFanOut = true
+
+	for _, ref := range []string{"foo-1", "foo-2"} {
+		source := &JSONSource{Key: "TestFoo", Ref: ref, Tags: []string{"kind/flake"}, Scopes: []string{"sig/storage", "sig/node"}}
+		results, err := s.SyncAll(context.Background(), []IssueSource{source})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 1 || results[0].Issue == 0 {
+			t.Fatalf("expected a result about the tracking issue, got %+v", results)
+		}
+	}
+
+	parents := tracker.OpenIssues("TestFoo")
+	nodes := tracker.OpenIssues("TestFoo [sig/node]")
+	storages := tracker.OpenIssues("TestFoo [sig/storage]")
+	if len(parents) != 1 || len(nodes) != 1 || len(storages) != 1 {
+		t.Fatalf("expected a tracking issue and one per owner, got %v %v %v", parents, nodes, storages)
+	}
+	parent, node, storage := parents[0], nodes[0], storages[0]
+	for _, issue := range tracker.Issues() {
+		labels := []string{}
+		for _, l := range issue.Labels {
+			labels = append(labels, *l.Name)
+		}
+		switch *issue.Number {
+		case parent:
+			list := fanOutList(map[string]int{"sig/node": node, "sig/storage": storage})
+			if !strings.HasPrefix(*issue.Body, list+"\n\n") || strings.Count(*issue.Body, fanOutStart) != 1 {
+				t.Errorf("expected the tracking issue to list its children once:\n%v", *issue.Body)
+			}
+			if !reflect.DeepEqual(labels, []string{"kind/flake"}) {
+				t.Errorf("expected no owner labels on the tracking issue, got %v", labels)
+			}
+		case node, storage:
+			owner := "sig/node"
+			if *issue.Number == storage {
+				owner = "sig/storage"
+			}
+			if !strings.HasPrefix(*issue.Body, "This is the part of #") || !strings.Contains(*issue.Body, owner) {
+				t.Errorf("expected child issue %d to link the tracking issue:\n%v", *issue.Number, *issue.Body)
+			}
+			if !reflect.DeepEqual(labels, []string{"kind/flake", owner}) {
+				t.Errorf("expected child issue %d to be labeled for %v, got %v", *issue.Number, owner, labels)
+			}
+		}
+	}
+	for _, n := range []int{parent, node, storage} {
+		if comments := tracker.Comments(n); len(comments) != 1 || !strings.Contains(comments[0], "foo-2") {
+			t.Errorf("expected issue %d to get a comment about the second occurrence, got %q", n, comments)
+		}
+	}
+	if r, _ := s.Store.Get(node); r.Parent != parent {
+		t.Errorf("expected the child issue to record its parent %d, got %d", parent, r.Parent)
+	}
+}
+
+func TestFanOutListReplacedInPlace(t *testing.T) {
+	old := fanOutList(map[string]int{"sig/node": 2})
+	updated := fanOutList(map[string]int{"sig/node": 2, "sig/storage": 3})
+	body := replaceSection(replaceSection("Failed", fanOutStart, fanOutEnd, old, false), fanOutStart, fanOutEnd, updated, false)
+	if body != updated+"\n\nFailed" {
+		t.Errorf("expected the list to be replaced in place:\n%v", body)
+	}
+	expected := fanOutStart + "\nThis issue tracks the problem for 2 owners:\n\n- sig/node: #2\n- sig/storage: #3\n" + fanOutEnd
+	if updated != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, updated)
+	}
+}
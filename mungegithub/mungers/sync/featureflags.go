@@ -0,0 +1,187 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// SourceFlags are the switches operators have over the sources of one
+// provider of a Scheduler, see FeatureFlags.
+type SourceFlags struct {
+	// Enabled turns polling the provider off if false. Defaults to true.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Labels, if set, replace the labels of the provider's sources. Only
+	// JSON sources and plain ones can be relabeled: sensitive sources, and
+	// those with owners, a group and so on, keep their labels.
+	Labels []string `json:"labels,omitempty"`
+	// MaxSources, if set, is how many of the provider's sources are
+	// synced per poll, the most severe first. The rest wait for the next.
+	MaxSources int `json:"maxSources,omitempty"`
+	// DryRun has the provider's sources planned (see IssueSyncer.Plan)
+	// and logged instead of synced.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// IsEnabled returns true unless the provider was turned off.
+func (fl SourceFlags) IsEnabled() bool {
+	return fl.Enabled == nil || *fl.Enabled
+}
+
+// FeatureFlags are SourceFlags by provider name, read from a yaml (or
+// json) file which is read again whenever it changes, so that a
+// misbehaving source can be turned off without a redeploy, e.g.:
+//
+//	flakes:
+//	  maxSources: 50
+//	  labels: [kind/flake, priority/P2]
+//	cves:
+//	  enabled: false
+//	scans:
+//	  dryRun: true
+//
+// If the file can't be read or parsed, the last flags read are kept.
+type FeatureFlags struct {
+	path string
+
+	lock    sync.Mutex
+	modTime time.Time
+	flags   map[string]SourceFlags
+	// Logger is where reloads and failures to reload are logged.
+	Logger Logger
+}
+
+// LoadFeatureFlags reads the flags in `path`, which must be valid to start
+// with.
+func LoadFeatureFlags(path string) (*FeatureFlags, error) {
+	f := &FeatureFlags{path: path, Logger: &textLogger{}}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.load(info.ModTime()); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FeatureFlags) load(modTime time.Time) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	flags := map[string]SourceFlags{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(&flags); err != nil {
+		return fmt.Errorf("error parsing feature flags %v: %v", f.path, err)
+	}
+	for name, fl := range flags {
+		if fl.MaxSources < 0 {
+			return fmt.Errorf("negative maxSources for %v in %v", name, f.path)
+		}
+	}
+	f.flags, f.modTime = flags, modTime
+	return nil
+}
+
+// For returns the flags of provider `name`, reading the file again first
+// if it changed. A nil *FeatureFlags enables everything.
+func (f *FeatureFlags) For(name string) SourceFlags {
+	if f == nil {
+		return SourceFlags{}
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if info, err := os.Stat(f.path); err != nil {
+		f.Logger.Warningf("Unable to check feature flags %v, keeping the last ones: %v", f.path, err)
+	} else if !info.ModTime().Equal(f.modTime) {
+		if err := f.load(info.ModTime()); err != nil {
+			metrics.Add("featureFlagReloadErrors", 1)
+			f.Logger.Errorf("Unable to reload feature flags, keeping the last ones: %v", err)
+			// Don't try again until the file changes again.
+			f.modTime = info.ModTime()
+		} else {
+			metrics.Add("featureFlagReloads", 1)
+			f.Logger.Infof("Reloaded feature flags from %v", f.path)
+		}
+	}
+	return f.flags[name]
+}
+
+// Apply returns `sources` as the flags have them synced: relabeled and
+// capped. Whether they are synced at all, or only planned, is up to the
+// caller, see IsEnabled and DryRun.
+func (fl SourceFlags) Apply(sources []IssueSource) []IssueSource {
+	if fl.MaxSources > 0 && len(sources) > fl.MaxSources {
+		sources = prioritize(sources)[:fl.MaxSources]
+	}
+	if fl.Labels == nil {
+		return sources
+	}
+	out := make([]IssueSource, 0, len(sources))
+	for _, s := range sources {
+		if j, ok := s.(*JSONSource); ok {
+			// Copied rather than wrapped, so that it keeps its
+			// tenant, group, owners and so on.
+			c := *j
+			c.Tags = fl.Labels
+			out = append(out, &c)
+			continue
+		}
+		if isSensitive(s) || !plainSource(s) {
+			// A wrapper would hide what the source is, e.g. file it
+			// in public.
+			out = append(out, s)
+			continue
+		}
+		out = append(out, &relabeledSource{s, fl.Labels})
+	}
+	return out
+}
+
+// plainSource returns true if relabeledSource would hide nothing about `s`:
+// it is none of the optional kinds of source, other than a SensitiveSource
+// which isn't sensitive.
+func plainSource(s IssueSource) bool {
+	switch s.(type) {
+	case AttachmentSource, FileSource, OwnedSource, PRSource, SeveritySource,
+		TimedSource, KeyedSource, TenantSource, GroupedSource:
+		return false
+	}
+	return true
+}
+
+// relabeledSource is a source with the labels of its SourceFlags.
+type relabeledSource struct {
+	IssueSource
+	labels []string
+}
+
+func (r *relabeledSource) Labels() []string {
+	return r.labels
+}
+
+// Planner works out what syncing sources would do, like IssueSyncer.Plan.
+type Planner interface {
+	Plan(ctx context.Context, sources []IssueSource) (*SyncPlan, error)
+}
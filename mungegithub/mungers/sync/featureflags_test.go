@@ -0,0 +1,137 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// planningSyncer is a recordingSyncer which can plan, and records the IDs
+// of the sources it planned.
+type planningSyncer struct {
+	recordingSyncer
+	planned []string
+}
+
+func (p *planningSyncer) Plan(ctx context.Context, sources []IssueSource) (*SyncPlan, error) {
+	plan := &SyncPlan{}
+	for _, s := range sources {
+		p.planned = append(p.planned, s.ID())
+		plan.Actions = append(plan.Actions, PlannedAction{Title: s.Title(), ID: s.ID(), Decision: DecisionCreate})
+	}
+	return plan, nil
+}
+
+func TestFeatureFlags(t *testing.T) {
+	file, err := ioutil.TempFile("", "source-flags")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	write := func(content string, mod time.Time) {
+		if err := ioutil.WriteFile(file.Name(), []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Make sure the change is noticed, however coarse the file
+		// system's timestamps are.
+		os.Chtimes(file.Name(), mod, mod)
+	}
+	start := time.Now()
+	write(`
+flakes:
+  maxSources: 1
+cves:
+  enabled: false
+`, start)
+	flags, err := LoadFeatureFlags(file.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	syncer := &planningSyncer{}
+	s := NewScheduler(syncer)
+	s.Flags = flags
+	s.Register("flakes", 10*time.Minute, SourceProviderFunc(func(ctx context.Context) ([]IssueSource, error) {
+		return []IssueSource{
+			&JSONSource{Key: "TestFoo", Ref: "foo-1"},
+			&JSONSource{Key: "TestBar", Ref: "bar-1"},
+		}, nil
+	}))
+	s.Register("cves", 24*time.Hour, SourceProviderFunc(func(ctx context.Context) ([]IssueSource, error) {
+		return []IssueSource{&JSONSource{Key: "CVE-1", Ref: "cve-1"}}, nil
+	}))
+
+	poll := func() {
+		for _, name := range []string{"flakes", "cves"} {
+			if err := s.Poll(context.Background(), name); err != nil {
+				t.Errorf("unexpected error polling %v: %v", name, err)
+			}
+		}
+	}
+	poll()
+	if !reflect.DeepEqual(syncer.synced, []string{"foo-1"}) {
+		t.Errorf("expected only the first flake to be synced, got %v", syncer.synced)
+	}
+	if jobs := s.Jobs(); !jobs[0].Disabled || jobs[1].Disabled {
+		t.Errorf("expected only cves to be disabled, got %+v", jobs)
+	}
+
+	write(`
+cves:
+  dryRun: true
+`, start.Add(time.Minute))
+	poll()
+	if !reflect.DeepEqual(syncer.synced, []string{"foo-1", "foo-1", "bar-1"}) {
+		t.Errorf("expected all flakes to be synced once the cap is gone, got %v", syncer.synced)
+	}
+	if !reflect.DeepEqual(syncer.planned, []string{"cve-1"}) {
+		t.Errorf("expected cves to be planned instead of synced, got %v", syncer.planned)
+	}
+
+	write("cves: [", start.Add(2*time.Minute))
+	poll()
+	if !reflect.DeepEqual(syncer.planned, []string{"cve-1", "cve-1"}) {
+		t.Errorf("expected the last flags to be kept when the file is invalid, got %v", syncer.planned)
+	}
+}
+
+func TestSourceFlagsLabels(t *testing.T) {
+	flags := SourceFlags{Labels: []string{"kind/cve"}}
+	sources := flags.Apply([]IssueSource{
+		&JSONSource{Key: "CVE-1", Ref: "cve-1", Tags: []string{"kind/bug"}, Team: "security"},
+		&testSource{title: "CVE-2", id: "cve-2"},
+	})
+	for _, s := range sources {
+		if !reflect.DeepEqual(s.Labels(), []string{"kind/cve"}) {
+			t.Errorf("%v: expected the labels to be replaced, got %v", s.ID(), s.Labels())
+		}
+	}
+	if tenant := sources[0].(TenantSource).Tenant(); tenant != "security" {
+		t.Errorf("expected the tenant to be kept, got %q", tenant)
+	}
+
+	sensitive := &testSource{title: "CVE-3", id: "cve-3", sensitive: true}
+	if got := flags.Apply([]IssueSource{sensitive}); got[0] != IssueSource(sensitive) || !isSensitive(got[0]) {
+		t.Errorf("expected the sensitive source to be left as it is, got %#v", got[0])
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// DefaultFeedbackLabels are the labels whose decisions are passed on to a
+// SourceFeedback by default.
+var DefaultFeedbackLabels = []string{"kind/infra-flake", "wontfix"}
+
+// Feedback is a decision humans made about an issue we filed, by labeling
+// it: e.g. kind/infra-flake says the failures aren't the test's fault,
+// wontfix that nobody will work on them.
+type Feedback struct {
+	Issue int
+	// Title is the issue's title as we filed it, the key of the sources
+	// synced to it, and Members the titles of the members of an umbrella
+	// issue, see GroupedSource.
+	Title   string
+	Members []string
+	// Label was added, or removed if Removed is set.
+	Label   string
+	Removed bool
+}
+
+// SourceFeedback is told about the decisions humans make on the issues we
+// filed, so that the system the sources come from (e.g. a flake detector)
+// can mute or reclassify them.
+type SourceFeedback interface {
+	Feedback(ctx context.Context, f Feedback) error
+}
+
+// SourceFeedbackFunc is a function which is a SourceFeedback.
+type SourceFeedbackFunc func(ctx context.Context, f Feedback) error
+
+// Feedback implements SourceFeedback.
+func (f SourceFeedbackFunc) Feedback(ctx context.Context, feedback Feedback) error {
+	return f(ctx, feedback)
+}
+
+// feedbackLabels returns the labels whose decisions are passed on.
+func (s *IssueSyncer) feedbackLabels() []string {
+	if s.FeedbackLabels == nil {
+		return DefaultFeedbackLabels
+	}
+	return s.FeedbackLabels
+}
+
+// CollectFeedback goes through every open issue in the store and passes
+// decisions which weren't yet on to Feedback. The webhook does the same
+// before the next sync cycle for issues it hears about, see IssueChanged.
+func (s *IssueSyncer) CollectFeedback(ctx context.Context) error {
+	if s.Feedback == nil {
+		return nil
+	}
+	for _, r := range s.Store.List() {
+		if r.Closed {
+			continue
+		}
+		obj, err := s.getObject(ctx, r.Number)
+		if err != nil {
+			return err
+		}
+		if err := s.feedback(ctx, obj.Issue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// feedback passes the feedback labels `issue` gained or lost since the last
+// time on to Feedback. Labels we filed the issue with aren't decisions.
+func (s *IssueSyncer) feedback(ctx context.Context, issue *githubapi.Issue) error {
+	r, ok := s.Store.Get(*issue.Number)
+	if !ok {
+		return nil
+	}
+	watched := sets.NewString(s.feedbackLabels()...)
+	watched.Delete(r.Labels...)
+	labels := sets.NewString()
+	for _, l := range issue.Labels {
+		if l.Name != nil && watched.Has(*l.Name) {
+			labels.Insert(*l.Name)
+		}
+	}
+	told := sets.NewString(r.Feedback...)
+	members := []string{}
+	for m := range r.Members {
+		members = append(members, m)
+	}
+	f := Feedback{Issue: r.Number, Title: r.Title, Members: sets.NewString(members...).List()}
+	changes := []Feedback{}
+	for _, l := range labels.Difference(told).List() {
+		f.Label, f.Removed = l, false
+		changes = append(changes, f)
+	}
+	for _, l := range told.Difference(labels).List() {
+		f.Label, f.Removed = l, true
+		changes = append(changes, f)
+	}
+	for _, f := range changes {
+		s.logger().With("issue", r.Number).Infof("Passing on %v (removed: %v)", f.Label, f.Removed)
+		if err := s.Feedback.Feedback(ctx, f); err != nil {
+			return err
+		}
+		if f.Removed {
+			told.Delete(f.Label)
+		} else {
+			told.Insert(f.Label)
+		}
+		if err := s.Store.Update(r.Number, func(r *IssueRecord) { r.Feedback = told.List() }); err != nil {
+			return err
+		}
+		metrics.Add("feedback", 1)
+	}
+	return nil
+}
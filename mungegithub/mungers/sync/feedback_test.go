@@ -0,0 +1,128 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+// feedbackRecorder is a SourceFeedback which records what it is told.
+type feedbackRecorder []string
+
+func (r *feedbackRecorder) Feedback(ctx context.Context, f Feedback) error {
+	*r = append(*r, fmt.Sprintf("#%d %v %v %v", f.Issue, f.Title, f.Label, f.Removed))
+	return nil
+}
+
+func TestCollectFeedback(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	got := &feedbackRecorder{}
+	s.Feedback = got
+	for _, source := range []*JSONSource{
+		{Key: "TestFoo", Ref: "run-1"},
+		{Key: "TestBar", Ref: "run-2", Tags: []string{"wontfix"}},
+	} {
+		if err := s.Sync(context.Background(), source); err != nil {
+			t.Fatalf("unexpected error syncing %v: %v", source.Ref, err)
+		}
+	}
+	foo, bar := tracker.OpenIssues("TestFoo")[0], tracker.OpenIssues("TestBar")[0]
+
+	steps := []struct {
+		name     string
+		change   func()
+		expected []string
+	}{
+		{
+			name:     "no decisions",
+			change:   func() {},
+			expected: []string{},
+		},
+		{
+			name: "labeled",
+			change: func() {
+				tracker.AddLabel(foo, "kind/infra-flake")
+				tracker.AddLabel(foo, "priority/P2")
+				tracker.AddLabel(bar, "kind/infra-flake")
+			},
+			expected: []string{
+				fmt.Sprintf("#%d TestFoo kind/infra-flake false", foo),
+				fmt.Sprintf("#%d TestBar kind/infra-flake false", bar),
+			},
+		},
+		{
+			name:     "told once",
+			change:   func() {},
+			expected: []string{},
+		},
+		{
+			name: "unlabeled",
+			change: func() {
+				tracker.RemoveLabel(foo, "kind/infra-flake")
+				tracker.RemoveLabel(bar, "wontfix")
+			},
+			expected: []string{fmt.Sprintf("#%d TestFoo kind/infra-flake true", foo)},
+		},
+	}
+	for _, step := range steps {
+		*got = feedbackRecorder{}
+		step.change()
+		if err := s.CollectFeedback(context.Background()); err != nil {
+			t.Fatalf("%v: unexpected error: %v", step.name, err)
+		}
+		if !reflect.DeepEqual([]string(*got), step.expected) {
+			t.Errorf("%v: expected %q, got %q", step.name, step.expected, *got)
+		}
+	}
+}
+
+func TestWebhookFeedback(t *testing.T) {
+	index := NewIssueIndex(nil, nil, "")
+	s := NewIssueSyncer(nil, index)
+	got := &feedbackRecorder{}
+	s.Feedback = got
+	s.FeedbackLabels = []string{"triage/duplicate"}
+	s.Store.Update(5, func(r *IssueRecord) { r.Title = "TestFoo" })
+	w := NewWebhookReceiver([]byte("secret"), index)
+	w.Syncer = s
+
+	labeled := `{"action": "labeled", "issue": {"number": 5, "title": "TestFoo", "state": "open", "labels": [{"name": "triage/duplicate"}, {"name": "wontfix"}]}}`
+	req, _ := http.NewRequest("POST", "/webhook", strings.NewReader(labeled))
+	req.Header.Set("X-GitHub-Event", "issues")
+	req.Header.Set("X-Hub-Signature", sign("secret", labeled))
+	w.ServeHTTP(httptest.NewRecorder(), req)
+	s.applyChanges(context.Background())
+
+	if expected := []string{"#5 TestFoo triage/duplicate false"}; !reflect.DeepEqual([]string(*got), expected) {
+		t.Errorf("expected %q, got %q", expected, *got)
+	}
+	if r, _ := s.Store.Get(5); !reflect.DeepEqual(r.Feedback, []string{"triage/duplicate"}) {
+		t.Errorf("expected the feedback recorded, got %v", r.Feedback)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// gerritMagicPrefix precedes every JSON answer of the Gerrit REST API, to
+// keep it from being run as a script.
+const gerritMagicPrefix = ")]}'"
+
+// GerritTracker is an IssueTracker for projects which use Gerrit instead of
+// github issues: every issue is a tracking change in the project, tagged
+// with the tracker's hashtag, and comments are review messages on it. The
+// labels of an issue are more hashtags, and duplicates are abandoned.
+type GerritTracker struct {
+	// url is the Gerrit server, like "https://review.example.com".
+	url     string
+	project string
+	hashtag string
+	client  *http.Client
+
+	// Branch is the branch the tracking changes are made for.
+	Branch string
+	// Username and Password, if set, are the HTTP credentials requests
+	// are authenticated with.
+	Username string
+	Password string
+}
+
+// NewGerritTracker returns a GerritTracker filing tracking changes tagged
+// `hashtag` in `project` on the Gerrit server at `url`. A nil client is
+// http.DefaultClient.
+func NewGerritTracker(url, project, hashtag string, client *http.Client) *GerritTracker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &GerritTracker{
+		url:     strings.TrimSuffix(url, "/"),
+		project: project,
+		hashtag: hashtag,
+		client:  client,
+		Branch:  "master",
+	}
+}
+
+// call sends `in`, if it isn't nil, to `path` with `method`, decoding the
+// answer into `out` if it isn't nil.
+func (g *GerritTracker) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var data []byte
+	if in != nil {
+		var err error
+		if data, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	prefix := ""
+	if g.Username != "" {
+		prefix = "/a"
+	}
+	req, err := http.NewRequest(method, g.url+prefix+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.Username != "" {
+		req.SetBasicAuth(g.Username, g.Password)
+	}
+	req.Cancel = ctx.Done()
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &TrackerError{Op: fmt.Sprintf("gerrit %v %v", method, path), StatusCode: resp.StatusCode, Message: string(data)}
+	}
+	if out == nil {
+		return nil
+	}
+	data = bytes.TrimPrefix(data, []byte(gerritMagicPrefix))
+	return json.Unmarshal(data, out)
+}
+
+type gerritChange struct {
+	Number  int    `json:"_number"`
+	Subject string `json:"subject"`
+	Status  string `json:"status"`
+}
+
+// Find implements IssueTracker. Gerrit searches the commit messages of the
+// changes, which are just their subjects.
+func (g *GerritTracker) Find(ctx context.Context, title string) ([]TrackedIssue, error) {
+	// Quotes can't be escaped in Gerrit queries; titles are matched by
+	// key afterwards anyway.
+	q := fmt.Sprintf("project:%v hashtag:%v message:%q", g.project, g.hashtag, strings.Replace(title, `"`, " ", -1))
+	changes := []gerritChange{}
+	if err := g.call(ctx, "GET", "/changes/?q="+url.QueryEscape(q), nil, &changes); err != nil {
+		return nil, err
+	}
+	issues := []TrackedIssue{}
+	for _, c := range changes {
+		issues = append(issues, TrackedIssue{Number: c.Number, Title: c.Subject, Open: c.Status == "NEW"})
+	}
+	return issues, nil
+}
+
+// Comments implements IssueTracker.
+func (g *GerritTracker) Comments(ctx context.Context, number int) ([]string, error) {
+	messages := []struct {
+		Message string `json:"message"`
+	}{}
+	if err := g.call(ctx, "GET", fmt.Sprintf("/changes/%d/messages", number), nil, &messages); err != nil {
+		return nil, err
+	}
+	comments := []string{}
+	for _, m := range messages {
+		comments = append(comments, m.Message)
+	}
+	return comments, nil
+}
+
+// Create implements IssueTracker. The tracking change is made first, then
+// tagged, and the body is its first review message. If tagging fails, the
+// change could never be found, so it is abandoned; if the message fails,
+// the change is found and commented on the next time.
+func (g *GerritTracker) Create(ctx context.Context, title, body string, labels []string) (int, error) {
+	change := gerritChange{}
+	in := map[string]string{"project": g.project, "branch": g.Branch, "subject": title}
+	if err := g.call(ctx, "POST", "/changes/", in, &change); err != nil {
+		return 0, err
+	}
+	hashtags := append([]string{g.hashtag}, labels...)
+	if err := g.call(ctx, "POST", fmt.Sprintf("/changes/%d/hashtags", change.Number), map[string][]string{"add": hashtags}, nil); err != nil {
+		g.call(ctx, "POST", fmt.Sprintf("/changes/%d/abandon", change.Number), nil, nil)
+		return 0, err
+	}
+	if err := g.Comment(ctx, change.Number, body); err != nil {
+		return 0, err
+	}
+	return change.Number, nil
+}
+
+// Comment implements IssueTracker.
+func (g *GerritTracker) Comment(ctx context.Context, number int, body string) error {
+	in := map[string]string{"message": body, "tag": "autogenerated:sync"}
+	return g.call(ctx, "POST", fmt.Sprintf("/changes/%d/revisions/current/review", number), in, nil)
+}
+
+// CloseDuplicate implements IssueTracker by abandoning the change.
+func (g *GerritTracker) CloseDuplicate(ctx context.Context, number, of int, text string) error {
+	return g.call(ctx, "POST", fmt.Sprintf("/changes/%d/abandon", number), map[string]string{"message": text}, nil)
+}
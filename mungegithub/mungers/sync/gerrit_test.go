@@ -0,0 +1,161 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// fakeGerrit serves the parts of the Gerrit REST API a GerritTracker uses.
+type fakeGerrit struct {
+	changes  []*gerritChange
+	hashtags map[int][]string
+	messages map[int][]string
+	project  string
+	authed   bool
+}
+
+var fakeGerritPath = regexp.MustCompile(`^/a/changes/(\d+)/(hashtags|messages|revisions/current/review|abandon)$`)
+
+func (f *fakeGerrit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "bot" || pass != "secret" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	f.authed = true
+	in := map[string]interface{}{}
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var out interface{}
+	switch m := fakeGerritPath.FindStringSubmatch(r.URL.Path); {
+	case r.URL.Path == "/a/changes/" && r.Method == "GET":
+		found := []*gerritChange{}
+		q := r.URL.Query().Get("q")
+		for _, c := range f.changes {
+			tagged := false
+			for _, h := range f.hashtags[c.Number] {
+				tagged = tagged || strings.Contains(q, "hashtag:"+h+" ")
+			}
+			if tagged && strings.Contains(q, "project:"+f.project) && strings.Contains(q, fmt.Sprintf("message:%q", c.Subject)) {
+				found = append(found, c)
+			}
+		}
+		out = found
+	case r.URL.Path == "/a/changes/" && r.Method == "POST":
+		c := &gerritChange{Number: len(f.changes) + 1, Subject: in["subject"].(string), Status: "NEW"}
+		f.changes = append(f.changes, c)
+		out = c
+	case m != nil:
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "hashtags":
+			for _, h := range in["add"].([]interface{}) {
+				f.hashtags[n] = append(f.hashtags[n], h.(string))
+			}
+		case "messages":
+			messages := []map[string]string{}
+			for _, msg := range f.messages[n] {
+				messages = append(messages, map[string]string{"message": msg})
+			}
+			out = messages
+		case "revisions/current/review":
+			f.messages[n] = append(f.messages[n], "Patch Set 1:\n\n"+in["message"].(string))
+		case "abandon":
+			f.changes[n-1].Status = "ABANDONED"
+			f.messages[n] = append(f.messages[n], "Abandoned\n\n"+in["message"].(string))
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprintln(w, gerritMagicPrefix)
+	json.NewEncoder(w).Encode(out)
+}
+
+func TestGerritTracker(t *testing.T) {
+	fake := &fakeGerrit{hashtags: map[int][]string{}, messages: map[int][]string{}, project: "infra"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	tracker := NewGerritTracker(server.URL+"/", "infra", "flake", nil)
+	tracker.Username, tracker.Password = "bot", "secret"
+	s, err := NewTrackerSyncer(tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	first := &testSource{title: "TestFoo", id: "http://build/1"}
+	second := &testSource{title: "TestFoo", id: "http://build/2"}
+	results, err := s.SyncAll(ctx, []IssueSource{first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != DecisionCreate || results[0].Issue != 1 {
+		t.Fatalf("expected change 1 to be made, got %+v", results[0])
+	}
+	if e, a := "flake kind/bug", strings.Join(fake.hashtags[1], " "); e != a {
+		t.Errorf("expected hashtags %q, got %q", e, a)
+	}
+
+	// The source is found in the review messages by a new syncer.
+	s, err = NewTrackerSyncer(tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = s.SyncAll(ctx, []IssueSource{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != DecisionNone || results[1].Action != DecisionUpdate || results[1].Issue != 1 {
+		t.Errorf("expected only %v to be commented on change 1, got %+v", second.id, results)
+	}
+	if len(fake.messages[1]) != 2 || !strings.Contains(fake.messages[1][1], IDMarker(second.id)) {
+		t.Errorf("unexpected messages %q", fake.messages[1])
+	}
+
+	// A second tracking change is abandoned as a duplicate.
+	fake.changes = append(fake.changes, &gerritChange{Number: 2, Subject: "TestFoo", Status: "NEW"})
+	fake.hashtags[2] = []string{"flake"}
+	results, err = s.SyncAll(ctx, []IssueSource{&testSource{title: "TestFoo", id: "http://build/3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Issue != 1 || fake.changes[1].Status != "ABANDONED" {
+		t.Errorf("expected change 2 to be abandoned as a duplicate of 1, got %+v", results[0])
+	}
+	if !fake.authed {
+		t.Errorf("expected authenticated requests")
+	}
+
+	tracker.Password = "wrong"
+	_, err = tracker.Find(ctx, "TestFoo")
+	if e, ok := err.(*TrackerError); !ok || e.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected a TrackerError for the rejected credentials, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"expvar"
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// metrics are exported on /debug/vars: "created" counts issues filed,
+// "creationsCapped" sources which needed a new issue when we couldn't file
+// one, "cappedCycles" sync cycles which hit a creation cap and
+// "deferredLowBudget" sources not synced because we were low on API calls.
+var metrics = expvar.NewMap("issueSync")
+
+// CreationCappedError is returned by Sync when a source needs a new issue,
+// but the syncer may not file any more for now, see MaxCreatesPerCycle and
+// MaxOpenIssues. The source is worth syncing again later.
+type CreationCappedError struct {
+	Reason string
+}
+
+func (e *CreationCappedError) Error() string {
+	return "not filing a new issue: " + e.Reason
+}
+
+// openIssues counts the issues in the store which aren't known to be closed.
+func (s *IssueSyncer) openIssues() int {
+	open := 0
+	for _, r := range s.Store.List() {
+		if !r.Closed && !r.Created.IsZero() {
+			open++
+		}
+	}
+	return open
+}
+
+// checkCreationCap returns a *CreationCappedError if we may not file
+// another issue.
+func (s *IssueSyncer) checkCreationCap() error {
+	reason := ""
+	if s.MaxCreatesPerCycle > 0 && s.createdInCycle >= s.MaxCreatesPerCycle {
+		reason = fmt.Sprintf("already filed %d issues this cycle, the most allowed", s.createdInCycle)
+	} else if s.MaxOpenIssues > 0 {
+		if open := s.openIssues(); open >= s.MaxOpenIssues {
+			reason = fmt.Sprintf("%d issues filed by the syncer are open, the most allowed is %d", open, s.MaxOpenIssues)
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+	metrics.Add("creationsCapped", 1)
+	s.capped++
+	s.capReason = reason
+	return &CreationCappedError{Reason: reason}
+}
+
+// capSource is the meta-issue filed when a cap was hit. There's one issue,
+// with a comment per day on which the cap was hit.
+type capSource struct {
+	day    string
+	reason string
+	capped int
+}
+
+func (c *capSource) Title() string {
+	return "Issue syncer stopped filing issues"
+}
+
+func (c *capSource) ID() string {
+	return "issue-sync-capped:" + c.day
+}
+
+func (c *capSource) Body(newIssue bool) string {
+	return fmt.Sprintf("The issue syncer stopped filing new issues: %v. %d sources that needed a new issue were deferred; they'll be filed in later cycles, once issues are closed or the limit is raised. This usually means a source is misbehaving.\n\n%v", c.reason, c.capped, c.ID())
+}
+
+func (c *capSource) Labels() []string {
+	return nil
+}
+
+// reportCap files (or updates) the meta-issue if the cycle hit a cap.
+func (s *IssueSyncer) reportCap(ctx context.Context) {
+	if s.capped == 0 {
+		return
+	}
+	metrics.Add("cappedCycles", 1)
+	source := &capSource{
+		day:    s.now().UTC().Format(dateFormat),
+		reason: s.capReason,
+		capped: s.capped,
+	}
+	s.logger().Warningf("Hit the issue creation cap, deferred %d sources: %v", s.capped, s.capReason)
+	if err := s.sync(ctx, source); err != nil {
+		s.logger().Errorf("Unable to file the meta-issue about hitting the creation cap: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckCreationCap(t *testing.T) {
+	tests := []struct {
+		name      string
+		perCycle  int
+		maxOpen   int
+		created   int
+		open      int
+		closed    int
+		expectCap bool
+	}{
+		{name: "no caps", created: 100, open: 1000},
+		{name: "under caps", perCycle: 20, maxOpen: 500, created: 19, open: 499, closed: 10},
+		{name: "cycle cap", perCycle: 20, maxOpen: 500, created: 20, expectCap: true},
+		{name: "open cap", perCycle: 20, maxOpen: 5, open: 5, expectCap: true},
+		{name: "closed issues don't count", maxOpen: 5, open: 4, closed: 10},
+	}
+	for _, test := range tests {
+		s := NewIssueSyncer(nil, nil)
+		s.MaxCreatesPerCycle = test.perCycle
+		s.MaxOpenIssues = test.maxOpen
+		s.createdInCycle = test.created
+		for i := 0; i < test.open+test.closed; i++ {
+			closed := i >= test.open
+			s.Store.Update(i+1, func(r *IssueRecord) {
+				r.Created = time.Now()
+				r.Closed = closed
+			})
+		}
+		err := s.checkCreationCap()
+		if capped := err != nil; capped != test.expectCap {
+			t.Errorf("%v: expected capped=%v, got %v", test.name, test.expectCap, err)
+			continue
+		}
+		if err == nil {
+			continue
+		}
+		if !IsRetryable(err) {
+			t.Errorf("%v: capped sources should be retried later", test.name)
+		}
+		if s.capped != 1 {
+			t.Errorf("%v: expected 1 capped source, got %v", test.name, s.capped)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// SyncHealth is how the syncer is doing.
+type SyncHealth struct {
+	Healthy bool
+	// LastCycle is when the last sync cycle ended, and LastSuccess when the
+	// last one ended in which every source was synced.
+	LastCycle   time.Time
+	LastSuccess time.Time
+	// Pending is how many sources the last cycle failed to sync or deferred.
+	Pending int
+	// APIRemaining is how many github API calls are left, -1 if unknown.
+	APIRemaining int
+}
+
+// cycleHealth is what the syncer remembers about its cycles for SyncHealth.
+// It's read by http handlers while the syncer runs.
+type cycleHealth struct {
+	lock        sync.Mutex
+	lastCycle   time.Time
+	lastSuccess time.Time
+	pending     int
+	// held is how many sources are held for quiet hours, and errors the
+	// latest failures to sync, for the StatusPage.
+	held   int
+	errors []SyncError
+}
+
+func (c *cycleHealth) record(end time.Time, pending int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lastCycle = end
+	c.pending = pending
+	if pending == 0 {
+		c.lastSuccess = end
+	}
+}
+
+func (c *cycleHealth) recordHeld(held int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.held = held
+}
+
+func (c *cycleHealth) recordError(e SyncError) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.errors = append(c.errors, e)
+	if len(c.errors) > maxRecentErrors {
+		c.errors = c.errors[len(c.errors)-maxRecentErrors:]
+	}
+}
+
+// HealthReporter tells on-call whether issue syncing is stuck: it serves
+// the syncer's health over http and can publish it as a github status.
+type HealthReporter struct {
+	syncer  *IssueSyncer
+	started time.Time
+
+	// MaxAge is how long syncing may go without a successful cycle before
+	// it's unhealthy.
+	MaxAge time.Duration
+	// Status, if set, is the repo on which the health is published as the
+	// Context status of the head of Branch.
+	Status  *github.Config
+	Branch  string
+	Context string
+	// URL is linked from the status, e.g. the /healthz endpoint.
+	URL string
+}
+
+// NewHealthReporter constructs a HealthReporter for `syncer`, which becomes
+// unhealthy after an hour without a successful cycle.
+func NewHealthReporter(syncer *IssueSyncer) *HealthReporter {
+	return &HealthReporter{
+		syncer:  syncer,
+		started: syncer.now(),
+		MaxAge:  time.Hour,
+		Branch:  "master",
+		Context: "issue-sync",
+	}
+}
+
+// Health returns the syncer's current health.
+func (h *HealthReporter) Health() SyncHealth {
+	c := &h.syncer.health
+	c.lock.Lock()
+	health := SyncHealth{
+		LastCycle:    c.lastCycle,
+		LastSuccess:  c.lastSuccess,
+		Pending:      c.pending,
+		APIRemaining: -1,
+	}
+	c.lock.Unlock()
+
+	since := h.started
+	if health.LastSuccess.After(since) {
+		since = health.LastSuccess
+	}
+	health.Healthy = h.syncer.now().Sub(since) <= h.MaxAge
+	if h.syncer.config != nil {
+		if remaining, ok := h.syncer.config.APIRemaining(); ok {
+			health.APIRemaining = remaining
+		}
+	}
+	return health
+}
+
+// ServeHTTP serves the health as json, with status 503 when unhealthy.
+func (h *HealthReporter) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	health := h.Health()
+	data, err := json.MarshalIndent(health, "", "  ")
+	if err != nil {
+		res.Header().Set("Content-type", "text/plain")
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-type", "application/json")
+	if health.Healthy {
+		res.WriteHeader(http.StatusOK)
+	} else {
+		res.WriteHeader(http.StatusServiceUnavailable)
+	}
+	res.Write(data)
+}
+
+// Publish sets the health as a github status, if Status is set.
+func (h *HealthReporter) Publish() error {
+	if h.Status == nil {
+		return nil
+	}
+	health := h.Health()
+	state := "success"
+	if !health.Healthy {
+		state = "failure"
+	}
+	last := "never"
+	if !health.LastSuccess.IsZero() {
+		last = health.LastSuccess.UTC().Format(time.RFC3339)
+	}
+	// Statuses are shown cut at 140 characters.
+	description := fmt.Sprintf("last synced all: %v, pending: %d, API calls left: %d", last, health.Pending, health.APIRemaining)
+	return h.Status.SetBranchStatus(h.Branch, state, h.URL, description, h.Context)
+}
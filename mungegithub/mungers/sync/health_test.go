@@ -0,0 +1,62 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name     string
+		cycles   []int
+		elapsed  time.Duration
+		expected bool
+		status   int
+	}{
+		{name: "just started", elapsed: time.Minute, expected: true, status: http.StatusOK},
+		{name: "never synced", elapsed: 2 * time.Hour, expected: false, status: http.StatusServiceUnavailable},
+		{name: "synced", cycles: []int{0, 0}, elapsed: 2 * time.Hour, expected: true, status: http.StatusOK},
+		{name: "stuck", cycles: []int{0, 3}, elapsed: 4 * time.Hour, expected: false, status: http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		now := date("2016-07-01 12:00")
+		s := NewIssueSyncer(nil, nil)
+		s.now = func() time.Time { return now }
+		h := NewHealthReporter(s)
+		for _, pending := range test.cycles {
+			now = now.Add(test.elapsed / time.Duration(len(test.cycles)))
+			s.health.record(now, pending)
+		}
+		if len(test.cycles) == 0 {
+			now = now.Add(test.elapsed)
+		}
+
+		health := h.Health()
+		if health.Healthy != test.expected {
+			t.Errorf("%v: expected healthy=%v, got %+v", test.name, test.expected, health)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, nil)
+		if res.Code != test.status {
+			t.Errorf("%v: expected status %v, got %v", test.name, test.status, res.Code)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// The occurrence histogram of an issue is kept at the end of its body,
+// between these markers, see IssueSyncer.HistogramDays.
+const (
+	histogramStart = "<!-- sync-histogram-start -->"
+	histogramEnd   = "<!-- sync-histogram-end -->"
+)
+
+// sparks are the bars of the histogram, from lowest to highest. Days
+// without occurrences get noSpark.
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+const noSpark = '·'
+
+// occurrenceHistogram shows the occurrences per day in `daily` (see
+// IssueRecord.Daily) over the `days` days until `now`, oldest first, as a
+// sparkline scaled to the busiest day, with how many occurred in the last
+// week and the week before, so triagers can tell if it's getting worse.
+func occurrenceHistogram(daily map[string]int, days int, now time.Time) string {
+	now = now.UTC()
+	counts := make([]int, days)
+	max, total := 0, 0
+	for i := range counts {
+		day := now.AddDate(0, 0, i-days+1).Format(dateFormat)
+		counts[i] = daily[day]
+		total += counts[i]
+		if counts[i] > max {
+			max = counts[i]
+		}
+	}
+	line := make([]rune, days)
+	for i, n := range counts {
+		if n == 0 {
+			line[i] = noSpark
+			continue
+		}
+		// Rounded up, so any occurrence shows.
+		line[i] = sparks[(n*len(sparks)-1)/max]
+	}
+	text := fmt.Sprintf("**Occurrences per day** from %v to %v: `%v` %d in total",
+		now.AddDate(0, 0, 1-days).Format(dateFormat), now.Format(dateFormat), string(line), total)
+	if days >= 14 {
+		week, before := 0, 0
+		for i, n := range counts[days-14:] {
+			if i < 7 {
+				before += n
+			} else {
+				week += n
+			}
+		}
+		text += fmt.Sprintf(", %d in the last week and %d in the week before", week, before)
+	}
+	return histogramStart + "\n" + text + ".\n" + histogramEnd
+}
+
+// updateHistogram rewrites the occurrence histogram of issue `n` from the
+// store, once it has more than one occurrence.
+func (s *IssueSyncer) updateHistogram(ctx context.Context, n int) error {
+	r, ok := s.Store.Get(n)
+	if !ok || r.Occurrences < 2 {
+		return nil
+	}
+	days := s.HistogramDays
+	if max := int(dailyRetention / (24 * time.Hour)); days > max {
+		days = max
+	}
+	obj, err := s.getObject(ctx, n)
+	if err != nil {
+		return err
+	}
+	old := ""
+	if obj.Issue.Body != nil {
+		old = *obj.Issue.Body
+	}
+	body := replaceSection(old, histogramStart, histogramEnd, occurrenceHistogram(r.Daily, days, s.now()), true)
+	if body == old {
+		return nil
+	}
+	s.logger().With("issue", n).Debugf("Updating the occurrence histogram")
+	return s.editBody(ctx, fmt.Sprintf("editing the occurrence histogram of %v", n), obj, old, body)
+}
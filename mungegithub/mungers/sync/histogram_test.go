@@ -0,0 +1,113 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestOccurrenceHistogram(t *testing.T) {
+	daily := map[string]int{"2016-06-30": 9, "2016-07-01": 1, "2016-07-13": 2, "2016-07-14": 4}
+	tests := []struct {
+		name     string
+		daily    map[string]int
+		days     int
+		expected string
+	}{
+		{
+			name:     "two weeks",
+			daily:    daily,
+			days:     14,
+			expected: "**Occurrences per day** from 2016-07-01 to 2016-07-14: `▂···········▄█` 7 in total, 6 in the last week and 1 in the week before.",
+		},
+		{
+			name:     "short",
+			daily:    daily,
+			days:     3,
+			expected: "**Occurrences per day** from 2016-07-12 to 2016-07-14: `·▄█` 6 in total.",
+		},
+		{
+			name:     "none",
+			days:     3,
+			expected: "**Occurrences per day** from 2016-07-12 to 2016-07-14: `···` 0 in total.",
+		},
+	}
+	for _, test := range tests {
+		got := occurrenceHistogram(test.daily, test.days, date("2016-07-14 12:00"))
+		if expected := histogramStart + "\n" + test.expected + "\n" + histogramEnd; got != expected {
+			t.Errorf("%v: expected %q, got %q", test.name, expected, got)
+		}
+	}
+}
+
+func TestHistogramSection(t *testing.T) {
+	old := occurrenceHistogram(nil, 3, date("2016-07-14 12:00"))
+	updated := occurrenceHistogram(map[string]int{"2016-07-14": 1}, 3, date("2016-07-14 12:00"))
+	tests := []struct {
+		body, expected string
+	}{
+		{"Failed", "Failed\n\n" + updated},
+		{"Failed\n\n" + old, "Failed\n\n" + updated},
+		{"Failed\n\n" + old + "\n\nmore", "Failed\n\n" + updated + "\n\nmore"},
+	}
+	for _, test := range tests {
+		if got := replaceSection(test.body, histogramStart, histogramEnd, updated, true); got != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, got)
+		}
+	}
+}
+
+func TestHistogramSync(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.HistogramDays = 14
+	now := date("2016-07-13 12:00")
+	s.now = func() time.Time { return now }
+
+	histograms := func() []string {
+		issues := tracker.Issues()
+		if len(issues) != 1 {
+			t.Fatalf("expected one issue, got %d", len(issues))
+		}
+		return strings.Split(*issues[0].Body, histogramStart)[1:]
+	}
+	for i, ref := range []string{"foo-1", "foo-2", "foo-3"} {
+		if i == 2 {
+			now = now.Add(24 * time.Hour)
+		}
+		if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if i == 0 {
+			if h := histograms(); len(h) != 0 {
+				t.Errorf("expected no histogram for a single occurrence, got %q", h)
+			}
+		}
+	}
+	h := histograms()
+	if len(h) != 1 || !strings.Contains(h[0], "`············█▄` 3 in total") {
+		t.Errorf("expected the histogram to be updated in place, got %q", h)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IDMatching is how the syncer recognizes the ID of a source in what was
+// written on an issue, to tell whether the source was recorded there.
+type IDMatching string
+
+const (
+	// IDMatchExact recognizes the source's IDMarker, or its ID as a whole
+	// token, e.g. in issues written before the syncer wrote markers: run
+	// "123" isn't recorded by run "1234". The default.
+	IDMatchExact IDMatching = "exact"
+	// IDMatchMarker only recognizes the source's IDMarker (or the row of a
+	// sync section), once no issues from before markers matter anymore.
+	IDMatchMarker IDMatching = "marker"
+	// IDMatchSubstring recognizes the ID anywhere, even as part of another
+	// one, as the syncer used to.
+	IDMatchSubstring IDMatching = "substring"
+)
+
+// IDMarker is how the syncer marks the ID of a source in the issues and
+// comments it writes about the source. Sources needn't write it: bodies are
+// sanitized, so that they can't pass for markers, and the syncer appends it.
+func IDMarker(id string) string {
+	return fmt.Sprintf("<!-- sync-id: %v -->", strings.Replace(id, "--", "-"+zeroWidthSpace+"-", -1))
+}
+
+// recorded returns true if `text` records the source with `id`.
+func (s *IssueSyncer) recorded(text, id string) bool {
+	if strings.Contains(text, IDMarker(id)) {
+		return true
+	}
+	switch s.IDMatching {
+	case IDMatchMarker:
+		return false
+	case IDMatchSubstring:
+		return strings.Contains(text, id)
+	}
+	return containsToken(text, id)
+}
+
+// isTokenBoundary returns true if `r` may surround an ID written as a token.
+func isTokenBoundary(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("`'\"()[]<>|,;", r)
+}
+
+// containsToken returns true if `text` contains `id` surrounded by token
+// boundaries, or by the start and end of `text`. Punctuation ending a
+// sentence may follow it.
+func containsToken(text, id string) bool {
+	if id == "" {
+		return false
+	}
+	for start := 0; ; {
+		i := strings.Index(text[start:], id)
+		if i == -1 {
+			return false
+		}
+		i += start
+		start = i + 1
+		if before, _ := utf8.DecodeLastRuneInString(text[:i]); i > 0 && !isTokenBoundary(before) {
+			continue
+		}
+		rest := text[i+len(id):]
+		if rest != "" && strings.ContainsRune(".:!?", rune(rest[0])) {
+			rest = rest[1:]
+		}
+		if after, _ := utf8.DecodeRuneInString(rest); rest == "" || isTokenBoundary(after) {
+			return true
+		}
+	}
+}
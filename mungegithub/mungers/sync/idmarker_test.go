@@ -0,0 +1,72 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestRecorded(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		id       string
+		matching IDMatching
+		expected bool
+	}{
+		{name: "marker", text: "foo\n\n" + IDMarker("run/123"), id: "run/123", expected: true},
+		{name: "other marker", text: IDMarker("run/1234"), id: "run/123", expected: false},
+		{name: "marker with dashes", text: IDMarker("a-->b"), id: "a-->b", matching: IDMatchMarker, expected: true},
+		{name: "token", text: "Failed: run/123", id: "run/123", expected: true},
+		{name: "token in code", text: "see `run/123`", id: "run/123", expected: true},
+		{name: "token in link", text: "[run](http://ci/run/123)", id: "http://ci/run/123", expected: true},
+		{name: "end of sentence", text: "It failed in run/123.\nAgain.", id: "run/123", expected: true},
+		{name: "prefix", text: "Failed: run/1234", id: "run/123", expected: false},
+		{name: "suffix", text: "Failed: xrun/123", id: "run/123", expected: false},
+		{name: "prefix, then token", text: "run/1234 and run/123", id: "run/123", expected: true},
+		{name: "prefix as substring", text: "Failed: run/1234", id: "run/123", matching: IDMatchSubstring, expected: true},
+		{name: "token without marker", text: "Failed: run/123", id: "run/123", matching: IDMatchMarker, expected: false},
+		{name: "empty id", text: "anything", id: "", expected: false},
+	}
+	for _, test := range tests {
+		s := &IssueSyncer{IDMatching: test.matching}
+		if got := s.recorded(test.text, test.id); got != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestSyncPrefixID(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	n := tracker.AddIssue("TestFoo", "Failed in http://ci/foo/1234")
+	for _, ref := range []string{"http://ci/foo/1234", "http://ci/foo/123", "http://ci/foo/123"} {
+		// A new syncer each time, which only knows what's on the issue.
+		finder := NewSearchFinder(tracker.Config(), nil)
+		finder.MinInterval = 0
+		s := NewIssueSyncer(tracker.Config(), finder)
+		if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref}); err != nil {
+			t.Fatalf("unexpected error syncing %v: %v", ref, err)
+		}
+	}
+	if comments := tracker.Comments(n); len(comments) != 1 {
+		t.Errorf("expected one comment about run 123, got %q", comments)
+	}
+}
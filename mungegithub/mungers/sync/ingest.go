@@ -0,0 +1,193 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// maxIngestBody is the most bytes of sources accepted in one request.
+const maxIngestBody = 1 << 20
+
+// Ingester accepts sources POSTed as JSON (see ReadJSONSources) and syncs
+// them in the background, making the syncer a "file me an issue, deduped"
+// service. Requests must carry the shared token as
+// "Authorization: Bearer <token>".
+type Ingester struct {
+	syncer Syncer
+	token  string
+	after  func(time.Duration) <-chan time.Time
+
+	// Logger is where failures are logged.
+	Logger Logger
+
+	// MaxQueue is how many sources may wait to be synced. Requests are
+	// refused while the queue is full.
+	MaxQueue int
+	// MaxAttempts is how many cycles a source may fail to sync in before
+	// it's dropped.
+	MaxAttempts int
+
+	lock     sync.Mutex
+	queue    []IssueSource
+	attempts map[string]int
+}
+
+// NewIngester constructs an Ingester syncing with `syncer`. `token` must not
+// be empty. `syncer` may be an IssueSyncer, or a MultiSyncer.
+func NewIngester(syncer Syncer, token string) *Ingester {
+	return &Ingester{
+		syncer:      syncer,
+		token:       token,
+		after:       time.After,
+		Logger:      &textLogger{},
+		MaxQueue:    1000,
+		MaxAttempts: 5,
+		attempts:    map[string]int{},
+	}
+}
+
+// SetClock has the ingester wait between cycles with `clock`. Call it
+// before Run.
+func (i *Ingester) SetClock(clock Clock) {
+	i.after = clock.After
+}
+
+func (i *Ingester) authorized(req *http.Request) bool {
+	auth := req.Header.Get("Authorization")
+	if i.token == "" || !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(i.token)) == 1
+}
+
+// ServeHTTP queues the sources in the request body.
+func (i *Ingester) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(res, "sources must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !i.authorized(req) {
+		http.Error(res, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sources, err := ReadJSONSources(http.MaxBytesReader(res, req.Body, maxIngestBody))
+	if err != nil {
+		http.Error(res, fmt.Sprintf("invalid sources: %v", err), http.StatusBadRequest)
+		return
+	}
+	queued, ok := i.enqueue(sources)
+	if !ok {
+		http.Error(res, "too many sources waiting to be synced, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	data, _ := json.Marshal(map[string]int{"queued": queued})
+	res.Header().Set("Content-type", "application/json")
+	res.WriteHeader(http.StatusAccepted)
+	res.Write(data)
+}
+
+// enqueue adds the sources to the queue, unless they don't all fit. It
+// returns how many sources are queued.
+func (i *Ingester) enqueue(sources []*JSONSource) (int, bool) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if len(i.queue)+len(sources) > i.MaxQueue {
+		return len(i.queue), false
+	}
+	for _, s := range sources {
+		i.queue = append(i.queue, s)
+	}
+	return len(i.queue), true
+}
+
+// Queued returns how many sources wait to be synced.
+func (i *Ingester) Queued() int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return len(i.queue)
+}
+
+// Run syncs the queued sources every `interval`, until ctx is canceled.
+func (i *Ingester) Run(ctx context.Context, interval time.Duration) {
+	for {
+		i.syncQueued(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.after(interval):
+		}
+	}
+}
+
+// syncQueued syncs everything in the queue. Sources which fail to sync are
+// queued again, up to MaxAttempts times. Sources the syncer deferred, or
+// didn't get to, are queued again without counting an attempt.
+func (i *Ingester) syncQueued(ctx context.Context) {
+	i.lock.Lock()
+	sources := i.queue
+	i.queue = nil
+	i.lock.Unlock()
+	if len(sources) == 0 {
+		return
+	}
+
+	results, err := i.syncer.SyncAll(ctx, sources)
+	if err != nil {
+		i.Logger.Warningf("Unable to sync all ingested sources: %v", err)
+	}
+	byID := map[string]SyncResult{}
+	for _, r := range results {
+		byID[r.Source] = r
+	}
+
+	retry := []IssueSource{}
+	for _, s := range sources {
+		id := s.ID()
+		resultID := id
+		if isSensitive(s) {
+			resultID = redact(id)
+		}
+		result, ok := byID[resultID]
+		if !ok || result.Deferred {
+			retry = append(retry, s)
+			continue
+		}
+		if result.Err == nil {
+			delete(i.attempts, id)
+			continue
+		}
+		i.attempts[id]++
+		if i.attempts[id] >= i.MaxAttempts {
+			i.Logger.With("source", resultID).Errorf("Dropping source after %d attempts: %v", i.attempts[id], result.Err)
+			delete(i.attempts, id)
+			continue
+		}
+		retry = append(retry, s)
+	}
+	i.lock.Lock()
+	i.queue = append(retry, i.queue...)
+	i.lock.Unlock()
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestIngesterServeHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		auth     string
+		body     string
+		expected int
+		queued   int
+	}{
+		{
+			name:     "accepted",
+			method:   "POST",
+			auth:     "Bearer secret",
+			body:     `[{"title": "a", "id": "1"}, {"title": "b", "id": "2"}]`,
+			expected: http.StatusAccepted,
+			queued:   2,
+		},
+		{
+			name:     "wrong token",
+			method:   "POST",
+			auth:     "Bearer guess",
+			body:     `{"title": "a", "id": "1"}`,
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "no token",
+			method:   "POST",
+			body:     `{"title": "a", "id": "1"}`,
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "GET",
+			method:   "GET",
+			auth:     "Bearer secret",
+			expected: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "invalid",
+			method:   "POST",
+			auth:     "Bearer secret",
+			body:     `{"title": "a"}`,
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "queue full",
+			method:   "POST",
+			auth:     "Bearer secret",
+			body:     `[{"title": "a", "id": "1"}, {"title": "b", "id": "2"}, {"title": "c", "id": "3"}, {"title": "d", "id": "4"}]`,
+			expected: http.StatusServiceUnavailable,
+		},
+	}
+	for _, test := range tests {
+		i := NewIngester(NewIssueSyncer(nil, nil), "secret")
+		i.MaxQueue = 3
+		req, _ := http.NewRequest(test.method, "/sources", strings.NewReader(test.body))
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		res := httptest.NewRecorder()
+		i.ServeHTTP(res, req)
+		if res.Code != test.expected {
+			t.Errorf("%v: expected status %v, got %v: %v", test.name, test.expected, res.Code, res.Body.String())
+		}
+		if len(i.queue) != test.queued {
+			t.Errorf("%v: expected %v queued sources, got %v", test.name, test.queued, len(i.queue))
+		}
+	}
+}
+
+// resultSyncer returns the results it's told to for the sources it syncs,
+// and leaves out the rest, as if ctx was done before they were synced.
+type resultSyncer struct {
+	results map[string]SyncResult
+}
+
+func (r *resultSyncer) SyncAll(ctx context.Context, sources []IssueSource) ([]SyncResult, error) {
+	results := []SyncResult{}
+	for _, s := range sources {
+		if result, ok := r.results[s.ID()]; ok {
+			result.Source = s.ID()
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (r *resultSyncer) Synced(id string) bool { return false }
+
+func TestIngesterRetries(t *testing.T) {
+	syncer := &resultSyncer{results: map[string]SyncResult{
+		"synced":   {Action: DecisionCreate},
+		"skipped":  {Skipped: "too old"},
+		"deferred": {Deferred: true},
+		"failing":  {Err: fmt.Errorf("github is down")},
+	}}
+	i := NewIngester(syncer, "secret")
+	i.MaxAttempts = 2
+	for _, id := range []string{"synced", "skipped", "deferred", "failing", "unsynced"} {
+		i.queue = append(i.queue, &JSONSource{Key: id, Ref: id})
+	}
+
+	queued := func() []string {
+		ids := []string{}
+		for _, s := range i.queue {
+			ids = append(ids, s.ID())
+		}
+		return ids
+	}
+	i.syncQueued(context.Background())
+	if expected := []string{"deferred", "failing", "unsynced"}; !reflect.DeepEqual(queued(), expected) {
+		t.Errorf("expected %v queued again, got %v", expected, queued())
+	}
+	i.syncQueued(context.Background())
+	if expected := []string{"deferred", "unsynced"}; !reflect.DeepEqual(queued(), expected) {
+		t.Errorf("expected the failing source dropped, got %v", queued())
+	}
+	for j := 0; j < 5; j++ {
+		i.syncQueued(context.Background())
+	}
+	if expected := []string{"deferred", "unsynced"}; !reflect.DeepEqual(queued(), expected) {
+		t.Errorf("expected deferred sources to be kept, got %v", queued())
+	}
+}
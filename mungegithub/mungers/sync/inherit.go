@@ -0,0 +1,197 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// PriorityInheritance raises the priority of issues we filed which release
+// tracking issues reference: open issues with any of ParentLabels, e.g. a
+// release-blocking umbrella issue, or with a milestone. The issue gets
+// Priority, unless it has a more urgent one already, and from then on
+// follows the escalation schedule of Priority (see EscalationPolicy), from
+// its first step. Priorities are never lowered again. For example:
+//
+//	inheritance:
+//	  parentLabels: [release-blocker]
+//	  milestones: true
+//	  priority: priority/critical-urgent
+//	  priorities: [priority/critical-urgent, priority/important-soon, priority/backlog]
+type PriorityInheritance struct {
+	ParentLabels []string `json:"parentLabels"`
+	Milestones   bool     `json:"milestones"`
+	// Priority is the label referenced issues are raised to.
+	Priority string `json:"priority"`
+	// Priorities are the priority labels, most urgent first. The ones
+	// the issue has are replaced by Priority. If empty, all labels
+	// starting with "priority/" are.
+	Priorities []string `json:"priorities"`
+}
+
+// issueRefRE returns a regexp finding references to issues of the same
+// repo: "#123", or a link to it on `host`, e.g. github.com.
+func issueRefRE(host string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^\w/])#(\d+)\b|` + regexp.QuoteMeta(host) + `/([\w.-]+)/([\w.-]+)/issues/(\d+)`)
+}
+
+// references returns the issues of org/project which `body` references,
+// finding them with issueRefRE.
+func references(body string, refRE *regexp.Regexp, org, project string) []int {
+	out := []int{}
+	for _, m := range refRE.FindAllStringSubmatch(body, -1) {
+		ref := m[1]
+		if ref == "" {
+			if m[2] != org || m[3] != project {
+				continue
+			}
+			ref = m[4]
+		}
+		if n, err := strconv.Atoi(ref); err == nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// isParent returns true if `issue` is a release tracking issue.
+func (p *PriorityInheritance) isParent(issue *githubapi.Issue) bool {
+	if p.Milestones && issue.Milestone != nil {
+		return true
+	}
+	for _, l := range p.ParentLabels {
+		if hasLabels(issue, []string{l}) {
+			return true
+		}
+	}
+	return false
+}
+
+// rank returns how urgent `label` is, 0 being the most, or -1 if it isn't
+// one of Priorities.
+func (p *PriorityInheritance) rank(label string) int {
+	for i, l := range p.Priorities {
+		if l == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// priorities returns the priority labels `obj` has.
+func (p *PriorityInheritance) priorities(obj *github.MungeObject) []string {
+	if len(p.Priorities) == 0 {
+		return github.GetLabelsWithPrefix(obj.Issue.Labels, "priority/")
+	}
+	out := []string{}
+	for _, l := range p.Priorities {
+		if obj.HasLabel(l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// moreUrgent returns true if `obj` has a priority at least as urgent as
+// Priority.
+func (p *PriorityInheritance) moreUrgent(obj *github.MungeObject) bool {
+	if obj.HasLabel(p.Priority) {
+		return true
+	}
+	target := p.rank(p.Priority)
+	if target < 0 {
+		return false
+	}
+	for _, l := range p.priorities(obj) {
+		if r := p.rank(l); r >= 0 && r < target {
+			return true
+		}
+	}
+	return false
+}
+
+// InheritPriorities looks for release tracking issues which reference open
+// issues in the store, and raises their priority, see PriorityInheritance.
+func (s *IssueSyncer) InheritPriorities(ctx context.Context, p *PriorityInheritance) error {
+	var issues []*githubapi.Issue
+	if err := s.retry(ctx, "listing the open issues", func() (err error) {
+		issues, err = s.client(ctx).ListAllIssues(&githubapi.IssueListByRepoOptions{State: "open"})
+		return err
+	}); err != nil {
+		return err
+	}
+	// The parent which references each issue first.
+	parents := map[int]int{}
+	refRE := issueRefRE(s.config.WebHost())
+	for _, issue := range issues {
+		if issue.Number == nil || issue.Body == nil || issue.PullRequestLinks != nil || !p.isParent(issue) {
+			continue
+		}
+		for _, n := range references(*issue.Body, refRE, s.config.Org, s.config.Project) {
+			if _, ok := parents[n]; !ok && n != *issue.Number {
+				parents[n] = *issue.Number
+			}
+		}
+	}
+	for _, r := range s.Store.List() {
+		parent, ok := parents[r.Number]
+		if !ok || r.Closed || r.Created.IsZero() || r.InheritedPriority == p.Priority {
+			continue
+		}
+		if err := s.inheritPriority(ctx, p, r, parent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *IssueSyncer) inheritPriority(ctx context.Context, p *PriorityInheritance, r IssueRecord, parent int) error {
+	obj, err := s.getObject(ctx, r.Number)
+	if err != nil {
+		return err
+	}
+	if obj.Issue.State != nil && *obj.Issue.State == "closed" {
+		return s.noticeClosed(ctx, r.Number)
+	}
+	if !p.moreUrgent(obj) {
+		s.logger().With("issue", r.Number).Infof("Referenced from release tracking issue #%d, raising to %v", parent, p.Priority)
+		for _, l := range p.priorities(obj) {
+			if err := s.removeLabel(ctx, obj, l); err != nil {
+				return err
+			}
+		}
+		if err := s.addLabel(ctx, obj, p.Priority); err != nil {
+			return err
+		}
+		msg := fmt.Sprintf("Raising the priority to %v: release tracking issue #%d references this issue.", p.Priority, parent)
+		if err := s.writeComment(ctx, fmt.Sprintf("commenting on the priority of %v", r.Number), obj, s.text(msg)); err != nil {
+			return err
+		}
+		metrics.Add("prioritiesInherited", 1)
+	}
+	return s.Store.Update(r.Number, func(r *IssueRecord) {
+		r.InheritedPriority, r.InheritedFrom = p.Priority, parent
+		// Start over on the schedule of the priority.
+		r.Escalations = 0
+	})
+}
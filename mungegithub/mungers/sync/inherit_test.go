@@ -0,0 +1,95 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestReferences(t *testing.T) {
+	body := "Blocks the release:\n- [ ] #12\n- [x] https://github.com/o/r/issues/34\n- [ ] https://github.com/other/r/issues/5\n- [ ] other/r#6 and foo#7"
+	if refs, expected := references(body, issueRefRE("github.com"), "o", "r"), []int{12, 34}; !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected %v, got %v", expected, refs)
+	}
+	enterprise := "- [ ] https://ghe.example.com/o/r/issues/56\n- [ ] https://github.com/o/r/issues/34"
+	if refs, expected := references(enterprise, issueRefRE("ghe.example.com"), "o", "r"), []int{56}; !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected links to GitHub Enterprise issues, got %v", refs)
+	}
+}
+
+func TestInheritPriorities(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+
+	for _, title := range []string{"TestFoo", "TestBar"} {
+		if err := s.Sync(context.Background(), &JSONSource{Key: title, Ref: title + "-1", Tags: []string{"kind/flake", "priority/backlog"}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	foo, bar := tracker.OpenIssues("TestFoo")[0], tracker.OpenIssues("TestBar")[0]
+	tracker.AddIssue("v1.5 release blockers", fmt.Sprintf("- [ ] #%d", foo), "release-blocker")
+	tracker.AddIssue("Cleanups", fmt.Sprintf("- [ ] #%d", bar))
+
+	policy := &EscalationPolicy{
+		Inheritance: &PriorityInheritance{
+			ParentLabels: []string{"release-blocker"},
+			Priority:     "priority/critical-urgent",
+			Priorities:   []string{"priority/critical-urgent", "priority/important-soon", "priority/backlog"},
+		},
+		Schedules: map[string][]EscalationStep{
+			"priority/critical-urgent": {{Days: 0, Ping: "@kubernetes/release-team"}},
+			"kind/flake":               {{Days: 30, Ping: "@kubernetes/test-infra-maintainers"}},
+		},
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Escalate(context.Background(), policy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	labels := map[int][]string{}
+	for _, issue := range tracker.Issues() {
+		for _, l := range issue.Labels {
+			labels[*issue.Number] = append(labels[*issue.Number], *l.Name)
+		}
+	}
+	if expected := []string{"kind/flake", "priority/critical-urgent"}; !reflect.DeepEqual(labels[foo], expected) {
+		t.Errorf("expected the referenced issue to be raised to %v, got %v", expected, labels[foo])
+	}
+	if expected := []string{"kind/flake", "priority/backlog"}; !reflect.DeepEqual(labels[bar], expected) {
+		t.Errorf("expected the issue referenced by a non-release issue to be left alone, got %v", labels[bar])
+	}
+	comments := tracker.Comments(foo)
+	if len(comments) != 2 || !strings.Contains(comments[0], "priority/critical-urgent") || !strings.Contains(comments[1], "@kubernetes/release-team") {
+		t.Errorf("expected a comment about the priority and an escalation on the release schedule, got %q", comments)
+	}
+	if len(tracker.Comments(bar)) != 0 {
+		t.Errorf("expected no comments on the other issue, got %q", tracker.Comments(bar))
+	}
+	if r, _ := s.Store.Get(foo); r.InheritedFrom == 0 || r.InheritedPriority != "priority/critical-urgent" {
+		t.Errorf("expected the inheritance to be recorded, got %+v", r)
+	}
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// syncKeyRE finds the sync keys embedded in issue bodies.
+var syncKeyRE = regexp.MustCompile(`<!-- sync-key: (\S+) -->`)
+
+// SyncKeyMarker returns the text which embeds `key` in an issue body, where
+// IssueIndex will find it.
+func SyncKeyMarker(key string) string {
+	return fmt.Sprintf("<!-- sync-key: %v -->", key)
+}
+
+// SyncKeys returns all sync keys embedded in body.
+func SyncKeys(body string) []string {
+	keys := []string{}
+	for _, m := range syncKeyRE.FindAllStringSubmatch(body, -1) {
+		keys = append(keys, m[1])
+	}
+	return keys
+}
+
+// indexedIssue is what we remember about every issue.
+type indexedIssue struct {
+	Title string
+	State string
+	Keys  []string
+}
+
+// indexState is what gets written to disk.
+type indexState struct {
+	// LastUpdate is when the last successful listing started.
+	LastUpdate time.Time
+	Issues     map[int]indexedIssue
+}
+
+// IssueIndex is an IssueFinder which indexes all issues in the repo (or all
+// issues with the given labels) by title and by the sync keys embedded in
+// their bodies (see SyncKeyMarker). After the first full listing only issues
+// updated since the previous listing are fetched. If a path is given the
+// index is saved there after every update and loaded on construction, so a
+// restart doesn't need a full listing either.
+type IssueIndex struct {
+	config *github.Config
+	labels []string
+	path   string
+	// normalizer turns titles into the keys issues are indexed by.
+	normalizer *TitleNormalizer
+
+	lock    sync.RWMutex
+	state   indexState
+	byTitle map[string]sets.Int
+	byKey   map[string]sets.Int
+	synced  bool
+}
+
+// NewIssueIndex constructs an IssueIndex for issues which have all of
+// `labels`. `path` may be empty, in which case the index is only kept in
+// memory.
+func NewIssueIndex(config *github.Config, labels []string, path string) *IssueIndex {
+	i := &IssueIndex{
+		config: config,
+		labels: labels,
+		path:   path,
+	}
+	i.reset()
+	if path != "" {
+		if err := i.load(); err != nil {
+			glog.Errorf("Unable to load issue index from %v, starting from scratch: %v", path, err)
+			i.reset()
+		}
+	}
+	return i
+}
+
+func (i *IssueIndex) reset() {
+	i.state = indexState{Issues: map[int]indexedIssue{}}
+	i.byTitle = map[string]sets.Int{}
+	i.byKey = map[string]sets.Int{}
+}
+
+func (i *IssueIndex) load() error {
+	data, err := ioutil.ReadFile(i.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	state := indexState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for n, issue := range state.Issues {
+		i.insert(n, issue)
+	}
+	i.state.LastUpdate = state.LastUpdate
+	// A loaded index is as good as a full listing.
+	i.synced = true
+	return nil
+}
+
+func (i *IssueIndex) save() error {
+	i.lock.RLock()
+	data, err := json.Marshal(i.state)
+	i.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	tmp := i.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, i.path)
+}
+
+// Update lists every issue which changed since the last update and
+// re-indexes it. Call it periodically, e.g. once per munge loop.
+func (i *IssueIndex) Update() error {
+	i.lock.RLock()
+	since := i.state.LastUpdate
+	i.lock.RUnlock()
+
+	start := time.Now()
+	issues, err := i.config.ListAllIssues(&githubapi.IssueListByRepoOptions{
+		State:  "all",
+		Labels: i.labels,
+		Sort:   "updated",
+		Since:  since,
+	})
+	if err != nil {
+		return fmt.Errorf("error listing issues since %v: %v", since, err)
+	}
+	glog.V(2).Infof("Indexing %d issues updated since %v", len(issues), since)
+
+	func() {
+		i.lock.Lock()
+		defer i.lock.Unlock()
+		for _, issue := range issues {
+			i.add(issue)
+		}
+		i.state.LastUpdate = start
+		i.synced = true
+	}()
+
+	if i.path == "" {
+		return nil
+	}
+	if err := i.save(); err != nil {
+		glog.Errorf("Unable to save issue index to %v: %v", i.path, err)
+	}
+	return nil
+}
+
+// Observe implements IssueObserver: it re-indexes `issue` right away.
+func (i *IssueIndex) Observe(issue *githubapi.Issue) {
+	if !hasLabels(issue, i.labels) {
+		return
+	}
+	i.lock.Lock()
+	i.add(issue)
+	i.lock.Unlock()
+	if i.path == "" {
+		return
+	}
+	if err := i.save(); err != nil {
+		glog.Errorf("Unable to save issue index to %v: %v", i.path, err)
+	}
+}
+
+// add (re-)indexes issue. Must hold the lock.
+func (i *IssueIndex) add(issue *githubapi.Issue) {
+	if issue.Number == nil || issue.PullRequestLinks != nil {
+		return
+	}
+	indexed := indexedIssue{}
+	if issue.Title != nil {
+		indexed.Title = *issue.Title
+	}
+	if issue.State != nil {
+		indexed.State = *issue.State
+	}
+	if issue.Body != nil {
+		indexed.Keys = SyncKeys(*issue.Body)
+	}
+	i.insert(*issue.Number, indexed)
+}
+
+// insert replaces whatever we knew about issue n. Must hold the lock.
+func (i *IssueIndex) insert(n int, issue indexedIssue) {
+	if old, ok := i.state.Issues[n]; ok {
+		indexRemove(i.byTitle, i.normalizer.Normalize(old.Title), n)
+		for _, k := range old.Keys {
+			indexRemove(i.byKey, k, n)
+		}
+	}
+	i.state.Issues[n] = issue
+	indexInsert(i.byTitle, i.normalizer.Normalize(issue.Title), n)
+	for _, k := range issue.Keys {
+		indexInsert(i.byKey, k, n)
+	}
+}
+
+func indexInsert(m map[string]sets.Int, key string, n int) {
+	if _, ok := m[key]; !ok {
+		m[key] = sets.NewInt()
+	}
+	m[key].Insert(n)
+}
+
+func indexRemove(m map[string]sets.Int, key string, n int) {
+	if s, ok := m[key]; ok {
+		s.Delete(n)
+		if s.Len() == 0 {
+			delete(m, key)
+		}
+	}
+}
+
+// SetNormalizer has issues indexed by their titles normalized with `n`, see
+// IssueSyncer.Normalizer.
+func (i *IssueIndex) SetNormalizer(n *TitleNormalizer) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.normalizer = n
+	i.byTitle = map[string]sets.Int{}
+	for number, issue := range i.state.Issues {
+		indexInsert(i.byTitle, n.Normalize(issue.Title), number)
+	}
+}
+
+// AllIssuesForKey returns all issues, open or closed, whose title is `key`
+// or which embed `key` as a sync key, oldest first.
+func (i *IssueIndex) AllIssuesForKey(key string) []int {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	got := sets.NewInt()
+	if s, ok := i.byTitle[i.normalizer.Normalize(key)]; ok {
+		got = got.Union(s)
+	}
+	if s, ok := i.byKey[key]; ok {
+		got = got.Union(s)
+	}
+	// sets.Int.List() is sorted, and issue numbers only go up.
+	return got.List()
+}
+
+// Created indexes an issue we just filed, so that it is found before the
+// next Update.
+func (i *IssueIndex) Created(key string, number int) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	issue := i.state.Issues[number]
+	issue.Title = key
+	issue.State = "open"
+	i.insert(number, issue)
+}
+
+// IssuesForSyncKey implements SyncKeyFinder.
+func (i *IssueIndex) IssuesForSyncKey(key string) ([]int, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	if s, ok := i.byKey[key]; ok {
+		return s.List(), nil
+	}
+	return []int{}, nil
+}
+
+// CreatedSyncKey implements SyncKeyFinder.
+func (i *IssueIndex) CreatedSyncKey(key string, number int) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	issue := i.state.Issues[number]
+	issue.Keys = append(append([]string{}, issue.Keys...), key)
+	i.insert(number, issue)
+}
+
+// Synced returns true once the index has been populated, either by an
+// Update or from disk.
+func (i *IssueIndex) Synced() bool {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.synced
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	githubapi "github.com/google/go-github/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func indexIssue(number int, title, body string) *githubapi.Issue {
+	issue := github_test.Issue("bot", number, nil, false)
+	issue.Title = &title
+	issue.Body = &body
+	return issue
+}
+
+func TestIssueIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "issue-index")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.json")
+
+	i := NewIssueIndex(nil, nil, path)
+	if i.Synced() {
+		t.Errorf("empty index should not be synced")
+	}
+	i.add(indexIssue(1, "TestFoo", "flaked"))
+	i.add(indexIssue(2, "TestBar", "flaked "+SyncKeyMarker("abc")))
+	i.add(indexIssue(3, "TestFoo", SyncKeyMarker("def")+" and "+SyncKeyMarker("abc")))
+	// Humans renamed issue 1.
+	i.add(indexIssue(1, "TestFoo is flaky", "flaked"))
+	i.Created("TestBaz", 4)
+	i.synced = true
+
+	tests := []struct {
+		key      string
+		expected []int
+	}{
+		{key: "TestFoo", expected: []int{3}},
+		{key: "TestFoo is flaky", expected: []int{1}},
+		{key: "abc", expected: []int{2, 3}},
+		{key: "def", expected: []int{3}},
+		{key: "TestBaz", expected: []int{4}},
+		{key: "missing", expected: []int{}},
+	}
+	check := func(i *IssueIndex) {
+		for _, test := range tests {
+			if got := i.AllIssuesForKey(test.key); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("%q: expected %v, got %v", test.key, test.expected, got)
+			}
+		}
+	}
+	check(i)
+
+	if err := i.save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded := NewIssueIndex(nil, nil, path)
+	if !loaded.Synced() {
+		t.Errorf("loaded index should be synced")
+	}
+	check(loaded)
+}
@@ -18,9 +18,13 @@ package sync
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/golang/glog"
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
 	"k8s.io/contrib/mungegithub/github"
 	"k8s.io/kubernetes/pkg/util/sets"
 )
@@ -31,6 +35,14 @@ type IssueFinder interface {
 	Created(key string, number int)
 }
 
+// CheckedIssueFinder is an IssueFinder which can fail to look up a key, e.g.
+// because it has to ask github. The syncer skips sources whose key couldn't be
+// looked up instead of filing a (possibly duplicate) issue for them.
+type CheckedIssueFinder interface {
+	IssueFinder
+	IssuesForKey(key string) ([]int, error)
+}
+
 // IssueSource can be implemented by anything that wishes to be synced with
 // github issues.
 type IssueSource interface {
@@ -56,81 +68,743 @@ type IssueSource interface {
 }
 
 // IssueSyncer implements robust issue syncing logic and won't file duplicates etc.
+// Build it with New; a zero IssueSyncer doesn't work.
 type IssueSyncer struct {
 	config *github.Config
 	finder IssueFinder
 	synced sets.String
+	// comments of the issues we've checked for sources.
+	comments *commentCache
+
+	// Backoff controls how github calls which fail with transient errors
+	// are retried.
+	Backoff Backoff
+	// EditBody, if true, records occurrences in a section of the issue
+	// body instead of a comment each, see updateSection. EditRows is how
+	// many occurrences the section lists, DefaultEditRows if 0.
+	EditBody bool
+	EditRows int
+	// MaxCreatesPerCycle and MaxOpenIssues, if set, cap how many issues
+	// SyncAll files in a cycle, and how many issues filed by the syncer
+	// (according to Store) may be open. Sources which would go over are
+	// deferred, and a meta-issue is filed about it.
+	MaxCreatesPerCycle int
+	MaxOpenIssues      int
+	// MaxComments, if set, is how many comments about occurrences an
+	// issue may get (according to Store). The next occurrence goes to a
+	// new issue, which continues the old one, and the old one is closed.
+	MaxComments int
+	// BulkComments, if set, has the candidate issues for a source fetched
+	// in one GraphQL query, with this many of their most recent comments,
+	// instead of a few REST calls each. See bulkFetch.
+	BulkComments int
+	// MinResyncInterval, if set, is the least time between two comments
+	// about new occurrences on an issue. Occurrences in between are only
+	// counted in Store, which needs a path to keep this across restarts.
+	// Doesn't apply with EditBody or TaskList, which don't comment.
+	MinResyncInterval time.Duration
+	// MaxWarmUpAge bounds how far back WarmUp lists comments when Store
+	// doesn't know when it last warmed up, or did so longer ago.
+	MaxWarmUpAge time.Duration
+	// ExtraLabels are added to every new issue.
+	ExtraLabels []string
+	// Taxonomy, if set, checks the labels of new issues against the repo's.
+	Taxonomy *LabelTaxonomy
+	// ReactAfter, if set, is how many occurrences an issue may get comments
+	// for. Further occurrences are only recorded in Store, which then needs
+	// a path, and marked with Reaction (DefaultReaction if empty), so that
+	// watchers of busy issues aren't notified every time.
+	ReactAfter int
+	Reaction   string
+	// MuteLabel is the label humans put on an issue to silence it: while
+	// it has the label, occurrences are only counted in Store. Defaults
+	// to DefaultMuteLabel; empty turns muting off.
+	MuteLabel string
+	// TaskList, if set, records occurrences as tasks of a single comment,
+	// which humans check off, instead of a comment each.
+	TaskList *TaskList
+	// Triage, if set, has occurrences only recorded in the issue body,
+	// like EditBody does, while humans are working on the issue.
+	Triage *TriageSignals
+	// RecentlyClosed, if set, picks up where recently closed issues left
+	// off, instead of filing fresh ones.
+	RecentlyClosed *ClosedMatch
+	// Retest, if set, reruns the jobs of pull requests which failed with a
+	// flake we synced, see PRSource.
+	Retest *RetestPolicy
+	// Culprits, if set, finds the pull requests which likely introduced
+	// the failures of sources, to let their authors know.
+	Culprits CulpritFinder
+	// Feedback, if set, is told when humans add or remove any of
+	// FeedbackLabels (DefaultFeedbackLabels if nil) on issues we filed.
+	Feedback       SourceFeedback
+	FeedbackLabels []string
+	// Suspects, if set, lists the merges which landed shortly before a
+	// source first occurred in its new issue.
+	Suspects *SuspectMerges
+	// Locking, if set, locks issues we closed as duplicates or as stale
+	// once they have been closed for a while.
+	Locking *ClosedLocking
+	// HistogramDays, if set, keeps a sparkline of the occurrences per day
+	// over this many days (at most 35) in the body of issues with more
+	// than one occurrence. The counts come from Store, which then needs a
+	// path.
+	HistogramDays int
+	// OwnerFanOut, if set, files a tracking issue for sources with more
+	// than one owner (see OwnedSource), and a child issue per owner linked
+	// to it. Otherwise the issue for such a source gets all owner labels.
+	OwnerFanOut bool
+	// Upstream, if set, is the repo ours mirrors issues from. Sources with
+	// an open issue there are recorded on it instead of filed again.
+	Upstream *UpstreamRepo
+	// Board, if set, is a project board on which new issues get a card.
+	Board *ProjectBoard
+	// Stages are custom steps of syncing a source, see Phase.
+	Stages []Stage
+	// QuietHours, if set, are when SyncAll holds sources instead of
+	// syncing them, until the quiet hours are over.
+	QuietHours *QuietHours
+	// SortSources, if set, syncs sources of the same severity in the order
+	// of their IDs instead of the order they were passed in, so that which
+	// source files an issue first doesn't depend on how sources were
+	// listed.
+	SortSources bool
+	// MinAPIBudget, if set, is how many github API calls we want to keep in
+	// reserve. While fewer remain, SyncAll only syncs sources of at least
+	// SeverityHigh and defers the rest to later cycles.
+	MinAPIBudget int
+	// CallTimeout, if set, is the deadline for every github request.
+	CallTimeout time.Duration
+	// Normalizer, if set, turns titles into the keys issues are found
+	// by. The finder must use the same one.
+	Normalizer *TitleNormalizer
+	// Namespace, if set, is prefixed to the titles and labels of every
+	// issue we file and to everything we write, and only issues within
+	// the namespace are considered. See CleanupNamespace.
+	Namespace string
+	// Calendar decides which time counts for the syncer's timers, e.g.
+	// weekends and holidays don't. nil counts all time.
+	Calendar *Calendar
+	// Store keeps a record of every issue we filed. By default the records
+	// are only kept in memory.
+	Store *MetadataStore
+	// Security routes sources which are security-sensitive. Without it,
+	// Sync refuses to file them.
+	Security *SecurityRouting
+	// Related, if set, links every new issue to older issues which look
+	// related.
+	Related *RelatedIssues
+	// IDMatching is how sources are recognized in issues and comments,
+	// IDMatchExact if empty.
+	IDMatching IDMatching
+	// Redactor, if set, redacts what must not leak from the bodies of
+	// sources, e.g. IP addresses.
+	Redactor *Redactor
+	// Uploads, if set, handles the attachments of sources, see
+	// AttachmentSource.
+	Uploads *AttachmentUploads
+	// Templates, if set, customize what the syncer writes.
+	Templates *Templates
+	// Audit, if set, records every mutation the syncer makes, see Undo.
+	Audit *AuditLog
+	// History, if set, is sent every mutation and occurrence, see
+	// HistoryRecord.
+	History HistoryExporter
+	// Logger is where the syncer logs. Sync tags lines with the source and
+	// issue they are about, SyncAll with the sync cycle as well.
+	Logger Logger
+
+	// log is Logger, tagged for the cycle and source being synced, if
+	// any. See logger().
+	log    Logger
+	cycles int
+	// cycle and source are what is being synced, for the audit log.
+	cycle  string
+	source string
+	// createdInCycle, capped and capReason track the creation cap for the
+	// cycle.
+	createdInCycle int
+	capped         int
+	capReason      string
+	// member is the title of the grouped source being synced, and
+	// newMemberOf the umbrella issue it's new to, see GroupedSource.
+	member      string
+	newMemberOf int
+	// stageLabels are the labels stages added for the new issue being
+	// filed, see SyncState.Labels.
+	stageLabels []string
+	// fannedOut are the tracking issues of the fanned out sources synced
+	// so far, by ID, see OwnerFanOut.
+	fannedOut map[string]int
+	// running is held while Sync, SyncAll or Stop runs. stopped is set by
+	// Stop, and unfinished are the sources left unsynced since, see Stop.
+	running    sync.Mutex
+	stopLock   sync.Mutex
+	stopped    bool
+	unfinished []string
+	// changes are the issue changes queued by IssueChanged, applied by
+	// SyncAll and Stop.
+	changesLock sync.Mutex
+	changes     []issueChange
+	// held are the sources SyncAll got during quiet hours, by ID.
+	held map[string]IssueSource
+	// syncedTo is the issue the source being synced was recorded on, and
+	// state what its phases found and decided.
+	syncedTo int
+	state    *SyncState
+	// attached is what sourceBody adds about the attachments of the
+	// sources being synced, by ID.
+	attached map[string]string
+	// warm are the issues WarmUp listed, which weren't used yet.
+	warm map[int]*github.MungeObject
+	// prefetched are the recent comments of the last issues bulkFetch got.
+	prefetched map[int]prefetchedComments
+	// health is what the last cycle achieved, see HealthReporter.
+	health cycleHealth
+
+	after func(time.Duration) <-chan time.Time
+	now   func() time.Time
+	rand  Rand
 }
 
 // NewIssueSyncer constructs an issue syncer.
+//
+// Deprecated: use New, whose options can grow without breaking callers.
 func NewIssueSyncer(config *github.Config, finder IssueFinder) *IssueSyncer {
 	return &IssueSyncer{
 		config: config,
 		finder: finder,
 		synced: sets.NewString(),
+
+		comments: newCommentCache(),
+
+		Backoff:      DefaultBackoff,
+		Store:        &MetadataStore{records: map[int]*IssueRecord{}},
+		Logger:       &textLogger{},
+		MuteLabel:    DefaultMuteLabel,
+		MaxWarmUpAge: DefaultMaxWarmUpAge,
+		after:        time.After,
+		now:          time.Now,
+		rand:         globalRand{},
+	}
+}
+
+// SetClock has the syncer, and its finder if it can, tell the time and
+// wait with `clock`.
+func (s *IssueSyncer) SetClock(clock Clock) {
+	s.now, s.after = clock.Now, clock.After
+	if f, ok := s.finder.(interface {
+		SetClock(Clock)
+	}); ok {
+		f.SetClock(clock)
+	}
+}
+
+// SetRand has the backoff jitter come from `r`.
+func (s *IssueSyncer) SetRand(r Rand) {
+	s.rand = r
+}
+
+// order returns `sources` in the order they are synced: the worst problems
+// first, see SortSources.
+func (s *IssueSyncer) order(sources []IssueSource) []IssueSource {
+	if !s.SortSources {
+		return prioritize(sources)
 	}
+	sorted := append([]IssueSource(nil), sources...)
+	sort.Stable(byID(sorted))
+	return prioritize(sorted)
+}
+
+// SyncResult is what SyncAll did about one source.
+type SyncResult struct {
+	// Source is the source's ID, redacted for sensitive sources.
+	Source string
+	// Action is what was decided about the source, if syncing got that
+	// far. It is empty for sources routed by SecurityRouting.
+	Action Decision
+	// Skipped is why a stage skipped the source, if one did.
+	Skipped string
+	// Deferred is set if the source was left to a later cycle, because of
+	// QuietHours or MinAPIBudget, or because the syncer was stopped.
+	Deferred bool
+	// Issue is the issue the source was recorded on, if any.
+	Issue int
+	// Err is why syncing the source failed, see ErrorKind.
+	Err error
+}
+
+// SyncAll syncs every source as one sync cycle: log lines about it are
+// tagged with a cycle ID. Sources which fail are logged and skipped; there
+// is a result for every source, and the returned error only says how many
+// failed. If ctx is done, SyncAll stops and returns ctx.Err(), with results
+// for the sources synced so far. Once the syncer is stopped, SyncAll returns
+// ErrStopped, and the sources it didn't sync are deferred, see Stop.
+func (s *IssueSyncer) SyncAll(ctx context.Context, sources []IssueSource) ([]SyncResult, error) {
+	s.running.Lock()
+	defer s.running.Unlock()
+	if s.isStopped() {
+		return s.leaveUnfinished(sources), ErrStopped
+	}
+	s.applyChanges(ctx)
+	s.cycles++
+	cycle := fmt.Sprintf("%v-%d", s.now().UTC().Format("20060102T150405"), s.cycles)
+	log := s.logger().With("cycle", cycle)
+	s.cycle = cycle
+	defer func() {
+		if err := s.Store.Flush(); err != nil {
+			log.Errorf("Unable to persist the metadata: %v", err)
+		}
+	}()
+	s.createdInCycle, s.capped, s.capReason = 0, 0, ""
+	if s.Taxonomy != nil {
+		// Look the repo's labels up again, in case they changed.
+		s.Taxonomy.known = nil
+	}
+	defer func() { s.cycle = "" }()
+	results := []SyncResult{}
+	if s.QuietHours.Quiet(s.now(), s.Calendar) {
+		s.hold(sources)
+		s.health.recordHeld(len(s.held))
+		metrics.Add("quietCycles", 1)
+		log.Infof("Quiet hours, holding %d sources until they are over", len(s.held))
+		s.health.record(s.now(), 0)
+		for _, source := range sources {
+			results = append(results, SyncResult{Source: s.sourceID(source), Deferred: true})
+		}
+		return results, nil
+	}
+	sources = s.release(sources)
+	s.health.recordHeld(0)
+	log.Debugf("Syncing %d sources", len(sources))
+
+	// Sync the worst problems first, in case we hit a cap or run low on API
+	// calls.
+	sources = s.order(sources)
+	failed, deferred := 0, 0
+	stopped := false
+	for i, source := range sources {
+		if err := ctx.Err(); err != nil {
+			log.Warningf("Stopping with %d sources left to sync: %v", len(sources)-i, err)
+			return results, err
+		}
+		if s.isStopped() {
+			log.Warningf("Stopped with %d sources left to sync", len(sources)-i)
+			results = append(results, s.leaveUnfinished(sources[i:])...)
+			stopped = true
+			break
+		}
+		if severity(source) < SeverityHigh && s.lowOnBudget() {
+			deferred++
+			results = append(results, SyncResult{Source: s.sourceID(source), Deferred: true})
+			continue
+		}
+		result := s.syncWith(ctx, log, source)
+		results = append(results, result)
+		if err := result.Err; err != nil {
+			failed++
+			s.health.recordError(SyncError{At: s.now(), Source: result.Source, Error: err.Error()})
+			l := log.With("source", result.Source)
+			if IsRetryable(err) {
+				l.Warningf("Unable to sync, will try again next cycle: %v", err)
+			} else {
+				l.Errorf("Unable to sync: %v", err)
+			}
+		}
+	}
+	if deferred > 0 {
+		metrics.Add("deferredLowBudget", int64(deferred))
+		log.Warningf("Low on API calls, deferred %d sources to later cycles", deferred)
+	}
+	s.reportCap(ctx)
+	if err := s.CheckRetests(ctx); err != nil {
+		log.Errorf("Unable to check on retests: %v", err)
+	}
+	if err := s.LockClosed(ctx); err != nil {
+		log.Errorf("Unable to lock closed issues: %v", err)
+	}
+	s.health.record(s.now(), failed+deferred)
+	if stopped {
+		return results, ErrStopped
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d sources failed to sync in cycle %v", failed, len(sources), cycle)
+	}
+	return results, nil
+}
+
+// Synced implements Syncer.
+func (s *IssueSyncer) Synced(id string) bool {
+	return s.synced.Has(id)
+}
+
+// sourceID is how the source is identified in logs. IDs of sensitive sources
+// are redacted.
+func (s *IssueSyncer) sourceID(source IssueSource) string {
+	if isSensitive(source) {
+		return redact(source.ID())
+	}
+	return source.ID()
 }
 
 // Sync syncs the issue. It is fine and cheap to call Sync repeatedly for the
-// same source.
-func (s *IssueSyncer) Sync(source IssueSource) error {
-	if s.synced.Has(source.ID()) {
-		return nil
+// same source. Errors caused by github are returned as *APIError; use
+// IsRetryable to tell if the source is worth syncing again later.
+//
+// Sources implementing SensitiveSource are never filed publicly, see
+// SecurityRouting. Like SyncAll, Sync waits for the source being synced, if
+// any, and returns ErrStopped once the syncer is stopped.
+func (s *IssueSyncer) Sync(ctx context.Context, source IssueSource) error {
+	s.running.Lock()
+	defer s.running.Unlock()
+	if s.isStopped() {
+		s.leaveUnfinished([]IssueSource{source})
+		return ErrStopped
 	}
+	err := s.syncWith(ctx, s.logger(), source).Err
+	if flushErr := s.Store.Flush(); flushErr != nil {
+		s.logger().Errorf("Unable to persist the metadata: %v", flushErr)
+	}
+	return err
+}
 
-	found, updatableIssues, err := s.findPreviousIssues(source)
-	if err != nil {
-		return err
+// syncWith syncs the source, logging to `log` tagged with the source.
+func (s *IssueSyncer) syncWith(ctx context.Context, log Logger, source IssueSource) (result SyncResult) {
+	if s.fansOut(source) {
+		return s.fanOut(ctx, log, source)
+	}
+	result.Source = s.sourceID(source)
+	if s.synced.Has(source.ID()) {
+		result.Action = DecisionNone
+		return result
 	}
+	s.source = result.Source
+	s.log = log.With("source", s.source)
+	defer func() { s.log, s.source, s.attached = nil, "", nil }()
 
-	// Close dups if there are multiple open issues
-	if len(updatableIssues) > 1 {
-		obj := updatableIssues[0]
-		if err := s.markAsDups(updatableIssues[1:], *obj.Issue.Number); err != nil {
-			return err
+	if isSensitive(source) {
+		if result.Err = s.syncSensitive(ctx, source); result.Err == nil {
+			s.synced.Insert(source.ID())
 		}
+		return result
+	}
+	original := source
+	if key := groupKey(source); key != "" {
+		s.member = source.Title()
+		defer func() { s.member, s.newMemberOf = "", 0 }()
+		source = &umbrellaSource{source, key}
+	}
+	defer func() { s.syncedTo, s.state = 0, nil }()
+	result.Err = s.sync(ctx, source)
+	if st := s.state; st != nil {
+		result.Action, result.Skipped = st.Decision, st.skipped
+		if st.Issue != nil && st.Issue.Issue.Number != nil && st.Decision != DecisionCreate {
+			result.Issue = *st.Issue.Issue.Number
+		}
+	}
+	if s.syncedTo != 0 {
+		result.Issue = s.syncedTo
 	}
+	if result.Err != nil {
+		return result
+	}
+	if s.newMemberOf != 0 {
+		if err := s.updateUmbrella(ctx, s.newMemberOf); err != nil {
+			s.logger().With("issue", s.newMemberOf).Errorf("Unable to update the umbrella summary: %v", err)
+		}
+	}
+	if s.HistogramDays > 0 && s.syncedTo != 0 {
+		if err := s.updateHistogram(ctx, s.syncedTo); err != nil {
+			s.logger().With("issue", s.syncedTo).Errorf("Unable to update the occurrence histogram: %v", err)
+		}
+	}
+	if s.Culprits != nil && s.syncedTo != 0 {
+		if err := s.blame(ctx, s.syncedTo, original); err != nil {
+			s.logger().With("issue", s.syncedTo).Errorf("Unable to notify the culprit: %v", err)
+		}
+	}
+	if pr, ok := original.(PRSource); ok && s.syncedTo != 0 {
+		if err := s.retest(ctx, s.syncedTo, pr); err != nil {
+			s.logger().With("issue", s.syncedTo).Errorf("Unable to retest #%d: %v", pr.PullRequest(), err)
+		}
+	}
+	return result
+}
 
-	if found {
-		// Don't need to update, we were only here to close the dups.
-		s.synced.Insert(source.ID())
+// sync files or updates a public issue for the source, going through the
+// phases of syncing with Stages in between.
+func (s *IssueSyncer) sync(ctx context.Context, source IssueSource) error {
+	if s.synced.Has(source.ID()) {
 		return nil
 	}
+	st := &SyncState{Source: source}
+	if !internal(source) {
+		s.state = st
+	}
+	for _, phase := range phases {
+		if !internal(source) {
+			if err := s.runStages(ctx, phase, st); err != nil {
+				return err
+			}
+			if st.skipped != "" {
+				s.synced.Insert(source.ID())
+				return nil
+			}
+		}
+		if err := s.runPhase(ctx, phase, st); err != nil {
+			return err
+		}
+	}
+	s.synced.Insert(source.ID())
+	return nil
+}
 
-	// Update an issue if possible.
-	if len(updatableIssues) > 0 {
-		obj := updatableIssues[0]
+// mutate does what was decided about st.Source.
+func (s *IssueSyncer) mutate(ctx context.Context, st *SyncState) error {
+	source, obj := st.Source, st.Issue
+	switch st.Decision {
+	case DecisionNone, DecisionCount, DecisionReact, DecisionMute:
+	default:
+		s.attach(ctx, source)
+	}
+	switch st.Decision {
+	case DecisionNone:
+		s.logger().Debugf("Already recorded, not updating any issue")
+		return nil
+	case DecisionTransfer:
+		if err := s.transfer(ctx, obj, st.org, st.project); err != nil {
+			return err
+		}
+		if st.Found {
+			s.logger().Debugf("Already recorded before the issue was transferred")
+			return nil
+		}
+		r, _ := s.Store.Get(*obj.Issue.Number)
+		return s.syncTransferred(ctx, source, r)
+	case DecisionFollow:
+		return s.syncTransferred(ctx, source, st.transferred)
+	case DecisionUpstream:
+		return s.syncUpstream(ctx, st)
+	case DecisionRollover:
+		created, err := s.rollover(ctx, obj, source)
+		if err != nil {
+			return err
+		}
+		s.finder.Created(s.key(source), created)
+		if f, ok := s.finder.(SyncKeyFinder); ok {
+			f.CreatedSyncKey(s.syncKey(source), created)
+		}
+		s.recordOccurrence(created, func(r *IssueRecord) { r.LastUpdate = s.now() })
+		return nil
+	case DecisionCount:
+		n := *obj.Issue.Number
+		s.logger().With("issue", n).Debugf("Commented less than %v ago, only counting the occurrence", s.MinResyncInterval)
+		s.recordOccurrence(n, func(r *IssueRecord) { r.see(source.ID()) })
+		return nil
+	case DecisionMute:
+		n := *obj.Issue.Number
+		s.logger().With("issue", n).Debugf("Muted with %q, only counting the occurrence", s.MuteLabel)
+		s.recordOccurrence(n, func(r *IssueRecord) { r.see(source.ID()) })
+		return nil
+	case DecisionReact:
+		n := *obj.Issue.Number
+		s.logger().With("issue", n).Debugf("Busy issue, reacting instead of commenting")
+		if err := s.react(ctx, obj); err != nil {
+			return err
+		}
+		s.recordOccurrence(n, func(r *IssueRecord) { r.see(source.ID()) })
+		return nil
+	case DecisionUpdate:
 		// Update the chosen issue
-		if err := s.updateIssue(obj, source); err != nil {
-			return fmt.Errorf("error updating issue %v for %v: %v", *obj.Issue.Number, source.ID(), err)
+		n := *obj.Issue.Number
+		if err := s.updateIssue(ctx, obj, source); err != nil {
+			return err
+		}
+		if _, ok := s.finder.(SyncKeyFinder); ok {
+			if err := s.ensureSyncKey(ctx, obj, source); err != nil {
+				s.logger().With("issue", n).Errorf("Unable to add the sync key: %v", err)
+			}
+		}
+		s.recordOccurrence(n, func(r *IssueRecord) {
+			if r.Title == "" {
+				r.Title = s.title(source)
+			}
+			r.LastUpdate = s.now()
+		})
+		return nil
+	case DecisionReopen:
+		history, err := s.history(ctx, obj)
+		if err != nil {
+			return err
+		}
+		if err := s.reopen(ctx, obj, history); err != nil {
+			return err
 		}
-		s.synced.Insert(source.ID())
+		if err := s.updateIssue(ctx, obj, source); err != nil {
+			return err
+		}
+		s.recordOccurrence(*obj.Issue.Number, func(r *IssueRecord) {})
 		return nil
+	case DecisionCreate:
+		return s.create(ctx, st)
 	}
+	return fmt.Errorf("unknown decision %q", st.Decision)
+}
 
-	// No issue could be updated, create a new issue.
-	n, err := s.createIssue(source)
+// create files a new issue for st.Source.
+func (s *IssueSyncer) create(ctx context.Context, st *SyncState) error {
+	source := st.Source
+	history := ""
+	if st.Issue != nil {
+		var err error
+		if history, err = s.history(ctx, st.Issue); err != nil {
+			return err
+		}
+	}
+	if s.Suspects != nil && st.Decision == DecisionCreate && !internal(source) {
+		if suspects := s.suspectsText(ctx, source); suspects != "" {
+			if history != "" {
+				history += "\n\n"
+			}
+			history += suspects
+		}
+	}
+	for _, note := range st.Notes {
+		if history != "" {
+			history += "\n\n"
+		}
+		history += note
+	}
+	if _, ok := source.(*capSource); !ok {
+		if err := s.checkCreationCap(); err != nil {
+			return err
+		}
+	}
+	s.stageLabels = st.Labels
+	defer func() { s.stageLabels = nil }()
+	n, err := s.createIssue(ctx, source, history)
 	if err != nil {
-		return fmt.Errorf("error making issue for %v: %v", source.ID, err)
+		return err
+	}
+	s.createdInCycle++
+	metrics.Add("created", 1)
+	s.finder.Created(s.key(source), n)
+	if f, ok := s.finder.(SyncKeyFinder); ok {
+		f.CreatedSyncKey(s.syncKey(source), n)
+	}
+	s.recordOccurrence(n, func(r *IssueRecord) {
+		r.Title = s.title(source)
+		r.Labels = s.labels(source)
+		r.Created = s.now()
+		r.LastUpdate = r.Created
+	})
+	if _, ok := source.(*placeholderSource); !ok {
+		// Placeholders all look alike, and must not be linked to the
+		// public issues anyway.
+		s.linkRelated(ctx, n, s.title(source))
 	}
-	s.finder.Created(source.Title(), n)
-	s.synced.Insert(source.ID())
 	return nil
 }
 
+// client returns the config to talk to github with under ctx.
+func (s *IssueSyncer) client(ctx context.Context) *github.Config {
+	return s.config.WithContext(ctx, s.CallTimeout)
+}
+
+// getObject fetches issue `n`, retrying transient errors. Fetches go through
+// the config's HTTP cache, which the other mungers using the config share, so
+// issues which were looked at recently aren't fetched again from github.
+func (s *IssueSyncer) getObject(ctx context.Context, n int) (*github.MungeObject, error) {
+	var obj *github.MungeObject
+	err := s.retry(ctx, fmt.Sprintf("getting object for %v", n), func() (err error) {
+		obj, err = s.client(ctx).GetObject(n)
+		return err
+	})
+	return obj, err
+}
+
+// logger returns the logger for whatever the syncer is doing.
+func (s *IssueSyncer) logger() Logger {
+	if s.log != nil {
+		return s.log
+	}
+	if s.Logger == nil {
+		return &textLogger{}
+	}
+	return s.Logger
+}
+
+// throttled returns true if we may not comment on issue `n` about another
+// occurrence yet, see MinResyncInterval.
+func (s *IssueSyncer) throttled(n int) bool {
+	if s.MinResyncInterval <= 0 || s.EditBody || s.TaskList != nil {
+		return false
+	}
+	r, ok := s.Store.Get(n)
+	return ok && s.now().Sub(r.LastUpdate) < s.MinResyncInterval
+}
+
+// recordOccurrence notes in the store that a source was synced to issue
+// `number`. `fn` may fill in more of the record.
+func (s *IssueSyncer) recordOccurrence(number int, fn func(r *IssueRecord)) {
+	now := s.now()
+	var record IssueRecord
+	err := s.Store.Update(number, func(r *IssueRecord) {
+		r.Occurrences++
+		r.LastOccurrence = now
+		r.countDaily(now)
+		s.syncedTo = number
+		if s.member != "" {
+			if r.Members == nil {
+				r.Members = map[string]time.Time{}
+			}
+			if _, ok := r.Members[s.member]; !ok {
+				r.Members[s.member] = now
+				s.newMemberOf = number
+			}
+		}
+		fn(r)
+		record = *r
+	})
+	if err != nil {
+		s.logger().With("issue", number).Errorf("Unable to record occurrence: %v", err)
+	}
+	s.export(HistoryRecord{
+		Time:        now,
+		Kind:        HistoryOccurrence,
+		Issue:       number,
+		Occurrences: record.Occurrences,
+		Labels:      record.Labels,
+	})
+}
+
 // Look through all issues filed about this item.
 // If foundIn is > 0, then the particular item was found in that issue.
-// All open issues for this item are returned in updatableIssues.
-func (s *IssueSyncer) findPreviousIssues(source IssueSource) (found bool, updatableIssues []*github.MungeObject, err error) {
-	possibleIssues := s.finder.AllIssuesForKey(source.Title())
+// All open issues for this item are returned in updatableIssues, and closed
+// ones in closedIssues.
+func (s *IssueSyncer) findPreviousIssues(ctx context.Context, source IssueSource) (found bool, updatableIssues, closedIssues []*github.MungeObject, err error) {
+	possibleIssues, err := s.previousIssues(source)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	fetched := s.bulkFetch(ctx, possibleIssues)
 	for _, previousIssue := range possibleIssues {
-		obj, err := s.config.GetObject(previousIssue)
-		if err != nil {
-			return false, nil, fmt.Errorf("error getting object for %v: %v", previousIssue, err)
+		obj, ok := fetched[previousIssue]
+		if !ok {
+			obj, ok = s.warmObject(previousIssue)
+		}
+		if !ok {
+			var err error
+			if obj, err = s.getObject(ctx, previousIssue); err != nil {
+				return false, nil, nil, err
+			}
 		}
-		isRecorded, err := s.isRecorded(obj, source)
+		if s.collides(obj, source) {
+			continue
+		}
+		isRecorded, err := s.isRecorded(ctx, obj, source)
 		if err != nil {
-			return false, nil, fmt.Errorf("error checking whether item %v is recorded in issue %v: %v", source.ID(), previousIssue, err)
+			return false, nil, nil, err
 		}
 		if isRecorded {
 			found = true
@@ -138,18 +812,36 @@ func (s *IssueSyncer) findPreviousIssues(source IssueSource) (found bool, updata
 		}
 		if obj.Issue.State != nil && *obj.Issue.State == "open" {
 			updatableIssues = append(updatableIssues, obj)
+		} else {
+			closedIssues = append(closedIssues, obj)
 		}
 	}
-	return found, updatableIssues, nil
+	return found, updatableIssues, closedIssues, nil
+}
+
+func (s *IssueSyncer) issuesForKey(key string) ([]int, error) {
+	if f, ok := s.finder.(CheckedIssueFinder); ok {
+		return f.IssuesForKey(key)
+	}
+	return s.finder.AllIssuesForKey(key), nil
 }
 
 // Close all of the dups.
-func (s *IssueSyncer) markAsDups(dups []*github.MungeObject, of int) error {
+func (s *IssueSyncer) markAsDups(ctx context.Context, dups []*github.MungeObject, of int) error {
 	// Somehow we got duplicate issues all open at once.
 	// Close all of the older ones.
 	for _, dup := range dups {
-		if err := dup.CloseIssuef("This is a duplicate of #%v; closing", of); err != nil {
-			return fmt.Errorf("failed to close %v as a dup of %v: %v", *dup.Issue.Number, of, err)
+		n := *dup.Issue.Number
+		msg := s.text(s.duplicateText(n, of))
+		s.logger().With("issue", n).Infof("Closing as a duplicate of #%v", of)
+		if err := s.writeComment(ctx, fmt.Sprintf("commenting on dup %v of %v", n, of), dup, msg); err != nil {
+			return err
+		}
+		if err := s.closeIssue(ctx, fmt.Sprintf("closing %v as a dup of %v", n, of), dup); err != nil {
+			return err
+		}
+		if err := s.closedAs(n, ClosedDuplicate, of); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -157,21 +849,30 @@ func (s *IssueSyncer) markAsDups(dups []*github.MungeObject, of int) error {
 
 // Search through the body and comments to see if the given item is already
 // mentioned in the given github issue.
-func (s *IssueSyncer) isRecorded(obj *github.MungeObject, source IssueSource) (bool, error) {
+func (s *IssueSyncer) isRecorded(ctx context.Context, obj *github.MungeObject, source IssueSource) (bool, error) {
 	id := source.ID()
-	if obj.Issue.Body != nil && strings.Contains(*obj.Issue.Body, id) {
+	if obj.Issue.Body != nil && (s.recorded(*obj.Issue.Body, id) || recordedInSection(*obj.Issue.Body, id)) {
 		// We already wrote this item
 		return true, nil
 	}
-	comments, err := obj.ListComments()
+	if s.seen(*obj.Issue.Number, id) {
+		return true, nil
+	}
+	if p, ok := s.prefetchedFor(obj); ok && !p.Complete {
+		// We usually find our comment among the recent ones, and
+		// only need all of them if we don't.
+		for _, c := range p.Bodies {
+			if s.recorded(c, id) {
+				return true, nil
+			}
+		}
+	}
+	comments, err := s.commentBodies(ctx, obj)
 	if err != nil {
-		return false, fmt.Errorf("error getting comments for %v: %v", *obj.Issue.Number, err)
+		return false, err
 	}
 	for _, c := range comments {
-		if c.Body == nil {
-			continue
-		}
-		if strings.Contains(*c.Body, id) {
+		if s.recorded(c, id) {
 			// We already wrote this item
 			return true, nil
 		}
@@ -179,36 +880,96 @@ func (s *IssueSyncer) isRecorded(obj *github.MungeObject, source IssueSource) (b
 	return false, nil
 }
 
+// commentBodies returns the bodies of all comments on `obj`, using the cache.
+func (s *IssueSyncer) commentBodies(ctx context.Context, obj *github.MungeObject) ([]string, error) {
+	if p, ok := s.prefetchedFor(obj); ok && p.Complete {
+		return p.Bodies, nil
+	}
+	var comments []string
+	err := s.retry(ctx, fmt.Sprintf("getting comments for %v", *obj.Issue.Number), func() (err error) {
+		comments, err = s.comments.get(*obj.Issue.Number, obj.Issue.UpdatedAt, func(since time.Time) ([]githubapi.IssueComment, error) {
+			if since.IsZero() {
+				return obj.ListComments()
+			}
+			return obj.ListCommentsSince(since)
+		})
+		return err
+	})
+	return comments, err
+}
+
 // updateIssue adds a comment about the item to the github object.
-func (s *IssueSyncer) updateIssue(obj *github.MungeObject, source IssueSource) error {
-	body := source.Body(false)
+func (s *IssueSyncer) updateIssue(ctx context.Context, obj *github.MungeObject, source IssueSource) error {
+	body := s.text(s.recurrenceText(*obj.Issue.Number, s.sourceBody(source, false)))
 	id := source.ID()
 	if !strings.Contains(body, source.ID()) {
 		// prevent making tons of duplicate comments
 		panic(fmt.Errorf("Programmer error: %v does not contain %v!", body, id))
 	}
-	glog.Infof("Updating issue %v with item %v", *obj.Issue.Number, source.ID())
-	return obj.WriteComment(body)
+	if s.EditBody {
+		return s.editIssue(ctx, obj, source)
+	}
+	if s.TaskList != nil {
+		return s.appendTask(ctx, obj, source)
+	}
+	triaged, err := s.triaged(ctx, obj)
+	if err != nil {
+		return err
+	}
+	if triaged {
+		s.logger().With("issue", *obj.Issue.Number).Debugf("Being triaged, editing the body instead of commenting")
+		return s.editIssue(ctx, obj, source)
+	}
+	return s.commentIssue(ctx, obj, source, body)
+}
+
+// commentIssue records an occurrence of the source in a comment on the
+// issue, saying `body`.
+func (s *IssueSyncer) commentIssue(ctx context.Context, obj *github.MungeObject, source IssueSource, body string) error {
+	s.logger().With("issue", *obj.Issue.Number).Infof("Updating issue, it is the oldest open one for %q", s.title(source))
+	if err := s.writeComment(ctx, fmt.Sprintf("updating issue %v for %v", *obj.Issue.Number, source.ID()), obj, body); err != nil {
+		return err
+	}
+	return s.Store.Update(*obj.Issue.Number, func(r *IssueRecord) { r.Comments++ })
 }
 
 // createIssue makes a new issue for the given item. If we know about other
-// issues for the item, then they'll be referenced.
-func (s *IssueSyncer) createIssue(source IssueSource) (issueNumber int, err error) {
-	body := source.Body(true)
+// issues for the item, then they'll be referenced. `history`, if set, is
+// added to the body.
+func (s *IssueSyncer) createIssue(ctx context.Context, source IssueSource, history string) (issueNumber int, err error) {
+	body := s.sourceBody(source, true)
+	if full := Namespaced(s.Namespace, source.Title()); s.title(source) != full {
+		body += "\n\n" + fullTitleText(full)
+	}
+	if history != "" {
+		body += "\n\n" + history
+	}
+	body = s.text(body) + "\n\n" + SyncKeyMarker(s.syncKey(source))
 	id := source.ID()
 	if !strings.Contains(body, source.ID()) {
 		// prevent making tons of duplicate comments
 		panic(fmt.Errorf("Programmer error: %v does not contain %v!", body, id))
 	}
 
-	obj, err := s.config.NewIssue(
-		source.Title(),
-		body,
-		source.Labels(),
-	)
+	labels, err := s.checkLabels(ctx, s.labels(source))
+	if err != nil {
+		return 0, err
+	}
+	var obj *github.MungeObject
+	err = s.retry(ctx, fmt.Sprintf("making issue for %v", id), func() (err error) {
+		obj, err = s.client(ctx).NewIssue(
+			s.title(source),
+			body,
+			labels,
+		)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
-	glog.Infof("Created issue %v:\n%v", *obj.Issue.Number, body)
+	s.audit(AuditCreate, *obj.Issue.Number, s.title(source))
+	s.rememberBody(*obj.Issue.Number, body)
+	s.logger().With("issue", *obj.Issue.Number).Infof("Created issue, no open issue was found for %q:\n%v", s.title(source), body)
+	s.addToBoard(ctx, obj)
 	return *obj.Issue.Number, nil
 }
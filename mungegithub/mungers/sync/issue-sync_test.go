@@ -0,0 +1,50 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottled(t *testing.T) {
+	tests := []struct {
+		name       string
+		interval   time.Duration
+		editBody   bool
+		lastUpdate string
+		expected   bool
+	}{
+		{name: "no interval", lastUpdate: "2016-07-01 11:59"},
+		{name: "recent", interval: 24 * time.Hour, lastUpdate: "2016-07-01 02:00", expected: true},
+		{name: "long ago", interval: 24 * time.Hour, lastUpdate: "2016-06-30 11:00"},
+		{name: "never", interval: 24 * time.Hour},
+		{name: "edit body", interval: 24 * time.Hour, editBody: true, lastUpdate: "2016-07-01 02:00"},
+	}
+	for _, test := range tests {
+		s := NewIssueSyncer(nil, nil)
+		s.now = func() time.Time { return date("2016-07-01 12:00") }
+		s.MinResyncInterval = test.interval
+		s.EditBody = test.editBody
+		if test.lastUpdate != "" {
+			s.Store.Update(1, func(r *IssueRecord) { r.LastUpdate = date(test.lastUpdate) })
+		}
+		if got := s.throttled(1); got != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
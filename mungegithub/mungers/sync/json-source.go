@@ -0,0 +1,142 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// JSONSource is an IssueSource described in JSON, for sources from outside
+// of mungegithub, e.g.:
+//
+//	{"title": "Job foo is failing", "id": "http://ci/foo/123",
+//	 "body": "Failed at step bar.", "labels": ["kind/flake"]}
+type JSONSource struct {
+	Key     string   `json:"title"`
+	Ref     string   `json:"id"`
+	Details string   `json:"body"`
+	Tags    []string `json:"labels"`
+	// Team is the tenant of the source, see MultiSyncer.
+	Team string `json:"tenant,omitempty"`
+	// Group is the source's GroupKey.
+	Group string `json:"group,omitempty"`
+	// Stable is the source's SyncKey.
+	Stable string `json:"key,omitempty"`
+	// PR and Context are the pull request and the status context of the
+	// job, if it ran on one, see PRSource.
+	PR      int    `json:"pr,omitempty"`
+	Context string `json:"context,omitempty"`
+	// At is when the source first occurred, see TimedSource.
+	At time.Time `json:"at,omitempty"`
+	// Files are the source's attachments, see AttachmentSource.
+	Files []Attachment `json:"attachments,omitempty"`
+	// Path is the file the source failed in, see FileSource.
+	Path string `json:"file,omitempty"`
+	// Scopes are the labels of the source's owners, see OwnedSource.
+	Scopes []string `json:"owners,omitempty"`
+}
+
+// Title implements IssueSource.
+func (j *JSONSource) Title() string { return j.Key }
+
+// ID implements IssueSource.
+func (j *JSONSource) ID() string { return j.Ref }
+
+// Body implements IssueSource. The ID is added if the body doesn't mention
+// it, since the syncer finds sources in issues by their ID.
+func (j *JSONSource) Body(newIssue bool) string {
+	if strings.Contains(j.Details, j.Ref) {
+		return j.Details
+	}
+	if j.Details == "" {
+		return j.Ref
+	}
+	return j.Details + "\n\n" + j.Ref
+}
+
+// Labels implements IssueSource.
+func (j *JSONSource) Labels() []string { return j.Tags }
+
+// Tenant implements TenantSource.
+func (j *JSONSource) Tenant() string { return j.Team }
+
+// SyncKey implements KeyedSource.
+func (j *JSONSource) SyncKey() string { return j.Stable }
+
+// GroupKey implements GroupedSource.
+func (j *JSONSource) GroupKey() string { return j.Group }
+
+// PullRequest implements PRSource.
+func (j *JSONSource) PullRequest() int { return j.PR }
+
+// StatusContext implements PRSource.
+func (j *JSONSource) StatusContext() string { return j.Context }
+
+// OccurredAt implements TimedSource.
+func (j *JSONSource) OccurredAt() time.Time { return j.At }
+
+// Attachments implements AttachmentSource.
+func (j *JSONSource) Attachments() []Attachment { return j.Files }
+
+// File implements FileSource.
+func (j *JSONSource) File() string { return j.Path }
+
+// Owners implements OwnedSource.
+func (j *JSONSource) Owners() []string { return j.Scopes }
+
+// Validate returns an error if the source can't be synced.
+func (j *JSONSource) Validate() error {
+	if strings.TrimSpace(j.Key) == "" {
+		return &ValidationError{Source: j.Ref, Reason: "no title"}
+	}
+	if strings.TrimSpace(j.Ref) == "" {
+		return &ValidationError{Reason: fmt.Sprintf("source %q has no id", j.Key)}
+	}
+	return nil
+}
+
+// ReadJSONSources reads a JSONSource, or a list of them, from `r`.
+func ReadJSONSources(r io.Reader) ([]*JSONSource, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	sources := []*JSONSource{}
+	if len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &sources)
+	} else {
+		source := &JSONSource{}
+		err = json.Unmarshal(data, source)
+		sources = append(sources, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if err := source.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return sources, nil
+}
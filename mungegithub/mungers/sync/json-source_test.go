@@ -0,0 +1,82 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadJSONSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "one",
+			input:    `{"title": "Job foo is failing", "id": "http://ci/foo/1", "labels": ["kind/flake"]}`,
+			expected: []string{"http://ci/foo/1"},
+		},
+		{
+			name:     "list",
+			input:    ` [{"title": "a", "id": "1"}, {"title": "b", "id": "2"}]`,
+			expected: []string{"1", "2"},
+		},
+		{
+			name:  "no id",
+			input: `{"title": "a"}`,
+			err:   true,
+		},
+		{
+			name:  "garbage",
+			input: `{"title": `,
+			err:   true,
+		},
+	}
+	for _, test := range tests {
+		sources, err := ReadJSONSources(strings.NewReader(test.input))
+		if (err != nil) != test.err {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		ids := []string{}
+		for _, s := range sources {
+			ids = append(ids, s.ID())
+		}
+		if strings.Join(ids, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, ids)
+		}
+	}
+}
+
+func TestJSONSourceBody(t *testing.T) {
+	tests := []struct {
+		body, expected string
+	}{
+		{"", "http://ci/1"},
+		{"Failed at http://ci/1.", "Failed at http://ci/1."},
+		{"Failed.", "Failed.\n\nhttp://ci/1"},
+	}
+	for _, test := range tests {
+		s := &JSONSource{Key: "a", Ref: "http://ci/1", Details: test.body}
+		if got := s.Body(true); got != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, got)
+		}
+	}
+}
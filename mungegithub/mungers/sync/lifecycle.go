@@ -0,0 +1,193 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+const businessDay = 24 * time.Hour
+
+// Lifecycle moves the issues we filed through active, stale and rotten
+// states, and closes them at the end, based on how long it has been since
+// the source last occurred and since a human last commented. Durations are
+// counted in business time, see IssueSyncer.Calendar.
+type Lifecycle struct {
+	// Labels for the states.
+	ActiveLabel string
+	StaleLabel  string
+	RottenLabel string
+
+	// StaleAfter, RottenAfter and CloseAfter are how long an issue must
+	// have been idle to become stale, rotten and closed.
+	StaleAfter  time.Duration
+	RottenAfter time.Duration
+	CloseAfter  time.Duration
+	// Close, if set, decides when issues are closed instead of
+	// CloseAfter. Issues it leaves open stay rotten.
+	Close CloseCondition
+
+	// Bots are the logins whose comments are not human activity,
+	// including the syncer's own.
+	Bots sets.String
+}
+
+// NewLifecycle returns a Lifecycle with the default labels and durations.
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{
+		ActiveLabel: "lifecycle/active",
+		StaleLabel:  "lifecycle/stale",
+		RottenLabel: "lifecycle/rotten",
+		StaleAfter:  10 * businessDay,
+		RottenAfter: 20 * businessDay,
+		CloseAfter:  30 * businessDay,
+		Bots:        sets.NewString(),
+	}
+}
+
+// state returns the label for an issue idle for `idle`, or "" if it should be
+// closed.
+func (l *Lifecycle) state(idle time.Duration) string {
+	switch {
+	case idle < l.StaleAfter:
+		return l.ActiveLabel
+	case idle < l.RottenAfter:
+		return l.StaleLabel
+	case idle < l.CloseAfter:
+		return l.RottenLabel
+	}
+	return ""
+}
+
+// UpdateLifecycle applies the lifecycle to every open issue in the store.
+func (s *IssueSyncer) UpdateLifecycle(ctx context.Context, l *Lifecycle) error {
+	for _, r := range s.Store.List() {
+		if r.Closed || r.Created.IsZero() {
+			continue
+		}
+		if err := s.updateLifecycle(ctx, l, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *IssueSyncer) updateLifecycle(ctx context.Context, l *Lifecycle, r IssueRecord) error {
+	obj, err := s.getObject(ctx, r.Number)
+	if err != nil {
+		return err
+	}
+	if obj.Issue.State != nil && *obj.Issue.State == "closed" {
+		return s.noticeClosed(ctx, r.Number)
+	}
+
+	human, err := s.lastHumanActivity(ctx, l, obj, r)
+	if err != nil {
+		return err
+	}
+	if human.After(r.LastHumanActivity) {
+		if err := s.Store.Update(r.Number, func(r *IssueRecord) { r.LastHumanActivity = human }); err != nil {
+			return err
+		}
+	}
+	last := r.Created
+	if r.LastOccurrence.After(last) {
+		last = r.LastOccurrence
+	}
+	candidate := &CloseCandidate{Record: r, Issue: obj, SinceOccurrence: s.Calendar.Elapsed(last, s.now())}
+	if human.After(last) {
+		last = human
+	}
+	idle := s.Calendar.Elapsed(last, s.now())
+	candidate.Idle = idle
+	state := l.state(idle)
+
+	condition := l.Close
+	if condition == nil {
+		condition = IdleFor(l.CloseAfter)
+	}
+	shouldClose, reason, err := condition.ShouldClose(ctx, candidate)
+	if err != nil {
+		return err
+	}
+	if shouldClose {
+		msg := reason
+		if msg == "" {
+			msg = s.staleCloseText(r.Number, int(idle/businessDay))
+		}
+		if err := s.writeComment(ctx, fmt.Sprintf("commenting on rotten issue %v", r.Number), obj, s.text(msg)); err != nil {
+			return err
+		}
+		if err := s.closeIssue(ctx, fmt.Sprintf("closing rotten issue %v", r.Number), obj); err != nil {
+			return err
+		}
+		if err := s.noticeClosed(ctx, r.Number); err != nil {
+			return err
+		}
+		return s.closedAs(r.Number, ClosedStale, 0)
+	}
+	if state == "" {
+		state = l.RottenLabel
+	}
+
+	label := Namespaced(s.Namespace, state)
+	if obj.HasLabel(label) {
+		return nil
+	}
+	for _, other := range []string{l.ActiveLabel, l.StaleLabel, l.RottenLabel} {
+		other = Namespaced(s.Namespace, other)
+		if other == label || !obj.HasLabel(other) {
+			continue
+		}
+		if err := s.removeLabel(ctx, obj, other); err != nil {
+			return err
+		}
+	}
+	s.logger().With("issue", r.Number).Infof("Marking as %v", label)
+	return s.addLabel(ctx, obj, label)
+}
+
+// lastHumanActivity returns when someone who isn't a bot last commented on
+// the issue, looking only at comments since the last time we checked.
+func (s *IssueSyncer) lastHumanActivity(ctx context.Context, l *Lifecycle, obj *github.MungeObject, r IssueRecord) (time.Time, error) {
+	last := r.LastHumanActivity
+	since := last
+	if since.IsZero() {
+		since = r.Created
+	}
+	err := s.retry(ctx, fmt.Sprintf("getting comments for %v", r.Number), func() error {
+		comments, err := obj.ListCommentsSince(since)
+		if err != nil {
+			return err
+		}
+		for _, c := range comments {
+			if c.User == nil || c.User.Login == nil || l.Bots.Has(*c.User.Login) || c.CreatedAt == nil {
+				continue
+			}
+			if c.CreatedAt.After(last) {
+				last = *c.CreatedAt
+			}
+		}
+		return nil
+	})
+	return last, err
+}
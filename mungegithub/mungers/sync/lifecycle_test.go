@@ -0,0 +1,132 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestLifecycleState(t *testing.T) {
+	l := NewLifecycle()
+	tests := []struct {
+		idle     time.Duration
+		expected string
+	}{
+		{0, "lifecycle/active"},
+		{10*businessDay - time.Second, "lifecycle/active"},
+		{10 * businessDay, "lifecycle/stale"},
+		{25 * businessDay, "lifecycle/rotten"},
+		{30 * businessDay, ""},
+	}
+	for _, test := range tests {
+		if got := l.state(test.idle); got != test.expected {
+			t.Errorf("idle for %v: expected %q, got %q", test.idle, test.expected, got)
+		}
+	}
+}
+
+// lifecycleLabels returns the lifecycle labels of issue `n`.
+func lifecycleLabels(tracker *synctesting.Tracker, n int) []string {
+	labels := []string{}
+	for _, issue := range tracker.Issues() {
+		if *issue.Number != n {
+			continue
+		}
+		for _, l := range issue.Labels {
+			if *l.Name != "kind/flake" {
+				labels = append(labels, *l.Name)
+			}
+		}
+	}
+	return labels
+}
+
+func TestUpdateLifecycle(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	clock := NewFakeClock(date("2016-07-01 12:00"))
+	tracker.Now = clock.Now
+	s := newClockedSyncer(t, tracker, clock)
+	l := NewLifecycle()
+	l.Bots.Insert(tracker.Login)
+	ctx := context.Background()
+	if err := s.Sync(ctx, &JSONSource{Key: "TestFoo", Ref: "foo-1", Tags: []string{"kind/flake"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n := tracker.OpenIssues("TestFoo")[0]
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		comment string
+		labels  []string
+	}{
+		{name: "new", labels: []string{"lifecycle/active"}},
+		{name: "idle", advance: 12 * businessDay, labels: []string{"lifecycle/stale"}},
+		{name: "bot comment", advance: businessDay, comment: tracker.Login, labels: []string{"lifecycle/stale"}},
+		{name: "idle longer", advance: 8 * businessDay, labels: []string{"lifecycle/rotten"}},
+		// Human activity starts the clock over.
+		{name: "human comment", advance: businessDay, comment: "alice", labels: []string{"lifecycle/active"}},
+		{name: "idle again", advance: 25 * businessDay, labels: []string{"lifecycle/rotten"}},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		if step.comment != "" {
+			tracker.AddComment(n, step.comment, "any news?")
+		}
+		if err := s.UpdateLifecycle(ctx, l); err != nil {
+			t.Fatalf("%v: unexpected error: %v", step.name, err)
+		}
+		if labels := lifecycleLabels(tracker, n); !reflect.DeepEqual(labels, step.labels) {
+			t.Errorf("%v: expected labels %v, got %v", step.name, step.labels, labels)
+		}
+		if len(tracker.OpenIssues("TestFoo")) != 1 {
+			t.Fatalf("%v: issue closed too early", step.name)
+		}
+	}
+
+	// Closed once idle for CloseAfter since the human comment.
+	clock.Advance(5 * businessDay)
+	if err := s.UpdateLifecycle(ctx, l); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if open := tracker.OpenIssues("TestFoo"); len(open) != 0 {
+		t.Fatalf("expected the rotten issue to be closed, got %v open", open)
+	}
+	if r, _ := s.Store.Get(n); !r.Closed || r.ClosedAs != ClosedStale {
+		t.Errorf("expected the issue to be recorded as closed stale, got %+v", r)
+	}
+	comments := tracker.Comments(n)
+	if last := comments[len(comments)-1]; !strings.Contains(last, "30") {
+		t.Errorf("expected a comment about the idle days, got %q", last)
+	}
+
+	// Closed issues are left alone.
+	clock.Advance(businessDay)
+	if err := s.UpdateLifecycle(ctx, l); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := len(comments), len(tracker.Comments(n)); e != a {
+		t.Errorf("expected no more comments on the closed issue, got %d", a-e)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Why the syncer closed an issue, see IssueRecord.ClosedAs.
+const (
+	ClosedDuplicate = "duplicate"
+	ClosedStale     = "stale"
+)
+
+// ClosedLocking locks the conversations of issues the syncer closed as
+// duplicates or as stale, once a grace period has passed, so that comments
+// don't end up on dead issues nobody watches. Before locking, it points to
+// where the conversation continues: the issue a duplicate was closed in
+// favor of, or the open issue filed for the same title since.
+type ClosedLocking struct {
+	// After is the grace period, in business time (see
+	// IssueSyncer.Calendar), during which the issue stays open for
+	// comments, e.g. from someone who disagrees with closing it.
+	After time.Duration
+	// Reason is github's lock reason, "resolved" if empty.
+	Reason string
+}
+
+// NewClosedLocking returns a ClosedLocking with a grace period of a week.
+func NewClosedLocking() *ClosedLocking {
+	return &ClosedLocking{After: 5 * businessDay, Reason: "resolved"}
+}
+
+// closedAs records that we closed issue `n`, as a duplicate of `of` or as
+// stale.
+func (s *IssueSyncer) closedAs(n int, as string, of int) error {
+	return s.Store.Update(n, func(r *IssueRecord) {
+		if !r.Closed {
+			r.Closed, r.ClosedAt = true, s.now()
+		}
+		r.ClosedAs, r.DuplicateOf = as, of
+	})
+}
+
+// LockClosed locks the issues we closed whose grace period (see
+// ClosedLocking) is over. Issues which were reopened since are left alone.
+func (s *IssueSyncer) LockClosed(ctx context.Context) error {
+	if s.Locking == nil {
+		return nil
+	}
+	for _, r := range s.Store.List() {
+		if !r.Closed || r.ClosedAs == "" || r.Locked || s.Calendar.Elapsed(r.ClosedAt, s.now()) < s.Locking.After {
+			continue
+		}
+		if err := s.lockClosed(ctx, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *IssueSyncer) lockClosed(ctx context.Context, r IssueRecord) error {
+	log := s.logger().With("issue", r.Number)
+	obj, err := s.getObject(ctx, r.Number)
+	if err != nil {
+		return err
+	}
+	if obj.Issue.State != nil && *obj.Issue.State != "closed" {
+		log.Debugf("Reopened, not locking")
+		return s.Store.Update(r.Number, func(r *IssueRecord) {
+			r.Closed, r.ClosedAs, r.DuplicateOf = false, "", 0
+		})
+	}
+	of := r.DuplicateOf
+	if of == 0 {
+		of = s.successor(r)
+	}
+	if err := s.writeComment(ctx, fmt.Sprintf("pointing from locked %v to %v", r.Number, of), obj, s.text(s.lockText(r.Number, of))); err != nil {
+		return err
+	}
+	reason := s.Locking.Reason
+	if reason == "" {
+		reason = "resolved"
+	}
+	if err := s.retry(ctx, fmt.Sprintf("locking %v", r.Number), func() error { return obj.LockIssue(reason) }); err != nil {
+		return err
+	}
+	s.audit(AuditLock, r.Number, reason)
+	log.Infof("Locked closed issue, pointing to #%d", of)
+	return s.Store.Update(r.Number, func(r *IssueRecord) { r.Locked = true })
+}
+
+// successor returns the newest open issue we filed with the same title as
+// the closed issue `r`, or 0 if there is none.
+func (s *IssueSyncer) successor(r IssueRecord) int {
+	successor := 0
+	for _, other := range s.Store.List() {
+		if !other.Closed && other.Number > r.Number && other.Title != "" && other.Title == r.Title {
+			successor = other.Number
+		}
+	}
+	return successor
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestLockClosed(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.Locking = &ClosedLocking{After: time.Hour}
+	now := date("2016-07-01 12:00")
+	s.now = func() time.Time { return now }
+
+	dups := tracker.AddDuplicates("TestFoo", "Failed", 2)
+	if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: "foo-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r, _ := s.Store.Get(dups[1]); r.ClosedAs != ClosedDuplicate || r.DuplicateOf != dups[0] {
+		t.Fatalf("expected #%d to be recorded as a duplicate of #%d, got %+v", dups[1], dups[0], r)
+	}
+	// A stale issue which was filed again, and one which was reopened.
+	stale := tracker.AddIssue("TestBar", "Failed")
+	tracker.CloseIssue(stale)
+	again := tracker.AddIssue("TestBar", "Failed again")
+	reopened := tracker.AddIssue("TestBaz", "Failed")
+	for _, r := range []IssueRecord{
+		{Number: stale, Title: "TestBar", Closed: true, ClosedAt: now, ClosedAs: ClosedStale},
+		{Number: again, Title: "TestBar"},
+		{Number: reopened, Title: "TestBaz", Closed: true, ClosedAt: now, ClosedAs: ClosedStale},
+	} {
+		r := r
+		s.Store.Update(r.Number, func(rec *IssueRecord) { *rec = r })
+	}
+
+	if err := s.LockClosed(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if locked, _ := tracker.Locked(dups[1]); locked {
+		t.Errorf("expected no issue to be locked during the grace period")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err := s.LockClosed(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, test := range []struct {
+		number  int
+		pointer string
+	}{
+		{dups[1], fmt.Sprintf("Please continue in #%d.", dups[0])},
+		{stale, fmt.Sprintf("Please continue in #%d.", again)},
+	} {
+		if locked, reason := tracker.Locked(test.number); !locked || reason != "resolved" {
+			t.Errorf("expected #%d to be locked as resolved, got %v %q", test.number, locked, reason)
+		}
+		comments := tracker.Comments(test.number)
+		if len(comments) == 0 || !strings.HasPrefix(comments[len(comments)-1], test.pointer) {
+			t.Errorf("expected #%d to point on with %q, got %q", test.number, test.pointer, comments)
+		}
+		if r, _ := s.Store.Get(test.number); !r.Locked {
+			t.Errorf("expected #%d to be recorded as locked", test.number)
+		}
+	}
+	if locked, _ := tracker.Locked(reopened); locked {
+		t.Errorf("expected the reopened issue not to be locked")
+	}
+	if r, _ := s.Store.Get(reopened); r.Closed || r.ClosedAs != "" {
+		t.Errorf("expected the reopened issue to be recorded as open, got %+v", r)
+	}
+}
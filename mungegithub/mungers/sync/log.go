@@ -0,0 +1,176 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Logger is how the syncer logs. Lines are tagged with fields, e.g. the
+// sync cycle, the source and the issue they are about, so that a long run
+// can be searched for everything that happened to one source or issue.
+type Logger interface {
+	// With returns a logger which tags every line with key=value as well.
+	With(key string, value interface{}) Logger
+	// Debugf only logs at --v=2 and up.
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Log formats understood by NewLogger.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// NewLogger returns a logger for `format`. Text lines go to glog; JSON lines,
+// one object per line, go to stderr.
+func NewLogger(format string) (Logger, error) {
+	switch format {
+	case "", LogFormatText:
+		return &textLogger{}, nil
+	case LogFormatJSON:
+		return &jsonLogger{out: &lockedWriter{w: os.Stderr}, now: time.Now}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q, expected %q or %q", format, LogFormatText, LogFormatJSON)
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+func withField(fields []field, key string, value interface{}) []field {
+	if s, ok := value.(string); ok {
+		// Source IDs often end in a newline.
+		value = strings.TrimSpace(s)
+	}
+	out := make([]field, 0, len(fields)+1)
+	for _, f := range fields {
+		if f.key != key {
+			out = append(out, f)
+		}
+	}
+	return append(out, field{key, value})
+}
+
+// textLogger logs to glog, prefixing lines with their fields.
+type textLogger struct {
+	fields []field
+}
+
+func (l *textLogger) With(key string, value interface{}) Logger {
+	return &textLogger{fields: withField(l.fields, key, value)}
+}
+
+func (l *textLogger) format(format string, args []interface{}) string {
+	msg := fmt.Sprintf(format, args...)
+	if len(l.fields) == 0 {
+		return msg
+	}
+	tags := []string{}
+	for _, f := range l.fields {
+		tags = append(tags, fmt.Sprintf("%v=%v", f.key, f.value))
+	}
+	return fmt.Sprintf("[%v] %v", strings.Join(tags, " "), msg)
+}
+
+func (l *textLogger) Debugf(format string, args ...interface{}) {
+	if glog.V(2) {
+		glog.InfoDepth(1, l.format(format, args))
+	}
+}
+
+func (l *textLogger) Infof(format string, args ...interface{}) {
+	glog.InfoDepth(1, l.format(format, args))
+}
+
+func (l *textLogger) Warningf(format string, args ...interface{}) {
+	glog.WarningDepth(1, l.format(format, args))
+}
+
+func (l *textLogger) Errorf(format string, args ...interface{}) {
+	glog.ErrorDepth(1, l.format(format, args))
+}
+
+// jsonLogger writes a JSON object per line, with the fields as keys next
+// to "time", "level" and "msg".
+type jsonLogger struct {
+	out    io.Writer
+	fields []field
+	now    func() time.Time
+}
+
+func (l *jsonLogger) With(key string, value interface{}) Logger {
+	return &jsonLogger{out: l.out, fields: withField(l.fields, key, value), now: l.now}
+}
+
+func (l *jsonLogger) log(level, format string, args []interface{}) {
+	line := map[string]interface{}{}
+	for _, f := range l.fields {
+		line[f.key] = f.value
+	}
+	line["time"] = l.now().UTC().Format(time.RFC3339Nano)
+	line["level"] = level
+	line["msg"] = fmt.Sprintf(format, args...)
+	data, err := json.Marshal(line)
+	if err != nil {
+		glog.Errorf("Unable to marshal log line %v: %v", line, err)
+		return
+	}
+	l.out.Write(append(data, '\n'))
+}
+
+func (l *jsonLogger) Debugf(format string, args ...interface{}) {
+	if glog.V(2) {
+		l.log("debug", format, args)
+	}
+}
+
+func (l *jsonLogger) Infof(format string, args ...interface{}) {
+	l.log("info", format, args)
+}
+
+func (l *jsonLogger) Warningf(format string, args ...interface{}) {
+	l.log("warning", format, args)
+}
+
+func (l *jsonLogger) Errorf(format string, args ...interface{}) {
+	l.log("error", format, args)
+}
+
+// lockedWriter keeps lines from concurrent loggers apart.
+type lockedWriter struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.w.Write(p)
+}
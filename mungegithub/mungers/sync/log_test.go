@@ -0,0 +1,72 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+	var l Logger = &jsonLogger{out: out, now: func() time.Time { return date("2016-07-01 12:00") }}
+	l = l.With("cycle", "c1").With("source", "gs://bucket/job/1\n")
+	l.With("issue", 12).Infof("Created issue for %q", "TestFoo")
+	l.With("source", "gs://bucket/job/2").Errorf("failed")
+
+	dec := json.NewDecoder(out)
+	expected := []map[string]interface{}{
+		{
+			"time":   "2016-07-01T12:00:00Z",
+			"level":  "info",
+			"msg":    `Created issue for "TestFoo"`,
+			"cycle":  "c1",
+			"source": "gs://bucket/job/1",
+			"issue":  float64(12),
+		},
+		{
+			"time":   "2016-07-01T12:00:00Z",
+			"level":  "error",
+			"msg":    "failed",
+			"cycle":  "c1",
+			"source": "gs://bucket/job/2",
+		},
+	}
+	for i, e := range expected {
+		got := map[string]interface{}{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("line %d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, e) {
+			t.Errorf("line %d: expected %v, got %v", i, e, got)
+		}
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	for _, format := range []string{"", LogFormatText, LogFormatJSON} {
+		if _, err := NewLogger(format); err != nil {
+			t.Errorf("%q: unexpected error: %v", format, err)
+		}
+	}
+	if _, err := NewLogger("xml"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// IssueRecord is what the syncer remembers about an issue it filed.
+type IssueRecord struct {
+	Number int
+	Title  string
+	Labels []string `json:",omitempty"`
+	// Created is when we filed the issue.
+	Created time.Time
+	// Closed is set once we notice the issue was closed, at ClosedAt.
+	Closed   bool      `json:",omitempty"`
+	ClosedAt time.Time `json:",omitempty"`
+	// ClosedAs is why we closed the issue, if we did: ClosedDuplicate (of
+	// DuplicateOf) or ClosedStale. Locked is set once we locked it, see
+	// ClosedLocking.
+	ClosedAs    string `json:",omitempty"`
+	DuplicateOf int    `json:",omitempty"`
+	Locked      bool   `json:",omitempty"`
+	// Occurrences counts the sources synced to the issue, and Daily the
+	// recent ones by day, see FlakeReport.
+	Occurrences    int
+	LastOccurrence time.Time
+	Daily          map[string]int `json:",omitempty"`
+	// Members are the titles of the sources synced to an umbrella issue,
+	// with when they were first seen, see GroupedSource.
+	Members map[string]time.Time `json:",omitempty"`
+	// Children are the issues per owner of a tracking issue, by owner
+	// label, and Parent is the tracking issue of a child issue, see
+	// IssueSyncer.OwnerFanOut.
+	Children map[string]int `json:",omitempty"`
+	Parent   int            `json:",omitempty"`
+	// Seen are the IDs of sources which were only recorded here, not on
+	// github, see IssueSyncer.ReactAfter. Only the last maxSeen are kept.
+	Seen []string `json:",omitempty"`
+	// Comments counts the comments we made about occurrences. Once there
+	// are too many, the issue is continued in ContinuedIn, whose Part is
+	// one more and which is ContinuedFrom this issue, see MaxComments.
+	Comments      int `json:",omitempty"`
+	Part          int `json:",omitempty"`
+	ContinuedFrom int `json:",omitempty"`
+	ContinuedIn   int `json:",omitempty"`
+	// TransferredTo is the repo ("org/project") the issue was moved to,
+	// as issue TransferredAs, see TransferLabelPrefix.
+	TransferredTo string `json:",omitempty"`
+	TransferredAs int    `json:",omitempty"`
+	// TaskPinged is when we last commented about unchecked occurrences in
+	// the issue's task list, see TaskList.
+	TaskPinged time.Time `json:",omitempty"`
+	// Retests are the reruns we asked for on pull requests which failed
+	// with the issue's flake, see RetestPolicy.
+	Retests []Retest `json:",omitempty"`
+	// Culprits are the pull requests which likely introduced the issue,
+	// which we commented on, see CulpritFinder.
+	Culprits []int `json:",omitempty"`
+	// Feedback are the labels humans added which SourceFeedback was told
+	// about.
+	Feedback []string `json:",omitempty"`
+	// BodyHash and SectionHash are hashes of the body we last wrote, and
+	// of its sync section, to notice edits by others, see editIssue.
+	BodyHash    string `json:",omitempty"`
+	SectionHash string `json:",omitempty"`
+	// LastUpdate is when we last filed or commented about an occurrence.
+	LastUpdate time.Time `json:",omitempty"`
+	// LastHumanActivity is when someone other than a bot last commented.
+	LastHumanActivity time.Time `json:",omitempty"`
+
+	// Triaged is set once humans have looked at the issue, and TriagedAt
+	// is when they last showed it, see TriageSignals.
+	Triaged   bool      `json:",omitempty"`
+	TriagedAt time.Time `json:",omitempty"`
+	// SLOPriority is the priority label whose SLO the untriaged issue is
+	// held to since SLOStart, and SLOBreached is set once it missed it,
+	// see SLOPolicy.
+	SLOPriority string    `json:",omitempty"`
+	SLOStart    time.Time `json:",omitempty"`
+	SLOBreached bool      `json:",omitempty"`
+	// InheritedPriority is the priority the issue was raised to because
+	// release tracking issue InheritedFrom references it, see
+	// PriorityInheritance.
+	InheritedPriority string `json:",omitempty"`
+	InheritedFrom     int    `json:",omitempty"`
+	// Escalations is how many escalation steps were taken.
+	Escalations    int       `json:",omitempty"`
+	LastEscalation time.Time `json:",omitempty"`
+
+	// ProjectCard is the issue's card on the project board, and
+	// ProjectColumn the column we last put it in.
+	ProjectCard   int    `json:",omitempty"`
+	ProjectColumn string `json:",omitempty"`
+}
+
+// maxSeen is how many IDs IssueRecord.Seen keeps. A source seen longer ago
+// than that is recorded again if it comes back.
+const maxSeen = 500
+
+// see adds `id` to Seen, forgetting the oldest IDs past maxSeen.
+func (r *IssueRecord) see(id string) {
+	for _, seen := range r.Seen {
+		if seen == id {
+			return
+		}
+	}
+	r.Seen = append(r.Seen, id)
+	if len(r.Seen) > maxSeen {
+		r.Seen = append([]string{}, r.Seen[len(r.Seen)-maxSeen:]...)
+	}
+}
+
+// MetadataStore keeps an IssueRecord for every issue the syncer filed. If it
+// has a path, the records are written there by Flush, so they survive
+// restarts.
+type MetadataStore struct {
+	path string
+
+	lock     sync.RWMutex
+	records  map[int]*IssueRecord
+	warmedUp time.Time
+	// dirty is set when there are changes Flush didn't write yet.
+	dirty bool
+}
+
+// metadataFile is what a MetadataStore writes. Stores written before
+// WarmedUp was kept are a bare list of records.
+type metadataFile struct {
+	WarmedUp time.Time `json:",omitempty"`
+	Records  []IssueRecord
+}
+
+// NewMetadataStore constructs a store, loading `path` if it exists. An empty
+// path keeps the records only in memory.
+func NewMetadataStore(path string) (*MetadataStore, error) {
+	m := &MetadataStore{
+		path:    path,
+		records: map[int]*IssueRecord{},
+	}
+	if path == "" {
+		return m, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+	file := metadataFile{}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err = json.Unmarshal(data, &file.Records)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range file.Records {
+		m.records[file.Records[i].Number] = &file.Records[i]
+	}
+	m.warmedUp = file.WarmedUp
+	return m, nil
+}
+
+// Get returns a copy of the record for issue `number`.
+func (m *MetadataStore) Get(number int) (IssueRecord, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	r, ok := m.records[number]
+	if !ok {
+		return IssueRecord{}, false
+	}
+	return *r, true
+}
+
+// List returns copies of all records, ordered by issue number.
+func (m *MetadataStore) List() []IssueRecord {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	out := make([]IssueRecord, 0, len(m.records))
+	for _, r := range m.records {
+		out = append(out, *r)
+	}
+	sort.Sort(byIssueNumber(out))
+	return out
+}
+
+// Update calls fn with the record for issue `number` (a new one if there is
+// none yet). The result is saved by the next Flush.
+func (m *MetadataStore) Update(number int, fn func(r *IssueRecord)) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	r, ok := m.records[number]
+	if !ok {
+		r = &IssueRecord{Number: number}
+		m.records[number] = r
+	}
+	fn(r)
+	m.dirty = true
+	return nil
+}
+
+// WarmedUp returns when IssueSyncer.WarmUp last listed comments, see
+// SetWarmedUp.
+func (m *MetadataStore) WarmedUp() time.Time {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.warmedUp
+}
+
+// SetWarmedUp records that IssueSyncer.WarmUp listed comments at `t`, so
+// that the next warm-up after a restart only lists the comments since. It
+// is saved by the next Flush.
+func (m *MetadataStore) SetWarmedUp(t time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.warmedUp = t
+	m.dirty = true
+	return nil
+}
+
+// Flush writes all records to disk if they changed since the last Flush.
+// The syncer flushes at the end of every sync cycle, and when it's stopped.
+func (m *MetadataStore) Flush() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !m.dirty {
+		return nil
+	}
+	if err := m.save(); err != nil {
+		return err
+	}
+	m.dirty = false
+	return nil
+}
+
+// save writes all records to disk. Must hold the lock.
+func (m *MetadataStore) save() error {
+	if m.path == "" {
+		return nil
+	}
+	records := make([]IssueRecord, 0, len(m.records))
+	for _, r := range m.records {
+		records = append(records, *r)
+	}
+	sort.Sort(byIssueNumber(records))
+	data, err := json.MarshalIndent(metadataFile{WarmedUp: m.warmedUp, Records: records}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+type byIssueNumber []IssueRecord
+
+func (b byIssueNumber) Len() int           { return len(b) }
+func (b byIssueNumber) Less(i, j int) bool { return b[i].Number < b[j].Number }
+func (b byIssueNumber) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
@@ -0,0 +1,70 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetadataStoreFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metadata.json")
+	m, err := NewMetadataStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m.Update(5, func(r *IssueRecord) { r.Title = "TestFoo" })
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the update to be written only by Flush, got %v", err)
+	}
+	if err := m.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the store to be written: %v", err)
+	}
+	os.Remove(path)
+	if err := m.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written without changes, got %v", err)
+	}
+}
+
+func TestIssueRecordSee(t *testing.T) {
+	r := IssueRecord{}
+	for i := 0; i < maxSeen+10; i++ {
+		r.see(fmt.Sprintf("foo-%d", i))
+	}
+	r.see("foo-20")
+	if len(r.Seen) != maxSeen {
+		t.Fatalf("expected %d IDs kept, got %d", maxSeen, len(r.Seen))
+	}
+	if r.Seen[0] != "foo-10" || r.Seen[maxSeen-1] != fmt.Sprintf("foo-%d", maxSeen+9) {
+		t.Errorf("expected the oldest IDs to be forgotten, got %v ... %v", r.Seen[0], r.Seen[maxSeen-1])
+	}
+}
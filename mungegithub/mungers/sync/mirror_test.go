@@ -0,0 +1,97 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestMirrorMode(t *testing.T) {
+	production := synctesting.NewTracker()
+	defer production.Close()
+	staging := synctesting.NewTracker()
+	defer staging.Close()
+	existing := production.AddIssue("TestFoo", "Failed")
+	production.AddIssue("TestQux", "Failed")
+	// Has the number TestBar gets in staging, after the mirror of TestFoo
+	// and its comment.
+	other := production.AddIssue("TestBaz", "Failed")
+
+	config := production.Config()
+	if err := config.MirrorTo(staging.Config(), ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newSyncer := func() *IssueSyncer {
+		finder := NewSearchFinder(config, nil)
+		finder.MinInterval = 0
+		return NewIssueSyncer(config, finder)
+	}
+	s := newSyncer()
+	for _, source := range []*JSONSource{
+		{Key: "TestFoo", Ref: "foo-1"},
+		{Key: "TestFoo", Ref: "foo-1"},
+		{Key: "TestBar", Ref: "bar-1"},
+	} {
+		if err := s.Sync(context.Background(), source); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// A syncer which has to search for the issue filed in staging.
+	if err := newSyncer().Sync(context.Background(), &JSONSource{Key: "TestBar", Ref: "bar-2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if comments := production.Comments(existing); len(comments) != 0 {
+		t.Errorf("expected no comments in production, got %q", comments)
+	}
+	if issues := production.OpenIssues("TestBar"); len(issues) != 0 {
+		t.Errorf("expected no issues filed in production, got %v", issues)
+	}
+	mirrors := staging.OpenIssues(fmt.Sprintf("[o/r#%d] TestFoo", existing))
+	if len(mirrors) != 1 {
+		t.Fatalf("expected a mirror of #%d, got %v", existing, mirrors)
+	}
+	if comments := staging.Comments(mirrors[0]); len(comments) != 1 || !strings.Contains(comments[0], "foo-1") {
+		t.Errorf("expected one comment about foo-1 on the mirror, got %q", comments)
+	}
+	filed := staging.OpenIssues("TestBar")
+	if len(filed) != 1 {
+		t.Fatalf("expected TestBar to be filed once in staging, got %v", filed)
+	}
+	if comments := staging.Comments(filed[0]); len(comments) != 1 || !strings.Contains(comments[0], "bar-2") {
+		t.Errorf("expected one comment about bar-2 on the filed issue, got %q", comments)
+	}
+
+	// The issue filed in staging doesn't hide the one with its number.
+	if filed[0] != other {
+		t.Fatalf("expected TestBar to be filed as #%d in staging, got #%d", other, filed[0])
+	}
+	if err := newSyncer().Sync(context.Background(), &JSONSource{Key: "TestBaz", Ref: "baz-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if issues := staging.OpenIssues("TestBaz"); len(issues) != 0 {
+		t.Errorf("expected no new issue for TestBaz, got %v", issues)
+	}
+	if mirrors := staging.OpenIssues(fmt.Sprintf("[o/r#%d] TestBaz", other)); len(mirrors) != 1 {
+		t.Errorf("expected a mirror of #%d, got %v", other, mirrors)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// Namespaced returns `name` (a label or an issue title) inside of
+// `namespace`. An empty namespace leaves the name alone.
+func Namespaced(namespace, name string) string {
+	return namespace + name
+}
+
+// title is the title (and thus the finder key) used for the source, cut to
+// MaxTitleLength.
+func (s *IssueSyncer) title(source IssueSource) string {
+	return truncateTitle(Namespaced(s.Namespace, source.Title()))
+}
+
+// key is what issues for the source are found by: its title, normalized.
+func (s *IssueSyncer) key(source IssueSource) string {
+	return s.Normalizer.Normalize(s.title(source))
+}
+
+// labels are the labels applied to new issues for the source, with its
+// owners (see OwnedSource), after the Taxonomy's aliases, and the
+// ExtraLabels.
+func (s *IssueSyncer) labels(source IssueSource) []string {
+	owners := sourceOwners(source)
+	if s.Namespace == "" && s.Taxonomy == nil && len(s.ExtraLabels) == 0 && len(s.stageLabels) == 0 && len(owners) == 0 {
+		return source.Labels()
+	}
+	labels := []string{}
+	own := sets.NewString(source.Labels()...)
+	for _, l := range source.Labels() {
+		labels = append(labels, Namespaced(s.Namespace, s.Taxonomy.alias(l)))
+	}
+	for _, l := range owners {
+		if !own.Has(l) {
+			labels = append(labels, Namespaced(s.Namespace, s.Taxonomy.alias(l)))
+		}
+	}
+	for _, l := range s.ExtraLabels {
+		labels = append(labels, Namespaced(s.Namespace, l))
+	}
+	for _, l := range s.stageLabels {
+		labels = append(labels, Namespaced(s.Namespace, l))
+	}
+	return labels
+}
+
+// text marks any issue body or comment we write with the namespace, so that
+// humans can tell experiments from the real thing. It also adds the footer,
+// see Templates.
+func (s *IssueSyncer) text(body string) string {
+	if footer := s.footer(); footer != "" {
+		body = fmt.Sprintf("%v\n\n%v", body, footer)
+	}
+	if s.Namespace == "" {
+		return body
+	}
+	return fmt.Sprintf("[%v]\n\n%v", s.Namespace, body)
+}
+
+// CleanupNamespace removes everything an IssueSyncer with the given
+// Namespace created: every open issue with a label in the namespace is
+// closed, and then the labels themselves are deleted from the repo.
+func CleanupNamespace(config *github.Config, namespace string) error {
+	if namespace == "" {
+		return fmt.Errorf("refusing to clean up the empty namespace")
+	}
+	labels, err := config.ListLabels()
+	if err != nil {
+		return fmt.Errorf("error listing labels: %v", err)
+	}
+	for _, l := range labels {
+		if l.Name == nil || !strings.HasPrefix(*l.Name, namespace) {
+			continue
+		}
+		issues, err := config.ListAllIssues(&githubapi.IssueListByRepoOptions{
+			State:  "open",
+			Labels: []string{*l.Name},
+		})
+		if err != nil {
+			return fmt.Errorf("error listing issues labeled %q: %v", *l.Name, err)
+		}
+		for _, issue := range issues {
+			obj, err := config.GetObject(*issue.Number)
+			if err != nil {
+				return fmt.Errorf("error getting issue %v: %v", *issue.Number, err)
+			}
+			glog.Infof("Closing issue %v in namespace %q", *issue.Number, namespace)
+			if err := obj.CloseIssue(); err != nil {
+				return fmt.Errorf("error closing issue %v: %v", *issue.Number, err)
+			}
+		}
+		if err := config.DeleteLabel(*l.Name); err != nil {
+			return fmt.Errorf("error deleting label %q: %v", *l.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// NormalizeRule replaces every match of Pattern (a regexp) in a title with
+// Replacement, which may refer to groups as in regexp.ReplaceAllString.
+type NormalizeRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+
+	re *regexp.Regexp
+}
+
+// TitleNormalizer turns issue titles into the keys issues are deduplicated
+// by, so that titles which only differ by e.g. a timestamp or the node a test
+// ran on find the same issue. Rules are applied in order, then runs of
+// whitespace are collapsed. A nil *TitleNormalizer leaves titles alone.
+//
+// Keys must not change once issues were filed with them, or duplicates get
+// filed: the same normalizer must be used by the syncer and its finder, and
+// rules should only ever be added with care.
+type TitleNormalizer struct {
+	Lowercase bool            `json:"lowercase"`
+	Rules     []NormalizeRule `json:"rules"`
+}
+
+// DefaultTitleNormalizer lowercases titles and strips timestamps, UUIDs, run
+// and build numbers and node names.
+func DefaultTitleNormalizer() *TitleNormalizer {
+	n := &TitleNormalizer{
+		Lowercase: true,
+		Rules: []NormalizeRule{
+			{Pattern: `\d{4}-\d{2}-\d{2}([t ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(z|[+-]\d{2}:?\d{2})?)?`, Replacement: "<time>"},
+			{Pattern: `\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`, Replacement: "<id>"},
+			{Pattern: `\b(run|build|job) ?#?\d+\b`, Replacement: "$1 <n>"},
+			{Pattern: `\b[a-z0-9-]+-(node|minion)-[a-z0-9]+\b`, Replacement: "<node>"},
+		},
+	}
+	if err := n.compile(); err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// LoadTitleNormalizer reads a normalizer from a yaml (or json) file, e.g.:
+//
+//	lowercase: true
+//	rules:
+//	- pattern: '\[[0-9.]+s\]$'
+//	  replacement: ''
+func LoadTitleNormalizer(path string) (*TitleNormalizer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	n := &TitleNormalizer{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(n); err != nil {
+		return nil, fmt.Errorf("error parsing title normalization %v: %v", path, err)
+	}
+	if err := n.compile(); err != nil {
+		return nil, fmt.Errorf("error in title normalization %v: %v", path, err)
+	}
+	return n, nil
+}
+
+func (n *TitleNormalizer) compile() error {
+	for i := range n.Rules {
+		re, err := regexp.Compile(n.Rules[i].Pattern)
+		if err != nil {
+			return err
+		}
+		n.Rules[i].re = re
+	}
+	return nil
+}
+
+// Normalize returns the key for `title`. Keys normalize to themselves.
+func (n *TitleNormalizer) Normalize(title string) string {
+	if n == nil {
+		return title
+	}
+	if n.Lowercase {
+		title = strings.ToLower(title)
+	}
+	for _, r := range n.Rules {
+		title = r.re.ReplaceAllString(title, r.Replacement)
+	}
+	return strings.Join(strings.Fields(title), " ")
+}
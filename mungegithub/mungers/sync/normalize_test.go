@@ -0,0 +1,86 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	n := DefaultTitleNormalizer()
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{title: "TestFoo", expected: "testfoo"},
+		{title: "  Job  foo\tfailed ", expected: "job foo failed"},
+		{title: "Timeout at 2016-07-01T12:00:05Z", expected: "timeout at <time>"},
+		{title: "Run 1234 of e2e failed", expected: "run <n> of e2e failed"},
+		{title: "Build #56 broke", expected: "build <n> broke"},
+		{title: "Pod 0b5e5e1c-3f3a-11e6-9f1d-42010af00002 stuck", expected: "pod <id> stuck"},
+		{title: "Kubelet on e2e-test-minion-x7k2 crashed", expected: "kubelet on <node> crashed"},
+		{title: "Kubelet on gke-pool-node-ab12 crashed", expected: "kubelet on <node> crashed"},
+	}
+	for _, test := range tests {
+		got := n.Normalize(test.title)
+		if got != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.title, test.expected, got)
+		}
+		if again := n.Normalize(got); again != got {
+			t.Errorf("%q: key %q normalized to %q", test.title, got, again)
+		}
+	}
+	var none *TitleNormalizer
+	if got := none.Normalize(" Foo "); got != " Foo " {
+		t.Errorf("nil normalizer changed the title to %q", got)
+	}
+}
+
+func TestLoadTitleNormalizer(t *testing.T) {
+	file, err := ioutil.TempFile("", "normalize")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`
+rules:
+- pattern: ' \[[0-9.]+s\]$'
+  replacement: ''
+`)
+	file.Close()
+
+	n, err := LoadTitleNormalizer(file.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := n.Normalize("TestFoo [12.5s]"); got != "TestFoo" {
+		t.Errorf("expected TestFoo, got %q", got)
+	}
+}
+
+func TestIssueIndexNormalized(t *testing.T) {
+	i := NewIssueIndex(nil, nil, "")
+	i.add(indexIssue(1, "Kubelet on e2e-minion-1 crashed", ""))
+	i.SetNormalizer(DefaultTitleNormalizer())
+	i.add(indexIssue(2, "kubelet on e2e-minion-2  crashed", ""))
+	if got := i.AllIssuesForKey("Kubelet on e2e-minion-3 crashed"); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("expected both issues, got %v", got)
+	}
+}
@@ -0,0 +1,443 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// Option configures an IssueSyncer built by New.
+type Option func(*options) error
+
+// options are what New builds a syncer from.
+type options struct {
+	syncer *IssueSyncer
+	dryRun bool
+	labels []string
+	clock  Clock
+}
+
+// New constructs an issue syncer. It needs WithRepo; everything else has
+// defaults: issues are found with a SearchFinder, records are only kept in
+// memory, and DefaultTemplates are used. Settings are made with Options;
+// only fields which don't have one yet may still be set on the returned
+// syncer, before it is used.
+func New(opts ...Option) (*IssueSyncer, error) {
+	o := &options{syncer: NewIssueSyncer(nil, nil)}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	s := o.syncer
+	if s.config == nil {
+		return nil, fmt.Errorf("no repo to sync with, see WithRepo")
+	}
+	if o.dryRun {
+		s.config = s.config.ForRepo(s.config.Org, s.config.Project)
+		s.config.DryRun = true
+	}
+	if s.finder == nil {
+		labels := []string{}
+		for _, l := range o.labels {
+			labels = append(labels, Namespaced(s.Namespace, l))
+		}
+		finder := NewSearchFinder(s.config, labels)
+		finder.Normalizer = s.Normalizer
+		s.finder = finder
+	}
+	if o.clock != nil {
+		s.SetClock(o.clock)
+	}
+	return s, nil
+}
+
+// WithRepo has the syncer file issues in the repo `config` talks to.
+func WithRepo(config *github.Config) Option {
+	return func(o *options) error {
+		o.syncer.config = config
+		return nil
+	}
+}
+
+// WithFinder has the syncer look issues up with `finder`, instead of with
+// github search.
+func WithFinder(finder IssueFinder) Option {
+	return func(o *options) error {
+		o.syncer.finder = finder
+		return nil
+	}
+}
+
+// WithLabels has the syncer add `labels` to every new issue, and only
+// consider issues which have all of them.
+func WithLabels(labels ...string) Option {
+	return func(o *options) error {
+		o.labels = append(o.labels, labels...)
+		o.syncer.ExtraLabels = append(o.syncer.ExtraLabels, labels...)
+		return nil
+	}
+}
+
+// WithNamespace sets the syncer's Namespace.
+func WithNamespace(namespace string) Option {
+	return func(o *options) error {
+		o.syncer.Namespace = namespace
+		return nil
+	}
+}
+
+// WithNormalizer sets the syncer's Normalizer, which the default finder
+// uses as well.
+func WithNormalizer(normalizer *TitleNormalizer) Option {
+	return func(o *options) error {
+		o.syncer.Normalizer = normalizer
+		return nil
+	}
+}
+
+// WithDryRun has the syncer change nothing on github if `dryRun` is true.
+// The repo's config is copied, not changed.
+func WithDryRun(dryRun bool) Option {
+	return func(o *options) error {
+		o.dryRun = dryRun
+		return nil
+	}
+}
+
+// WithCreationBudget caps how many issues the syncer files in a cycle, and
+// how many of its issues may be open, see MaxCreatesPerCycle. 0 doesn't
+// cap.
+func WithCreationBudget(perCycle, open int) Option {
+	return func(o *options) error {
+		o.syncer.MaxCreatesPerCycle = perCycle
+		o.syncer.MaxOpenIssues = open
+		return nil
+	}
+}
+
+// WithAPIBudget has the syncer keep `calls` github API calls in reserve,
+// see MinAPIBudget.
+func WithAPIBudget(calls int) Option {
+	return func(o *options) error {
+		o.syncer.MinAPIBudget = calls
+		return nil
+	}
+}
+
+// WithPersistence keeps the syncer's records in the file at `path`, so that
+// they survive restarts. An empty path keeps them only in memory.
+func WithPersistence(path string) Option {
+	return func(o *options) (err error) {
+		o.syncer.Store, err = NewMetadataStore(path)
+		return err
+	}
+}
+
+// WithTemplates customizes what the syncer writes, see Templates.
+func WithTemplates(templates *Templates) Option {
+	return func(o *options) error {
+		o.syncer.Templates = templates
+		return nil
+	}
+}
+
+// WithTemplatesFile customizes what the syncer writes with the templates in
+// the file at `path`, see LoadTemplates. An empty path keeps the defaults.
+func WithTemplatesFile(path string) Option {
+	return func(o *options) (err error) {
+		if path != "" {
+			o.syncer.Templates, err = LoadTemplates(path)
+		}
+		return err
+	}
+}
+
+// WithUpstream has the syncer record sources which have an open issue in
+// the repo `config` talks to there, instead of filing them again, see
+// UpstreamRepo. A nil config doesn't.
+func WithUpstream(config *github.Config) Option {
+	return func(o *options) error {
+		if config != nil {
+			o.syncer.Upstream = NewUpstreamRepo(config)
+		}
+		return nil
+	}
+}
+
+// WithClock has the syncer, and its finder, tell the time and wait with
+// `clock`, see Clock.
+func WithClock(clock Clock) Option {
+	return func(o *options) error {
+		o.clock = clock
+		return nil
+	}
+}
+
+// WithRand has the syncer's backoff jitter come from `r`.
+func WithRand(r Rand) Option {
+	return func(o *options) error {
+		o.syncer.SetRand(r)
+		return nil
+	}
+}
+
+// WithSortedSources has the syncer sync sources of the same severity in
+// the order of their IDs, see SortSources.
+func WithSortedSources(sorted bool) Option {
+	return func(o *options) error {
+		o.syncer.SortSources = sorted
+		return nil
+	}
+}
+
+// WithIDMatching sets how the syncer recognizes sources in issues, see
+// IDMatching.
+func WithIDMatching(matching IDMatching) Option {
+	return func(o *options) error {
+		switch matching {
+		case "", IDMatchExact, IDMatchMarker, IDMatchSubstring:
+		default:
+			return fmt.Errorf("unknown ID matching %q", matching)
+		}
+		o.syncer.IDMatching = matching
+		return nil
+	}
+}
+
+// WithRedactor has the syncer redact the bodies of sources with
+// `redactor`.
+func WithRedactor(redactor *Redactor) Option {
+	return func(o *options) error {
+		o.syncer.Redactor = redactor
+		return nil
+	}
+}
+
+// WithLogger has the syncer log to `logger`.
+func WithLogger(logger Logger) Option {
+	return func(o *options) error {
+		o.syncer.Logger = logger
+		return nil
+	}
+}
+
+// WithAudit has the syncer record every mutation in `audit`.
+func WithAudit(audit *AuditLog) Option {
+	return func(o *options) error {
+		o.syncer.Audit = audit
+		return nil
+	}
+}
+
+// WithHistory has the syncer export its history to `history`.
+func WithHistory(history HistoryExporter) Option {
+	return func(o *options) error {
+		o.syncer.History = history
+		return nil
+	}
+}
+
+// WithBackoff sets how the syncer retries github calls which fail with
+// transient errors.
+func WithBackoff(backoff Backoff) Option {
+	return func(o *options) error {
+		o.syncer.Backoff = backoff
+		return nil
+	}
+}
+
+// WithCallTimeout sets the deadline of every github request, see
+// CallTimeout. 0 doesn't limit them.
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(o *options) error {
+		o.syncer.CallTimeout = timeout
+		return nil
+	}
+}
+
+// WithEditBody has the syncer record occurrences in the issue body instead
+// of a comment each if `edit` is true, see EditBody.
+func WithEditBody(edit bool) Option {
+	return func(o *options) error {
+		o.syncer.EditBody = edit
+		return nil
+	}
+}
+
+// WithTaskList has the syncer record occurrences in `list`, see TaskList.
+func WithTaskList(list *TaskList) Option {
+	return func(o *options) error {
+		o.syncer.TaskList = list
+		return nil
+	}
+}
+
+// WithCommentLimits caps how often the syncer comments about occurrences:
+// at most once per `interval` on an issue (see MinResyncInterval), at most
+// `max` times per issue (see MaxComments), and with reactions instead after
+// `reactAfter` occurrences (see ReactAfter). 0 doesn't cap.
+func WithCommentLimits(interval time.Duration, max, reactAfter int) Option {
+	return func(o *options) error {
+		o.syncer.MinResyncInterval = interval
+		o.syncer.MaxComments = max
+		o.syncer.ReactAfter = reactAfter
+		return nil
+	}
+}
+
+// WithBulkComments has the syncer fetch candidate issues in one query,
+// with their last `comments` comments, see BulkComments.
+func WithBulkComments(comments int) Option {
+	return func(o *options) error {
+		o.syncer.BulkComments = comments
+		return nil
+	}
+}
+
+// WithMuteLabel sets the label which silences an issue, see MuteLabel.
+func WithMuteLabel(label string) Option {
+	return func(o *options) error {
+		o.syncer.MuteLabel = label
+		return nil
+	}
+}
+
+// WithHistogram has the syncer keep a sparkline of the occurrences of the
+// last `days` days in issue bodies, see HistogramDays.
+func WithHistogram(days int) Option {
+	return func(o *options) error {
+		o.syncer.HistogramDays = days
+		return nil
+	}
+}
+
+// WithOwnerFanOut has the syncer file a child issue per owner of sources
+// with several owners if `fanOut` is true, see OwnerFanOut.
+func WithOwnerFanOut(fanOut bool) Option {
+	return func(o *options) error {
+		o.syncer.OwnerFanOut = fanOut
+		return nil
+	}
+}
+
+// WithCalendar sets which time counts for the syncer's timers, see
+// Calendar.
+func WithCalendar(calendar *Calendar) Option {
+	return func(o *options) error {
+		o.syncer.Calendar = calendar
+		return nil
+	}
+}
+
+// WithQuietHours has the syncer hold sources during `quiet`, see
+// QuietHours.
+func WithQuietHours(quiet *QuietHours) Option {
+	return func(o *options) error {
+		o.syncer.QuietHours = quiet
+		return nil
+	}
+}
+
+// WithTaxonomy has the syncer check the labels of new issues against
+// `taxonomy`.
+func WithTaxonomy(taxonomy *LabelTaxonomy) Option {
+	return func(o *options) error {
+		o.syncer.Taxonomy = taxonomy
+		return nil
+	}
+}
+
+// WithSuspects has the syncer list suspect merges in new issues, see
+// Suspects.
+func WithSuspects(suspects *SuspectMerges) Option {
+	return func(o *options) error {
+		o.syncer.Suspects = suspects
+		return nil
+	}
+}
+
+// WithLocking has the syncer lock the issues it closed, see Locking.
+func WithLocking(locking *ClosedLocking) Option {
+	return func(o *options) error {
+		o.syncer.Locking = locking
+		return nil
+	}
+}
+
+// WithRecentlyClosed has the syncer pick up where recently closed issues
+// left off, see RecentlyClosed.
+func WithRecentlyClosed(match *ClosedMatch) Option {
+	return func(o *options) error {
+		o.syncer.RecentlyClosed = match
+		return nil
+	}
+}
+
+// WithTriage has the syncer stay quiet on issues humans are working on,
+// see Triage.
+func WithTriage(triage *TriageSignals) Option {
+	return func(o *options) error {
+		o.syncer.Triage = triage
+		return nil
+	}
+}
+
+// WithRelated has the syncer link new issues to related ones, see Related.
+func WithRelated(related *RelatedIssues) Option {
+	return func(o *options) error {
+		o.syncer.Related = related
+		return nil
+	}
+}
+
+// WithBoard has the syncer put a card for every new issue on `board`.
+func WithBoard(board *ProjectBoard) Option {
+	return func(o *options) error {
+		o.syncer.Board = board
+		return nil
+	}
+}
+
+// WithStages adds custom steps of syncing a source, see Stages.
+func WithStages(stages ...Stage) Option {
+	return func(o *options) error {
+		o.syncer.Stages = append(o.syncer.Stages, stages...)
+		return nil
+	}
+}
+
+// WithUploads has the syncer handle the attachments of sources with
+// `uploads`, see AttachmentSource.
+func WithUploads(uploads *AttachmentUploads) Option {
+	return func(o *options) error {
+		o.syncer.Uploads = uploads
+		return nil
+	}
+}
+
+// WithRetest has the syncer rerun the jobs of pull requests which failed
+// with a flake it synced, see Retest.
+func WithRetest(retest *RetestPolicy) Option {
+	return func(o *options) error {
+		o.syncer.Retest = retest
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New(); err == nil {
+		t.Errorf("expected an error without a repo")
+	}
+
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	dir, err := ioutil.TempDir("", "options")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	metadata := filepath.Join(dir, "metadata.json")
+
+	config := tracker.Config()
+	s, err := New(
+		WithRepo(config),
+		WithNamespace("canary"),
+		WithLabels("kind/flake"),
+		WithCreationBudget(1, 5),
+		WithPersistence(metadata),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.finder.(*SearchFinder).MinInterval = 0
+	for _, ref := range []string{"run-1", "run-2"} {
+		if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref}); err != nil {
+			t.Fatalf("unexpected error syncing %v: %v", ref, err)
+		}
+	}
+	open := tracker.OpenIssues(Namespaced("canary", "TestFoo"))
+	if len(open) != 1 {
+		t.Fatalf("expected one issue, got %v", tracker.Issues())
+	}
+	if issue := tracker.Issues()[0]; len(issue.Labels) != 1 || *issue.Labels[0].Name != Namespaced("canary", "kind/flake") {
+		t.Errorf("expected the namespaced label, got %v", issue.Labels)
+	}
+	if s.MaxCreatesPerCycle != 1 || s.MaxOpenIssues != 5 {
+		t.Errorf("expected the creation budget, got %v and %v", s.MaxCreatesPerCycle, s.MaxOpenIssues)
+	}
+	if _, err := os.Stat(metadata); err != nil {
+		t.Errorf("expected the records persisted: %v", err)
+	}
+
+	dry, err := New(WithRepo(config), WithDryRun(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !dry.config.DryRun || config.DryRun {
+		t.Errorf("expected only the syncer's copy of the config to be dry-run")
+	}
+
+	if _, err := New(WithRepo(config), WithTemplatesFile(filepath.Join(dir, "missing.yaml"))); err == nil {
+		t.Errorf("expected an error for missing templates")
+	}
+}
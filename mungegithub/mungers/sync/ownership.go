@@ -0,0 +1,157 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// IssueOwner describes who is responsible for an open issue filed by the
+// syncer. An issue with no Teams, Assignee or Owners is unowned.
+type IssueOwner struct {
+	Number   int      `json:"number"`
+	Title    string   `json:"title"`
+	URL      string   `json:"url"`
+	Teams    []string `json:"teams,omitempty"`
+	Assignee string   `json:"assignee,omitempty"`
+	Owners   []string `json:"owners,omitempty"`
+}
+
+// OwnershipExporter writes a machine readable list of who owns the open
+// issues filed by the syncer, for dashboards which track unowned failures.
+type OwnershipExporter struct {
+	config *github.Config
+
+	// Label selects the issues filed by the syncer.
+	Label string
+	// TeamPrefixes are the label prefixes which name an owning team.
+	TeamPrefixes []string
+	// TeamPaths maps team labels to a directory whose OWNERS files list
+	// the people responsible for the team's issues.
+	TeamPaths map[string]string
+	// Owners returns the people listed in the OWNERS files for a
+	// directory, normally features.RepoInfo.Assignees. May be nil.
+	Owners func(path string) sets.String
+}
+
+// NewOwnershipExporter constructs an exporter for the open issues labeled
+// with `label`.
+func NewOwnershipExporter(config *github.Config, label string) *OwnershipExporter {
+	return &OwnershipExporter{
+		config:       config,
+		Label:        label,
+		TeamPrefixes: []string{"sig/", "team/"},
+		TeamPaths:    map[string]string{},
+	}
+}
+
+// Ownership lists the owners of every open issue with the exporter's label.
+func (e *OwnershipExporter) Ownership() ([]IssueOwner, error) {
+	issues, err := e.config.ListAllIssues(&githubapi.IssueListByRepoOptions{
+		State:  "open",
+		Labels: []string{e.Label},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing issues labeled %q: %v", e.Label, err)
+	}
+	out := []IssueOwner{}
+	for _, issue := range issues {
+		out = append(out, e.ownerOf(issue))
+	}
+	sort.Sort(byNumber(out))
+	return out, nil
+}
+
+func (e *OwnershipExporter) ownerOf(issue *githubapi.Issue) IssueOwner {
+	o := IssueOwner{Number: *issue.Number}
+	if issue.Title != nil {
+		o.Title = *issue.Title
+	}
+	if issue.HTMLURL != nil {
+		o.URL = *issue.HTMLURL
+	}
+	if issue.Assignee != nil && issue.Assignee.Login != nil {
+		o.Assignee = *issue.Assignee.Login
+	}
+	owners := sets.NewString()
+	for _, l := range issue.Labels {
+		if l.Name == nil {
+			continue
+		}
+		for _, prefix := range e.TeamPrefixes {
+			if !strings.HasPrefix(*l.Name, prefix) {
+				continue
+			}
+			o.Teams = append(o.Teams, *l.Name)
+			if path, ok := e.TeamPaths[*l.Name]; ok && e.Owners != nil {
+				owners = owners.Union(e.Owners(path))
+			}
+			break
+		}
+	}
+	sort.Strings(o.Teams)
+	if owners.Len() > 0 {
+		o.Owners = owners.List()
+	}
+	return o
+}
+
+type byNumber []IssueOwner
+
+func (b byNumber) Len() int           { return len(b) }
+func (b byNumber) Less(i, j int) bool { return b[i].Number < b[j].Number }
+func (b byNumber) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// Export writes the ownership of all open issues to `dest` as JSON. `dest`
+// is either a local file (e.g. inside a git checkout which is pushed
+// elsewhere) or a gs:// URL, which is uploaded with gsutil.
+func (e *OwnershipExporter) Export(dest string) error {
+	owners, err := e.Ownership()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(owners, "", "  ")
+	if err != nil {
+		return err
+	}
+	glog.Infof("Exporting ownership of %d issues to %v", len(owners), dest)
+	return writeDest(dest, data)
+}
+
+// writeDest writes data to a local file or a gs:// URL.
+func writeDest(dest string, data []byte) error {
+	if !strings.HasPrefix(dest, "gs://") {
+		return ioutil.WriteFile(dest, data, 0644)
+	}
+	cmd := exec.Command("gsutil", "-q", "cp", "-", dest)
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error uploading to %v: %v: %s", dest, err, out)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"testing"
+
+	githubapi "github.com/google/go-github/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+func TestOwnerOf(t *testing.T) {
+	e := NewOwnershipExporter(nil, "kind/flake")
+	e.TeamPaths["sig/node"] = "pkg/kubelet"
+	e.Owners = func(path string) sets.String {
+		if path == "pkg/kubelet" {
+			return sets.NewString("dchen", "yujuhong")
+		}
+		return sets.NewString()
+	}
+
+	assigned := github_test.Issue("bot", 2, []string{"kind/flake", "team/cluster"}, false)
+	login := "alice"
+	assigned.Assignee = &githubapi.User{Login: &login}
+
+	tests := []struct {
+		issue    *githubapi.Issue
+		expected IssueOwner
+	}{
+		{
+			issue: github_test.Issue("bot", 1, []string{"kind/flake"}, false),
+			expected: IssueOwner{
+				Number: 1,
+				Title:  "My issue title",
+				URL:    "Issue URL",
+			},
+		},
+		{
+			issue: assigned,
+			expected: IssueOwner{
+				Number:   2,
+				Title:    "My issue title",
+				URL:      "Issue URL",
+				Teams:    []string{"team/cluster"},
+				Assignee: "alice",
+			},
+		},
+		{
+			issue: github_test.Issue("bot", 3, []string{"kind/flake", "sig/node", "sig/storage"}, false),
+			expected: IssueOwner{
+				Number: 3,
+				Title:  "My issue title",
+				URL:    "Issue URL",
+				Teams:  []string{"sig/node", "sig/storage"},
+				Owners: []string{"dchen", "yujuhong"},
+			},
+		},
+	}
+	for _, test := range tests {
+		if got := e.ownerOf(test.issue); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("expected %#v, got %#v", test.expected, got)
+		}
+	}
+}
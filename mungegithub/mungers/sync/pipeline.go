@@ -0,0 +1,265 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"regexp"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// Phase is a step of syncing a source. Sync goes through PhaseFind,
+// PhaseDedup, PhaseDecide and PhaseMutate in order, running the
+// IssueSyncer.Stages due before each.
+type Phase string
+
+const (
+	// PhaseFind looks for issues about the source.
+	PhaseFind Phase = "find"
+	// PhaseDedup closes all but the oldest open issue.
+	PhaseDedup Phase = "dedup"
+	// PhaseDecide decides what to do, see Decision.
+	PhaseDecide Phase = "decide"
+	// PhaseMutate does it.
+	PhaseMutate Phase = "mutate"
+)
+
+var phases = []Phase{PhaseFind, PhaseDedup, PhaseDecide, PhaseMutate}
+
+// Decision is what PhaseDecide decided to do about a source.
+type Decision string
+
+const (
+	// DecisionNone: the source is already recorded.
+	DecisionNone Decision = "none"
+	// DecisionUpdate comments on (or edits) the open issue.
+	DecisionUpdate Decision = "update"
+	// DecisionRollover continues the open issue in a new one, see
+	// IssueSyncer.MaxComments.
+	DecisionRollover Decision = "rollover"
+	// DecisionCount only counts the occurrence, see MinResyncInterval.
+	DecisionCount Decision = "count"
+	// DecisionReact reacts to the open issue, see ReactAfter.
+	DecisionReact Decision = "react"
+	// DecisionMute only counts the occurrence, humans muted the open
+	// issue, see MuteLabel.
+	DecisionMute Decision = "mute"
+	// DecisionTransfer moves the open issue to another repo, see
+	// TransferLabelPrefix, and DecisionFollow records the source where
+	// the issue was moved to.
+	DecisionTransfer Decision = "transfer"
+	DecisionFollow   Decision = "follow"
+	// DecisionUpstream records the source on the open issue for it in the
+	// repo ours mirrors, instead of filing a new one, see UpstreamRepo.
+	DecisionUpstream Decision = "upstream"
+	// DecisionReopen reopens a recently closed issue, see RecentlyClosed.
+	DecisionReopen Decision = "reopen"
+	// DecisionCreate files a new issue.
+	DecisionCreate Decision = "create"
+)
+
+// SyncState is what the phases of syncing a source found and decided.
+// Stages may change it.
+type SyncState struct {
+	Source IssueSource
+	// Found is set if the source is already recorded in one of the
+	// issues, Open (oldest first, only the oldest after PhaseDedup) or
+	// Closed.
+	Found  bool
+	Open   []*github.MungeObject
+	Closed []*github.MungeObject
+	// Decision is set by PhaseDecide, with Issue the issue it is about,
+	// if any. For DecisionCreate, Issue is a recently closed issue whose
+	// history is added to the new one.
+	Decision Decision
+	Issue    *github.MungeObject
+	// Labels are added to a new issue, in addition to the source's, and
+	// Notes to its body, e.g. who to cc.
+	Labels []string
+	Notes  []string
+
+	skipped string
+	// org and project are where DecisionTransfer moves the issue, and
+	// transferred the record of the issue DecisionFollow follows.
+	org, project string
+	transferred  IssueRecord
+	// upstream is the issue DecisionUpstream records the source on.
+	upstream *github.MungeObject
+}
+
+// Skip stops syncing the source, treating it as synced.
+func (st *SyncState) Skip(reason string) {
+	st.skipped = reason
+}
+
+// Stage is a custom step of the sync pipeline, which runs before a phase,
+// e.g. to filter sources (see SkipTitles), enrich them (see AddLabels) or
+// veto what was decided (see VetoCreation). An error fails the sync.
+type Stage struct {
+	Name   string
+	Before Phase
+	Run    func(ctx context.Context, st *SyncState) error
+}
+
+// SkipTitles returns a stage which skips sources whose titles match `re`.
+func SkipTitles(re *regexp.Regexp) Stage {
+	return Stage{
+		Name:   "skip-titles",
+		Before: PhaseFind,
+		Run: func(ctx context.Context, st *SyncState) error {
+			if re.MatchString(st.Source.Title()) {
+				st.Skip(fmt.Sprintf("title matches %v", re))
+			}
+			return nil
+		},
+	}
+}
+
+// AddLabels returns a stage which adds the labels `fn` returns to the new
+// issues of sources.
+func AddLabels(fn func(IssueSource) []string) Stage {
+	return Stage{
+		Name:   "add-labels",
+		Before: PhaseMutate,
+		Run: func(ctx context.Context, st *SyncState) error {
+			if st.Decision == DecisionCreate {
+				st.Labels = append(st.Labels, fn(st.Source)...)
+			}
+			return nil
+		},
+	}
+}
+
+// VetoCreation returns a stage which skips sources that would get a new
+// issue if `fn` returns a reason not to.
+func VetoCreation(fn func(st *SyncState) string) Stage {
+	return Stage{
+		Name:   "veto-creation",
+		Before: PhaseMutate,
+		Run: func(ctx context.Context, st *SyncState) error {
+			if st.Decision != DecisionCreate {
+				return nil
+			}
+			if reason := fn(st); reason != "" {
+				st.Skip(reason)
+			}
+			return nil
+		},
+	}
+}
+
+// internal returns true for the sources the syncer makes up itself, which
+// stages don't see.
+func internal(source IssueSource) bool {
+	switch source.(type) {
+	case *capSource, *placeholderSource:
+		return true
+	}
+	return false
+}
+
+// runStages runs the stages due before `phase`, until one skips the source.
+func (s *IssueSyncer) runStages(ctx context.Context, phase Phase, st *SyncState) error {
+	for _, stage := range s.Stages {
+		if stage.Before != phase {
+			continue
+		}
+		if err := stage.Run(ctx, st); err != nil {
+			return &StageError{Stage: stage.Name, Err: err}
+		}
+		if st.skipped != "" {
+			s.logger().Infof("Skipped by stage %v: %v", stage.Name, st.skipped)
+			return nil
+		}
+	}
+	return nil
+}
+
+// runPhase runs `phase` of syncing a source.
+func (s *IssueSyncer) runPhase(ctx context.Context, phase Phase, st *SyncState) (err error) {
+	switch phase {
+	case PhaseFind:
+		st.Found, st.Open, st.Closed, err = s.findPreviousIssues(ctx, st.Source)
+		return err
+	case PhaseDedup:
+		// Close dups if there are multiple open issues
+		if len(st.Open) > 1 {
+			if err := s.markAsDups(ctx, st.Open[1:], *st.Open[0].Issue.Number); err != nil {
+				return err
+			}
+			st.Open = st.Open[:1]
+		}
+		return nil
+	case PhaseDecide:
+		s.decide(st)
+		return s.findUpstream(ctx, st)
+	case PhaseMutate:
+		return s.mutate(ctx, st)
+	}
+	return fmt.Errorf("unknown phase %q", phase)
+}
+
+// decide sets st.Decision.
+func (s *IssueSyncer) decide(st *SyncState) {
+	var open *github.MungeObject
+	if len(st.Open) > 0 {
+		open = st.Open[0]
+	}
+	// Move the issue if humans asked for it, and follow it where it went.
+	if open != nil {
+		if org, project, ok := s.transferTarget(open); ok {
+			st.Decision, st.Issue, st.org, st.project = DecisionTransfer, open, org, project
+			return
+		}
+	} else if r, ok := s.transferred(st.Closed); ok {
+		st.Decision, st.transferred = DecisionFollow, r
+		if st.Found {
+			st.Decision = DecisionNone
+		}
+		return
+	}
+
+	switch {
+	case st.Found:
+		// Don't need to update, we were only here to close the dups.
+		st.Decision = DecisionNone
+	case open != nil:
+		// Update an issue if possible.
+		n := *open.Issue.Number
+		st.Issue = open
+		switch {
+		case s.muted(open):
+			st.Decision = DecisionMute
+		case s.tooManyComments(n):
+			st.Decision = DecisionRollover
+		case s.throttled(n):
+			st.Decision = DecisionCount
+		case s.quiet(n):
+			st.Decision = DecisionReact
+		default:
+			st.Decision = DecisionUpdate
+		}
+	default:
+		// Pick up where a recently closed issue left off.
+		st.Decision, st.Issue = DecisionCreate, s.recentlyClosed(st.Closed)
+		if st.Issue != nil && s.RecentlyClosed.Reopen {
+			st.Decision = DecisionReopen
+		}
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// PlannedAction is what syncing a source would do, see Plan.
+type PlannedAction struct {
+	Title string
+	ID    string
+	// Decision is what would be done, about Issue unless a new issue is
+	// filed. Labels are those of the new issue.
+	Decision Decision
+	Issue    int
+	Labels   []string
+	// Dups are the open issues which would be closed as duplicates of
+	// Issue.
+	Dups []int
+	// Skipped is set if a stage would skip the source, Sensitive if it
+	// would be routed privately, see SecurityRouting.
+	Skipped   string
+	Sensitive bool
+	// Upstream is the issue in the upstream repo ("org/project#n") the
+	// source would be recorded on, see UpstreamRepo.
+	Upstream string
+	// Err is set if looking the source up failed.
+	Err error
+}
+
+// SyncPlan is what the next SyncAll would do with some sources.
+type SyncPlan struct {
+	Actions []PlannedAction
+}
+
+// Count returns how many actions would make decision `d`.
+func (p *SyncPlan) Count(d Decision) int {
+	n := 0
+	for _, a := range p.Actions {
+		if a.Decision == d && a.Skipped == "" && a.Err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Dups returns how many issues would be closed as duplicates.
+func (p *SyncPlan) Dups() int {
+	n := 0
+	for _, a := range p.Actions {
+		n += len(a.Dups)
+	}
+	return n
+}
+
+// String renders the plan like a diff: "+" for new issues, "-" for closed
+// ones, "~" for issues which would change and " " for sources which would
+// be left alone.
+func (p *SyncPlan) String() string {
+	var b bytes.Buffer
+	for _, a := range p.Actions {
+		switch {
+		case a.Err != nil:
+			fmt.Fprintf(&b, "! %q (%v): %v\n", a.Title, a.ID, a.Err)
+			continue
+		case a.Sensitive:
+			fmt.Fprintf(&b, "  %v: sensitive, routed privately\n", a.ID)
+			continue
+		case a.Skipped != "":
+			fmt.Fprintf(&b, "  %q (%v): skipped, %v\n", a.Title, a.ID, a.Skipped)
+			continue
+		}
+		for _, dup := range a.Dups {
+			fmt.Fprintf(&b, "- #%d: close as a duplicate of #%d\n", dup, a.Issue)
+		}
+		switch a.Decision {
+		case DecisionCreate:
+			fmt.Fprintf(&b, "+ %q (%v)", a.Title, a.ID)
+			if len(a.Labels) > 0 {
+				fmt.Fprintf(&b, " [%v]", strings.Join(a.Labels, ", "))
+			}
+			if a.Issue != 0 {
+				fmt.Fprintf(&b, ", seen before in #%d", a.Issue)
+			}
+			b.WriteString("\n")
+		case DecisionNone:
+			fmt.Fprintf(&b, "  %q (%v): already recorded\n", a.Title, a.ID)
+		case DecisionUpstream:
+			fmt.Fprintf(&b, "~ %v %q (%v): open upstream\n", a.Upstream, a.Title, a.ID)
+		case DecisionRollover:
+			fmt.Fprintf(&b, "- #%d: close, too many comments\n", a.Issue)
+			fmt.Fprintf(&b, "+ %q (%v), continuing #%d\n", a.Title, a.ID, a.Issue)
+		default:
+			issue := "new issue"
+			if a.Issue != 0 {
+				issue = fmt.Sprintf("#%d", a.Issue)
+			}
+			fmt.Fprintf(&b, "~ %v %q (%v): %v\n", issue, a.Title, a.ID, a.Decision)
+		}
+	}
+	created := p.Count(DecisionCreate) + p.Count(DecisionRollover)
+	updated := 0
+	for _, d := range []Decision{DecisionUpdate, DecisionCount, DecisionReact, DecisionMute, DecisionReopen, DecisionTransfer, DecisionFollow, DecisionUpstream} {
+		updated += p.Count(d)
+	}
+	fmt.Fprintf(&b, "%d to create, %d to update, %d to close as duplicates\n", created, updated, p.Dups())
+	return b.String()
+}
+
+// Plan works out what SyncAll would do with `sources`, without changing
+// anything: the issues are looked up and Stages run, but nothing is filed,
+// commented on or closed. Sources which can't be looked up are part of the
+// plan with their error; only a canceled context fails it.
+func (s *IssueSyncer) Plan(ctx context.Context, sources []IssueSource) (*SyncPlan, error) {
+	plan := &SyncPlan{}
+	// New issues the plan files, by key, which later sources would update.
+	planned := map[string]bool{}
+	for _, source := range s.order(sources) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if s.synced.Has(source.ID()) {
+			continue
+		}
+		if isSensitive(source) {
+			plan.Actions = append(plan.Actions, PlannedAction{ID: s.sourceID(source), Sensitive: true})
+			continue
+		}
+		if key := groupKey(source); key != "" {
+			source = &umbrellaSource{source, key}
+		}
+		a := PlannedAction{Title: s.title(source), ID: source.ID()}
+		if planned[s.key(source)] {
+			a.Decision = DecisionUpdate
+		} else {
+			s.plan(ctx, source, &a)
+			if a.Decision == DecisionCreate || a.Decision == DecisionRollover {
+				planned[s.key(source)] = true
+			}
+		}
+		plan.Actions = append(plan.Actions, a)
+	}
+	return plan, nil
+}
+
+// plan fills in what syncing `source` would do.
+func (s *IssueSyncer) plan(ctx context.Context, source IssueSource, a *PlannedAction) {
+	s.source = source.ID()
+	s.log = s.logger().With("source", s.source)
+	defer func() { s.log, s.source = nil, "" }()
+
+	st := &SyncState{Source: source}
+	for _, phase := range phases {
+		if err := s.runStages(ctx, phase, st); err != nil {
+			a.Err = err
+			return
+		}
+		if st.skipped != "" {
+			a.Skipped = st.skipped
+			return
+		}
+		switch phase {
+		case PhaseDedup:
+			if len(st.Open) > 1 {
+				for _, dup := range st.Open[1:] {
+					a.Dups = append(a.Dups, *dup.Issue.Number)
+				}
+				st.Open = st.Open[:1]
+			}
+		case PhaseMutate:
+		default:
+			if err := s.runPhase(ctx, phase, st); err != nil {
+				a.Err = err
+				return
+			}
+		}
+	}
+	a.Decision = st.Decision
+	if st.upstream != nil {
+		a.Upstream = fmt.Sprintf("%v#%d", s.Upstream, *st.upstream.Issue.Number)
+	}
+	switch {
+	case st.Issue != nil:
+		a.Issue = *st.Issue.Issue.Number
+	case len(st.Open) > 0:
+		a.Issue = *st.Open[0].Issue.Number
+	}
+	if st.Decision == DecisionCreate {
+		s.stageLabels = st.Labels
+		a.Labels = s.labels(source)
+		s.stageLabels = nil
+	}
+}
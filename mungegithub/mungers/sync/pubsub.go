@@ -0,0 +1,164 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+const (
+	pubSubAPI = "https://pubsub.googleapis.com/v1/"
+	// metadataTokenURL hands out tokens for the GCE service account.
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// PubSubQueue is a MessageQueue reading from a Google Cloud Pub/Sub
+// subscription, using the REST API.
+type PubSubQueue struct {
+	// subscription is like "projects/my-project/subscriptions/issues".
+	subscription string
+	client       *http.Client
+	// api is the Pub/Sub API endpoint, replaced in tests.
+	api string
+}
+
+// NewPubSubQueue constructs a PubSubQueue for `subscription`, e.g.
+// "projects/my-project/subscriptions/issues". A nil client authenticates as
+// the service account of the GCE instance we run on.
+func NewPubSubQueue(subscription string, client *http.Client) *PubSubQueue {
+	if client == nil {
+		client = &http.Client{
+			Transport: &oauth2.Transport{Source: oauth2.ReuseTokenSource(nil, metadataTokenSource{})},
+		}
+	}
+	return &PubSubQueue{subscription: subscription, client: client, api: pubSubAPI}
+}
+
+// call POSTs `in` to the subscription's `method`, decoding the answer into
+// `out` if it isn't nil.
+func (p *PubSubQueue) call(ctx context.Context, method string, in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", p.api+p.subscription+":"+method, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Cancel = ctx.Done()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("pubsub %v on %v failed: %v: %s", method, p.subscription, resp.Status, body)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+type pubSubPullResponse struct {
+	ReceivedMessages []struct {
+		AckID   string `json:"ackId"`
+		Message struct {
+			Data string `json:"data"`
+		} `json:"message"`
+	} `json:"receivedMessages"`
+}
+
+// Pull implements MessageQueue.
+func (p *PubSubQueue) Pull(ctx context.Context, max int) ([]Message, error) {
+	pulled := pubSubPullResponse{}
+	err := p.call(ctx, "pull", map[string]interface{}{
+		"maxMessages":       max,
+		"returnImmediately": true,
+	}, &pulled)
+	if err != nil {
+		return nil, err
+	}
+	messages := []Message{}
+	for _, r := range pulled.ReceivedMessages {
+		data, err := base64.StdEncoding.DecodeString(r.Message.Data)
+		if err != nil {
+			// Still hand it over, to be dropped as invalid.
+			data = nil
+		}
+		ackID := r.AckID
+		messages = append(messages, Message{
+			Data: data,
+			Ack: func() error {
+				return p.call(ctx, "acknowledge", map[string]interface{}{"ackIds": []string{ackID}}, nil)
+			},
+			Nack: func() error {
+				return p.call(ctx, "modifyAckDeadline", map[string]interface{}{
+					"ackIds":             []string{ackID},
+					"ackDeadlineSeconds": 0,
+				}, nil)
+			},
+		})
+	}
+	return messages, nil
+}
+
+// metadataTokenSource gets tokens from the GCE metadata server.
+type metadataTokenSource struct{}
+
+func (metadataTokenSource) Token() (*oauth2.Token, error) {
+	req, err := http.NewRequest("GET", metadataTokenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting a token from the metadata server: %v", resp.Status)
+	}
+	t := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		TokenType   string `json:"token_type"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken: t.AccessToken,
+		TokenType:   t.TokenType,
+		Expiry:      time.Now().Add(time.Duration(t.ExpiresIn) * time.Second),
+	}, nil
+}
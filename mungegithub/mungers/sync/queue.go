@@ -0,0 +1,153 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Message is a source received from a MessageQueue.
+type Message struct {
+	// Data should hold a JSONSource, see ReadJSONSources.
+	Data []byte
+	// Ack tells the queue the message was handled, Nack that it should be
+	// delivered again.
+	Ack  func() error
+	Nack func() error
+}
+
+// MessageQueue is where sources are received from, e.g. a Pub/Sub
+// subscription (see PubSubQueue). Adapters for other queues, like NATS or
+// Kafka, only need to implement Pull.
+type MessageQueue interface {
+	// Pull returns up to `max` waiting messages, or none if there are none.
+	Pull(ctx context.Context, max int) ([]Message, error)
+}
+
+// QueueAdapter feeds the syncer from a MessageQueue. Messages are acked once
+// their source was synced, and nacked otherwise, so the queue delivers them
+// again; messages which don't hold a valid source are acked and dropped.
+type QueueAdapter struct {
+	queue  MessageQueue
+	syncer Syncer
+	after  func(time.Duration) <-chan time.Time
+
+	// Logger is where failures are logged.
+	Logger Logger
+
+	// MaxMessages is how many messages are synced in one cycle.
+	MaxMessages int
+}
+
+// NewQueueAdapter constructs a QueueAdapter feeding `syncer` (an IssueSyncer
+// or a MultiSyncer) from `queue`.
+func NewQueueAdapter(queue MessageQueue, syncer Syncer) *QueueAdapter {
+	return &QueueAdapter{
+		queue:       queue,
+		syncer:      syncer,
+		after:       time.After,
+		Logger:      &textLogger{},
+		MaxMessages: 100,
+	}
+}
+
+// SetClock has the adapter wait between cycles with `clock`. Call it
+// before Run.
+func (q *QueueAdapter) SetClock(clock Clock) {
+	q.after = clock.After
+}
+
+// Run pulls and syncs messages every `interval`, until ctx is canceled.
+func (q *QueueAdapter) Run(ctx context.Context, interval time.Duration) {
+	for {
+		if err := q.SyncOnce(ctx); err != nil {
+			q.Logger.Errorf("Unable to sync sources from the queue: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.after(interval):
+		}
+	}
+}
+
+// SyncOnce pulls the waiting messages and syncs their sources.
+func (q *QueueAdapter) SyncOnce(ctx context.Context) error {
+	messages, err := q.queue.Pull(ctx, q.MaxMessages)
+	if err != nil || len(messages) == 0 {
+		return err
+	}
+	log := q.Logger
+
+	sources := []IssueSource{}
+	// bySource maps source IDs to the messages holding them, by index.
+	bySource := map[string][]int{}
+	// pending counts the sources of a message, -1 for invalid messages.
+	pending := make([]int, len(messages))
+	for i, m := range messages {
+		parsed, err := ReadJSONSources(bytes.NewReader(m.Data))
+		if err != nil {
+			log.Errorf("Dropping invalid message: %v", err)
+			pending[i] = -1
+			continue
+		}
+		for _, s := range parsed {
+			if _, ok := bySource[s.ID()]; !ok {
+				sources = append(sources, s)
+			}
+			bySource[s.ID()] = append(bySource[s.ID()], i)
+			pending[i]++
+		}
+	}
+
+	if len(sources) > 0 {
+		if _, err := q.syncer.SyncAll(ctx, sources); err != nil {
+			log.Warningf("Unable to sync all sources from the queue: %v", err)
+		}
+	}
+	for _, s := range sources {
+		if !q.syncer.Synced(s.ID()) {
+			continue
+		}
+		for _, i := range bySource[s.ID()] {
+			pending[i]--
+		}
+	}
+
+	// A message is acked once all of its sources were synced.
+	for i, m := range messages {
+		q.settle(m, pending[i] <= 0)
+	}
+	return nil
+}
+
+// settle acks or nacks the message, logging failures: the queue delivers
+// the message again, which is harmless since syncing is idempotent.
+func (q *QueueAdapter) settle(m Message, ack bool) {
+	var err error
+	if ack {
+		err = m.Ack()
+	} else {
+		err = m.Nack()
+	}
+	if err != nil {
+		q.Logger.Warningf("Unable to settle message (ack=%v): %v", ack, err)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// failingFinder can't look anything up, so every sync fails.
+type failingFinder struct{}
+
+func (failingFinder) AllIssuesForKey(key string) []int { return nil }
+func (failingFinder) Created(key string, number int)   {}
+func (failingFinder) IssuesForKey(key string) ([]int, error) {
+	return nil, fmt.Errorf("can't look up %q", key)
+}
+
+type fakeQueue struct {
+	data    []string
+	settled map[string]bool
+}
+
+func (f *fakeQueue) Pull(ctx context.Context, max int) ([]Message, error) {
+	messages := []Message{}
+	for _, d := range f.data {
+		d := d
+		messages = append(messages, Message{
+			Data: []byte(d),
+			Ack:  func() error { f.settled[d] = true; return nil },
+			Nack: func() error { f.settled[d] = false; return nil },
+		})
+	}
+	return messages, nil
+}
+
+func TestQueueAdapter(t *testing.T) {
+	synced := `{"title": "a", "id": "synced"}`
+	failing := `{"title": "b", "id": "failing"}`
+	mixed := `[{"title": "a", "id": "synced"}, {"title": "b", "id": "failing"}]`
+	invalid := `{"title": "c"}`
+	q := &fakeQueue{
+		data:    []string{synced, failing, mixed, invalid},
+		settled: map[string]bool{},
+	}
+	s := NewIssueSyncer(nil, failingFinder{})
+	s.synced.Insert("synced")
+
+	if err := NewQueueAdapter(q, s).SyncOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]bool{synced: true, failing: false, mixed: false, invalid: true}
+	for d, ack := range expected {
+		got, ok := q.settled[d]
+		if !ok {
+			t.Errorf("message %v was not settled", d)
+		} else if got != ack {
+			t.Errorf("message %v: expected ack=%v, got %v", d, ack, got)
+		}
+	}
+}
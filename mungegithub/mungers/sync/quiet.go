@@ -0,0 +1,212 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// QuietWindow is a recurring period during which the syncer is quiet, e.g.
+// the weekend, or every night.
+type QuietWindow struct {
+	// Days are the days of the week the window is on, every day if empty.
+	Days []time.Weekday
+	// Start and End are the times of day ("15:04") the window starts and
+	// ends, the whole day if both are empty. A window which ends before
+	// it starts goes on past midnight, e.g. 22:00 to 06:00.
+	Start, End string
+}
+
+// QuietHours decides when the syncer holds off, e.g. over the weekend or
+// during a code freeze: sources are held, without any calls to github, and
+// synced first once the quiet hours are over. For example:
+//
+//	timezone: America/Los_Angeles
+//	windows:
+//	- days: [Saturday, Sunday]
+//	- start: "22:00"
+//	  end: "06:00"
+//	freezes: true
+//	freezeFile: /etc/sync-freeze/frozen
+type QuietHours struct {
+	// Location is where windows are in. Defaults to UTC.
+	Location *time.Location
+	Windows  []QuietWindow
+	// Freezes, if true, has the syncer quiet during the freezes of its
+	// Calendar as well.
+	Freezes bool
+	// FreezeFile, if set, is a file (e.g. a mounted ConfigMap key) which
+	// freezes the syncer while it says "true".
+	FreezeFile string
+}
+
+// quietConfig is how quiet hours are written down, see QuietHours.
+type quietConfig struct {
+	Timezone string `json:"timezone"`
+	Windows  []struct {
+		Days  []string `json:"days"`
+		Start string   `json:"start"`
+		End   string   `json:"end"`
+	} `json:"windows"`
+	Freezes    bool   `json:"freezes"`
+	FreezeFile string `json:"freezeFile"`
+}
+
+// LoadQuietHours reads quiet hours from a yaml (or json) file.
+func LoadQuietHours(path string) (*QuietHours, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cfg := quietConfig{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("error parsing quiet hours %v: %v", path, err)
+	}
+	q := &QuietHours{Location: time.UTC, Freezes: cfg.Freezes, FreezeFile: cfg.FreezeFile}
+	if cfg.Timezone != "" {
+		if q.Location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	for _, w := range cfg.Windows {
+		window := QuietWindow{Start: w.Start, End: w.End}
+		for _, name := range w.Days {
+			day, ok := weekdays[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown weekday %q in %v", name, path)
+			}
+			window.Days = append(window.Days, day)
+		}
+		for _, t := range []string{w.Start, w.End} {
+			if _, err := minuteOfDay(t); err != nil {
+				return nil, fmt.Errorf("invalid window in %v: %v", path, err)
+			}
+		}
+		q.Windows = append(q.Windows, window)
+	}
+	return q, nil
+}
+
+// minuteOfDay parses "15:04" into minutes since midnight. "" is midnight.
+func minuteOfDay(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// on returns true if the window is on `day` of the week.
+func (w QuietWindow) on(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if `t` is within the window.
+func (w QuietWindow) contains(t time.Time) bool {
+	start, _ := minuteOfDay(w.Start)
+	end, _ := minuteOfDay(w.End)
+	now := t.Hour()*60 + t.Minute()
+	switch {
+	case start == end:
+		return w.on(t.Weekday())
+	case start < end:
+		return w.on(t.Weekday()) && now >= start && now < end
+	case now >= start:
+		return w.on(t.Weekday())
+	default:
+		// After midnight, in the window which started the day before.
+		return now < end && w.on(t.AddDate(0, 0, -1).Weekday())
+	}
+}
+
+// Quiet returns true if `t` is within quiet hours. `cal` is the syncer's
+// calendar, for Freezes.
+func (q *QuietHours) Quiet(t time.Time, cal *Calendar) bool {
+	if q == nil {
+		return false
+	}
+	if q.Freezes && cal.Frozen(t) {
+		return true
+	}
+	if q.FreezeFile != "" {
+		if data, err := ioutil.ReadFile(q.FreezeFile); err == nil && strings.TrimSpace(strings.ToLower(string(data))) == "true" {
+			return true
+		}
+	}
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, w := range q.Windows {
+		if w.contains(t.In(loc)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hold keeps `sources` until release, see QuietHours. A source held again
+// replaces the earlier one with its ID.
+func (s *IssueSyncer) hold(sources []IssueSource) {
+	if s.held == nil {
+		s.held = map[string]IssueSource{}
+	}
+	for _, source := range sources {
+		s.held[source.ID()] = source
+	}
+}
+
+// release returns the held sources followed by `sources`, and forgets the
+// held ones. Held sources which are synced again in `sources`, e.g. because
+// the caller queued them again when they were deferred, are only synced
+// once.
+func (s *IssueSyncer) release(sources []IssueSource) []IssueSource {
+	for _, source := range sources {
+		delete(s.held, source.ID())
+	}
+	ids := []string{}
+	for id := range s.held {
+		ids = append(ids, id)
+	}
+	// In a stable order, rather than the map's.
+	sort.Strings(ids)
+	released := []IssueSource{}
+	for _, id := range ids {
+		released = append(released, s.held[id])
+	}
+	s.held = nil
+	return append(released, sources...)
+}
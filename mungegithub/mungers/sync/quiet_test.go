@@ -0,0 +1,106 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestQuietHours(t *testing.T) {
+	file, err := ioutil.TempFile("", "quiet")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	freeze, err := ioutil.TempFile("", "freeze")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(freeze.Name())
+	file.WriteString(`
+windows:
+- days: [Saturday, Sunday]
+- days: [Friday]
+  start: "22:00"
+  end: "06:00"
+freezes: true
+freezeFile: ` + freeze.Name() + "\n")
+	file.Close()
+
+	q, err := LoadQuietHours(file.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cal := NewCalendar()
+	cal.Freezes = []Period{{Start: date("2016-12-19 00:00"), End: date("2017-01-03 00:00")}}
+	tests := []struct {
+		when   string
+		frozen bool
+		quiet  bool
+	}{
+		{when: "2016-07-01 21:59"},              // Friday
+		{when: "2016-07-01 22:00", quiet: true}, // Friday night
+		{when: "2016-07-02 05:00", quiet: true}, // Saturday
+		{when: "2016-07-04 05:00"},              // Monday
+		{when: "2016-07-09 03:00", quiet: true}, // After Friday midnight
+		{when: "2016-07-08 03:00"},              // After Thursday midnight
+		{when: "2016-12-20 12:00", quiet: true}, // Calendar freeze
+		{when: "2016-07-05 12:00", frozen: true, quiet: true},
+	}
+	for _, test := range tests {
+		state := "false"
+		if test.frozen {
+			state = "true\n"
+		}
+		ioutil.WriteFile(freeze.Name(), []byte(state), 0644)
+		if got := q.Quiet(date(test.when), cal); got != test.quiet {
+			t.Errorf("%v: expected quiet %v, got %v", test.when, test.quiet, got)
+		}
+	}
+}
+
+func TestSyncAllHoldsDuringQuietHours(t *testing.T) {
+	s := NewIssueSyncer(nil, failingFinder{})
+	s.QuietHours = &QuietHours{Windows: []QuietWindow{{Days: []time.Weekday{time.Saturday}}}}
+	s.now = func() time.Time { return date("2016-07-02 12:00") }
+	sources := []IssueSource{&testSource{title: "a", id: "1"}, &testSource{title: "b", id: "2"}}
+	if _, err := s.SyncAll(context.Background(), sources); err != nil {
+		t.Fatalf("expected sources to be held, got %v", err)
+	}
+	if _, err := s.SyncAll(context.Background(), sources[:1]); err != nil {
+		t.Fatalf("expected sources to be held, got %v", err)
+	}
+	if len(s.held) != 2 {
+		t.Errorf("expected 2 sources held, got %v", s.held)
+	}
+
+	// A held source synced again is only synced once.
+	s.now = func() time.Time { return date("2016-07-04 12:00") }
+	_, err := s.SyncAll(context.Background(), sources[:1])
+	if err == nil || !strings.HasPrefix(err.Error(), "2 of 2 sources failed") {
+		t.Errorf("expected the held sources to be synced once, got %v", err)
+	}
+	if len(s.held) != 0 {
+		t.Errorf("expected no sources held, got %v", s.held)
+	}
+}
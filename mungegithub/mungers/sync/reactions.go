@@ -0,0 +1,79 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// DefaultReaction is what busy issues are reacted to with, see ReactAfter.
+const DefaultReaction = "eyes"
+
+// DefaultMuteLabel is what humans label issues with to silence them, see
+// MuteLabel.
+const DefaultMuteLabel = "bot-mute"
+
+// quiet returns true if issue `n` had so many occurrences that new ones are
+// only recorded in the store and marked with a reaction, see ReactAfter.
+// Editing the body doesn't notify anyone, so there is no need with EditBody.
+func (s *IssueSyncer) quiet(n int) bool {
+	if s.ReactAfter <= 0 || s.EditBody || s.TaskList != nil {
+		return false
+	}
+	r, ok := s.Store.Get(n)
+	return ok && r.Occurrences >= s.ReactAfter
+}
+
+// muted returns true if humans labeled `obj` to only have occurrences
+// recorded in the store, see MuteLabel.
+func (s *IssueSyncer) muted(obj *github.MungeObject) bool {
+	return s.MuteLabel != "" && obj.HasLabel(s.MuteLabel)
+}
+
+// react marks that we saw another occurrence on `obj`, without notifying
+// everyone watching it.
+func (s *IssueSyncer) react(ctx context.Context, obj *github.MungeObject) error {
+	n := *obj.Issue.Number
+	reaction := s.Reaction
+	if reaction == "" {
+		reaction = DefaultReaction
+	}
+	if err := s.retry(ctx, fmt.Sprintf("reacting to %v", n), func() error {
+		return obj.AddReaction(reaction)
+	}); err != nil {
+		return err
+	}
+	s.audit(AuditReact, n, reaction)
+	return nil
+}
+
+// seen returns true if the store recorded source `id` for issue `n`.
+func (s *IssueSyncer) seen(n int, id string) bool {
+	r, ok := s.Store.Get(n)
+	if !ok {
+		return false
+	}
+	for _, seen := range r.Seen {
+		if seen == id {
+			return true
+		}
+	}
+	return false
+}
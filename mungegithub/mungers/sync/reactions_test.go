@@ -0,0 +1,111 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestQuiet(t *testing.T) {
+	tests := []struct {
+		name        string
+		reactAfter  int
+		editBody    bool
+		occurrences int
+		expected    bool
+	}{
+		{name: "disabled", occurrences: 100},
+		{name: "quiet", reactAfter: 10, occurrences: 10, expected: true},
+		{name: "not busy yet", reactAfter: 10, occurrences: 9},
+		{name: "edit body", reactAfter: 10, editBody: true, occurrences: 10},
+	}
+	for _, test := range tests {
+		s := NewIssueSyncer(nil, nil)
+		s.ReactAfter = test.reactAfter
+		s.EditBody = test.editBody
+		s.Store.Update(1, func(r *IssueRecord) { r.Occurrences = test.occurrences })
+		if got := s.quiet(1); got != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestReact(t *testing.T) {
+	issue := github_test.Issue("bot", 1, nil, false)
+	client, server, mux := github_test.InitServer(t, issue, nil, nil, nil, nil, nil)
+	defer server.Close()
+	config := &github.Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+	reactions := []string{}
+	mux.HandleFunc("/repos/o/r/issues/1/reactions", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		reactions = append(reactions, string(body))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	})
+
+	s := NewIssueSyncer(config, nil)
+	obj := github.TestObject(config, issue, nil, nil, nil)
+	if err := s.react(context.Background(), obj); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reactions) != 1 || reactions[0] != "{\"content\":\"eyes\"}\n" {
+		t.Errorf("unexpected reactions %q", reactions)
+	}
+
+	s.Store.Update(1, func(r *IssueRecord) { r.Seen = []string{"a"} })
+	if !s.seen(1, "a") || s.seen(1, "b") || s.seen(2, "a") {
+		t.Errorf("unexpected seen sources: %v", s.Store.List())
+	}
+}
+
+func TestMuteLabel(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	n := tracker.AddIssue("TestFoo", "Failed", DefaultMuteLabel)
+
+	for _, ref := range []string{"foo-1", "foo-2"} {
+		if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if comments := tracker.Comments(n); len(comments) != 0 {
+		t.Errorf("expected no comments on the muted issue, got %q", comments)
+	}
+	if r, _ := s.Store.Get(n); r.Occurrences != 2 || len(r.Seen) != 2 {
+		t.Errorf("expected the occurrences to be counted, got %+v", r)
+	}
+
+	tracker.RemoveLabel(n, DefaultMuteLabel)
+	if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: "foo-3"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if comments := tracker.Comments(n); len(comments) != 1 || !strings.Contains(comments[0], "foo-3") {
+		t.Errorf("expected a comment about foo-3 once unmuted, got %q", comments)
+	}
+}
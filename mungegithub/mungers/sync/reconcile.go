@@ -0,0 +1,205 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// orphanCloseText is what we comment on orphans when we close them.
+const orphanCloseText = "None of the sources of this issue are active any more. Closing it; it will be filed again if they come back."
+
+// ReconcileReport is how the open issues of the syncer drifted from the
+// sources which are active, see Reconcile.
+type ReconcileReport struct {
+	// Orphans are open issues of ours on which none of the active
+	// sources are recorded.
+	Orphans []int
+	// Missing are the IDs of active sources without an open issue.
+	Missing []string
+	// Unlabeled are open issues of ours which lack some of the labels
+	// all our issues get (see ExtraLabels), with the ones they lack. The
+	// finder doesn't find them.
+	Unlabeled map[int][]string
+	// Fixed is set if the drift was fixed: orphans closed, missing
+	// sources synced and missing labels added.
+	Fixed bool
+}
+
+// Drifted returns true if any drift was found.
+func (r *ReconcileReport) Drifted() bool {
+	return len(r.Orphans) > 0 || len(r.Missing) > 0 || len(r.Unlabeled) > 0
+}
+
+// String lists the drift, an issue or source per line.
+func (r *ReconcileReport) String() string {
+	var b bytes.Buffer
+	for _, n := range r.Orphans {
+		fmt.Fprintf(&b, "orphaned: #%d\n", n)
+	}
+	for _, id := range r.Missing {
+		fmt.Fprintf(&b, "missing: %v\n", id)
+	}
+	unlabeled := []int{}
+	for n := range r.Unlabeled {
+		unlabeled = append(unlabeled, n)
+	}
+	sort.Ints(unlabeled)
+	for _, n := range unlabeled {
+		fmt.Fprintf(&b, "unlabeled: #%d lacks %v\n", n, strings.Join(r.Unlabeled[n], ", "))
+	}
+	fixed := ""
+	if r.Fixed {
+		fixed = ", fixed"
+	}
+	fmt.Fprintf(&b, "%d orphaned, %d missing, %d unlabeled%v\n", len(r.Orphans), len(r.Missing), len(r.Unlabeled), fixed)
+	return b.String()
+}
+
+// Reconcile compares `sources`, all of the sources which are active, with
+// the open issues of ours: those with all of ExtraLabels, and those in the
+// Store. If `fix` is set, orphans are closed, missing sources synced
+// (through SyncAll, with its caps) and missing labels added. Sensitive
+// sources, which aren't filed in our repo, are left out.
+func (s *IssueSyncer) Reconcile(ctx context.Context, sources []IssueSource, fix bool) (*ReconcileReport, error) {
+	var issues []*githubapi.Issue
+	if err := s.retry(ctx, "listing the open issues", func() (err error) {
+		issues, err = s.client(ctx).ListAllIssues(&githubapi.IssueListByRepoOptions{State: "open"})
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	required := []string{}
+	for _, l := range s.ExtraLabels {
+		required = append(required, Namespaced(s.Namespace, l))
+	}
+	internalTitle := s.title(&capSource{})
+	ours := []*githubapi.Issue{}
+	for _, issue := range issues {
+		if issue.Number == nil || issue.Title == nil || issue.PullRequestLinks != nil || *issue.Title == internalTitle {
+			continue
+		}
+		r, filed := s.Store.Get(*issue.Number)
+		if (len(required) > 0 && hasLabels(issue, required)) || (filed && !r.Created.IsZero()) {
+			ours = append(ours, issue)
+		}
+	}
+
+	report := &ReconcileReport{Unlabeled: map[int][]string{}}
+	matched, orphaned := map[int]bool{}, map[int]bool{}
+	missing := []IssueSource{}
+	for _, source := range sources {
+		if isSensitive(source) {
+			continue
+		}
+		keyed := source
+		if key := groupKey(source); key != "" {
+			keyed = &umbrellaSource{source, key}
+		}
+		found := false
+		for _, issue := range ours {
+			if s.reconciles(issue, keyed) {
+				matched[*issue.Number], found = true, true
+			}
+		}
+		if !found {
+			report.Missing = append(report.Missing, source.ID())
+			missing = append(missing, source)
+		}
+	}
+	for _, issue := range ours {
+		n := *issue.Number
+		r, _ := s.Store.Get(n)
+		// Children of fanned out sources live as long as their parent.
+		if !matched[n] && !(r.Parent != 0 && matched[r.Parent]) {
+			report.Orphans = append(report.Orphans, n)
+			orphaned[n] = true
+		}
+		lacking := []string{}
+		for _, l := range required {
+			if !hasLabels(issue, []string{l}) {
+				lacking = append(lacking, l)
+			}
+		}
+		if len(lacking) > 0 {
+			report.Unlabeled[n] = lacking
+		}
+	}
+	sort.Ints(report.Orphans)
+	s.logger().Infof("Reconciled %d sources with %d open issues: %d orphaned, %d missing, %d unlabeled", len(sources), len(ours), len(report.Orphans), len(report.Missing), len(report.Unlabeled))
+	if !fix || !report.Drifted() {
+		return report, nil
+	}
+
+	for _, issue := range ours {
+		n := *issue.Number
+		obj := s.config.IssueObject(issue)
+		for _, l := range report.Unlabeled[n] {
+			if err := s.addLabel(ctx, obj, l); err != nil {
+				return report, err
+			}
+		}
+		if orphaned[n] {
+			if err := s.closeOrphan(ctx, obj); err != nil {
+				return report, err
+			}
+		}
+	}
+	if len(missing) > 0 {
+		if _, err := s.SyncAll(ctx, missing); err != nil {
+			return report, err
+		}
+	}
+	metrics.Add("reconciledOrphans", int64(len(report.Orphans)))
+	metrics.Add("reconciledMissing", int64(len(report.Missing)))
+	metrics.Add("reconciledUnlabeled", int64(len(report.Unlabeled)))
+	report.Fixed = true
+	return report, nil
+}
+
+// reconciles returns true if `source` is recorded in `issue`: it embeds the
+// source's sync key, or is titled like it.
+func (s *IssueSyncer) reconciles(issue *githubapi.Issue, source IssueSource) bool {
+	if issue.Body != nil && strings.Contains(*issue.Body, SyncKeyMarker(s.syncKey(source))) {
+		return true
+	}
+	return s.Normalizer.Normalize(*issue.Title) == s.key(source)
+}
+
+// closeOrphan closes `obj`, which none of the active sources are recorded
+// on.
+func (s *IssueSyncer) closeOrphan(ctx context.Context, obj *github.MungeObject) error {
+	n := *obj.Issue.Number
+	s.logger().With("issue", n).Infof("Closing, none of its sources are active")
+	if err := s.writeComment(ctx, fmt.Sprintf("commenting on orphaned issue %v", n), obj, s.text(orphanCloseText)); err != nil {
+		return err
+	}
+	if err := s.closeIssue(ctx, fmt.Sprintf("closing orphaned issue %v", n), obj); err != nil {
+		return err
+	}
+	if err := s.noticeClosed(ctx, n); err != nil {
+		return err
+	}
+	return s.closedAs(n, ClosedStale, 0)
+}
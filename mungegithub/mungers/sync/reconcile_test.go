@@ -0,0 +1,83 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestReconcile(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.ExtraLabels = []string{"kind/flake"}
+
+	foo := &JSONSource{Key: "TestFoo", Ref: "foo-1"}
+	if _, err := s.SyncAll(context.Background(), []IssueSource{foo, &JSONSource{Key: "TestBar", Ref: "bar-1"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fooIssue, barIssue := tracker.OpenIssues("TestFoo")[0], tracker.OpenIssues("TestBar")[0]
+	tracker.RemoveLabel(fooIssue, "kind/flake")
+	bazIssue := tracker.AddIssue("TestBaz", "filed by hand", "kind/flake")
+	tracker.AddIssue("Unrelated", "not ours")
+
+	active := []IssueSource{foo, &JSONSource{Key: "TestQux", Ref: "qux-1"}}
+	report, err := s.Reconcile(context.Background(), active, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &ReconcileReport{
+		Orphans:   []int{barIssue, bazIssue},
+		Missing:   []string{"qux-1"},
+		Unlabeled: map[int][]string{fooIssue: {"kind/flake"}},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v, got %+v", expected, report)
+	}
+	if len(tracker.OpenIssues("TestQux")) != 0 || len(tracker.OpenIssues("TestBar")) != 1 {
+		t.Errorf("expected nothing to change without fixing")
+	}
+
+	if report, err = s.Reconcile(context.Background(), active, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.Fixed {
+		t.Errorf("expected the drift to be fixed")
+	}
+	if len(tracker.OpenIssues("TestBar")) != 0 || len(tracker.OpenIssues("TestBaz")) != 0 {
+		t.Errorf("expected the orphans to be closed")
+	}
+	if r, _ := s.Store.Get(barIssue); r.ClosedAs != ClosedStale {
+		t.Errorf("expected the orphan to be recorded as closed stale, got %+v", r)
+	}
+	if len(tracker.OpenIssues("TestQux")) != 1 {
+		t.Errorf("expected the missing source to be filed")
+	}
+
+	if report, err = s.Reconcile(context.Background(), active, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Drifted() {
+		t.Errorf("expected no drift once fixed, got %v", report)
+	}
+}
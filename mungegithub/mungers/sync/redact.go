@@ -0,0 +1,172 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// RedactionRule replaces what Pattern matches in source bodies with
+// Replacement, which may refer to the pattern's groups like ${1}. An empty
+// Replacement is "[redacted <Name>]".
+type RedactionRule struct {
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+
+	re *regexp.Regexp
+}
+
+// DefaultRedactionRules redact what CI logs commonly leak: IP addresses and
+// bearer tokens. Internal hostnames differ for every org, add a rule for
+// them.
+var DefaultRedactionRules = []RedactionRule{
+	{Name: "ip-address", Pattern: `\b(?:\d{1,3}\.){3}\d{1,3}\b`},
+	{Name: "bearer-token", Pattern: `(?i)\b(bearer\s+)[\w\-.~+/]+=*`, Replacement: "${1}[redacted bearer-token]"},
+}
+
+// Redaction is a match of a rule, see Redactor.Report.
+type Redaction struct {
+	Rule string
+	// Line is the 1-based line of Text.
+	Line int
+	Text string
+}
+
+// Redactor keeps what must not leak out of CI logs from public issues: the
+// syncer applies its rules to every source's Body before posting it. The
+// source's ID is kept, since the syncer finds sources by it. With
+// ReportOnly, nothing is redacted and what would be is only logged, to try
+// rules out.
+type Redactor struct {
+	Rules      []RedactionRule
+	ReportOnly bool
+}
+
+// NewRedactor compiles `rules`.
+func NewRedactor(rules []RedactionRule) (*Redactor, error) {
+	r := &Redactor{}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("redaction rule %q has no name", rule.Pattern)
+		}
+		var err error
+		if rule.re, err = regexp.Compile(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid redaction rule %q: %v", rule.Name, err)
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = fmt.Sprintf("[redacted %v]", rule.Name)
+		}
+		r.Rules = append(r.Rules, rule)
+	}
+	return r, nil
+}
+
+// redactorConfig is how redactors are written down, e.g.:
+//
+//	reportOnly: true
+//	defaults: true
+//	rules:
+//	- name: internal-hostname
+//	  pattern: '\b[\w.-]+\.corp\.example\.com\b'
+type redactorConfig struct {
+	ReportOnly bool            `json:"reportOnly"`
+	Defaults   bool            `json:"defaults"`
+	Rules      []RedactionRule `json:"rules"`
+}
+
+// LoadRedactor reads a redactor from a yaml (or json) file. With "defaults"
+// the DefaultRedactionRules come before its rules.
+func LoadRedactor(path string) (*Redactor, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cfg := redactorConfig{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("error parsing redaction rules %v: %v", path, err)
+	}
+	rules := cfg.Rules
+	if cfg.Defaults {
+		rules = append(append([]RedactionRule{}, DefaultRedactionRules...), rules...)
+	}
+	r, err := NewRedactor(rules)
+	if err != nil {
+		return nil, fmt.Errorf("error in %v: %v", path, err)
+	}
+	r.ReportOnly = cfg.ReportOnly
+	return r, nil
+}
+
+// Redact returns `text` with every match of the rules replaced, except for
+// `keep`, and what was replaced. Rules apply in order.
+func (r *Redactor) Redact(text, keep string) (string, []Redaction) {
+	if r == nil {
+		return text, nil
+	}
+	// Set what is kept aside, so that no rule matches it.
+	placeholder := ""
+	if keep != "" && strings.Contains(text, keep) {
+		placeholder = "\x00keep\x00"
+		text = strings.Replace(text, keep, placeholder, -1)
+	}
+	redactions := []Redaction{}
+	for _, rule := range r.Rules {
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			for _, m := range rule.re.FindAllString(line, -1) {
+				redactions = append(redactions, Redaction{Rule: rule.Name, Line: i + 1, Text: m})
+			}
+			lines[i] = rule.re.ReplaceAllString(line, rule.Replacement)
+		}
+		text = strings.Join(lines, "\n")
+	}
+	if placeholder != "" {
+		text = strings.Replace(text, placeholder, keep, -1)
+	}
+	return text, redactions
+}
+
+// Report returns what Redact would replace in `text`.
+func (r *Redactor) Report(text, keep string) []Redaction {
+	_, redactions := r.Redact(text, keep)
+	return redactions
+}
+
+// redact applies the syncer's Redactor to the body of `source`.
+func (s *IssueSyncer) redact(source IssueSource, body string) string {
+	redacted, redactions := s.Redactor.Redact(body, source.ID())
+	if len(redactions) == 0 {
+		return body
+	}
+	if s.Redactor.ReportOnly {
+		for _, r := range redactions {
+			s.logger().Warningf("Would redact %q on line %d (rule %v)", r.Text, r.Line, r.Rule)
+		}
+		metrics.Add("redactionsReported", int64(len(redactions)))
+		return body
+	}
+	s.logger().Debugf("Redacted %d matches", len(redactions))
+	metrics.Add("redactions", int64(len(redactions)))
+	return redacted
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestRedact(t *testing.T) {
+	r, err := NewRedactor(append(DefaultRedactionRules, RedactionRule{Name: "internal-hostname", Pattern: `\b[\w.-]+\.corp\.example\.com\b`}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name     string
+		body     string
+		keep     string
+		expected string
+		rules    []string
+	}{
+		{
+			name:     "nothing to redact",
+			body:     "TestFoo failed",
+			expected: "TestFoo failed",
+			rules:    []string{},
+		},
+		{
+			name:     "ip address",
+			body:     "dial tcp 10.240.0.4:443: timeout",
+			expected: "dial tcp [redacted ip-address]:443: timeout",
+			rules:    []string{"ip-address"},
+		},
+		{
+			name:     "bearer token",
+			body:     "curl\nAuthorization: Bearer abc.DEF-123=\ndone",
+			expected: "curl\nAuthorization: Bearer [redacted bearer-token]\ndone",
+			rules:    []string{"bearer-token"},
+		},
+		{
+			name:     "custom rule",
+			body:     "pulling from registry.corp.example.com failed on 10.0.0.1",
+			expected: "pulling from [redacted internal-hostname] failed on [redacted ip-address]",
+			rules:    []string{"ip-address", "internal-hostname"},
+		},
+		{
+			name:     "id is kept",
+			body:     "http://10.0.0.1/run/1 failed on 10.0.0.2",
+			keep:     "http://10.0.0.1/run/1",
+			expected: "http://10.0.0.1/run/1 failed on [redacted ip-address]",
+			rules:    []string{"ip-address"},
+		},
+	}
+	for _, test := range tests {
+		got, redactions := r.Redact(test.body, test.keep)
+		if got != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.name, test.expected, got)
+		}
+		rules := []string{}
+		for _, redaction := range redactions {
+			rules = append(rules, redaction.Rule)
+		}
+		if strings.Join(rules, ",") != strings.Join(test.rules, ",") {
+			t.Errorf("%v: expected redactions by %v, got %v", test.name, test.rules, redactions)
+		}
+	}
+
+	if report := r.Report("a\nb 1.2.3.4", ""); len(report) != 1 || report[0].Line != 2 || report[0].Text != "1.2.3.4" {
+		t.Errorf("unexpected report %v", report)
+	}
+	if _, err := NewRedactor([]RedactionRule{{Name: "bad", Pattern: "("}}); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
+
+func TestLoadRedactor(t *testing.T) {
+	file, err := ioutil.TempFile("", "redact")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`
+reportOnly: true
+defaults: true
+rules:
+- name: internal-hostname
+  pattern: '\b[\w.-]+\.corp\.example\.com\b'
+  replacement: internal-host
+`)
+	file.Close()
+
+	r, err := LoadRedactor(file.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !r.ReportOnly || len(r.Rules) != len(DefaultRedactionRules)+1 {
+		t.Errorf("unexpected redactor %+v", r)
+	}
+	if got, _ := r.Redact("a.corp.example.com at 1.2.3.4", ""); got != "internal-host at [redacted ip-address]" {
+		t.Errorf("unexpected redaction %q", got)
+	}
+}
+
+func TestSyncRedacts(t *testing.T) {
+	for _, reportOnly := range []bool{false, true} {
+		tracker := synctesting.NewTracker()
+		defer tracker.Close()
+		finder := NewSearchFinder(tracker.Config(), nil)
+		finder.MinInterval = 0
+		s := NewIssueSyncer(tracker.Config(), finder)
+		var err error
+		if s.Redactor, err = NewRedactor(DefaultRedactionRules); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s.Redactor.ReportOnly = reportOnly
+		for _, ref := range []string{"run-1", "run-2"} {
+			if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref, Details: "node 10.0.0.1 is down"}); err != nil {
+				t.Fatalf("unexpected error syncing %v: %v", ref, err)
+			}
+		}
+		n := tracker.OpenIssues("TestFoo")[0]
+		texts := append([]string{*tracker.Issues()[0].Body}, tracker.Comments(n)...)
+		for _, text := range texts {
+			if leaked := strings.Contains(text, "10.0.0.1"); leaked != reportOnly {
+				t.Errorf("report only %v: unexpected %q", reportOnly, text)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/context"
+)
+
+// RelatedIssues finds issues which look related to a newly filed one, so
+// that humans can spot shared root causes. Issues are related if their titles
+// are similar or if they are about tests in the same package.
+type RelatedIssues struct {
+	// MinSimilarity is the least title similarity, from 0 to 1, for an
+	// issue to count as related.
+	MinSimilarity float64
+	// MaxLinks is the most issues linked from a new issue.
+	MaxLinks int
+}
+
+// NewRelatedIssues returns a RelatedIssues with the default settings.
+func NewRelatedIssues() *RelatedIssues {
+	return &RelatedIssues{
+		MinSimilarity: 0.6,
+		MaxLinks:      5,
+	}
+}
+
+type relatedIssue struct {
+	number int
+	score  float64
+}
+
+type byRelatedness []relatedIssue
+
+func (b byRelatedness) Len() int      { return len(b) }
+func (b byRelatedness) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byRelatedness) Less(i, j int) bool {
+	if b[i].score != b[j].score {
+		return b[i].score > b[j].score
+	}
+	// Prefer recent issues.
+	return b[i].number > b[j].number
+}
+
+// Find returns the numbers of the records related to an issue titled
+// `title`, most related first.
+func (r *RelatedIssues) Find(title string, records []IssueRecord) []int {
+	related := []relatedIssue{}
+	pkg := testPackage(title)
+	for _, rec := range records {
+		if rec.Title == "" || rec.Title == title {
+			continue
+		}
+		a, b, recPkg := title, rec.Title, testPackage(rec.Title)
+		if pkg != "" && recPkg != "" {
+			// Package paths look alike; compare the test names.
+			a, b = strings.TrimPrefix(a, pkg), strings.TrimPrefix(b, recPkg)
+		}
+		score := titleSimilarity(a, b)
+		if score < r.MinSimilarity && (pkg == "" || recPkg != pkg) {
+			continue
+		}
+		related = append(related, relatedIssue{rec.Number, score})
+	}
+	sort.Sort(byRelatedness(related))
+	if r.MaxLinks > 0 && len(related) > r.MaxLinks {
+		related = related[:r.MaxLinks]
+	}
+	numbers := []int{}
+	for _, rel := range related {
+		numbers = append(numbers, rel.number)
+	}
+	return numbers
+}
+
+// linkRelated comments on the new issue `number` with links to related
+// issues. Github shows the links on the related issues as well. Errors are
+// only logged: the issue was filed, which is what matters.
+func (s *IssueSyncer) linkRelated(ctx context.Context, number int, title string) {
+	if s.Related == nil {
+		return
+	}
+	related := s.Related.Find(title, s.Store.List())
+	if len(related) == 0 {
+		return
+	}
+	links := []string{}
+	for _, n := range related {
+		if n != number {
+			links = append(links, fmt.Sprintf("#%v", n))
+		}
+	}
+	if len(links) == 0 {
+		return
+	}
+	msg := s.text(fmt.Sprintf("Possibly related issues: %v", strings.Join(links, " ")))
+	obj, err := s.getObject(ctx, number)
+	if err == nil {
+		err = s.writeComment(ctx, fmt.Sprintf("linking related issues to %v", number), obj, msg)
+	}
+	if err != nil {
+		s.logger().With("issue", number).Errorf("Unable to link related issues: %v", err)
+	}
+}
+
+// testPackage guesses the package of the test a title is about: unit test
+// titles start with the package's import path. Returns "" if there's none.
+func testPackage(title string) string {
+	fields := strings.Fields(title)
+	if len(fields) < 2 || !strings.Contains(fields[0], "/") {
+		return ""
+	}
+	return strings.TrimSuffix(fields[0], ":")
+}
+
+// titleSimilarity scores how alike two titles are from 0 to 1, taking the
+// better of their token overlap and their edit distance.
+func titleSimilarity(a, b string) float64 {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	overlap := jaccard(tokens(a), tokens(b))
+	longest := len([]rune(a))
+	if l := len([]rune(b)); l > longest {
+		longest = l
+	}
+	if longest == 0 {
+		return 0
+	}
+	edit := 1 - float64(levenshtein(a, b))/float64(longest)
+	if edit > overlap {
+		return edit
+	}
+	return overlap
+}
+
+func tokens(s string) map[string]bool {
+	out := map[string]bool{}
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		out[t] = true
+	}
+	return out
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range a {
+		if b[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRelatedIssuesFind(t *testing.T) {
+	records := []IssueRecord{
+		{Number: 1, Title: "k8s.io/kubernetes/pkg/kubelet TestSyncPods"},
+		{Number: 2, Title: "k8s.io/kubernetes/pkg/kubelet TestGarbageCollect"},
+		{Number: 3, Title: "[k8s.io] Kubectl client [k8s.io] Simple pod should support exec"},
+		{Number: 4, Title: "[k8s.io] Kubectl client [k8s.io] Simple pod should support exec through an HTTP proxy"},
+		{Number: 5, Title: "[k8s.io] Networking should function for intra-pod communication"},
+		{Number: 6, Title: "k8s.io/kubernetes/pkg/kubelet TestSyncPods"},
+	}
+	tests := []struct {
+		title    string
+		expected []int
+	}{
+		{
+			title:    "k8s.io/kubernetes/pkg/kubelet TestSyncPods",
+			expected: []int{2},
+		},
+		{
+			title:    "[k8s.io] Kubectl client [k8s.io] Simple pod should support exec",
+			expected: []int{4},
+		},
+		{
+			title:    "[k8s.io] Kubectl client [k8s.io] Simple pod should support exec through an HTTPS proxy",
+			expected: []int{4, 3},
+		},
+		{
+			title:    "k8s.io/kubernetes/pkg/api TestRoundTrip",
+			expected: []int{},
+		},
+	}
+	r := NewRelatedIssues()
+	for _, test := range tests {
+		if got := r.Find(test.title, records); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.title, test.expected, got)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+	}
+	for _, test := range tests {
+		if got := levenshtein(test.a, test.b); got != test.expected {
+			t.Errorf("levenshtein(%q, %q): expected %v, got %v", test.a, test.b, test.expected, got)
+		}
+	}
+}
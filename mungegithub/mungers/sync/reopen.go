@@ -0,0 +1,117 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// maxResolutionLength is how much of a resolution comment is quoted.
+const maxResolutionLength = 500
+
+// ClosedMatch decides what happens when a source has no open issue, but
+// matches one which was closed recently: rather than starting from scratch
+// in a new issue, the old one is reopened, or linked from the new one, with
+// a summary of how it was handled.
+type ClosedMatch struct {
+	// Within is how recently the issue must have been closed.
+	Within time.Duration
+	// Reopen reopens the closed issue instead of filing a new one.
+	Reopen bool
+	// Bots are ignored when looking for the comment which resolved the
+	// issue.
+	Bots sets.String
+}
+
+// NewClosedMatch returns a ClosedMatch which links to issues closed in the
+// last two weeks.
+func NewClosedMatch() *ClosedMatch {
+	return &ClosedMatch{
+		Within: 14 * 24 * time.Hour,
+		Bots:   sets.NewString(),
+	}
+}
+
+// recentlyClosed returns the most recently closed of `closed`, if it was
+// closed within RecentlyClosed.Within.
+func (s *IssueSyncer) recentlyClosed(closed []*github.MungeObject) *github.MungeObject {
+	if s.RecentlyClosed == nil {
+		return nil
+	}
+	var latest *github.MungeObject
+	for _, obj := range closed {
+		at := obj.Issue.ClosedAt
+		if at == nil || s.now().Sub(*at) > s.RecentlyClosed.Within {
+			continue
+		}
+		if latest == nil || at.After(*latest.Issue.ClosedAt) {
+			latest = obj
+		}
+	}
+	return latest
+}
+
+// history summarizes how a closed issue was handled: when it was closed,
+// who it was assigned to, and the last comment by a human.
+func (s *IssueSyncer) history(ctx context.Context, obj *github.MungeObject) (string, error) {
+	n := *obj.Issue.Number
+	lines := []string{fmt.Sprintf("This was seen before in #%d, closed %v.", n, obj.Issue.ClosedAt.UTC().Format(dateFormat))}
+	if a := obj.Issue.Assignee; a != nil && a.Login != nil {
+		lines = append(lines, fmt.Sprintf("It was last assigned to @%v.", *a.Login))
+	}
+	err := s.retry(ctx, fmt.Sprintf("getting comments for %v", n), func() error {
+		comments, err := obj.ListComments()
+		if err != nil {
+			return err
+		}
+		for i := len(comments) - 1; i >= 0; i-- {
+			c := comments[i]
+			if c.User == nil || c.User.Login == nil || c.Body == nil || s.RecentlyClosed.Bots.Has(*c.User.Login) {
+				continue
+			}
+			body := strings.TrimSpace(*c.Body)
+			if len(body) > maxResolutionLength {
+				body = body[:maxResolutionLength] + "..."
+			}
+			lines = append(lines, fmt.Sprintf("The last comment, by @%v, was:\n\n> %v", *c.User.Login, strings.Replace(body, "\n", "\n> ", -1)))
+			return nil
+		}
+		return nil
+	})
+	return strings.Join(lines, " "), err
+}
+
+// reopen reopens a recently closed issue for the source, explaining why.
+func (s *IssueSyncer) reopen(ctx context.Context, obj *github.MungeObject, history string) error {
+	n := *obj.Issue.Number
+	s.logger().With("issue", n).Infof("Reopening, it was closed recently")
+	if err := s.retry(ctx, fmt.Sprintf("reopening %v", n), obj.ReopenIssue); err != nil {
+		return err
+	}
+	s.audit(AuditReopen, n, "")
+	if err := s.Store.Update(n, func(r *IssueRecord) { r.Closed = false }); err != nil {
+		s.logger().With("issue", n).Errorf("Unable to record reopening: %v", err)
+	}
+	msg := "Reopening, this happened again. " + strings.Replace(history, fmt.Sprintf("seen before in #%d", n), "seen before here", 1)
+	return s.writeComment(ctx, fmt.Sprintf("commenting on reopened %v", n), obj, s.text(msg))
+}
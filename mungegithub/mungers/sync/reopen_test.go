@@ -0,0 +1,89 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func closedIssue(config *github.Config, number int, closed string) *github.MungeObject {
+	issue := github_test.Issue("bot", number, nil, false)
+	state := "closed"
+	issue.State = &state
+	at := date(closed)
+	issue.ClosedAt = &at
+	return github.TestObject(config, issue, nil, nil, nil)
+}
+
+func TestRecentlyClosed(t *testing.T) {
+	s := NewIssueSyncer(nil, nil)
+	s.now = func() time.Time { return date("2016-07-20 12:00") }
+	closed := []*github.MungeObject{
+		closedIssue(nil, 1, "2016-06-01 12:00"),
+		closedIssue(nil, 2, "2016-07-10 12:00"),
+		closedIssue(nil, 3, "2016-07-08 12:00"),
+	}
+	if obj := s.recentlyClosed(closed); obj != nil {
+		t.Errorf("expected nothing without RecentlyClosed, got %v", *obj.Issue.Number)
+	}
+	s.RecentlyClosed = NewClosedMatch()
+	if obj := s.recentlyClosed(closed); obj == nil || *obj.Issue.Number != 2 {
+		t.Errorf("expected #2, the latest closed, got %v", obj)
+	}
+	s.RecentlyClosed.Within = 24 * time.Hour
+	if obj := s.recentlyClosed(closed); obj != nil {
+		t.Errorf("expected nothing closed in the last day, got %v", *obj.Issue.Number)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	client, server, mux := github_test.InitServer(t, github_test.Issue("bot", 1, nil, false), nil, nil, nil, nil, nil)
+	defer server.Close()
+	config := &github.Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+	mux.HandleFunc("/repos/o/r/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal([]githubapi.IssueComment{
+			github_test.Comment(1, "alice", date("2016-07-01 12:00"), "Fixed by #5.\nThanks!"),
+			github_test.Comment(2, "k8s-bot", date("2016-07-02 12:00"), "Still failing"),
+		})
+		w.Write(data)
+	})
+
+	s := NewIssueSyncer(config, nil)
+	s.RecentlyClosed = NewClosedMatch()
+	s.RecentlyClosed.Bots.Insert("k8s-bot")
+	obj := closedIssue(config, 1, "2016-07-03 12:00")
+	login := "bob"
+	obj.Issue.Assignee = &githubapi.User{Login: &login}
+
+	history, err := s.history(context.Background(), obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "This was seen before in #1, closed 2016-07-03. It was last assigned to @bob. The last comment, by @alice, was:\n\n> Fixed by #5.\n> Thanks!"
+	if history != expected {
+		t.Errorf("expected %q, got %q", expected, history)
+	}
+}
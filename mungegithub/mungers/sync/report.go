@@ -0,0 +1,229 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// dailyRetention is how long IssueRecord.Daily keeps counts for.
+const dailyRetention = 35 * 24 * time.Hour
+
+// ReportEntry is an issue in a FlakeReport.
+type ReportEntry struct {
+	Number int
+	Title  string
+	// Occurrences is how many sources were synced to the issue during
+	// the report's period.
+	Occurrences int
+}
+
+// FlakeReport summarizes what happened to the issues in the store during a
+// period: the issues with the most occurrences, the new ones and the ones
+// which were closed.
+type FlakeReport struct {
+	Start, End time.Time
+	Top        []ReportEntry
+	New        []ReportEntry
+	Resolved   []ReportEntry
+}
+
+// NewFlakeReport builds the report for the `period` until `end`, listing at
+// most `top` of the issues with the most occurrences.
+func NewFlakeReport(store *MetadataStore, end time.Time, period time.Duration, top int) *FlakeReport {
+	r := &FlakeReport{Start: end.Add(-period), End: end}
+	within := func(t time.Time) bool { return !t.Before(r.Start) && t.Before(r.End) }
+	for _, rec := range store.List() {
+		e := ReportEntry{Number: rec.Number, Title: rec.Title, Occurrences: rec.occurrencesBetween(r.Start, r.End)}
+		if e.Occurrences > 0 && !rec.Closed {
+			r.Top = append(r.Top, e)
+		}
+		if within(rec.Created) {
+			r.New = append(r.New, e)
+		}
+		if rec.Closed && within(rec.ClosedAt) {
+			r.Resolved = append(r.Resolved, e)
+		}
+	}
+	sort.Sort(byOccurrences(r.Top))
+	if len(r.Top) > top {
+		r.Top = r.Top[:top]
+	}
+	return r
+}
+
+// countDaily adds an occurrence at `t` to the record's daily counts, and
+// forgets the counts older than dailyRetention.
+func (r *IssueRecord) countDaily(t time.Time) {
+	if r.Daily == nil {
+		r.Daily = map[string]int{}
+	}
+	r.Daily[t.UTC().Format(dateFormat)]++
+	oldest := t.Add(-dailyRetention).UTC().Format(dateFormat)
+	for day := range r.Daily {
+		if day < oldest {
+			delete(r.Daily, day)
+		}
+	}
+}
+
+// occurrencesBetween counts the occurrences on the days from `start` until
+// `end`.
+func (r *IssueRecord) occurrencesBetween(start, end time.Time) int {
+	from, until := start.UTC().Format(dateFormat), end.UTC().Format(dateFormat)
+	count := 0
+	for day, n := range r.Daily {
+		if day >= from && day < until {
+			count += n
+		}
+	}
+	return count
+}
+
+type byOccurrences []ReportEntry
+
+func (b byOccurrences) Len() int      { return len(b) }
+func (b byOccurrences) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byOccurrences) Less(i, j int) bool {
+	if b[i].Occurrences != b[j].Occurrences {
+		return b[i].Occurrences > b[j].Occurrences
+	}
+	return b[i].Number < b[j].Number
+}
+
+// Title names the report after its period.
+func (r *FlakeReport) Title() string {
+	return fmt.Sprintf("Flake report from %v to %v", r.Start.UTC().Format(dateFormat), r.End.UTC().Format(dateFormat))
+}
+
+// Markdown renders the report for github.
+func (r *FlakeReport) Markdown() string {
+	lines := []string{"# " + r.Title(), "", "## Top flakes", ""}
+	if len(r.Top) == 0 {
+		lines = append(lines, "None.")
+	} else {
+		lines = append(lines, "| Issue | Title | Occurrences |", "| --- | --- | --- |")
+		for _, e := range r.Top {
+			lines = append(lines, fmt.Sprintf("| #%d | %v | %d |", e.Number, strings.Replace(e.Title, "|", `\|`, -1), e.Occurrences))
+		}
+	}
+	for _, section := range []struct {
+		name    string
+		entries []ReportEntry
+	}{{"New flakes", r.New}, {"Resolved flakes", r.Resolved}} {
+		lines = append(lines, "", "## "+section.name, "")
+		if len(section.entries) == 0 {
+			lines = append(lines, "None.")
+		}
+		for _, e := range section.entries {
+			lines = append(lines, fmt.Sprintf("- #%d %v", e.Number, e.Title))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+var reportHTML = template.Must(template.New("report").Parse(`<html>
+<head><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<h2>Top flakes</h2>
+{{if .Top}}<table>
+<tr><th>Issue</th><th>Title</th><th>Occurrences</th></tr>
+{{range .Top}}<tr><td>#{{.Number}}</td><td>{{.Title}}</td><td>{{.Occurrences}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+<h2>New flakes</h2>
+{{if .New}}<ul>
+{{range .New}}<li>#{{.Number}} {{.Title}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+<h2>Resolved flakes</h2>
+{{if .Resolved}}<ul>
+{{range .Resolved}}<li>#{{.Number}} {{.Title}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+</body>
+</html>
+`))
+
+// HTML renders the report as a web page.
+func (r *FlakeReport) HTML() (string, error) {
+	out := &bytes.Buffer{}
+	if err := reportHTML.Execute(out, r); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// PublishReport posts the report to `dest`: "issue" files it as an issue
+// (unless there is one for the period already), "gist" as a secret gist, and
+// anything else is a file or a gs:// URL, written as HTML if it ends in
+// ".html" and as Markdown otherwise.
+func (s *IssueSyncer) PublishReport(ctx context.Context, r *FlakeReport, dest string) error {
+	log := s.logger().With("report", r.Title())
+	switch {
+	case dest == "issue":
+		title := Namespaced(s.Namespace, r.Title())
+		existing, err := NewSearchFinder(s.client(ctx), nil).IssuesForKey(title)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			log.Debugf("Already filed as #%d", existing[0])
+			return nil
+		}
+		var obj *github.MungeObject
+		err = s.retry(ctx, "filing report", func() (err error) {
+			obj, err = s.client(ctx).NewIssue(title, s.text(r.Markdown()), nil)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		s.audit(AuditCreate, *obj.Issue.Number, title)
+		log.With("issue", *obj.Issue.Number).Infof("Filed report")
+		return nil
+	case dest == "gist":
+		var url string
+		err := s.retry(ctx, "creating report gist", func() (err error) {
+			url, err = s.client(ctx).CreateGist(r.Title(), "flake-report.md", r.Markdown())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		log.Infof("Published report to %v", url)
+		return nil
+	}
+	data := r.Markdown()
+	if strings.HasSuffix(dest, ".html") {
+		var err error
+		if data, err = r.HTML(); err != nil {
+			return err
+		}
+	}
+	if err := writeDest(dest, []byte(data)); err != nil {
+		return err
+	}
+	log.Infof("Published report to %v", dest)
+	return nil
+}
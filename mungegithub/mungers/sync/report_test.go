@@ -0,0 +1,92 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestFlakeReport(t *testing.T) {
+	s := NewIssueSyncer(nil, nil)
+	occur := func(n int, title, when string, times int) {
+		s.now = func() time.Time { return date(when) }
+		for i := 0; i < times; i++ {
+			s.recordOccurrence(n, func(r *IssueRecord) {
+				r.Title = title
+				if r.Created.IsZero() {
+					r.Created = date(when)
+				}
+			})
+		}
+	}
+	occur(1, "TestOld", "2016-05-01 12:00", 5)
+	occur(1, "TestOld", "2016-06-28 12:00", 2)
+	occur(2, "TestNew", "2016-06-30 12:00", 3)
+	occur(3, "TestFixed", "2016-06-20 12:00", 1)
+	occur(4, "TestQuiet", "2016-06-20 12:00", 1)
+	s.now = func() time.Time { return date("2016-06-29 12:00") }
+	s.noticeClosed(context.Background(), 3)
+
+	if r, _ := s.Store.Get(1); len(r.Daily) != 1 {
+		t.Errorf("expected old daily counts to be forgotten: %v", r.Daily)
+	}
+
+	r := NewFlakeReport(s.Store, date("2016-07-01 00:00"), 7*24*time.Hour, 1)
+	expected := `# Flake report from 2016-06-24 to 2016-07-01
+
+## Top flakes
+
+| Issue | Title | Occurrences |
+| --- | --- | --- |
+| #2 | TestNew | 3 |
+
+## New flakes
+
+- #2 TestNew
+
+## Resolved flakes
+
+- #3 TestFixed
+`
+	if got := r.Markdown(); got != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, got)
+	}
+	html, err := r.HTML()
+	if err != nil || !strings.Contains(html, "<td>#2</td><td>TestNew</td><td>3</td>") {
+		t.Errorf("unexpected html (%v):\n%v", err, html)
+	}
+
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "report.html")
+	if err := s.PublishReport(context.Background(), r, dest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(dest); string(data) != html {
+		t.Errorf("expected the html report in %v, got:\n%s", dest, data)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultRetestCommand is what we comment on pull requests to rerun their
+// failed jobs, see RetestPolicy.
+const DefaultRetestCommand = "/retest"
+
+// PRSource is an IssueSource about a job which failed on a pull request.
+type PRSource interface {
+	IssueSource
+	// PullRequest is the number of the pull request, 0 if there is none.
+	PullRequest() int
+	// StatusContext is the job's status context on the pull request.
+	StatusContext() string
+}
+
+// RetestPolicy has the syncer rerun the failed jobs of pull requests once a
+// PRSource was synced to a flake issue, and report how the rerun went on the
+// issue, see CheckRetests.
+type RetestPolicy struct {
+	// Command is commented on the pull request to rerun its jobs,
+	// DefaultRetestCommand if empty.
+	Command string
+	// MaxPerPR is how many times a pull request is retested, 1 if 0, so
+	// that real failures don't loop forever.
+	MaxPerPR int
+}
+
+// Retest is a rerun we asked for, see RetestPolicy.
+type Retest struct {
+	PR      int
+	Context string
+	At      time.Time
+	// Outcome is the status the job ended up with, or "closed" if the pull
+	// request was closed first. Empty while the job runs.
+	Outcome string `json:",omitempty"`
+}
+
+// retests returns how many times pull request `pr` was retested.
+func (s *IssueSyncer) retests(pr int) int {
+	count := 0
+	for _, r := range s.Store.List() {
+		for _, rt := range r.Retests {
+			if rt.PR == pr {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// retest reruns the jobs of the pull request `source` failed on, now that
+// it was synced to issue `n`.
+func (s *IssueSyncer) retest(ctx context.Context, n int, source PRSource) error {
+	pr := source.PullRequest()
+	if s.Retest == nil || pr <= 0 {
+		return nil
+	}
+	max := s.Retest.MaxPerPR
+	if max <= 0 {
+		max = 1
+	}
+	log := s.logger().With("issue", n).With("pr", pr)
+	if s.retests(pr) >= max {
+		log.Debugf("Retested %d times already, leaving it alone", max)
+		return nil
+	}
+	obj, err := s.getObject(ctx, pr)
+	if err != nil {
+		return err
+	}
+	if obj.Issue.State != nil && *obj.Issue.State == "closed" {
+		log.Debugf("Closed, not retesting")
+		return nil
+	}
+	command := s.Retest.Command
+	if command == "" {
+		command = DefaultRetestCommand
+	}
+	// The command must come first, so no namespace or footer.
+	msg := fmt.Sprintf("%v\n\n`%v` failed with what looks like the flake in #%d.", command, source.StatusContext(), n)
+	log.Infof("Retesting %v", source.StatusContext())
+	if err := s.writeComment(ctx, fmt.Sprintf("retesting %v", pr), obj, msg); err != nil {
+		return err
+	}
+	return s.Store.Update(n, func(r *IssueRecord) {
+		r.Retests = append(r.Retests, Retest{PR: pr, Context: source.StatusContext(), At: s.now()})
+	})
+}
+
+// CheckRetests looks at the reruns we asked for which hadn't finished yet,
+// and reports on the flake issue how those which did went. Does nothing
+// without a RetestPolicy.
+func (s *IssueSyncer) CheckRetests(ctx context.Context) error {
+	if s.Retest == nil {
+		return nil
+	}
+	for _, r := range s.Store.List() {
+		for i, rt := range r.Retests {
+			if rt.Outcome != "" {
+				continue
+			}
+			outcome, err := s.retestOutcome(ctx, rt)
+			if err != nil {
+				return err
+			}
+			if outcome == "" {
+				continue
+			}
+			if err := s.reportRetest(ctx, r.Number, rt, outcome); err != nil {
+				return err
+			}
+			if err := s.Store.Update(r.Number, func(r *IssueRecord) { r.Retests[i].Outcome = outcome }); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// retestOutcome returns how the rerun `rt` went, or "" if it didn't finish
+// yet.
+func (s *IssueSyncer) retestOutcome(ctx context.Context, rt Retest) (string, error) {
+	obj, err := s.getObject(ctx, rt.PR)
+	if err != nil {
+		return "", err
+	}
+	status := obj.GetStatus(rt.Context)
+	if status != nil && status.State != nil && *status.State != "pending" {
+		// Statuses from before the rerun are still around until it
+		// starts.
+		if status.UpdatedAt == nil || status.UpdatedAt.After(rt.At) {
+			return *status.State, nil
+		}
+	}
+	if obj.Issue.State != nil && *obj.Issue.State == "closed" {
+		return "closed", nil
+	}
+	return "", nil
+}
+
+func (s *IssueSyncer) reportRetest(ctx context.Context, n int, rt Retest, outcome string) error {
+	obj, err := s.getObject(ctx, n)
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("Reran `%v` on #%d: %v.", rt.Context, rt.PR, outcome)
+	switch outcome {
+	case "success":
+		msg = fmt.Sprintf("Reran `%v` on #%d, and it passed: this looks like a flake indeed.", rt.Context, rt.PR)
+	case "closed":
+		msg = fmt.Sprintf("#%d was closed before `%v` was rerun.", rt.PR, rt.Context)
+	}
+	s.logger().With("issue", n).With("pr", rt.PR).Infof("Retest finished: %v", outcome)
+	return s.writeComment(ctx, fmt.Sprintf("reporting retest on %v", n), obj, s.text(msg))
+}
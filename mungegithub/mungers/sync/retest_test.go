@@ -0,0 +1,88 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func TestRetest(t *testing.T) {
+	prIssue := github_test.Issue("bob", 2, nil, true)
+	pr := github_test.PullRequest("bob", false, true, true)
+	pr.Number = prIssue.Number
+	status := github_test.Status("mysha", nil, nil, []string{"e2e"}, nil)
+	client, server, mux := github_test.InitServer(t, prIssue, pr, nil, nil, status, nil)
+	defer server.Close()
+	github_test.ServeIssue(t, mux, github_test.Issue("bot", 1, nil, false))
+	config := &github.Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+	comments := map[int][]string{}
+	for _, n := range []int{1, 2} {
+		n := n
+		mux.HandleFunc(fmt.Sprintf("/repos/o/r/issues/%d/comments", n), func(w http.ResponseWriter, r *http.Request) {
+			c := struct{ Body string }{}
+			json.NewDecoder(r.Body).Decode(&c)
+			comments[n] = append(comments[n], c.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("{}"))
+		})
+	}
+
+	s := NewIssueSyncer(config, nil)
+	s.now = func() time.Time { return date("2016-07-01 12:00") }
+	s.Retest = &RetestPolicy{}
+	source := &JSONSource{Key: "TestFoo", Ref: "gs://job/1", PR: 2, Context: "e2e"}
+	for i := 0; i < 2; i++ {
+		if err := s.retest(context.Background(), 1, source); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(comments[2]) != 1 || !strings.HasPrefix(comments[2][0], "/retest\n\n`e2e` failed") {
+		t.Fatalf("expected a single retest, got %q", comments[2])
+	}
+
+	if err := s.CheckRetests(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(comments[1]) != 0 {
+		t.Errorf("reported a rerun which is still pending: %q", comments[1])
+	}
+
+	done, success := date("2016-07-01 13:00"), "success"
+	status.Statuses[0].State = &success
+	status.Statuses[0].UpdatedAt = &done
+	for i := 0; i < 2; i++ {
+		if err := s.CheckRetests(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(comments[1]) != 1 || !strings.Contains(comments[1][0], "and it passed") {
+		t.Errorf("expected the outcome reported once, got %q", comments[1])
+	}
+	if r, _ := s.Store.Get(1); len(r.Retests) != 1 || r.Retests[0].Outcome != "success" {
+		t.Errorf("unexpected retests %+v", r.Retests)
+	}
+}
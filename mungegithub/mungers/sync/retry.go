@@ -0,0 +1,180 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+)
+
+// Backoff configures how failed github mutations are retried.
+type Backoff struct {
+	// Steps is the maximum number of attempts, including the first one.
+	Steps int
+	// Initial is the delay before the first retry.
+	Initial time.Duration
+	// Factor multiplies the delay after every retry.
+	Factor float64
+	// Jitter adds up to Jitter*delay of random extra delay to every retry.
+	Jitter float64
+	// Cap is the longest we will ever wait between two attempts, including
+	// when github tells us to wait for the rate limit to reset.
+	Cap time.Duration
+}
+
+// DefaultBackoff is used by NewIssueSyncer.
+var DefaultBackoff = Backoff{
+	Steps:   5,
+	Initial: 2 * time.Second,
+	Factor:  2,
+	Jitter:  0.2,
+	Cap:     5 * time.Minute,
+}
+
+// delay returns how long to wait before retry number `retry` (starting at
+// 0), with jitter from `r`.
+func (b Backoff) delay(retry int, r Rand) time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < retry; i++ {
+		d *= b.Factor
+	}
+	if b.Jitter > 0 {
+		d += d * b.Jitter * r.Float64()
+	}
+	if b.Cap > 0 && d > float64(b.Cap) {
+		return b.Cap
+	}
+	return time.Duration(d)
+}
+
+// APIError is returned when a github call made by the syncer failed.
+// Retryable is true if the failure was transient (rate limiting, server
+// errors, network trouble), meaning the same source can simply be synced
+// again on a later pass. Kind, if set, is the error's kind, see ErrorKind;
+// otherwise it follows from Err.
+type APIError struct {
+	Op        string
+	Err       error
+	Retryable bool
+	Kind      error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Op, e.Err)
+}
+
+// IsRetryable returns true if err was returned by the syncer because of a
+// transient failure, or because it was capped.
+func IsRetryable(err error) bool {
+	switch e := err.(type) {
+	case *APIError:
+		return e.Retryable
+	case *CreationCappedError:
+		return true
+	}
+	return false
+}
+
+// classifyAt decides if err is worth retrying. If github told us when the
+// rate limit resets, wait is how long after `now` that is.
+func classifyAt(err error, now time.Time) (retryable bool, wait time.Duration) {
+	if _, ok := err.(*url.Error); ok {
+		// We never got an answer from github.
+		return true, 0
+	}
+	if e, ok := err.(*TrackerError); ok {
+		return e.retryable(), 0
+	}
+	errResp, ok := err.(*githubapi.ErrorResponse)
+	if !ok || errResp.Response == nil {
+		return false, 0
+	}
+	resp := errResp.Response
+	switch {
+	case resp.StatusCode >= 500:
+		return true, 0
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, rateLimitWait(resp, now)
+	case resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		return true, rateLimitWait(resp, now)
+	}
+	return false, 0
+}
+
+func rateLimitWait(resp *http.Response, now time.Time) time.Duration {
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	if s := resp.Header.Get("X-RateLimit-Reset"); s != "" {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(v, 0).Sub(now)
+		}
+	}
+	return 0
+}
+
+// retry calls fn until it succeeds, fails with a permanent error, we run
+// out of attempts, or ctx is done. `op` describes what fn does and ends up in the returned
+// *APIError.
+//
+// Note that not every mutation is idempotent: if github fails a create call
+// after actually creating the issue we may create a second copy. Those are
+// closed as dups on the next pass, which beats dropping the source.
+func (s *IssueSyncer) retry(ctx context.Context, op string, fn func() error) error {
+	var err error
+	steps := s.Backoff.Steps
+	if steps < 1 {
+		steps = 1
+	}
+	for i := 0; i < steps; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			// Canceled or out of time, not github's fault.
+			return &APIError{Op: op, Err: ctx.Err(), Retryable: true}
+		}
+		retryable, wait := classifyAt(err, s.now())
+		if !retryable {
+			return &APIError{Op: op, Err: err}
+		}
+		if i == steps-1 {
+			break
+		}
+		if d := s.Backoff.delay(i, s.rand); d > wait {
+			wait = d
+		}
+		if s.Backoff.Cap > 0 && wait > s.Backoff.Cap {
+			wait = s.Backoff.Cap
+		}
+		s.logger().Warningf("%v failed (attempt %d of %d), retrying in %v: %v", op, i+1, steps, wait, err)
+		select {
+		case <-s.after(wait):
+		case <-ctx.Done():
+			return &APIError{Op: op, Err: ctx.Err(), Retryable: true}
+		}
+	}
+	return &APIError{Op: op, Err: err, Retryable: true}
+}
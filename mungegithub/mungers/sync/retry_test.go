@@ -0,0 +1,201 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+)
+
+func errorResponse(code int, header map[string]string) error {
+	resp := &http.Response{
+		StatusCode: code,
+		Header:     http.Header{},
+		Request:    &http.Request{Method: "POST", URL: &url.URL{Path: "/repos/o/r/issues"}},
+	}
+	for k, v := range header {
+		resp.Header.Set(k, v)
+	}
+	return &githubapi.ErrorResponse{Response: resp}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+		wait      time.Duration
+	}{
+		{
+			name:      "server error",
+			err:       errorResponse(http.StatusBadGateway, nil),
+			retryable: true,
+		},
+		{
+			name:      "not found",
+			err:       errorResponse(http.StatusNotFound, nil),
+			retryable: false,
+		},
+		{
+			name:      "forbidden",
+			err:       errorResponse(http.StatusForbidden, nil),
+			retryable: false,
+		},
+		{
+			name:      "rate limited",
+			err:       errorResponse(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "Retry-After": "30"}),
+			retryable: true,
+			wait:      30 * time.Second,
+		},
+		{
+			name:      "rate limit reset",
+			err:       errorResponse(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": fmt.Sprint(date("2016-07-01 12:02").Unix())}),
+			retryable: true,
+			wait:      2 * time.Minute,
+		},
+		{
+			name:      "abuse limited",
+			err:       errorResponse(http.StatusTooManyRequests, map[string]string{"Retry-After": "7"}),
+			retryable: true,
+			wait:      7 * time.Second,
+		},
+		{
+			name:      "network",
+			err:       &url.Error{Op: "Post", URL: "https://api.github.com", Err: errors.New("connection reset")},
+			retryable: true,
+		},
+		{
+			name:      "other",
+			err:       errors.New("can't make issues in dry-run mode"),
+			retryable: false,
+		},
+	}
+	for _, test := range tests {
+		retryable, wait := classifyAt(test.err, date("2016-07-01 12:00"))
+		if retryable != test.retryable || wait != test.wait {
+			t.Errorf("%v: expected %v/%v, got %v/%v", test.name, test.retryable, test.wait, retryable, wait)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		calls     int
+		sleeps    []time.Duration
+		fail      bool
+		retryable bool
+	}{
+		{
+			name:  "success",
+			errs:  []error{nil},
+			calls: 1,
+		},
+		{
+			name:   "transient then success",
+			errs:   []error{errorResponse(500, nil), errorResponse(502, nil), nil},
+			calls:  3,
+			sleeps: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:  "permanent",
+			errs:  []error{errorResponse(422, nil)},
+			calls: 1,
+			fail:  true,
+		},
+		{
+			name:      "out of attempts",
+			errs:      []error{errorResponse(500, nil), errorResponse(500, nil), errorResponse(500, nil)},
+			calls:     3,
+			sleeps:    []time.Duration{time.Second, 2 * time.Second},
+			fail:      true,
+			retryable: true,
+		},
+		{
+			name:   "capped rate limit",
+			errs:   []error{errorResponse(403, map[string]string{"X-RateLimit-Remaining": "0", "Retry-After": "3600"}), nil},
+			calls:  2,
+			sleeps: []time.Duration{10 * time.Second},
+		},
+	}
+	for _, test := range tests {
+		slept := []time.Duration{}
+		s := &IssueSyncer{
+			Backoff: Backoff{Steps: 3, Initial: time.Second, Factor: 2, Cap: 10 * time.Second},
+			now:     time.Now,
+			after: func(d time.Duration) <-chan time.Time {
+				slept = append(slept, d)
+				c := make(chan time.Time, 1)
+				c <- time.Time{}
+				return c
+			},
+		}
+		calls := 0
+		err := s.retry(context.Background(), test.name, func() error {
+			err := test.errs[calls]
+			calls++
+			return err
+		})
+		if calls != test.calls {
+			t.Errorf("%v: expected %d calls, got %d", test.name, test.calls, calls)
+		}
+		if (err != nil) != test.fail {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		}
+		if IsRetryable(err) != test.retryable {
+			t.Errorf("%v: expected retryable %v, got %v", test.name, test.retryable, IsRetryable(err))
+		}
+		if len(slept) != len(test.sleeps) {
+			t.Errorf("%v: expected sleeps %v, got %v", test.name, test.sleeps, slept)
+			continue
+		}
+		for i := range slept {
+			if slept[i] != test.sleeps[i] {
+				t.Errorf("%v: expected sleeps %v, got %v", test.name, test.sleeps, slept)
+			}
+		}
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &IssueSyncer{
+		Backoff: Backoff{Steps: 3, Initial: time.Hour},
+		now:     time.Now,
+		after:   time.After,
+	}
+	calls := 0
+	err := s.retry(ctx, "canceled", func() error {
+		calls++
+		cancel()
+		return errors.New("request canceled")
+	})
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+	if !IsRetryable(err) || err.(*APIError).Err != context.Canceled {
+		t.Errorf("expected a retryable cancelation, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// syncSection returns the sync section of `body`, see updateSection, or "" if
+// it has none.
+func syncSection(body string) string {
+	start := strings.Index(body, sectionStart)
+	if start == -1 {
+		return ""
+	}
+	end := strings.Index(body[start:], sectionEnd)
+	if end == -1 {
+		return ""
+	}
+	return body[start : start+end+len(sectionEnd)]
+}
+
+// tooManyComments returns true if we commented on issue `n` so often that it
+// should be rolled over, see MaxComments.
+func (s *IssueSyncer) tooManyComments(n int) bool {
+	if s.MaxComments <= 0 {
+		return false
+	}
+	r, ok := s.Store.Get(n)
+	return ok && r.Comments >= s.MaxComments
+}
+
+// rollover continues `old`, which has too many comments, in a new issue for
+// the source: the new issue links back and takes over the sync section and
+// the record in the store, and `old` is closed with a summary. Returns the
+// new issue.
+func (s *IssueSyncer) rollover(ctx context.Context, old *github.MungeObject, source IssueSource) (int, error) {
+	n := *old.Issue.Number
+	r, _ := s.Store.Get(n)
+	part := r.Part
+	if part == 0 {
+		part = 1
+	}
+	history := fmt.Sprintf("This is part %d of this issue, continued from #%d which got too many comments to stay usable.", part+1, n)
+	if old.Issue.Body != nil {
+		if section := syncSection(*old.Issue.Body); section != "" {
+			history += "\n\n" + section
+		}
+	}
+	created, err := s.createIssue(ctx, source, history)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Store.Update(created, func(nr *IssueRecord) {
+		nr.Title = r.Title
+		nr.Labels = r.Labels
+		nr.Created = s.now()
+		nr.Occurrences = r.Occurrences
+		nr.Daily = r.Daily
+		nr.Members = r.Members
+		nr.Part = part + 1
+		nr.ContinuedFrom = n
+	}); err != nil {
+		return 0, err
+	}
+
+	msg := fmt.Sprintf("We commented here about %d occurrences, which is too many for this issue to stay usable, so it continues in #%d.", r.Comments, created)
+	if err := s.writeComment(ctx, fmt.Sprintf("rolling over issue %v", n), old, s.text(msg)); err != nil {
+		return 0, err
+	}
+	if err := s.closeIssue(ctx, fmt.Sprintf("closing rolled over issue %v", n), old); err != nil {
+		return 0, err
+	}
+	if err := s.noticeClosed(ctx, n); err != nil {
+		return 0, err
+	}
+	s.logger().With("issue", n).Infof("Rolled over to #%d after %d comments", created, r.Comments)
+	return created, s.Store.Update(n, func(r *IssueRecord) { r.ContinuedIn = created })
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func TestRollover(t *testing.T) {
+	section := updateSection("", "Failed", "gs://job/1", date("2016-07-01 12:00"), 2)
+	old := github_test.Issue("bot", 1, []string{"kind/flake"}, false)
+	old.Body = &section
+	client, server, mux := github_test.InitServer(t, old, nil, nil, nil, nil, nil)
+	defer server.Close()
+	config := &github.Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+
+	var created string
+	mux.HandleFunc("/repos/o/r/issues", func(w http.ResponseWriter, r *http.Request) {
+		req := struct{ Body string }{}
+		json.NewDecoder(r.Body).Decode(&req)
+		created = req.Body
+		data, _ := json.Marshal(github_test.Issue("bot", 2, nil, false))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(data)
+	})
+	comments := []string{}
+	mux.HandleFunc("/repos/o/r/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		c := struct{ Body string }{}
+		json.NewDecoder(r.Body).Decode(&c)
+		comments = append(comments, c.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	})
+
+	s := NewIssueSyncer(config, nil)
+	s.now = func() time.Time { return date("2016-07-02 12:00") }
+	s.MaxComments = 3
+	s.Store.Update(1, func(r *IssueRecord) {
+		r.Title = "TestFoo"
+		r.Occurrences = 4
+		r.Comments = 2
+	})
+	if s.tooManyComments(1) {
+		t.Errorf("rolled over too early")
+	}
+	s.Store.Update(1, func(r *IssueRecord) { r.Comments = 3 })
+	if !s.tooManyComments(1) {
+		t.Fatalf("expected a rollover")
+	}
+
+	n, err := s.rollover(context.Background(), github.TestObject(config, old, nil, nil, nil), &testSource{title: "TestFoo", id: "gs://job/2\n"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected issue 2, got %v", n)
+	}
+	if !strings.Contains(created, "part 2 of this issue, continued from #1") || !strings.Contains(created, section) {
+		t.Errorf("expected a link back and the sync section in the new issue:\n%v", created)
+	}
+	if len(comments) != 1 || !strings.Contains(comments[0], "continues in #2") {
+		t.Errorf("expected a summary on the old issue, got %q", comments)
+	}
+	if r, _ := s.Store.Get(1); !r.Closed || r.ContinuedIn != 2 {
+		t.Errorf("unexpected old record %+v", r)
+	}
+	if r, _ := s.Store.Get(2); r.Part != 2 || r.ContinuedFrom != 1 || r.Occurrences != 4 || r.Comments != 0 {
+		t.Errorf("unexpected new record %+v", r)
+	}
+}
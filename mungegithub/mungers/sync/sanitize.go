@@ -0,0 +1,84 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// zeroWidthSpace breaks up text without changing how it looks.
+const zeroWidthSpace = "\u200b"
+
+var (
+	// mentionRE finds @-mentions of users and teams, but not e.g. emails.
+	mentionRE = regexp.MustCompile("(^|[^\\w`])@([\\w-]+(/[\\w-]+)?)")
+	// commandRE finds lines which bots take as commands, e.g. "/close".
+	commandRE = regexp.MustCompile(`^(\s*)/(\w)`)
+)
+
+// SanitizeBody neutralizes what github and bots would act on in `body`, so
+// that e.g. a log excerpt can't mention people or close the issue: @-mentions
+// outside of code and lines starting with a /command are broken up with a
+// zero width space, and HTML comments are escaped so they can't pass for the
+// markers the syncer embeds in issues. The syncer does this to every source's
+// Body.
+func SanitizeBody(body string) string {
+	lines := strings.Split(body, "\n")
+	fenced := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if !fenced {
+			line = escapeMentions(line)
+		}
+		// Bots read commands in code blocks too.
+		lines[i] = commandRE.ReplaceAllString(line, "${1}"+zeroWidthSpace+"/${2}")
+	}
+	return strings.Replace(strings.Join(lines, "\n"), "<!--", "<"+zeroWidthSpace+"!--", -1)
+}
+
+// escapeMentions breaks up the mentions in `line` which aren't inline code.
+func escapeMentions(line string) string {
+	parts := strings.Split(line, "`")
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = mentionRE.ReplaceAllString(parts[i], "${1}@"+zeroWidthSpace+"${2}")
+	}
+	return strings.Join(parts, "`")
+}
+
+// sourceBody returns the redacted and sanitized body of `source`, with its
+// attachments (see AttachmentUploads), marked with its IDMarker. The syncer
+// finds sources by their ID, so it is kept intact.
+func (s *IssueSyncer) sourceBody(source IssueSource, newIssue bool) string {
+	raw := source.Body(newIssue)
+	body := s.redact(source, raw)
+	if attached := s.attached[source.ID()]; attached != "" {
+		// Attachments were redacted when they were attached.
+		body += "\n\n" + attached
+	}
+	body = SanitizeBody(body)
+	id := source.ID()
+	if strings.Contains(raw, id) && !strings.Contains(body, id) {
+		body = fmt.Sprintf("%v\n\n`%v`", body, id)
+	}
+	return body + "\n\n" + IDMarker(id)
+}
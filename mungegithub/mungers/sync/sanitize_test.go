@@ -0,0 +1,69 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeBody(t *testing.T) {
+	z := zeroWidthSpace
+	tests := []struct {
+		name, body, expected string
+	}{
+		{
+			name:     "mentions",
+			body:     "cc @alice and @kubernetes/sig-node, mail bob@example.com",
+			expected: "cc @" + z + "alice and @" + z + "kubernetes/sig-node, mail bob@example.com",
+		},
+		{
+			name:     "inline code",
+			body:     "ran `ssh @host` for @alice",
+			expected: "ran `ssh @host` for @" + z + "alice",
+		},
+		{
+			name:     "code block",
+			body:     "```\n@alice\n  /close\n```\n/assign @bob",
+			expected: "```\n@alice\n  " + z + "/close\n```\n" + z + "/assign @" + z + "bob",
+		},
+		{
+			name:     "paths",
+			body:     "failed reading /tmp/foo",
+			expected: "failed reading /tmp/foo",
+		},
+		{
+			name:     "markers",
+			body:     "<!-- sync-key: abc -->",
+			expected: "<" + z + "!-- sync-key: abc -->",
+		},
+	}
+	for _, test := range tests {
+		if got := SanitizeBody(test.body); got != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestSourceBodyKeepsID(t *testing.T) {
+	s := NewIssueSyncer(nil, nil)
+	source := &JSONSource{Key: "TestFoo", Ref: "/logs/123", Details: "/logs/123 failed"}
+	body := s.sourceBody(source, true)
+	if !strings.Contains(body, source.ID()) || strings.HasPrefix(body, source.ID()) {
+		t.Errorf("expected the ID to be kept, but not as a command, got %q", body)
+	}
+}
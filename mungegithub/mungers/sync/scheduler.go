@@ -0,0 +1,340 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// SourceProvider produces sources to sync, e.g. the flakes of the latest CI
+// runs, or the findings of a CVE scan.
+type SourceProvider interface {
+	Sources(ctx context.Context) ([]IssueSource, error)
+}
+
+// SourceProviderFunc is a function which is a SourceProvider.
+type SourceProviderFunc func(ctx context.Context) ([]IssueSource, error)
+
+// Sources implements SourceProvider.
+func (f SourceProviderFunc) Sources(ctx context.Context) ([]IssueSource, error) {
+	return f(ctx)
+}
+
+// CommandProvider runs a command which prints sources as JSON, see
+// ReadJSONSources. The command is killed if ctx is done before it exits.
+type CommandProvider struct {
+	Command []string
+}
+
+// Sources implements SourceProvider.
+func (c *CommandProvider) Sources(ctx context.Context) ([]IssueSource, error) {
+	if len(c.Command) == 0 {
+		return nil, fmt.Errorf("no command to run")
+	}
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("stopped running %v: %v", c.Command, ctx.Err())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error running %v: %v: %s", c.Command, err, stderr)
+	}
+	parsed, err := ReadJSONSources(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("error reading the sources %v printed: %v", c.Command, err)
+	}
+	sources := []IssueSource{}
+	for _, s := range parsed {
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+// Scheduler polls providers, each on its own cadence, and syncs the sources
+// they return, e.g. flakes every 10 minutes and CVE scans daily. A provider
+// isn't polled again while its last poll is still going on, and only one
+// SyncAll runs at a time.
+type Scheduler struct {
+	syncer Syncer
+
+	// Logger is where polls and failures are logged.
+	Logger Logger
+	// Jitter delays every poll by up to Jitter times the cadence, so that
+	// providers with the same cadence don't sync at the same time.
+	Jitter float64
+	// Flags, if set, turn providers off, relabel, cap or dry-run their
+	// sources, see FeatureFlags.
+	Flags *FeatureFlags
+
+	lock    sync.Mutex
+	jobs    map[string]*scheduledJob
+	syncing sync.Mutex
+
+	after func(time.Duration) <-chan time.Time
+	now   func() time.Time
+	rand  Rand
+}
+
+type scheduledJob struct {
+	provider SourceProvider
+	every    time.Duration
+	status   JobStatus
+}
+
+// JobStatus is how polling a provider went, see Scheduler.Jobs.
+type JobStatus struct {
+	Name    string
+	Every   time.Duration
+	Running bool
+	// LastPoll is when the provider was last polled, LastSources how
+	// many sources it returned and LastError why polling or syncing them
+	// failed, if it did.
+	LastPoll    time.Time `json:",omitempty"`
+	LastSources int
+	LastError   string `json:",omitempty"`
+	// Skipped counts the polls which were due while the last one was
+	// still going on.
+	Skipped int `json:",omitempty"`
+	// Disabled is set while the provider is turned off, see Flags.
+	Disabled bool `json:",omitempty"`
+}
+
+// NewScheduler constructs a Scheduler syncing with `syncer` (an IssueSyncer
+// or a MultiSyncer), with a jitter of 10%.
+func NewScheduler(syncer Syncer) *Scheduler {
+	return &Scheduler{
+		syncer: syncer,
+		Logger: &textLogger{},
+		Jitter: 0.1,
+		jobs:   map[string]*scheduledJob{},
+		after:  time.After,
+		now:    time.Now,
+		rand:   globalRand{},
+	}
+}
+
+// SetClock has the scheduler tell the time, and wait for polls, with
+// `clock`. Call it before Run.
+func (s *Scheduler) SetClock(clock Clock) {
+	s.now, s.after = clock.Now, clock.After
+}
+
+// SetRand has the jitter come from `r`. Call it before Run.
+func (s *Scheduler) SetRand(r Rand) {
+	s.rand = r
+}
+
+// Register has the scheduler poll `provider` every `every`, as `name`.
+func (s *Scheduler) Register(name string, every time.Duration, provider SourceProvider) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.jobs[name] = &scheduledJob{
+		provider: provider,
+		every:    every,
+		status:   JobStatus{Name: name, Every: every},
+	}
+}
+
+// Jobs returns how polling every provider went, by name.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	out := []JobStatus{}
+	for _, j := range s.jobs {
+		out = append(out, j.status)
+	}
+	sort.Sort(byJobName(out))
+	return out
+}
+
+// Run polls every provider right away (after a jitter), and then on its
+// cadence, until ctx is canceled.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.lock.Lock()
+	names := []string{}
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	s.lock.Unlock()
+
+	wg := sync.WaitGroup{}
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			s.loop(ctx, name)
+		}(name)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, name string) {
+	s.lock.Lock()
+	every := s.jobs[name].every
+	s.lock.Unlock()
+	wait := s.jitter(every)
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.after(wait):
+		}
+		if err := s.Poll(ctx, name); err != nil {
+			s.Logger.With("provider", name).Errorf("Unable to sync: %v", err)
+		}
+		wait = every + s.jitter(every)
+	}
+}
+
+func (s *Scheduler) jitter(every time.Duration) time.Duration {
+	if s.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(float64(every) * s.Jitter * s.rand.Float64())
+}
+
+// Poll polls the provider `name` now and syncs its sources, unless it is
+// being polled already.
+func (s *Scheduler) Poll(ctx context.Context, name string) error {
+	s.lock.Lock()
+	j, ok := s.jobs[name]
+	if !ok {
+		s.lock.Unlock()
+		return fmt.Errorf("no provider named %q", name)
+	}
+	flags := s.Flags.For(name)
+	j.status.Disabled = !flags.IsEnabled()
+	if j.status.Disabled {
+		s.lock.Unlock()
+		metrics.Add("scheduledPollsDisabled", 1)
+		s.Logger.With("provider", name).Debugf("Disabled by the feature flags, not polling")
+		return nil
+	}
+	if j.status.Running {
+		j.status.Skipped++
+		s.lock.Unlock()
+		metrics.Add("scheduledPollsSkipped", 1)
+		s.Logger.With("provider", name).Warningf("Still polling since %v, skipping", j.status.LastPoll)
+		return nil
+	}
+	j.status.Running, j.status.LastPoll = true, s.now()
+	s.lock.Unlock()
+
+	count, err := s.poll(ctx, name, j.provider, flags)
+	s.lock.Lock()
+	j.status.Running, j.status.LastSources, j.status.LastError = false, count, ""
+	if err != nil {
+		j.status.LastError = err.Error()
+	}
+	s.lock.Unlock()
+	metrics.Add("scheduledPolls", 1)
+	return err
+}
+
+func (s *Scheduler) poll(ctx context.Context, name string, provider SourceProvider, flags SourceFlags) (int, error) {
+	sources, err := provider.Sources(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(sources) == 0 {
+		return 0, nil
+	}
+	flagged := flags.Apply(sources)
+	if len(flagged) < len(sources) {
+		s.Logger.With("provider", name).Warningf("Syncing %d of %d sources, the most allowed per poll", len(flagged), len(sources))
+	}
+	sources = flagged
+	s.syncing.Lock()
+	defer s.syncing.Unlock()
+	if flags.DryRun {
+		return len(sources), s.dryRun(ctx, name, sources)
+	}
+	_, err = s.syncer.SyncAll(ctx, sources)
+	return len(sources), err
+}
+
+type byJobName []JobStatus
+
+func (b byJobName) Len() int           { return len(b) }
+func (b byJobName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+func (b byJobName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// scheduleConfig is how a schedule of commands is written down, see
+// LoadSchedule.
+type scheduleConfig struct {
+	Every   string   `json:"every"`
+	Command []string `json:"command"`
+}
+
+// LoadSchedule registers the commands (see CommandProvider) of a yaml (or
+// json) file with `s`, e.g.:
+//
+//	flakes:
+//	  every: 10m
+//	  command: [./find-flakes.sh, --since, 1h]
+//	cves:
+//	  every: 24h
+//	  command: [./scan.py, --format, json]
+func LoadSchedule(s *Scheduler, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	configs := map[string]scheduleConfig{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(&configs); err != nil {
+		return fmt.Errorf("error parsing schedule %v: %v", path, err)
+	}
+	for name, c := range configs {
+		every, err := time.ParseDuration(c.Every)
+		if err != nil || every <= 0 {
+			return fmt.Errorf("invalid cadence %q for %v in %v", c.Every, name, path)
+		}
+		if len(c.Command) == 0 {
+			return fmt.Errorf("%v in %v has no command", name, path)
+		}
+		s.Register(name, every, &CommandProvider{Command: c.Command})
+	}
+	return nil
+}
+
+// dryRun logs what syncing `sources` would do, if the syncer can tell.
+func (s *Scheduler) dryRun(ctx context.Context, name string, sources []IssueSource) error {
+	log := s.Logger.With("provider", name)
+	planner, ok := s.syncer.(Planner)
+	if !ok {
+		log.Infof("Dry run, not syncing %d sources", len(sources))
+		return nil
+	}
+	plan, err := planner.Plan(ctx, sources)
+	if err != nil {
+		return err
+	}
+	log.Infof("Dry run, syncing would do:\n%v", plan)
+	return nil
+}
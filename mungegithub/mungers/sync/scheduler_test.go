@@ -0,0 +1,166 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// recordingSyncer records the IDs of the sources it was asked to sync.
+type recordingSyncer struct {
+	lock   sync.Mutex
+	synced []string
+}
+
+func (r *recordingSyncer) SyncAll(ctx context.Context, sources []IssueSource) ([]SyncResult, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, s := range sources {
+		r.synced = append(r.synced, s.ID())
+	}
+	return nil, nil
+}
+
+func (r *recordingSyncer) Synced(id string) bool { return false }
+
+func TestSchedulerPoll(t *testing.T) {
+	syncer := &recordingSyncer{}
+	s := NewScheduler(syncer)
+	release := make(chan struct{})
+	s.Register("flakes", 10*time.Minute, SourceProviderFunc(func(ctx context.Context) ([]IssueSource, error) {
+		<-release
+		return []IssueSource{&JSONSource{Key: "TestFoo", Ref: "foo-1"}}, nil
+	}))
+	s.Register("cves", 24*time.Hour, SourceProviderFunc(func(ctx context.Context) ([]IssueSource, error) {
+		return nil, fmt.Errorf("scanner unavailable")
+	}))
+
+	done := make(chan error)
+	go func() { done <- s.Poll(context.Background(), "flakes") }()
+	for !s.Jobs()[1].Running {
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.Poll(context.Background(), "flakes"); err != nil {
+		t.Errorf("expected the overlapping poll to be skipped, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := s.Poll(context.Background(), "cves"); err == nil {
+		t.Errorf("expected the failing provider to fail")
+	}
+	if err := s.Poll(context.Background(), "unknown"); err == nil {
+		t.Errorf("expected an error for an unknown provider")
+	}
+
+	if !reflect.DeepEqual(syncer.synced, []string{"foo-1"}) {
+		t.Errorf("expected foo-1 to be synced once, got %v", syncer.synced)
+	}
+	jobs := s.Jobs()
+	if j := jobs[0]; j.Name != "cves" || j.LastError != "scanner unavailable" {
+		t.Errorf("unexpected status of cves: %+v", j)
+	}
+	if j := jobs[1]; j.Name != "flakes" || j.Running || j.LastSources != 1 || j.Skipped != 1 || j.LastError != "" {
+		t.Errorf("unexpected status of flakes: %+v", j)
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	syncer := &recordingSyncer{}
+	s := NewScheduler(syncer)
+	ctx, cancel := context.WithCancel(context.Background())
+	lock := sync.Mutex{}
+	waits := map[time.Duration]int{}
+	s.after = func(d time.Duration) <-chan time.Time {
+		lock.Lock()
+		defer lock.Unlock()
+		waits[d/time.Minute*time.Minute]++
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}
+	s.Jitter = 0
+	polls := 0
+	s.Register("flakes", 10*time.Minute, SourceProviderFunc(func(ctx context.Context) ([]IssueSource, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if polls++; polls == 3 {
+			cancel()
+		}
+		return []IssueSource{&JSONSource{Key: "TestFoo", Ref: fmt.Sprintf("foo-%d", polls)}}, nil
+	}))
+	s.Run(ctx)
+
+	if !reflect.DeepEqual(syncer.synced, []string{"foo-1", "foo-2", "foo-3"}) {
+		t.Errorf("expected three polls, got %v", syncer.synced)
+	}
+	if waits[0] != 1 || waits[10*time.Minute] < 2 {
+		t.Errorf("expected a poll right away and then every 10m, waited %v", waits)
+	}
+}
+
+func TestLoadSchedule(t *testing.T) {
+	file, err := ioutil.TempFile("", "schedule")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`
+flakes:
+  every: 10m
+  command: [echo, '{"title": "TestFoo", "id": "foo-1"}']
+`)
+	file.Close()
+
+	syncer := &recordingSyncer{}
+	s := NewScheduler(syncer)
+	if err := LoadSchedule(s, file.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if jobs := s.Jobs(); len(jobs) != 1 || jobs[0].Every != 10*time.Minute {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+	if err := s.Poll(context.Background(), "flakes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(syncer.synced, []string{"foo-1"}) {
+		t.Errorf("expected the command's source to be synced, got %v", syncer.synced)
+	}
+}
+
+func TestCommandProviderCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := (&CommandProvider{Command: []string{"sleep", "10"}}).Sources(ctx)
+	if err == nil || !strings.Contains(err.Error(), "stopped running") {
+		t.Errorf("expected the command to be stopped, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the command to be killed, it ran for %v", elapsed)
+	}
+}
@@ -0,0 +1,309 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// SearchFinder is a CheckedIssueFinder which looks keys up with the github
+// search API, matching them against issue titles. It needs no warm up, which
+// makes it a good fallback while an IssueIndex is still being populated, but
+// search has a rate limit of its own (30 requests a minute), so results are
+// cached and searches are spaced out.
+type SearchFinder struct {
+	config *github.Config
+	labels []string
+	// Normalizer, if set, turns titles into keys, see
+	// IssueSyncer.Normalizer.
+	Normalizer *TitleNormalizer
+
+	// TTL is how long search results are trusted.
+	TTL time.Duration
+	// MinInterval is the least time between two searches.
+	MinInterval time.Duration
+
+	lock       sync.Mutex
+	cache      map[string]searchResult
+	lastSearch time.Time
+	// notBefore is set when github told us we're out of search requests.
+	notBefore time.Time
+	now       func() time.Time
+	after     func(time.Duration) <-chan time.Time
+}
+
+type searchResult struct {
+	numbers sets.Int
+	at      time.Time
+}
+
+// NewSearchFinder constructs a SearchFinder for issues with all of `labels`.
+func NewSearchFinder(config *github.Config, labels []string) *SearchFinder {
+	return &SearchFinder{
+		config:      config,
+		labels:      labels,
+		TTL:         10 * time.Minute,
+		MinInterval: 2 * time.Second,
+		cache:       map[string]searchResult{},
+		now:         time.Now,
+		after:       time.After,
+	}
+}
+
+// SetClock has the finder tell the time, and space searches out, with
+// `clock`.
+func (f *SearchFinder) SetClock(clock Clock) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now, f.after = clock.Now, clock.After
+}
+
+func (f *SearchFinder) query(key string) string {
+	q := []string{
+		fmt.Sprintf("repo:%v/%v", f.config.Org, f.config.Project),
+		"is:issue",
+		"in:title",
+	}
+	if h := titleHash(key); h != "" {
+		// Cut titles are too long to search for, but their hash is
+		// as good.
+		q = append(q, h)
+	} else if f.Normalizer == nil {
+		q = append(q, fmt.Sprintf("%q", strings.Replace(key, `"`, " ", -1)))
+	} else {
+		// Normalized keys aren't phrases of the title, search for the
+		// words which are left.
+		for _, word := range strings.Fields(key) {
+			if word = strings.Trim(word, `"<>`); word != "" && !strings.ContainsAny(word, "<>") {
+				q = append(q, fmt.Sprintf("%q", word))
+			}
+		}
+	}
+	for _, l := range f.labels {
+		q = append(q, fmt.Sprintf("label:%q", l))
+	}
+	return strings.Join(q, " ")
+}
+
+// IssuesForKey returns all issues, open or closed, titled `key`, oldest first.
+func (f *SearchFinder) IssuesForKey(key string) ([]int, error) {
+	key = f.Normalizer.Normalize(key)
+	return f.search(key, f.query(key), func(issue *githubapi.Issue) bool {
+		// Search matches words, not whole titles.
+		return issue.Title != nil && f.Normalizer.Normalize(*issue.Title) == key
+	})
+}
+
+// IssuesForSyncKey implements SyncKeyFinder.
+func (f *SearchFinder) IssuesForSyncKey(key string) ([]int, error) {
+	q := []string{
+		fmt.Sprintf("repo:%v/%v", f.config.Org, f.config.Project),
+		"is:issue",
+		"in:body",
+		key,
+	}
+	for _, l := range f.labels {
+		q = append(q, fmt.Sprintf("label:%q", l))
+	}
+	marker := SyncKeyMarker(key)
+	return f.search(syncKeyCacheKey(key), strings.Join(q, " "), func(issue *githubapi.Issue) bool {
+		return issue.Body != nil && strings.Contains(*issue.Body, marker)
+	})
+}
+
+// syncKeyCacheKey keeps sync keys apart from titles in the cache.
+func syncKeyCacheKey(key string) string {
+	return "sync-key:" + key
+}
+
+// search returns the issues found by `query` which match, caching them as
+// `cacheKey`.
+func (f *SearchFinder) search(cacheKey, query string, match func(*githubapi.Issue) bool) ([]int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := f.now()
+	if r, ok := f.cache[cacheKey]; ok && now.Sub(r.at) < f.TTL {
+		return r.numbers.List(), nil
+	}
+	if now.Before(f.notBefore) {
+		return nil, &APIError{
+			Op:        fmt.Sprintf("searching for %q", cacheKey),
+			Err:       fmt.Errorf("search rate limit exceeded until %v", f.notBefore),
+			Retryable: true,
+			Kind:      ErrRateLimited,
+		}
+	}
+	if wait := f.lastSearch.Add(f.MinInterval).Sub(now); wait > 0 {
+		<-f.after(wait)
+	}
+	f.lastSearch = f.now()
+
+	issues, err := f.config.SearchIssues(query)
+	if err != nil {
+		retryable, wait := classifyAt(err, f.now())
+		if wait > 0 {
+			f.notBefore = f.now().Add(wait)
+		}
+		return nil, &APIError{Op: fmt.Sprintf("searching for %q", cacheKey), Err: err, Retryable: retryable}
+	}
+	numbers := sets.NewInt()
+	for _, issue := range issues {
+		if issue.Number != nil && match(&issue) {
+			numbers.Insert(*issue.Number)
+		}
+	}
+	f.cache[cacheKey] = searchResult{numbers: numbers, at: f.lastSearch}
+	return numbers.List(), nil
+}
+
+// AllIssuesForKey implements IssueFinder. Errors are logged and treated as no
+// issues being found; the syncer uses IssuesForKey instead.
+func (f *SearchFinder) AllIssuesForKey(key string) []int {
+	numbers, err := f.IssuesForKey(key)
+	if err != nil {
+		glog.Errorf("Unable to search for issues titled %q: %v", key, err)
+	}
+	return numbers
+}
+
+// Created remembers an issue we just filed, since search only finds new
+// issues after github indexed them.
+func (f *SearchFinder) Created(key string, number int) {
+	f.cached(f.Normalizer.Normalize(key), number)
+}
+
+// CreatedSyncKey implements SyncKeyFinder.
+func (f *SearchFinder) CreatedSyncKey(key string, number int) {
+	f.cached(syncKeyCacheKey(key), number)
+}
+
+// cached adds issue `number` to the cached results for `cacheKey`.
+func (f *SearchFinder) cached(cacheKey string, number int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if r, ok := f.cache[cacheKey]; ok {
+		r.numbers.Insert(number)
+		return
+	}
+	f.cache[cacheKey] = searchResult{numbers: sets.NewInt(number), at: f.now()}
+}
+
+// Observe implements IssueObserver: an issue which changed is added to the
+// cached results it belongs to, since search would only find it once github
+// indexed it. Results which aren't cached are left to the next search.
+func (f *SearchFinder) Observe(issue *githubapi.Issue) {
+	if issue.Number == nil || issue.Title == nil || !hasLabels(issue, f.labels) {
+		return
+	}
+	cacheKeys := []string{f.Normalizer.Normalize(*issue.Title)}
+	if issue.Body != nil {
+		for _, k := range SyncKeys(*issue.Body) {
+			cacheKeys = append(cacheKeys, syncKeyCacheKey(k))
+		}
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, k := range cacheKeys {
+		if r, ok := f.cache[k]; ok {
+			r.numbers.Insert(*issue.Number)
+		}
+	}
+}
+
+// SyncedIssueFinder is an IssueFinder which needs to be populated before it
+// can be trusted, like IssueIndex.
+type SyncedIssueFinder interface {
+	IssueFinder
+	Synced() bool
+}
+
+// FallbackFinder uses Primary once it has synced, and Fallback until then.
+type FallbackFinder struct {
+	Primary  SyncedIssueFinder
+	Fallback CheckedIssueFinder
+}
+
+// IssuesForKey implements CheckedIssueFinder.
+func (f *FallbackFinder) IssuesForKey(key string) ([]int, error) {
+	if f.Primary.Synced() {
+		if checked, ok := f.Primary.(CheckedIssueFinder); ok {
+			return checked.IssuesForKey(key)
+		}
+		return f.Primary.AllIssuesForKey(key), nil
+	}
+	glog.V(2).Infof("Issue finder not synced yet, searching for %q", key)
+	return f.Fallback.IssuesForKey(key)
+}
+
+// AllIssuesForKey implements IssueFinder.
+func (f *FallbackFinder) AllIssuesForKey(key string) []int {
+	if f.Primary.Synced() {
+		return f.Primary.AllIssuesForKey(key)
+	}
+	return f.Fallback.AllIssuesForKey(key)
+}
+
+// Created tells both finders about the new issue.
+func (f *FallbackFinder) Created(key string, number int) {
+	f.Primary.Created(key, number)
+	f.Fallback.Created(key, number)
+}
+
+// IssuesForSyncKey implements SyncKeyFinder, if the finder in use does.
+func (f *FallbackFinder) IssuesForSyncKey(key string) ([]int, error) {
+	var finder interface{} = f.Fallback
+	if f.Primary.Synced() {
+		finder = f.Primary
+	}
+	if k, ok := finder.(SyncKeyFinder); ok {
+		return k.IssuesForSyncKey(key)
+	}
+	return nil, nil
+}
+
+// CreatedSyncKey tells both finders about the new issue, if they find issues
+// by sync key.
+func (f *FallbackFinder) CreatedSyncKey(key string, number int) {
+	for _, finder := range []interface{}{f.Primary, f.Fallback} {
+		if k, ok := finder.(SyncKeyFinder); ok {
+			k.CreatedSyncKey(key, number)
+		}
+	}
+}
+
+// Observe passes `issue` on to both finders, if they observe issues.
+func (f *FallbackFinder) Observe(issue *githubapi.Issue) {
+	for _, finder := range []interface{}{f.Primary, f.Fallback} {
+		if o, ok := finder.(IssueObserver); ok {
+			o.Observe(issue)
+		}
+	}
+}
+
+// Synced is always true: the fallback can always answer.
+func (f *FallbackFinder) Synced() bool {
+	return true
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// In edit mode (IssueSyncer.EditBody) the syncer maintains a section of the
+// issue body between these markers, instead of commenting every time a
+// source occurs again.
+const (
+	sectionStart = "<!-- sync-section-start -->"
+	sectionEnd   = "<!-- sync-section-end -->"
+
+	// DefaultEditRows is how many occurrences the section lists by default.
+	DefaultEditRows = 20
+)
+
+var (
+	sectionCountRE = regexp.MustCompile(`\*\*Occurrences:\*\* (\d+)`)
+	sectionRowRE   = regexp.MustCompile("^\\| ([^|]+) \\| `([^`]+)` \\|$")
+)
+
+type occurrenceRow struct {
+	when string
+	id   string
+}
+
+// replaceSection returns `body` with the section between the markers
+// `start` and `end` replaced by `text`, which has the markers. A body
+// without the section gets it first, or last if `atEnd`, set apart by an
+// empty line. An empty text removes the section.
+func replaceSection(body, start, end, text string, atEnd bool) string {
+	if i := strings.Index(body, start); i != -1 {
+		if j := strings.Index(body[i:], end); j != -1 {
+			before, after := body[:i], body[i+j+len(end):]
+			switch {
+			case text != "":
+				return before + text + after
+			case atEnd:
+				return strings.TrimSuffix(before, "\n\n") + after
+			default:
+				return before + strings.TrimPrefix(after, "\n\n")
+			}
+		}
+	}
+	switch {
+	case text == "":
+		return body
+	case atEnd:
+		return body + "\n\n" + text
+	default:
+		return text + "\n\n" + body
+	}
+}
+
+// updateSection returns `body` with its sync section updated for another
+// occurrence, described by `latest` and identified by `id`. The section keeps
+// a count and the newest `rows` occurrences; it is added if there is none.
+func updateSection(body, latest, id string, when time.Time, rows int) string {
+	before, section, after := body, "", ""
+	if start := strings.Index(body, sectionStart); start != -1 {
+		if end := strings.Index(body[start:], sectionEnd); end != -1 {
+			end += start + len(sectionEnd)
+			before, section, after = body[:start], body[start:end], body[end:]
+		}
+	}
+	if section == "" {
+		before += "\n\n"
+	}
+
+	count := 0
+	if m := sectionCountRE.FindStringSubmatch(section); m != nil {
+		count, _ = strconv.Atoi(m[1])
+	}
+	count++
+	occurrences := []occurrenceRow{{when.UTC().Format("2006-01-02 15:04 MST"), sectionID(id)}}
+	for _, line := range strings.Split(section, "\n") {
+		if m := sectionRowRE.FindStringSubmatch(line); m != nil {
+			occurrences = append(occurrences, occurrenceRow{m[1], m[2]})
+		}
+	}
+	if rows > 0 && len(occurrences) > rows {
+		occurrences = occurrences[:rows]
+	}
+
+	lines := []string{
+		sectionStart,
+		fmt.Sprintf("**Occurrences:** %d, most recently:", count),
+		"",
+		latest,
+		"",
+		"| When | Occurrence |",
+		"| --- | --- |",
+	}
+	for _, o := range occurrences {
+		lines = append(lines, fmt.Sprintf("| %v | `%v` |", o.when, o.id))
+	}
+	lines = append(lines, sectionEnd)
+	return before + strings.Join(lines, "\n") + after
+}
+
+// sectionID is how an ID is written in the section's table.
+func sectionID(id string) string {
+	return strings.NewReplacer("`", "", "|", "", "\n", " ").Replace(strings.TrimSpace(id))
+}
+
+// recordedInSection returns true if the section in `body` lists `id`. Only
+// the newest occurrences are listed, so older ones can't be recognized.
+func recordedInSection(body, id string) bool {
+	return strings.Contains(body, "`"+sectionID(id)+"`")
+}
+
+// editIssue records an occurrence of the source in the sync section of the
+// issue body. The issue is fetched again first, so that edits by others
+// aren't clobbered: if they edited the sync section, the occurrence is
+// commented instead.
+func (s *IssueSyncer) editIssue(ctx context.Context, obj *github.MungeObject, source IssueSource) error {
+	n := *obj.Issue.Number
+	obj, err := s.getObject(ctx, n)
+	if err != nil {
+		return err
+	}
+	old := ""
+	if obj.Issue.Body != nil {
+		old = *obj.Issue.Body
+	}
+	switch s.checkEdited(n, old) {
+	case conflictSection:
+		s.logger().With("issue", n).Warningf("Someone else edited the sync section, commenting instead")
+		return s.commentIssue(ctx, obj, source, s.text(s.recurrenceText(n, s.sourceBody(source, false))))
+	case conflictMerged:
+		s.logger().With("issue", n).Infof("Someone else edited the body, updating the sync section around their changes")
+	}
+	rows := s.EditRows
+	if rows == 0 {
+		rows = DefaultEditRows
+	}
+	body := updateSection(old, s.recurrenceText(n, s.sourceBody(source, false)), source.ID(), s.now(), rows)
+	s.logger().With("issue", n).Infof("Updating the body of the issue, it is the oldest open one for %q", s.title(source))
+	return s.editBody(ctx, fmt.Sprintf("editing issue %v for %v", n, source.ID()), obj, old, body)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpdateSection(t *testing.T) {
+	body := "Failed: TestFoo\n\ngs://job/1\n"
+	body = updateSection(body, "Failed again\n\ngs://job/2\n", "gs://job/2\n", date("2016-07-01 12:00"), 2)
+	expected := "Failed: TestFoo\n\ngs://job/1\n\n\n" + sectionStart + `
+**Occurrences:** 1, most recently:
+
+Failed again
+
+gs://job/2
+
+
+| When | Occurrence |
+| --- | --- |
+| 2016-07-01 12:00 UTC | ` + "`gs://job/2`" + ` |
+` + sectionEnd
+	if body != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, body)
+	}
+
+	body += "\n\nSomething a human added."
+	body = updateSection(body, "gs://job/3", "gs://job/3\n", date("2016-07-01 13:00"), 2)
+	body = updateSection(body, "gs://job/4", "gs://job/4\n", date("2016-07-01 14:00"), 2)
+	if !strings.Contains(body, "**Occurrences:** 3,") {
+		t.Errorf("expected 3 occurrences:\n%v", body)
+	}
+	if strings.Count(body, sectionStart) != 1 || !strings.HasSuffix(body, "Something a human added.") {
+		t.Errorf("section was not replaced in place:\n%v", body)
+	}
+	for id, recorded := range map[string]bool{
+		"gs://job/2\n": false, // dropped from the table
+		"gs://job/3\n": true,
+		"gs://job/4\n": true,
+		"gs://job/":    false,
+	} {
+		if got := recordedInSection(body, id); got != recorded {
+			t.Errorf("%q: expected recorded %v, got %v", id, recorded, got)
+		}
+	}
+	if !strings.Contains(body, "| 2016-07-01 14:00 UTC | `gs://job/4` |\n| 2016-07-01 13:00 UTC | `gs://job/3` |") {
+		t.Errorf("expected newest occurrences first:\n%v", body)
+	}
+}
+
+func TestReplaceSection(t *testing.T) {
+	old, updated := "<!-- s -->\nold\n<!-- e -->", "<!-- s -->\nnew\n<!-- e -->"
+	tests := []struct {
+		body, text string
+		atEnd      bool
+		expected   string
+	}{
+		{"details", updated, false, updated + "\n\ndetails"},
+		{"details", updated, true, "details\n\n" + updated},
+		{old + "\n\ndetails", updated, false, updated + "\n\ndetails"},
+		{"details\n\n" + old, updated, true, "details\n\n" + updated},
+		{old + "\n\ndetails", "", false, "details"},
+		{"details\n\n" + old, "", true, "details"},
+		{"details", "", false, "details"},
+		// A section which isn't closed is left alone.
+		{"<!-- s -->\nold", updated, false, updated + "\n\n<!-- s -->\nold"},
+	}
+	for _, test := range tests {
+		if got := replaceSection(test.body, "<!-- s -->", "<!-- e -->", test.text, test.atEnd); got != test.expected {
+			t.Errorf("replaceSection(%q, %q, %v): expected %q, got %q", test.body, test.text, test.atEnd, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// SensitiveSource is an IssueSource which may carry security-sensitive
+// details, e.g. a vulnerability report. Sources which say they are sensitive
+// are never filed as public issues; see SecurityRouting.
+type SensitiveSource interface {
+	IssueSource
+	Sensitive() bool
+}
+
+func isSensitive(source IssueSource) bool {
+	s, ok := source.(SensitiveSource)
+	return ok && s.Sensitive()
+}
+
+// SecurityRouting decides where sensitive sources go. Exactly one of Private
+// and Advisories should be set.
+type SecurityRouting struct {
+	// Private files sensitive sources as issues in a private repo, with
+	// the usual deduplication.
+	Private *IssueSyncer
+	// Advisories files sensitive sources as draft security advisories of
+	// the repo it talks to, one per title. Advisories have no comments, so
+	// later occurrences of a source are not recorded.
+	Advisories *github.Config
+
+	// Placeholders, if true, also files a public issue for every sensitive
+	// source, which says no more than that a report exists.
+	Placeholders bool
+	// PlaceholderLabels are applied to placeholder issues.
+	PlaceholderLabels []string
+
+	// summaries of the advisories which exist, nil until listed.
+	summaries sets.String
+}
+
+// redact returns a short stable stand-in for sensitive text.
+func redact(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))[:12]
+}
+
+// syncSensitive routes a sensitive source according to s.Security.
+func (s *IssueSyncer) syncSensitive(ctx context.Context, source IssueSource) error {
+	ref := redact(source.ID())
+	r := s.Security
+	if r == nil || (r.Private == nil && r.Advisories == nil) {
+		return &ValidationError{Source: ref, Reason: "refusing to file security-sensitive report publicly, no security routing is configured"}
+	}
+	if r.Private != nil {
+		if err := r.Private.sync(ctx, source); err != nil {
+			return err
+		}
+	} else if err := s.fileAdvisory(ctx, s.title(source), s.sourceBody(source, true)); err != nil {
+		return err
+	}
+	if r.Placeholders {
+		if err := s.sync(ctx, &placeholderSource{
+			key:    redact(s.title(source)),
+			ref:    ref,
+			labels: r.PlaceholderLabels,
+		}); err != nil {
+			return err
+		}
+	}
+	s.logger().Infof("Routed security-sensitive report %v privately", ref)
+	return nil
+}
+
+func (s *IssueSyncer) fileAdvisory(ctx context.Context, summary, description string) error {
+	r := s.Security
+	if r.summaries == nil {
+		var advisories []github.SecurityAdvisory
+		if err := s.retry(ctx, "listing security advisories", func() (err error) {
+			advisories, err = r.Advisories.WithContext(ctx, s.CallTimeout).ListSecurityAdvisories("")
+			return err
+		}); err != nil {
+			return err
+		}
+		r.summaries = sets.NewString()
+		for _, a := range advisories {
+			if a.Summary != nil {
+				r.summaries.Insert(*a.Summary)
+			}
+		}
+	}
+	if r.summaries.Has(summary) {
+		return nil
+	}
+	if err := s.retry(ctx, fmt.Sprintf("creating security advisory %v", redact(summary)), func() error {
+		_, err := r.Advisories.WithContext(ctx, s.CallTimeout).CreateSecurityAdvisory(summary, description)
+		return err
+	}); err != nil {
+		return err
+	}
+	r.summaries.Insert(summary)
+	s.audit(AuditAdvisory, 0, redact(summary))
+	return nil
+}
+
+// placeholderSource is the public stand-in for a sensitive source. It keeps
+// nothing of the source but hashes, so that occurrences of the same report
+// end up in the same issue.
+type placeholderSource struct {
+	key    string
+	ref    string
+	labels []string
+}
+
+func (p *placeholderSource) Title() string {
+	return fmt.Sprintf("Security-sensitive report %v", p.key)
+}
+
+func (p *placeholderSource) ID() string {
+	return "security-report:" + p.ref
+}
+
+func (p *placeholderSource) Body(newIssue bool) string {
+	if newIssue {
+		return fmt.Sprintf("A security-sensitive report was routed privately to the people who handle such reports. Its details are intentionally left out.\n\n%v", p.ID())
+	}
+	return fmt.Sprintf("The report occurred again.\n\n%v", p.ID())
+}
+
+func (p *placeholderSource) Labels() []string {
+	return p.labels
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+type testSource struct {
+	title, id string
+	sensitive bool
+}
+
+func (t *testSource) Title() string             { return t.title }
+func (t *testSource) ID() string                { return t.id }
+func (t *testSource) Body(newIssue bool) string { return "details " + t.id }
+func (t *testSource) Labels() []string          { return []string{"kind/bug"} }
+func (t *testSource) Sensitive() bool           { return t.sensitive }
+
+func TestSensitiveWithoutRouting(t *testing.T) {
+	s := NewIssueSyncer(nil, nil)
+	err := s.Sync(context.Background(), &testSource{title: "CVE in foo", id: "http://report/1", sensitive: true})
+	if err == nil {
+		t.Fatalf("expected sensitive source to be refused")
+	}
+	if strings.Contains(err.Error(), "report/1") || strings.Contains(err.Error(), "foo") {
+		t.Errorf("error leaks details of the source: %v", err)
+	}
+	if s.synced.Has("http://report/1") {
+		t.Errorf("refused source should not be marked synced")
+	}
+}
+
+func TestPlaceholderSource(t *testing.T) {
+	source := &testSource{title: "CVE in foo", id: "http://report/1", sensitive: true}
+	p := &placeholderSource{key: redact(source.Title()), ref: redact(source.ID())}
+	for _, text := range []string{p.Title(), p.ID(), p.Body(true), p.Body(false)} {
+		if strings.Contains(text, "foo") || strings.Contains(text, "report/1") {
+			t.Errorf("placeholder leaks details of the source: %q", text)
+		}
+	}
+	if !strings.Contains(p.Body(true), p.ID()) || !strings.Contains(p.Body(false), p.ID()) {
+		t.Errorf("placeholder body must contain its ID")
+	}
+	other := &placeholderSource{key: redact("CVE in bar"), ref: redact("http://report/2")}
+	if p.Title() == other.Title() || p.ID() == other.ID() {
+		t.Errorf("placeholders for different sources must differ")
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"sort"
+)
+
+// Severity ranks sources, so that when the syncer can't keep up, issues for
+// the worst problems are filed first.
+type Severity int
+
+const (
+	SeverityLow Severity = iota - 1
+	// SeverityNormal is the severity of sources which don't have one.
+	SeverityNormal
+	SeverityHigh
+	SeverityCritical
+)
+
+// SeveritySource is an IssueSource which knows how bad it is.
+type SeveritySource interface {
+	IssueSource
+	Severity() Severity
+}
+
+func severity(source IssueSource) Severity {
+	if s, ok := source.(SeveritySource); ok {
+		return s.Severity()
+	}
+	return SeverityNormal
+}
+
+type bySeverity []IssueSource
+
+func (b bySeverity) Len() int           { return len(b) }
+func (b bySeverity) Less(i, j int) bool { return severity(b[i]) > severity(b[j]) }
+func (b bySeverity) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+type byID []IssueSource
+
+func (b byID) Len() int           { return len(b) }
+func (b byID) Less(i, j int) bool { return b[i].ID() < b[j].ID() }
+func (b byID) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// prioritize returns the sources, most severe first. Sources of the same
+// severity keep their order.
+func prioritize(sources []IssueSource) []IssueSource {
+	sorted := append([]IssueSource(nil), sources...)
+	sort.Stable(bySeverity(sorted))
+	return sorted
+}
+
+// lowOnBudget returns true if github says we have fewer API calls left than
+// MinAPIBudget.
+func (s *IssueSyncer) lowOnBudget() bool {
+	if s.MinAPIBudget <= 0 {
+		return false
+	}
+	remaining, ok := s.config.APIRemaining()
+	return ok && remaining < s.MinAPIBudget
+}
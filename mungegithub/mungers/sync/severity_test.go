@@ -0,0 +1,50 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"testing"
+)
+
+type severeSource struct {
+	testSource
+	severity Severity
+}
+
+func (s *severeSource) Severity() Severity { return s.severity }
+
+func TestPrioritize(t *testing.T) {
+	sources := []IssueSource{
+		&testSource{id: "normal-1"},
+		&severeSource{testSource{id: "low"}, SeverityLow},
+		&severeSource{testSource{id: "critical"}, SeverityCritical},
+		&testSource{id: "normal-2"},
+		&severeSource{testSource{id: "high"}, SeverityHigh},
+	}
+	got := []string{}
+	for _, s := range prioritize(sources) {
+		got = append(got, s.ID())
+	}
+	expected := []string{"critical", "high", "normal-1", "normal-2", "low"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if sources[0].ID() != "normal-1" {
+		t.Errorf("prioritize must not reorder its argument")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/net/context"
+)
+
+// ErrStopped is returned by SyncAll once the syncer was stopped, see Stop.
+var ErrStopped = errors.New("syncer stopped")
+
+// Start has a stopped syncer accept sources again. New syncers are started.
+func (s *IssueSyncer) Start() {
+	s.stopLock.Lock()
+	defer s.stopLock.Unlock()
+	s.stopped = false
+	s.unfinished = nil
+}
+
+// Stop stops the syncer gracefully, e.g. when we're asked to terminate, so
+// that no issue is left half updated: SyncAll stops accepting sources, and
+// the one running finishes the source it is syncing and wraps the cycle up
+// (e.g. filing the meta-issue about capped sources) without syncing the
+// rest. Stop waits for that until ctx is done, applies the changes queued
+// by IssueChanged, persists the Store and the Audit log, flushes the
+// History, and returns the IDs of the sources which were left unsynced,
+// including the ones held for QuietHours, so that they can be reported.
+func (s *IssueSyncer) Stop(ctx context.Context) ([]string, error) {
+	s.stopLock.Lock()
+	s.stopped = true
+	s.stopLock.Unlock()
+
+	idle := make(chan struct{})
+	go func() {
+		s.running.Lock()
+		close(idle)
+	}()
+	select {
+	case <-idle:
+		defer s.running.Unlock()
+	case <-ctx.Done():
+		go func() {
+			<-idle
+			s.running.Unlock()
+		}()
+		return s.unfinishedIDs(), fmt.Errorf("still syncing when stopped: %v", ctx.Err())
+	}
+
+	s.applyChanges(ctx)
+	unfinished := s.unfinishedIDs()
+	for _, source := range s.held {
+		unfinished = append(unfinished, s.sourceID(source))
+	}
+	sort.Strings(unfinished)
+	if err := s.Store.Flush(); err != nil {
+		return unfinished, fmt.Errorf("error persisting the metadata: %v", err)
+	}
+	if s.Audit != nil {
+		if err := s.Audit.Sync(); err != nil {
+			return unfinished, fmt.Errorf("error persisting the audit log: %v", err)
+		}
+	}
+	if s.History != nil {
+		if err := s.History.Flush(); err != nil {
+			return unfinished, fmt.Errorf("error exporting the history: %v", err)
+		}
+	}
+	return unfinished, nil
+}
+
+// isStopped returns true if the syncer was stopped, see Stop.
+func (s *IssueSyncer) isStopped() bool {
+	s.stopLock.Lock()
+	defer s.stopLock.Unlock()
+	return s.stopped
+}
+
+// leaveUnfinished remembers `sources` weren't synced because the syncer was
+// stopped, and returns a deferred result for each.
+func (s *IssueSyncer) leaveUnfinished(sources []IssueSource) []SyncResult {
+	results := []SyncResult{}
+	s.stopLock.Lock()
+	defer s.stopLock.Unlock()
+	for _, source := range sources {
+		id := s.sourceID(source)
+		s.unfinished = append(s.unfinished, id)
+		results = append(results, SyncResult{Source: id, Deferred: true})
+	}
+	return results
+}
+
+func (s *IssueSyncer) unfinishedIDs() []string {
+	s.stopLock.Lock()
+	defer s.stopLock.Unlock()
+	return append([]string{}, s.unfinished...)
+}
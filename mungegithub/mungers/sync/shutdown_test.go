@@ -0,0 +1,109 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestStop(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	history := &recordingExporter{}
+	s.History = history
+
+	// Stop while the first source is being synced.
+	stopped := make(chan []string)
+	s.Stages = []Stage{{
+		Name:   "stop",
+		Before: PhaseMutate,
+		Run: func(ctx context.Context, st *SyncState) error {
+			if st.Source.ID() != "foo-1" {
+				return nil
+			}
+			go func() {
+				unfinished, err := s.Stop(context.Background())
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				stopped <- unfinished
+			}()
+			for !s.isStopped() {
+				time.Sleep(time.Millisecond)
+			}
+			return nil
+		},
+	}}
+	sources := []IssueSource{
+		&JSONSource{Key: "TestFoo", Ref: "foo-1"},
+		&JSONSource{Key: "TestBar", Ref: "bar-1"},
+		&JSONSource{Key: "TestBaz", Ref: "baz-1"},
+	}
+	results, err := s.SyncAll(context.Background(), sources)
+	if err != ErrStopped {
+		t.Errorf("expected ErrStopped, got %v", err)
+	}
+	if len(results) != 3 || results[0].Action != DecisionCreate || !results[1].Deferred || !results[2].Deferred {
+		t.Errorf("expected the first source to be synced and the rest deferred, got %+v", results)
+	}
+	if unfinished := <-stopped; !reflect.DeepEqual(unfinished, []string{"bar-1", "baz-1"}) {
+		t.Errorf("expected the unsynced sources to be reported, got %v", unfinished)
+	}
+	if history.flushes != 1 {
+		t.Errorf("expected the history to be flushed once, got %d", history.flushes)
+	}
+	if len(tracker.OpenIssues("TestFoo")) != 1 || len(tracker.Issues()) != 1 {
+		t.Errorf("expected only the first source to be filed, got %d issues", len(tracker.Issues()))
+	}
+
+	if _, err := s.SyncAll(context.Background(), sources[1:]); err != ErrStopped {
+		t.Errorf("expected a stopped syncer to refuse sources, got %v", err)
+	}
+	if err := s.Sync(context.Background(), sources[1]); err != ErrStopped {
+		t.Errorf("expected a stopped syncer to refuse a single source, got %v", err)
+	}
+	s.Start()
+	if _, err := s.SyncAll(context.Background(), sources[1:]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tracker.Issues()) != 3 {
+		t.Errorf("expected the rest to be filed once started again, got %d issues", len(tracker.Issues()))
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	s := NewIssueSyncer(nil, nil)
+	s.running.Lock()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Stop(ctx); err == nil {
+		t.Errorf("expected an error while still syncing")
+	}
+	s.running.Unlock()
+	// The syncer isn't left locked.
+	if _, err := s.Stop(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// The SLO countdown of an issue is kept at the start of its body, between
+// these markers.
+const (
+	sloStart = "<!-- slo-start -->"
+	sloEnd   = "<!-- slo-end -->"
+)
+
+// SLO is how soon issues with a priority label must be triaged.
+type SLO struct {
+	// Priority is the label, e.g. "priority/P0".
+	Priority string `json:"priority"`
+	// Within is how long after the issue got the label it must be triaged,
+	// e.g. "24h".
+	Within string `json:"within"`
+	// Ping is mentioned when the SLO is breached, e.g. "@kubernetes/oncall".
+	Ping string `json:"ping"`
+
+	within time.Duration
+}
+
+// name is how the SLO is called in issues, e.g. "P0".
+func (slo *SLO) name() string {
+	return slo.Priority[strings.LastIndex(slo.Priority, "/")+1:]
+}
+
+// SLOPolicy holds high priority issues we filed to deadlines: they get a
+// countdown until they must be triaged, and are escalated if they aren't.
+// For example:
+//
+//	triageLabel: triaged
+//	slos:
+//	- priority: priority/P0
+//	  within: 24h
+//	  ping: "@kubernetes/test-infra-maintainers"
+//	- priority: priority/P1
+//	  within: 72h
+type SLOPolicy struct {
+	// TriageLabel marks an issue as triaged, as do an assignee and a
+	// comment starting with "/triage", see EscalationPolicy.
+	TriageLabel string `json:"triageLabel"`
+	// SLOs apply in order: an issue is held to the first one whose
+	// priority label it has.
+	SLOs []SLO `json:"slos"`
+}
+
+// LoadSLOPolicy reads a policy from a yaml (or json) file.
+func LoadSLOPolicy(path string) (*SLOPolicy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	p := &SLOPolicy{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(p); err != nil {
+		return nil, fmt.Errorf("error parsing SLO policy %v: %v", path, err)
+	}
+	for i := range p.SLOs {
+		slo := &p.SLOs[i]
+		if slo.Priority == "" {
+			return nil, fmt.Errorf("SLO %d in %v has no priority", i+1, path)
+		}
+		if slo.within, err = time.ParseDuration(slo.Within); err != nil || slo.within <= 0 {
+			return nil, fmt.Errorf("invalid within %q for %v in %v", slo.Within, slo.Priority, path)
+		}
+	}
+	return p, nil
+}
+
+func (p *SLOPolicy) slo(obj *github.MungeObject) *SLO {
+	for i := range p.SLOs {
+		if obj.HasLabel(p.SLOs[i].Priority) {
+			return &p.SLOs[i]
+		}
+	}
+	return nil
+}
+
+// sloCountdown is the countdown shown on an issue held to `slo` since
+// `start`. `triaged` is when it was triaged, if it was.
+func sloCountdown(slo *SLO, start, triaged, now time.Time) string {
+	due := start.Add(slo.within)
+	format := func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 MST") }
+	var text string
+	switch {
+	case !triaged.IsZero() && triaged.After(due):
+		text = fmt.Sprintf("**%v:** triaged %v, after the %v SLO.", slo.name(), format(triaged), slo.Within)
+	case !triaged.IsZero():
+		text = fmt.Sprintf("**%v:** triaged %v, within the %v SLO.", slo.name(), format(triaged), slo.Within)
+	case now.After(due):
+		text = fmt.Sprintf("**%v:** must be triaged within %v, was due %v. :warning: **Overdue.**", slo.name(), slo.Within, format(due))
+	default:
+		text = fmt.Sprintf("**%v:** must be triaged within %v, due %v.", slo.name(), slo.Within, format(due))
+	}
+	return sloStart + "\n" + text + "\n" + sloEnd
+}
+
+// TrackSLOs goes through every open issue in the store which is held to an
+// SLO of `policy`, or was: the countdown in the issue is kept up to date,
+// and the issue escalated once it's overdue. Triaged issues are left alone
+// once their countdown says so.
+func (s *IssueSyncer) TrackSLOs(ctx context.Context, policy *SLOPolicy) error {
+	for _, r := range s.Store.List() {
+		if r.Closed || r.Created.IsZero() || (r.Triaged && r.SLOPriority == "") {
+			continue
+		}
+		if err := s.trackSLO(ctx, policy, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *IssueSyncer) trackSLO(ctx context.Context, policy *SLOPolicy, r IssueRecord) error {
+	obj, err := s.getObject(ctx, r.Number)
+	if err != nil {
+		return err
+	}
+	if obj.Issue.State != nil && *obj.Issue.State == "closed" {
+		return s.noticeClosed(ctx, r.Number)
+	}
+	log := s.logger().With("issue", r.Number)
+	now := s.now()
+
+	countdown := ""
+	slo := policy.slo(obj)
+	if slo != nil {
+		start := r.SLOStart
+		switch {
+		case r.SLOPriority == "" && sets.NewString(r.Labels...).Has(slo.Priority):
+			// We filed the issue with the priority.
+			start = r.Created
+		case r.SLOPriority != slo.Priority:
+			// Humans raised (or lowered) the priority.
+			start = now
+		}
+		triaged, err := s.isTriaged(ctx, obj, &EscalationPolicy{TriageLabel: policy.TriageLabel})
+		if err != nil {
+			return err
+		}
+		var triagedAt time.Time
+		if triaged {
+			if triagedAt = r.TriagedAt; triagedAt.IsZero() || triagedAt.Before(start) {
+				triagedAt = now
+			}
+		}
+		countdown = sloCountdown(slo, start, triagedAt, now)
+		breached := !triaged && now.After(start.Add(slo.within)) && !(r.SLOBreached && r.SLOPriority == slo.Priority)
+		if breached {
+			if err := s.breachSLO(ctx, obj, slo); err != nil {
+				return err
+			}
+		}
+		if err := s.Store.Update(r.Number, func(r *IssueRecord) {
+			if r.SLOPriority != slo.Priority {
+				r.SLOBreached = false
+			}
+			r.SLOPriority, r.SLOStart = slo.Priority, start
+			r.SLOBreached = r.SLOBreached || breached
+			if triaged {
+				// Done, see TrackSLOs.
+				r.Triaged, r.TriagedAt = true, triagedAt
+				r.SLOPriority = ""
+			}
+		}); err != nil {
+			return err
+		}
+	} else if r.SLOPriority != "" {
+		log.Debugf("No longer held to the %v SLO", r.SLOPriority)
+		if err := s.Store.Update(r.Number, func(r *IssueRecord) {
+			r.SLOPriority, r.SLOStart, r.SLOBreached = "", time.Time{}, false
+		}); err != nil {
+			return err
+		}
+	}
+
+	old := ""
+	if obj.Issue.Body != nil {
+		old = *obj.Issue.Body
+	}
+	body := replaceSection(old, sloStart, sloEnd, countdown, false)
+	if body == old {
+		return nil
+	}
+	log.Debugf("Updating the SLO countdown")
+	return s.editBody(ctx, fmt.Sprintf("editing the SLO countdown of %v", r.Number), obj, old, body)
+}
+
+// breachSLO escalates `obj`, which wasn't triaged within `slo`.
+func (s *IssueSyncer) breachSLO(ctx context.Context, obj *github.MungeObject, slo *SLO) error {
+	n := *obj.Issue.Number
+	metrics.Add("sloBreaches", 1)
+	metrics.Add("sloBreaches:"+slo.Priority, 1)
+	msg := s.sloBreachText(n, slo)
+	s.logger().With("issue", n).Infof("SLO breached: %v", msg)
+	if err := s.writeComment(ctx, fmt.Sprintf("escalating %v", n), obj, s.text(msg)); err != nil {
+		return err
+	}
+	if s.Board != nil {
+		s.moveCard(ctx, n, s.Board.EscalatedColumn)
+	}
+	return nil
+}
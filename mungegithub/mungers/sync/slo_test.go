@@ -0,0 +1,155 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestTrackSLOs(t *testing.T) {
+	policy := &SLOPolicy{
+		TriageLabel: "triaged",
+		SLOs: []SLO{
+			{Priority: "priority/P0", Within: "24h", Ping: "@oncall", within: 24 * time.Hour},
+			{Priority: "priority/P1", Within: "72h", within: 72 * time.Hour},
+		},
+	}
+	tests := []struct {
+		name   string
+		labels []string
+		// filed are the labels we filed the issue with.
+		filed    []string
+		now      string
+		body     string
+		comments int
+		breached bool
+		triaged  bool
+	}{
+		{
+			name:   "due",
+			labels: []string{"priority/P0"},
+			filed:  []string{"priority/P0"},
+			now:    "2016-07-01 20:00",
+			body:   "**P0:** must be triaged within 24h, due 2016-07-02 12:00 UTC.",
+		},
+		{
+			name:     "overdue",
+			labels:   []string{"priority/P0"},
+			filed:    []string{"priority/P0"},
+			now:      "2016-07-02 13:00",
+			body:     "**P0:** must be triaged within 24h, was due 2016-07-02 12:00 UTC. :warning: **Overdue.**",
+			comments: 1,
+			breached: true,
+		},
+		{
+			name:    "triaged",
+			labels:  []string{"priority/P1", "triaged"},
+			filed:   []string{"priority/P1"},
+			now:     "2016-07-02 13:00",
+			body:    "**P1:** triaged 2016-07-02 13:00 UTC, within the 72h SLO.",
+			triaged: true,
+		},
+		{
+			name:   "raised by humans",
+			labels: []string{"priority/P0"},
+			now:    "2016-07-03 13:00",
+			body:   "**P0:** must be triaged within 24h, due 2016-07-04 13:00 UTC.",
+		},
+		{
+			name:   "no SLO",
+			labels: []string{"priority/P3"},
+			filed:  []string{"priority/P3"},
+			now:    "2016-07-03 13:00",
+		},
+	}
+	for _, test := range tests {
+		tracker := synctesting.NewTracker()
+		n := tracker.AddIssue("TestFoo", "details", test.labels...)
+		s := NewIssueSyncer(tracker.Config(), nil)
+		s.now = func() time.Time { return date(test.now) }
+		s.Store.Update(n, func(r *IssueRecord) {
+			r.Labels = test.filed
+			r.Created = date("2016-07-01 12:00")
+		})
+		// Twice, to make sure issues are escalated once.
+		for i := 0; i < 2; i++ {
+			if err := s.TrackSLOs(context.Background(), policy); err != nil {
+				t.Errorf("%v: unexpected error: %v", test.name, err)
+			}
+		}
+		body := *tracker.Issues()[0].Body
+		if test.body == "" && body != "details" {
+			t.Errorf("%v: expected the body unchanged, got %q", test.name, body)
+		} else if test.body != "" && body != sloStart+"\n"+test.body+"\n"+sloEnd+"\n\ndetails" {
+			t.Errorf("%v: expected countdown %q, got %q", test.name, test.body, body)
+		}
+		comments := tracker.Comments(n)
+		if len(comments) != test.comments {
+			t.Errorf("%v: expected %d comments, got %q", test.name, test.comments, comments)
+		} else if test.comments > 0 && !strings.HasPrefix(comments[0], "@oncall This P0 issue was not triaged within 24h.") {
+			t.Errorf("%v: unexpected escalation %q", test.name, comments[0])
+		}
+		r, _ := s.Store.Get(n)
+		if r.SLOBreached != test.breached || r.Triaged != test.triaged {
+			t.Errorf("%v: unexpected record %#v", test.name, r)
+		}
+		tracker.Close()
+	}
+}
+
+func TestSLOCountdownSection(t *testing.T) {
+	countdown := sloStart + "\nnew\n" + sloEnd
+	tests := []struct {
+		body, countdown, expected string
+	}{
+		{"details", countdown, countdown + "\n\ndetails"},
+		{sloStart + "\nold\n" + sloEnd + "\n\ndetails", countdown, countdown + "\n\ndetails"},
+		{sloStart + "\nold\n" + sloEnd + "\n\ndetails", "", "details"},
+		{"details", "", "details"},
+	}
+	for _, test := range tests {
+		if got := replaceSection(test.body, sloStart, sloEnd, test.countdown, false); got != test.expected {
+			t.Errorf("%q with countdown %q: expected %q, got %q", test.body, test.countdown, test.expected, got)
+		}
+	}
+}
+
+func TestLoadSLOPolicy(t *testing.T) {
+	file, err := ioutil.TempFile("", "slo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`
+slos:
+- priority: priority/P0
+  within: 24h
+- priority: priority/P1
+  within: soon
+`)
+	file.Close()
+	if _, err := LoadSLOPolicy(file.Name()); err == nil || !strings.Contains(err.Error(), "soon") {
+		t.Errorf("expected an invalid duration to be refused, got %v", err)
+	}
+}
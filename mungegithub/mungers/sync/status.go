@@ -0,0 +1,149 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRecentErrors is how many failures to sync the StatusPage shows.
+const maxRecentErrors = 20
+
+// SyncError is a failure to sync a source.
+type SyncError struct {
+	At     time.Time
+	Source string
+	Error  string
+}
+
+// SyncStatus is the state of a syncer, for operators.
+type SyncStatus struct {
+	// Repo is where the syncer files issues, as "org/project".
+	Repo   string
+	Health SyncHealth
+	// OpenByLabel counts the open issues we filed by label, and
+	// CreatedLastDay the issues filed in the last 24 hours.
+	OpenByLabel    map[string]int
+	Open           int
+	CreatedLastDay int
+	// Backlog is how many sources wait to be synced: the ones the last
+	// cycle failed to sync or deferred (Health.Pending), the ones Held for
+	// quiet hours and the ones Queued by an Ingester.
+	Backlog int
+	Held    int
+	Queued  int
+	// Errors are the latest failures to sync, oldest first.
+	Errors []SyncError
+}
+
+// StatusPage serves the state of a syncer as an html page, or as json for
+// requests which accept it (or ask for ?format=json).
+type StatusPage struct {
+	health *HealthReporter
+
+	// Ingester, if set, is what feeds the syncer sources.
+	Ingester *Ingester
+}
+
+// NewStatusPage constructs a StatusPage for `syncer`.
+func NewStatusPage(syncer *IssueSyncer) *StatusPage {
+	return &StatusPage{health: NewHealthReporter(syncer)}
+}
+
+// Status returns the syncer's current state.
+func (p *StatusPage) Status() SyncStatus {
+	s := p.health.syncer
+	status := SyncStatus{
+		Health:      p.health.Health(),
+		OpenByLabel: map[string]int{},
+	}
+	if s.config != nil {
+		status.Repo = fmt.Sprintf("%v/%v", s.config.Org, s.config.Project)
+	}
+	dayAgo := s.now().Add(-24 * time.Hour)
+	for _, r := range s.Store.List() {
+		if !r.Created.Before(dayAgo) {
+			status.CreatedLastDay++
+		}
+		if r.Closed {
+			continue
+		}
+		status.Open++
+		for _, l := range r.Labels {
+			status.OpenByLabel[l]++
+		}
+	}
+	s.health.lock.Lock()
+	status.Held = s.health.held
+	status.Errors = append([]SyncError{}, s.health.errors...)
+	s.health.lock.Unlock()
+	if p.Ingester != nil {
+		status.Queued = p.Ingester.Queued()
+	}
+	status.Backlog = status.Health.Pending + status.Held + status.Queued
+	return status
+}
+
+var statusHTML = template.Must(template.New("status").Parse(`<html>
+<head><title>Issue sync status{{if .Repo}} for {{.Repo}}{{end}}</title></head>
+<body>
+<h1>Issue sync status{{if .Repo}} for {{.Repo}}{{end}}</h1>
+<table>
+<tr><th>Healthy</th><td>{{.Health.Healthy}}</td></tr>
+<tr><th>Last cycle</th><td>{{if .Health.LastCycle.IsZero}}never{{else}}{{.Health.LastCycle.UTC}}{{end}}</td></tr>
+<tr><th>Last success</th><td>{{if .Health.LastSuccess.IsZero}}never{{else}}{{.Health.LastSuccess.UTC}}{{end}}</td></tr>
+<tr><th>API calls left</th><td>{{if lt .Health.APIRemaining 0}}unknown{{else}}{{.Health.APIRemaining}}{{end}}</td></tr>
+<tr><th>Filed in the last 24h</th><td>{{.CreatedLastDay}}</td></tr>
+<tr><th>Backlog</th><td>{{.Backlog}} ({{.Health.Pending}} pending, {{.Held}} held, {{.Queued}} queued)</td></tr>
+</table>
+<h2>Open issues</h2>
+{{if .OpenByLabel}}<table>
+<tr><th>Label</th><th>Open</th></tr>
+{{range $label, $open := .OpenByLabel}}<tr><td>{{$label}}</td><td>{{$open}}</td></tr>
+{{end}}<tr><th>Total</th><td>{{.Open}}</td></tr>
+</table>{{else}}<p>{{.Open}}</p>{{end}}
+<h2>Recent errors</h2>
+{{if .Errors}}<ul>
+{{range .Errors}}<li>{{.At.UTC}} {{.Source}}: {{.Error}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+</body>
+</html>
+`))
+
+// ServeHTTP serves the status.
+func (p *StatusPage) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	status := p.Status()
+	if req.URL.Query().Get("format") == "json" || strings.Contains(req.Header.Get("Accept"), "application/json") {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res.Header().Set("Content-type", "application/json")
+		res.Write(data)
+		return
+	}
+	res.Header().Set("Content-type", "text/html; charset=utf-8")
+	if err := statusHTML.Execute(res, status); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
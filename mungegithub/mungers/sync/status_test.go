@@ -0,0 +1,89 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestStatusPage(t *testing.T) {
+	now := date("2016-07-02 12:00")
+	s := NewIssueSyncer(nil, failingFinder{})
+	s.now = func() time.Time { return now }
+	for _, r := range []IssueRecord{
+		{Number: 1, Labels: []string{"kind/flake", "sig/node"}, Created: now.Add(-48 * time.Hour)},
+		{Number: 2, Labels: []string{"kind/flake"}, Created: now.Add(-time.Hour)},
+		{Number: 3, Labels: []string{"kind/flake"}, Created: now.Add(-time.Hour), Closed: true},
+	} {
+		r := r
+		s.Store.Update(r.Number, func(rec *IssueRecord) { *rec = r })
+	}
+	if _, err := s.SyncAll(context.Background(), []IssueSource{&testSource{title: "a", id: "1"}}); err == nil {
+		t.Fatalf("expected syncing to fail")
+	}
+	ingester := NewIngester(s, "token")
+	ingester.enqueue([]*JSONSource{{Key: "b", Ref: "2"}})
+	page := NewStatusPage(s)
+	page.Ingester = ingester
+
+	status := page.Status()
+	if expected := map[string]int{"kind/flake": 2, "sig/node": 1}; !reflect.DeepEqual(status.OpenByLabel, expected) {
+		t.Errorf("expected open issues %v, got %v", expected, status.OpenByLabel)
+	}
+	if status.CreatedLastDay != 2 {
+		t.Errorf("expected 2 issues filed in the last day, got %v", status.CreatedLastDay)
+	}
+	if status.Backlog != 2 || status.Queued != 1 {
+		t.Errorf("expected a backlog of 1 failed and 1 queued source, got %+v", status)
+	}
+	if len(status.Errors) != 1 || status.Errors[0].Source != "1" {
+		t.Errorf("expected the failure to be listed, got %v", status.Errors)
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/status?format=json", nil)
+	page.ServeHTTP(res, req)
+	served := SyncStatus{}
+	if err := json.Unmarshal(res.Body.Bytes(), &served); err != nil || served.Open != 2 {
+		t.Errorf("expected the status as json, got %v: %v", res.Body.String(), err)
+	}
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/status", nil)
+	page.ServeHTTP(res, req)
+	if body := res.Body.String(); !strings.Contains(body, "<td>kind/flake</td><td>2</td>") {
+		t.Errorf("expected open issues by label in the page, got %v", body)
+	}
+}
+
+func TestRecentErrors(t *testing.T) {
+	c := &cycleHealth{}
+	for i := 0; i < maxRecentErrors+5; i++ {
+		c.recordError(SyncError{Source: fmt.Sprint(i)})
+	}
+	if len(c.errors) != maxRecentErrors || c.errors[0].Source != "5" {
+		t.Errorf("expected the latest %d errors, got %v", maxRecentErrors, c.errors)
+	}
+}
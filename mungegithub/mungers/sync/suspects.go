@@ -0,0 +1,145 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+)
+
+var mergeMessageRE = regexp.MustCompile(`^Merge pull request #(\d+)`)
+
+// TimedSource is an IssueSource which knows when it occurred. Other sources
+// occurred when they are synced.
+type TimedSource interface {
+	IssueSource
+	OccurredAt() time.Time
+}
+
+// SuspectMerges lists the merges which landed shortly before a source first
+// occurred in the body of its new issue, since one of them likely broke it.
+type SuspectMerges struct {
+	// Branch is where merges land, "master" if empty.
+	Branch string
+	// Window is how long before the first occurrence merges are suspect.
+	Window time.Duration
+	// Max is how many merges are listed, the newest first.
+	Max int
+}
+
+// NewSuspectMerges lists at most 20 merges to master in the 6 hours before
+// the first occurrence.
+func NewSuspectMerges() *SuspectMerges {
+	return &SuspectMerges{Branch: "master", Window: 6 * time.Hour, Max: 20}
+}
+
+// suspect is a merge in the suspect window.
+type suspect struct {
+	sha     string
+	pr      int
+	subject string
+	at      time.Time
+}
+
+// suspects returns the merges before `at` in the window, newest first.
+func (s *IssueSyncer) suspects(ctx context.Context, at time.Time) ([]suspect, error) {
+	branch := s.Suspects.Branch
+	if branch == "" {
+		branch = "master"
+	}
+	var commits []githubapi.RepositoryCommit
+	err := s.retry(ctx, fmt.Sprintf("listing the commits on %v before %v", branch, at), func() (err error) {
+		commits, err = s.client(ctx).ListCommitsBetween(branch, at.Add(-s.Suspects.Window), at)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	suspects := []suspect{}
+	for _, c := range commits {
+		if c.SHA == nil || c.Commit == nil || len(c.Parents) < 2 {
+			// Only merges; their other parent has the changes.
+			continue
+		}
+		m := suspect{sha: *c.SHA}
+		if c.Commit.Message != nil {
+			lines := strings.Split(*c.Commit.Message, "\n")
+			m.subject = lines[0]
+			if pr := mergeMessageRE.FindStringSubmatch(lines[0]); pr != nil {
+				m.pr, _ = strconv.Atoi(pr[1])
+				// The title of the pull request follows the merge
+				// line.
+				for _, l := range lines[1:] {
+					if l = strings.TrimSpace(l); l != "" {
+						m.subject = l
+						break
+					}
+				}
+			}
+		}
+		if c.Commit.Committer != nil && c.Commit.Committer.Date != nil {
+			m.at = *c.Commit.Committer.Date
+		}
+		suspects = append(suspects, m)
+	}
+	return suspects, nil
+}
+
+// suspectsText lists the suspect merges for a new issue about `source`, or
+// returns "" if there are none or they can't be listed: the issue matters
+// more than the list.
+func (s *IssueSyncer) suspectsText(ctx context.Context, source IssueSource) string {
+	at := s.now()
+	if t, ok := source.(TimedSource); ok && !t.OccurredAt().IsZero() {
+		at = t.OccurredAt()
+	}
+	suspects, err := s.suspects(ctx, at)
+	if err != nil {
+		s.logger().Errorf("Unable to list the suspect merges: %v", err)
+		return ""
+	}
+	if len(suspects) == 0 {
+		return ""
+	}
+	lines := []string{fmt.Sprintf("Merges in the %v before this first occurred, one of which may have caused it:", s.Suspects.Window), ""}
+	for i, m := range suspects {
+		if s.Suspects.Max > 0 && i == s.Suspects.Max {
+			lines = append(lines, fmt.Sprintf("- and %d more", len(suspects)-i))
+			break
+		}
+		line := "- "
+		if m.pr != 0 {
+			line += fmt.Sprintf("#%d ", m.pr)
+		}
+		sha := m.sha
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		line += fmt.Sprintf("%v (%v", SanitizeBody(m.subject), sha)
+		if !m.at.IsZero() {
+			line += ", " + m.at.UTC().Format("2006-01-02 15:04 MST")
+		}
+		lines = append(lines, line+")")
+	}
+	return strings.Join(lines, "\n")
+}
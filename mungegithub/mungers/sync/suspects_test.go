@@ -0,0 +1,84 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestSuspectMerges(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	tracker.AddMerge(10, "Too early", date("2016-07-01 02:00"))
+	tracker.AddMerge(11, "Refactor the kubelet", date("2016-07-01 08:00"))
+	tracker.AddMerge(12, "Speed up the scheduler", date("2016-07-01 10:00"))
+	tracker.AddMerge(13, "Too late", date("2016-07-01 13:00"))
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.now = func() time.Time { return date("2016-07-01 14:00") }
+	s.Suspects = NewSuspectMerges()
+	s.Suspects.Max = 1
+
+	source := &JSONSource{Key: "TestFoo", Ref: "run-1", At: date("2016-07-01 12:00")}
+	if err := s.Sync(context.Background(), source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: "run-2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	issue := tracker.Issues()[len(tracker.Issues())-1]
+	body := *issue.Body
+	for _, expected := range []string{"Merges in the 6h0m0s before", "- #12 Speed up the scheduler (", "2016-07-01 10:00 UTC)", "- and 1 more"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in the body, got:\n%v", expected, body)
+		}
+	}
+	for _, unexpected := range []string{"#10", "#11", "#13"} {
+		if strings.Contains(body, unexpected) {
+			t.Errorf("unexpected %q in the body:\n%v", unexpected, body)
+		}
+	}
+	for _, c := range tracker.Comments(*issue.Number) {
+		if strings.Contains(c, "Merges in the") {
+			t.Errorf("expected suspects only for new issues, got comment %q", c)
+		}
+	}
+}
+
+func TestSuspectMergesUnavailable(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	tracker.FailNext("GET", "/repos/o/r/commits", 404, 10)
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.Suspects = NewSuspectMerges()
+
+	if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: "run-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tracker.OpenIssues("TestFoo")) != 1 {
+		t.Errorf("expected the issue filed without suspects, got %v", tracker.Issues())
+	}
+}
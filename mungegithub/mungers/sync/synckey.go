@@ -0,0 +1,87 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// KeyedSource is an IssueSource with a stable machine key, e.g. the ID of a
+// test rather than its name. Issues embed a hash of it (see SyncKeyMarker),
+// and are found by it in preference to their title, so that renames of the
+// test, or edits of the title by humans, don't lead to duplicates. Sources
+// which aren't keyed are keyed by their normalized title, which still
+// survives title edits.
+type KeyedSource interface {
+	IssueSource
+	SyncKey() string
+}
+
+// SyncKeyFinder is an IssueFinder which can also find issues by the sync
+// keys embedded in them.
+type SyncKeyFinder interface {
+	IssueFinder
+	// IssuesForSyncKey returns all issues, open or closed, which embed
+	// `key`, oldest first.
+	IssuesForSyncKey(key string) ([]int, error)
+	// CreatedSyncKey tells the finder about an issue we just filed with
+	// `key`.
+	CreatedSyncKey(key string, number int)
+}
+
+// syncKey is the key embedded in issues for the source.
+func (s *IssueSyncer) syncKey(source IssueSource) string {
+	key := s.key(source)
+	if k, ok := source.(KeyedSource); ok && k.SyncKey() != "" {
+		key = Namespaced(s.Namespace, k.SyncKey())
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
+
+// previousIssues returns the issues for the source: those which embed its
+// sync key if there are any, or else those with its title.
+func (s *IssueSyncer) previousIssues(source IssueSource) ([]int, error) {
+	if f, ok := s.finder.(SyncKeyFinder); ok {
+		numbers, err := f.IssuesForSyncKey(s.syncKey(source))
+		if err != nil || len(numbers) > 0 {
+			return numbers, err
+		}
+	}
+	return s.issuesForKey(s.key(source))
+}
+
+// ensureSyncKey embeds the sync key of the source in `obj` if it doesn't have
+// it yet, e.g. because it was filed before we had sync keys.
+func (s *IssueSyncer) ensureSyncKey(ctx context.Context, obj *github.MungeObject, source IssueSource) error {
+	marker := SyncKeyMarker(s.syncKey(source))
+	old := ""
+	if obj.Issue.Body != nil {
+		old = *obj.Issue.Body
+	}
+	if strings.Contains(old, marker) {
+		return nil
+	}
+	n := *obj.Issue.Number
+	body := old + "\n\n" + marker
+	s.logger().With("issue", n).Infof("Adding the sync key to the issue")
+	return s.editBody(ctx, fmt.Sprintf("adding the sync key to %v", n), obj, old, body)
+}
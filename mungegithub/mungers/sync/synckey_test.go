@@ -0,0 +1,80 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"testing"
+)
+
+type keyFinder struct {
+	titles map[string][]int
+	keys   map[string][]int
+}
+
+func (k *keyFinder) AllIssuesForKey(key string) []int { return k.titles[key] }
+func (k *keyFinder) Created(key string, number int)   { k.titles[key] = append(k.titles[key], number) }
+func (k *keyFinder) IssuesForSyncKey(key string) ([]int, error) {
+	return k.keys[key], nil
+}
+func (k *keyFinder) CreatedSyncKey(key string, number int) {
+	k.keys[key] = append(k.keys[key], number)
+}
+
+func TestPreviousIssues(t *testing.T) {
+	s := NewIssueSyncer(nil, nil)
+	renamed := &JSONSource{Key: "TestFoo renamed", Ref: "1", Stable: "pkg/foo#TestFoo"}
+	unkeyed := &JSONSource{Key: "TestBar", Ref: "2"}
+	if s.syncKey(renamed) != s.syncKey(&JSONSource{Key: "TestFoo", Stable: "pkg/foo#TestFoo"}) {
+		t.Errorf("sync key should not depend on the title")
+	}
+	if s.syncKey(unkeyed) == s.syncKey(&JSONSource{Key: "TestBaz"}) {
+		t.Errorf("unkeyed sources should be keyed by title")
+	}
+
+	s.finder = &keyFinder{
+		titles: map[string][]int{"TestFoo renamed": {3}, "TestBar": {4}},
+		keys:   map[string][]int{s.syncKey(renamed): {1}},
+	}
+	tests := []struct {
+		name     string
+		source   IssueSource
+		expected []int
+	}{
+		{name: "by sync key", source: renamed, expected: []int{1}},
+		{name: "by title", source: unkeyed, expected: []int{4}},
+	}
+	for _, test := range tests {
+		got, err := s.previousIssues(test.source)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		} else if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestIssueIndexSyncKeys(t *testing.T) {
+	i := NewIssueIndex(nil, nil, "")
+	i.add(indexIssue(1, "TestFoo", "flaked "+SyncKeyMarker("abc")))
+	i.CreatedSyncKey("def", 2)
+	for key, expected := range map[string][]int{"abc": {1}, "def": {2}, "TestFoo": {}} {
+		if got, _ := i.IssuesForSyncKey(key); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q: expected %v, got %v", key, expected, got)
+		}
+	}
+}
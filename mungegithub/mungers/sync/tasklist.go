@@ -0,0 +1,170 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// taskListMarker marks the comment holding an issue's task list.
+const taskListMarker = "<!-- sync-task-list -->"
+
+var taskRE = regexp.MustCompile(`^- \[([ xX])\] (.+)$`)
+
+// TaskList keeps the occurrences of an issue as a task list in a single
+// comment, one unchecked box each, instead of a comment each. Humans check
+// the boxes of occurrences they looked at; the unchecked ones are mentioned
+// again every PingInterval, with a comment, while new ones come in.
+type TaskList struct {
+	// PingInterval is the least time between two comments about unchecked
+	// occurrences.
+	PingInterval time.Duration
+	// MaxItems is how many occurrences the list keeps. The oldest checked
+	// ones are dropped first.
+	MaxItems int
+}
+
+// NewTaskList returns a TaskList which keeps 200 occurrences and pings
+// daily.
+func NewTaskList() *TaskList {
+	return &TaskList{PingInterval: 24 * time.Hour, MaxItems: 200}
+}
+
+type task struct {
+	checked bool
+	text    string
+}
+
+func (t task) String() string {
+	if t.checked {
+		return "- [x] " + t.text
+	}
+	return "- [ ] " + t.text
+}
+
+// parseTasks returns the tasks of a task list comment, in order.
+func parseTasks(body string) []task {
+	tasks := []task{}
+	for _, line := range strings.Split(body, "\n") {
+		if m := taskRE.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil {
+			tasks = append(tasks, task{checked: m[1] != " ", text: m[2]})
+		}
+	}
+	return tasks
+}
+
+// trimTasks drops the oldest tasks, checked ones first, until at most `max` are
+// left.
+func trimTasks(tasks []task, max int) []task {
+	for max > 0 && len(tasks) > max {
+		drop := 0
+		for i, t := range tasks {
+			if t.checked {
+				drop = i
+				break
+			}
+		}
+		tasks = append(tasks[:drop:drop], tasks[drop+1:]...)
+	}
+	return tasks
+}
+
+func (s *IssueSyncer) taskListText(tasks []task) string {
+	lines := []string{
+		taskListMarker,
+		"Occurrences of this issue, newest last. Please check the ones you looked at: the others will be mentioned again while new ones come in.",
+		"",
+	}
+	for _, t := range tasks {
+		lines = append(lines, t.String())
+	}
+	return s.text(strings.Join(lines, "\n"))
+}
+
+// appendTask records an occurrence of the source as a task of the issue's
+// task list, starting the list if there is none.
+func (s *IssueSyncer) appendTask(ctx context.Context, obj *github.MungeObject, source IssueSource) error {
+	n := *obj.Issue.Number
+	log := s.logger().With("issue", n)
+	var list *githubapi.IssueComment
+	err := s.retry(ctx, fmt.Sprintf("getting comments for %v", n), func() error {
+		comments, err := obj.ListComments()
+		list = nil
+		for i := range comments {
+			if c := &comments[i]; c.Body != nil && strings.Contains(*c.Body, taskListMarker) {
+				list = c
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	id := source.ID()
+	item := task{text: fmt.Sprintf("%v %v", s.now().UTC().Format("2006-01-02 15:04 MST"), sectionID(id))}
+	if list == nil {
+		log.Infof("Starting a task list of the occurrences of %q", s.title(source))
+		if err := s.writeComment(ctx, fmt.Sprintf("starting the task list of %v for %v", n, id), obj, s.taskListText([]task{item})); err != nil {
+			return err
+		}
+		return s.Store.Update(n, func(r *IssueRecord) {
+			r.Comments++
+			r.TaskPinged = s.now()
+			r.see(id)
+		})
+	}
+
+	tasks := trimTasks(append(parseTasks(*list.Body), item), s.TaskList.MaxItems)
+	body := s.taskListText(tasks)
+	log.Debugf("Adding an occurrence to the task list")
+	if err := s.retry(ctx, fmt.Sprintf("editing the task list of %v for %v", n, id), func() error {
+		return obj.EditComment(list, body)
+	}); err != nil {
+		return err
+	}
+	s.auditEntry(AuditEntry{Action: AuditEditComment, Issue: n, Comment: *list.ID, Detail: *list.Body})
+
+	unchecked := 0
+	for _, t := range tasks {
+		if !t.checked {
+			unchecked++
+		}
+	}
+	r, _ := s.Store.Get(n)
+	ping := unchecked > 0 && s.now().Sub(r.TaskPinged) >= s.TaskList.PingInterval
+	if ping {
+		msg := fmt.Sprintf("%d occurrences in the task list above are unchecked, most recently %v.", unchecked, id)
+		if err := s.writeComment(ctx, fmt.Sprintf("pinging about the task list of %v", n), obj, s.text(msg)); err != nil {
+			return err
+		}
+	}
+	return s.Store.Update(n, func(r *IssueRecord) {
+		if ping {
+			r.Comments++
+			r.TaskPinged = s.now()
+		}
+		r.see(id)
+	})
+}
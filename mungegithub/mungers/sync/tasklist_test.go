@@ -0,0 +1,94 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestTaskList(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.TaskList = NewTaskList()
+	now := date("2016-07-01 12:00")
+	s.now = func() time.Time { return now }
+	sync := func(ref string) {
+		if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref, Details: "failed"}); err != nil {
+			t.Fatalf("unexpected error syncing %v: %v", ref, err)
+		}
+	}
+
+	sync("run-1")
+	sync("run-2")
+	now = now.Add(time.Hour)
+	sync("run-3")
+	n := tracker.OpenIssues("TestFoo")[0]
+	comments := tracker.Comments(n)
+	if len(comments) != 1 {
+		t.Fatalf("expected a single task list comment, got %q", comments)
+	}
+	expected := []task{
+		{text: "2016-07-01 12:00 UTC run-2"},
+		{text: "2016-07-01 13:00 UTC run-3"},
+	}
+	if tasks := parseTasks(comments[0]); !reflect.DeepEqual(tasks, expected) {
+		t.Errorf("expected tasks %v, got %v", expected, tasks)
+	}
+
+	// Humans looked at run-2, run-3 and run-4 are mentioned once a day
+	// has passed.
+	tracker.EditComment(n, 0, strings.Replace(comments[0], "- [ ] 2016-07-01 12:00 UTC run-2", "- [x] 2016-07-01 12:00 UTC run-2", 1))
+	now = now.Add(24 * time.Hour)
+	sync("run-4")
+	comments = tracker.Comments(n)
+	if len(comments) != 2 || !strings.HasPrefix(comments[1], "2 occurrences in the task list above are unchecked, most recently run-4.") {
+		t.Errorf("expected a ping about 2 occurrences, got %q", comments)
+	}
+	if tasks := parseTasks(comments[0]); len(tasks) != 3 || !tasks[0].checked {
+		t.Errorf("expected run-2 to stay checked, got %v", tasks)
+	}
+	sync("run-5")
+	if comments := tracker.Comments(n); len(comments) != 2 {
+		t.Errorf("expected no ping until a day has passed, got %q", comments)
+	}
+}
+
+func TestTrimTasks(t *testing.T) {
+	tasks := []task{{text: "1"}, {text: "2", checked: true}, {text: "3"}, {text: "4", checked: true}, {text: "5"}}
+	tests := []struct {
+		max      int
+		expected []task
+	}{
+		{max: 0, expected: tasks},
+		{max: 4, expected: []task{{text: "1"}, {text: "3"}, {text: "4", checked: true}, {text: "5"}}},
+		{max: 2, expected: []task{{text: "3"}, {text: "5"}}},
+	}
+	for _, test := range tests {
+		if got := trimTasks(append([]task{}, tasks...), test.max); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("max %d: expected %v, got %v", test.max, test.expected, got)
+		}
+	}
+}
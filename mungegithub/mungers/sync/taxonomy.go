@@ -0,0 +1,137 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/net/context"
+	"k8s.io/kubernetes/pkg/util/sets"
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// defaultLabelColor is the color of created labels which have no LabelSpec.
+const defaultLabelColor = "ededed"
+
+// LabelSpec is how a missing label is created.
+type LabelSpec struct {
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+// LabelTaxonomy checks the labels of new issues against the labels which
+// exist in the repo, since github drops unknown labels. For example:
+//
+//	aliases:
+//	  flake: kind/flake
+//	autoCreate: true
+//	labels:
+//	  kind/flake:
+//	    color: f7c6c7
+//	    description: Categorizes issue as related to a flaky test.
+type LabelTaxonomy struct {
+	// Aliases map labels of sources to the labels to use instead.
+	Aliases map[string]string `json:"aliases"`
+	// AutoCreate creates missing labels, as described in Labels if they
+	// are there. Otherwise missing labels are left out.
+	AutoCreate bool                 `json:"autoCreate"`
+	Labels     map[string]LabelSpec `json:"labels"`
+
+	// known are the repo's labels, looked up once per sync cycle.
+	known sets.String
+}
+
+// LoadLabelTaxonomy reads a taxonomy from a yaml (or json) file.
+func LoadLabelTaxonomy(path string) (*LabelTaxonomy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	t := &LabelTaxonomy{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(t); err != nil {
+		return nil, fmt.Errorf("error parsing label taxonomy %v: %v", path, err)
+	}
+	return t, nil
+}
+
+// alias returns the label to use for a source's label `l`.
+func (t *LabelTaxonomy) alias(l string) string {
+	if t == nil {
+		return l
+	}
+	if a, ok := t.Aliases[l]; ok {
+		return a
+	}
+	return l
+}
+
+// checkLabels returns the `labels` which exist in the repo, creating the
+// missing ones if the Taxonomy says so.
+func (s *IssueSyncer) checkLabels(ctx context.Context, labels []string) ([]string, error) {
+	t := s.Taxonomy
+	if t == nil {
+		return labels, nil
+	}
+	if t.known == nil {
+		err := s.retry(ctx, "listing labels", func() error {
+			repoLabels, err := s.client(ctx).ListLabels()
+			if err != nil {
+				return err
+			}
+			t.known = sets.NewString()
+			for _, l := range repoLabels {
+				if l.Name != nil {
+					t.known.Insert(*l.Name)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	valid := []string{}
+	for _, l := range labels {
+		if t.known.Has(l) {
+			valid = append(valid, l)
+			continue
+		}
+		if !t.AutoCreate {
+			s.logger().Warningf("Leaving out label %q, which the repo doesn't have", l)
+			continue
+		}
+		spec, ok := t.Labels[l]
+		if !ok {
+			spec = t.Labels[strings.TrimPrefix(l, s.Namespace)]
+		}
+		if spec.Color == "" {
+			spec.Color = defaultLabelColor
+		}
+		if err := s.retry(ctx, fmt.Sprintf("creating label %q", l), func() error {
+			return s.client(ctx).CreateLabel(l, spec.Color, spec.Description)
+		}); err != nil {
+			return nil, err
+		}
+		t.known.Insert(l)
+		valid = append(valid, l)
+	}
+	return valid, nil
+}
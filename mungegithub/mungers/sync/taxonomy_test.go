@@ -0,0 +1,111 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func TestCheckLabels(t *testing.T) {
+	tests := []struct {
+		name       string
+		autoCreate bool
+		labels     []string
+		expected   []string
+		created    []map[string]string
+	}{
+		{
+			name:     "known",
+			labels:   []string{"kind/flake", "sig/node"},
+			expected: []string{"kind/flake", "sig/node"},
+		},
+		{
+			name:     "unknown left out",
+			labels:   []string{"kind/flake", "team/unknown"},
+			expected: []string{"kind/flake"},
+		},
+		{
+			name:       "created",
+			autoCreate: true,
+			labels:     []string{"kind/flake", "team/new", "priority/P2"},
+			expected:   []string{"kind/flake", "team/new", "priority/P2"},
+			created: []map[string]string{
+				{"name": "team/new", "color": "ededed", "description": ""},
+				{"name": "priority/P2", "color": "fbca04", "description": "Soon"},
+			},
+		},
+	}
+	for _, test := range tests {
+		client, server, mux := github_test.InitServer(t, nil, nil, nil, nil, nil, nil)
+		config := &github.Config{Org: "o", Project: "r"}
+		config.SetClient(client)
+		created := []map[string]string{}
+		mux.HandleFunc("/repos/o/r/labels", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				body, _ := ioutil.ReadAll(r.Body)
+				label := map[string]string{}
+				json.Unmarshal(body, &label)
+				created = append(created, label)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("{}"))
+				return
+			}
+			w.Write([]byte(`[{"name": "kind/flake"}, {"name": "sig/node"}]`))
+		})
+
+		s := NewIssueSyncer(config, nil)
+		s.Taxonomy = &LabelTaxonomy{
+			AutoCreate: test.autoCreate,
+			Labels:     map[string]LabelSpec{"priority/P2": {Color: "fbca04", Description: "Soon"}},
+		}
+		got, err := s.checkLabels(context.Background(), test.labels)
+		server.Close()
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, got)
+		}
+		if len(created) != len(test.created) || (len(created) > 0 && !reflect.DeepEqual(created, test.created)) {
+			t.Errorf("%v: expected to create %v, created %v", test.name, test.created, created)
+		}
+	}
+}
+
+func TestLabelAliases(t *testing.T) {
+	s := NewIssueSyncer(nil, nil)
+	s.Namespace = "test-"
+	s.Taxonomy = &LabelTaxonomy{Aliases: map[string]string{"flake": "kind/flake"}}
+	got := s.labels(&testSource{})
+	if !reflect.DeepEqual(got, []string{"test-kind/bug"}) {
+		t.Errorf("unexpected labels %v", got)
+	}
+	s.Taxonomy.Aliases["kind/bug"] = "kind/flake"
+	got = s.labels(&testSource{})
+	if !reflect.DeepEqual(got, []string{"test-kind/flake"}) {
+		t.Errorf("unexpected labels %v", got)
+	}
+}
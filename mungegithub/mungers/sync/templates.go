@@ -0,0 +1,203 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// Templates are the texts the syncer writes, as text/template templates,
+// which makes them the message catalog of a deployment: its language, and
+// who the bot says it is. Some orgs' bot policies require e.g. a footer
+// identifying the bot and saying how to opt out. An empty template means the
+// default. Every template can use .Bot and .HelpURL.
+type Templates struct {
+	// Bot is the name the bot signs with, e.g. "the Acme CI bot", and
+	// HelpURL where people can find out more about it. With either, the
+	// default Footer signs everything the syncer writes.
+	Bot     string `json:"bot"`
+	HelpURL string `json:"helpURL"`
+
+	// Duplicate is the comment on an issue closed as a duplicate. Fields:
+	// .Number is the duplicate, .Of the issue it duplicates.
+	Duplicate string `json:"duplicate"`
+	// Recurrence is the comment added to an issue when its source occurs
+	// again. Fields: .Number, .Body is what the source wants to say, which
+	// must be included.
+	Recurrence string `json:"recurrence"`
+	// StaleClose is the comment on an issue closed at the end of its
+	// lifecycle. Fields: .Number, .Days it has been idle.
+	StaleClose string `json:"staleClose"`
+	// Lock is the comment on an issue we closed before locking it, see
+	// ClosedLocking. Fields: .Number, .Of the issue to go to instead, 0 if
+	// there is none.
+	Lock string `json:"lock"`
+	// Escalation is the comment on an issue which hasn't been triaged,
+	// see EscalationPolicy. Fields: .Number, .Days since it was filed,
+	// .Mentions who should take a look, if anyone, and .Priority the
+	// priority it is raised to, if any.
+	Escalation string `json:"escalation"`
+	// SLOBreach is the comment on an issue which wasn't triaged within
+	// its SLO, see SLOPolicy. Fields: .Number, .Name of the SLO, .Within
+	// how long it allows, .Mentions who should take a look, if anyone.
+	SLOBreach string `json:"sloBreach"`
+	// Footer is appended to every issue and comment the syncer writes.
+	Footer string `json:"footer"`
+}
+
+// DefaultTemplates are used for any template which isn't set.
+var DefaultTemplates = Templates{
+	Duplicate:  "This is a duplicate of #{{.Of}}; closing",
+	Recurrence: "{{.Body}}",
+	StaleClose: "This issue has seen no activity in {{.Days}} business days; closing. Comment here or file a new issue if it's still a problem.",
+	Lock:       "{{if .Of}}Please continue in #{{.Of}}.{{else}}Please file a new issue if this is still a problem.{{end}} Locking this closed issue to keep comments where they are seen.",
+	Escalation: "{{if .Mentions}}{{.Mentions}} {{end}}This issue has not been triaged in {{.Days}} business days{{if .Mentions}}, can you please take a look?{{else}}.{{end}}{{if .Priority}} Raising the priority to `{{.Priority}}`.{{end}}",
+	SLOBreach:  "{{if .Mentions}}{{.Mentions}} {{end}}This {{.Name}} issue was not triaged within {{.Within}}.{{if .Mentions}} Can you please take a look?{{end}}",
+	Footer:     "{{if .Bot}}_Filed by {{.Bot}}.{{if .HelpURL}} See {{.HelpURL}} for help._{{else}}_{{end}}{{else if .HelpURL}}_I am a bot, see {{.HelpURL}} for help._{{end}}",
+}
+
+// LoadTemplates reads templates from a yaml (or json) file and checks that
+// they parse.
+func LoadTemplates(path string) (*Templates, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	t := &Templates{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(t); err != nil {
+		return nil, fmt.Errorf("error parsing templates %v: %v", path, err)
+	}
+	for name, text := range map[string]string{
+		"duplicate":  t.Duplicate,
+		"recurrence": t.Recurrence,
+		"staleClose": t.StaleClose,
+		"lock":       t.Lock,
+		"escalation": t.Escalation,
+		"sloBreach":  t.SLOBreach,
+		"footer":     t.Footer,
+	} {
+		if _, err := template.New(name).Parse(text); err != nil {
+			return nil, fmt.Errorf("error parsing %v template in %v: %v", name, path, err)
+		}
+	}
+	return t, nil
+}
+
+// templateData is what templates can refer to.
+type templateData struct {
+	Number   int
+	Of       int
+	Body     string
+	Days     int
+	Mentions string
+	Priority string
+	Name     string
+	Within   string
+
+	Bot     string
+	HelpURL string
+}
+
+// render executes the template `text`, falling back to `fallback` if it is
+// empty or fails.
+func (s *IssueSyncer) render(name, text, fallback string, data templateData) string {
+	t := s.templates()
+	data.Bot, data.HelpURL = t.Bot, t.HelpURL
+	if text == "" {
+		text = fallback
+	}
+	out, err := execute(name, text, data)
+	if err != nil && text != fallback {
+		s.logger().Errorf("Unable to render the %v template, using the default: %v", name, err)
+		out, err = execute(name, fallback, data)
+	}
+	if err != nil {
+		// The defaults are ours; this is a programmer error.
+		panic(err)
+	}
+	return out
+}
+
+func execute(name, text string, data templateData) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (s *IssueSyncer) templates() Templates {
+	if s.Templates == nil {
+		return DefaultTemplates
+	}
+	return *s.Templates
+}
+
+func (s *IssueSyncer) duplicateText(number, of int) string {
+	return s.render("duplicate", s.templates().Duplicate, DefaultTemplates.Duplicate, templateData{Number: number, Of: of})
+}
+
+func (s *IssueSyncer) recurrenceText(number int, body string) string {
+	out := s.render("recurrence", s.templates().Recurrence, DefaultTemplates.Recurrence, templateData{Number: number, Body: body})
+	if !strings.Contains(out, body) {
+		// Without the body we can't tell that the source was recorded.
+		s.logger().Errorf("The recurrence template must include {{.Body}}, using the default")
+		return body
+	}
+	return out
+}
+
+func (s *IssueSyncer) staleCloseText(number, days int) string {
+	return s.render("staleClose", s.templates().StaleClose, DefaultTemplates.StaleClose, templateData{Number: number, Days: days})
+}
+
+func (s *IssueSyncer) lockText(number, of int) string {
+	return s.render("lock", s.templates().Lock, DefaultTemplates.Lock, templateData{Number: number, Of: of})
+}
+
+func (s *IssueSyncer) escalationText(number, days int, mentions []string, priority string) string {
+	return s.render("escalation", s.templates().Escalation, DefaultTemplates.Escalation, templateData{
+		Number:   number,
+		Days:     days,
+		Mentions: strings.Join(mentions, " "),
+		Priority: priority,
+	})
+}
+
+func (s *IssueSyncer) sloBreachText(number int, slo *SLO) string {
+	return s.render("sloBreach", s.templates().SLOBreach, DefaultTemplates.SLOBreach, templateData{
+		Number:   number,
+		Name:     slo.name(),
+		Within:   slo.Within,
+		Mentions: slo.Ping,
+	})
+}
+
+func (s *IssueSyncer) footer() string {
+	return s.render("footer", s.templates().Footer, DefaultTemplates.Footer, templateData{})
+}
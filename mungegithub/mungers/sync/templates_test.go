@@ -0,0 +1,119 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestTemplates(t *testing.T) {
+	s := NewIssueSyncer(nil, nil)
+	if got, expected := s.text(s.duplicateText(2, 1)), "This is a duplicate of #1; closing"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	s.Namespace = "test-"
+	s.Templates = &Templates{
+		Duplicate:  "Closing #{{.Number}} in favor of #{{.Of}}.",
+		Recurrence: "Seen again:\n{{.Body}}",
+		Footer:     "_I am a bot. To opt out, add the `no-bot` label._",
+	}
+	tests := []struct {
+		got, expected string
+	}{
+		{
+			got:      s.text(s.duplicateText(2, 1)),
+			expected: "[test-]\n\nClosing #2 in favor of #1.\n\n_I am a bot. To opt out, add the `no-bot` label._",
+		},
+		{
+			got:      s.recurrenceText(1, "gs://job/1"),
+			expected: "Seen again:\ngs://job/1",
+		},
+		{
+			// Default.
+			got:      s.staleCloseText(1, 3),
+			expected: "This issue has seen no activity in 3 business days; closing. Comment here or file a new issue if it's still a problem.",
+		},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, test.got)
+		}
+	}
+
+	s.Templates = &Templates{
+		Duplicate:  "{{.Missing}}",
+		Recurrence: "No body",
+	}
+	if got, expected := s.duplicateText(2, 1), "This is a duplicate of #1; closing"; got != expected {
+		t.Errorf("broken template: expected %q, got %q", expected, got)
+	}
+	if got, expected := s.recurrenceText(1, "gs://job/1"), "gs://job/1"; got != expected {
+		t.Errorf("template without body: expected %q, got %q", expected, got)
+	}
+}
+
+func TestTemplatesPersona(t *testing.T) {
+	s := NewIssueSyncer(nil, nil)
+	s.Templates = &Templates{
+		Bot:        "the Acme CI bot",
+		HelpURL:    "https://acme.example/ci-bot",
+		Escalation: "{{.Mentions}} : ce ticket n'a pas été trié depuis {{.Days}} jours ouvrés.",
+	}
+	tests := []struct {
+		got, expected string
+	}{
+		{
+			got:      s.text(s.duplicateText(2, 1)),
+			expected: "This is a duplicate of #1; closing\n\n_Filed by the Acme CI bot. See https://acme.example/ci-bot for help._",
+		},
+		{
+			got:      s.escalationText(1, 3, []string{"@acme/infra"}, ""),
+			expected: "@acme/infra : ce ticket n'a pas été trié depuis 3 jours ouvrés.",
+		},
+		{
+			// Default.
+			got:      s.sloBreachText(1, &SLO{Priority: "priority/P0", Within: "4h", Ping: "@oncall"}),
+			expected: "@oncall This P0 issue was not triaged within 4h. Can you please take a look?",
+		},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, test.got)
+		}
+	}
+
+	s.Templates = &Templates{HelpURL: "https://acme.example/ci-bot"}
+	if got, expected := s.footer(), "_I am a bot, see https://acme.example/ci-bot for help._"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	file, err := ioutil.TempFile("", "templates")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("duplicate: \"{{.Of\"\n")
+	file.Close()
+	if _, err := LoadTemplates(file.Name()); err == nil {
+		t.Errorf("expected an error for an unparsable template")
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// Syncer syncs sources in batches, like IssueSyncer and MultiSyncer.
+type Syncer interface {
+	SyncAll(ctx context.Context, sources []IssueSource) ([]SyncResult, error)
+	// Synced returns true once the source with `id` was synced.
+	Synced(id string) bool
+}
+
+// TenantSource is an IssueSource which belongs to one of the tenants of a
+// MultiSyncer.
+type TenantSource interface {
+	IssueSource
+	Tenant() string
+}
+
+// TenantConfig is how a tenant is configured, see LoadTenants.
+type TenantConfig struct {
+	// Org and Project are the repo the tenant's issues are filed in.
+	Org     string `json:"org"`
+	Project string `json:"project"`
+	// Namespace and Labels, which are added to every new issue, keep the
+	// tenant's issues apart from those of other tenants in the same repo.
+	Namespace string   `json:"namespace"`
+	Labels    []string `json:"labels"`
+	// Templates, Escalation and Metadata are paths, see LoadTemplates,
+	// LoadEscalationPolicy and NewMetadataStore.
+	Templates          string `json:"templates"`
+	Escalation         string `json:"escalation"`
+	Metadata           string `json:"metadata"`
+	MaxCreatesPerCycle int    `json:"maxCreatesPerCycle"`
+	MaxOpenIssues      int    `json:"maxOpenIssues"`
+}
+
+// Tenant is a team with its own syncer.
+type Tenant struct {
+	Syncer *IssueSyncer
+	// Escalation, if set, is the tenant's escalation policy.
+	Escalation *EscalationPolicy
+}
+
+// MultiSyncer serves several tenants from one process, handing each source
+// to the syncer of its tenant.
+type MultiSyncer struct {
+	// Default is the tenant of sources which don't name one.
+	Default string
+
+	tenants map[string]*Tenant
+}
+
+// NewMultiSyncer constructs a MultiSyncer without tenants.
+func NewMultiSyncer() *MultiSyncer {
+	return &MultiSyncer{tenants: map[string]*Tenant{}}
+}
+
+// AddTenant adds (or replaces) tenant `name`.
+func (m *MultiSyncer) AddTenant(name string, t *Tenant) {
+	m.tenants[name] = t
+}
+
+// Tenant returns tenant `name`, or nil.
+func (m *MultiSyncer) Tenant(name string) *Tenant {
+	return m.tenants[name]
+}
+
+// LoadTenants reads the tenants from a yaml (or json) file mapping tenant
+// names to TenantConfigs, e.g.:
+//
+//	node:
+//	  org: kubernetes
+//	  project: kubernetes
+//	  labels: [sig/node]
+//	  maxCreatesPerCycle: 10
+//	  escalation: node-escalation.yaml
+//
+// Issues are looked up with github search, see SearchFinder. Every tenant's
+// syncer is built with `opts` as well, after the tenant's own settings, and
+// logs tagged with the tenant's name.
+func LoadTenants(path string, config *github.Config, opts ...Option) (*MultiSyncer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	configs := map[string]TenantConfig{}
+	if err := yaml.NewYAMLToJSONDecoder(file).Decode(&configs); err != nil {
+		return nil, fmt.Errorf("error parsing tenants %v: %v", path, err)
+	}
+
+	m := NewMultiSyncer()
+	for name, c := range configs {
+		if c.Org == "" || c.Project == "" {
+			return nil, fmt.Errorf("tenant %q in %v needs an org and a project", name, path)
+		}
+		tenantOpts := []Option{
+			WithRepo(config.ForRepo(c.Org, c.Project)),
+			WithNamespace(c.Namespace),
+			WithLabels(c.Labels...),
+			WithCreationBudget(c.MaxCreatesPerCycle, c.MaxOpenIssues),
+			WithTemplatesFile(c.Templates),
+			WithPersistence(c.Metadata),
+		}
+		tenantOpts = append(tenantOpts, opts...)
+		s, err := New(append(tenantOpts, withTenant(name))...)
+		if err != nil {
+			return nil, err
+		}
+		t := &Tenant{Syncer: s}
+		if c.Escalation != "" {
+			if t.Escalation, err = LoadEscalationPolicy(c.Escalation); err != nil {
+				return nil, err
+			}
+		}
+		m.AddTenant(name, t)
+	}
+	return m, nil
+}
+
+// withTenant tags what the syncer logs with tenant `name`.
+func withTenant(name string) Option {
+	return func(o *options) error {
+		o.syncer.Logger = o.syncer.Logger.With("tenant", name)
+		return nil
+	}
+}
+
+// Tenants returns the tenants' names, sorted.
+func (m *MultiSyncer) Tenants() []string {
+	names := []string{}
+	for name := range m.tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SyncAll hands every source to the syncer of its tenant. Sources of
+// unknown tenants aren't synced, their results have a *ValidationError.
+func (m *MultiSyncer) SyncAll(ctx context.Context, sources []IssueSource) ([]SyncResult, error) {
+	byTenant := map[string][]IssueSource{}
+	results := []SyncResult{}
+	for _, s := range sources {
+		name := m.Default
+		if ts, ok := s.(TenantSource); ok && ts.Tenant() != "" {
+			name = ts.Tenant()
+		}
+		if _, ok := m.tenants[name]; !ok {
+			id := s.ID()
+			if isSensitive(s) {
+				id = redact(id)
+			}
+			results = append(results, SyncResult{
+				Source: id,
+				Err:    &ValidationError{Source: id, Reason: fmt.Sprintf("unknown tenant %q", name)},
+			})
+			continue
+		}
+		byTenant[name] = append(byTenant[name], s)
+	}
+	unknown := len(results)
+
+	failed := []string{}
+	for _, name := range m.Tenants() {
+		if len(byTenant[name]) == 0 {
+			continue
+		}
+		t := m.tenants[name]
+		synced, err := t.Syncer.SyncAll(ctx, byTenant[name])
+		results = append(results, synced...)
+		if err != nil {
+			t.Syncer.logger().With("tenant", name).Errorf("Unable to sync all sources: %v", err)
+			failed = append(failed, name)
+		}
+	}
+	if unknown > 0 {
+		return results, fmt.Errorf("%d sources have an unknown tenant", unknown)
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("unable to sync all sources of tenants %v", failed)
+	}
+	return results, nil
+}
+
+// Synced implements Syncer.
+func (m *MultiSyncer) Synced(id string) bool {
+	for _, t := range m.tenants {
+		if t.Syncer.Synced(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Escalate escalates the issues of every tenant with an escalation policy.
+func (m *MultiSyncer) Escalate(ctx context.Context) error {
+	for _, name := range m.Tenants() {
+		t := m.tenants[name]
+		if t.Escalation == nil {
+			continue
+		}
+		if err := t.Syncer.Escalate(ctx, t.Escalation); err != nil {
+			return fmt.Errorf("error escalating issues of tenant %q: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// recordingFinder records the keys looked up, and fails to find them.
+type recordingFinder struct {
+	keys []string
+}
+
+func (r *recordingFinder) AllIssuesForKey(key string) []int { return nil }
+func (r *recordingFinder) Created(key string, number int)   {}
+func (r *recordingFinder) IssuesForKey(key string) ([]int, error) {
+	r.keys = append(r.keys, key)
+	return nil, fmt.Errorf("not looking")
+}
+
+func TestMultiSyncerRouting(t *testing.T) {
+	node, infra := &recordingFinder{}, &recordingFinder{}
+	m := NewMultiSyncer()
+	m.Default = "infra"
+	m.AddTenant("node", &Tenant{Syncer: NewIssueSyncer(nil, node)})
+	m.AddTenant("infra", &Tenant{Syncer: NewIssueSyncer(nil, infra)})
+
+	results, err := m.SyncAll(context.Background(), []IssueSource{
+		&JSONSource{Key: "a", Ref: "1", Team: "node"},
+		&JSONSource{Key: "b", Ref: "2"},
+		&JSONSource{Key: "c", Ref: "3", Team: "storage"},
+		&testSource{title: "d", id: "4"},
+	})
+	if err == nil {
+		t.Errorf("expected an error for the unknown tenant")
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected a result for every source, got %+v", results)
+	}
+	if r := results[0]; r.Source != "3" || ErrorKind(r.Err) != ErrValidation {
+		t.Errorf("expected the unknown tenant's source to be invalid, got %+v", r)
+	}
+	if !reflect.DeepEqual(node.keys, []string{"a"}) {
+		t.Errorf("unexpected keys for node: %v", node.keys)
+	}
+	if !reflect.DeepEqual(infra.keys, []string{"b", "d"}) {
+		t.Errorf("unexpected keys for infra: %v", infra.keys)
+	}
+}
+
+func TestLoadTenants(t *testing.T) {
+	file, err := ioutil.TempFile("", "tenants")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`
+node:
+  org: kubernetes
+  project: kubernetes
+  namespace: "node-"
+  labels: [sig/node]
+  maxCreatesPerCycle: 10
+infra:
+  org: kubernetes
+  project: test-infra
+`)
+	file.Close()
+
+	m, err := LoadTenants(file.Name(), &github.Config{}, WithMuteLabel("shush"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(m.Tenants(), []string{"infra", "node"}) {
+		t.Errorf("unexpected tenants %v", m.Tenants())
+	}
+	s := m.Tenant("node").Syncer
+	if s.config.Project != "kubernetes" || s.MaxCreatesPerCycle != 10 {
+		t.Errorf("unexpected node syncer: %+v", s)
+	}
+	if labels := s.labels(&testSource{}); !reflect.DeepEqual(labels, []string{"node-kind/bug", "node-sig/node"}) {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	if m.Tenant("infra").Syncer.config.Project != "test-infra" {
+		t.Errorf("unexpected infra repo")
+	}
+	for _, name := range m.Tenants() {
+		if label := m.Tenant(name).Syncer.MuteLabel; label != "shush" {
+			t.Errorf("expected %v to get the shared options, got mute label %q", name, label)
+		}
+	}
+}
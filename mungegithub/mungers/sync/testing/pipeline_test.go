@@ -0,0 +1,143 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/mungers/sync"
+)
+
+func TestStages(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		stages []sync.Stage
+		// existing is an open issue about the source, if set.
+		existing bool
+		failed   bool
+		open     int
+		labels   []string
+	}{
+		{
+			name:   "no stages",
+			open:   1,
+			labels: []string{"kind/flake"},
+		},
+		{
+			name:   "skipped title",
+			stages: []sync.Stage{sync.SkipTitles(regexp.MustCompile("^Test"))},
+		},
+		{
+			name:   "other title",
+			stages: []sync.Stage{sync.SkipTitles(regexp.MustCompile("^Bar"))},
+			open:   1,
+			labels: []string{"kind/flake"},
+		},
+		{
+			name: "added labels",
+			stages: []sync.Stage{sync.AddLabels(func(source sync.IssueSource) []string {
+				return []string{"sig/node"}
+			})},
+			open:   1,
+			labels: []string{"kind/flake", "sig/node"},
+		},
+		{
+			name: "vetoed creation",
+			stages: []sync.Stage{sync.VetoCreation(func(st *sync.SyncState) string {
+				return "not today"
+			})},
+		},
+		{
+			name: "veto only applies to creation",
+			stages: []sync.Stage{sync.VetoCreation(func(st *sync.SyncState) string {
+				return "not today"
+			})},
+			existing: true,
+			open:     1,
+		},
+		{
+			name: "failing stage",
+			stages: []sync.Stage{{
+				Name:   "broken",
+				Before: sync.PhaseDecide,
+				Run: func(ctx context.Context, st *sync.SyncState) error {
+					return fmt.Errorf("broken")
+				},
+			}},
+			failed: true,
+		},
+	}
+	for _, test := range tests {
+		tracker := NewTracker()
+		if test.existing {
+			tracker.AddIssue("TestFoo", "failed")
+		}
+		s := newSyncer(tracker)
+		s.Stages = test.stages
+		source := &sync.JSONSource{Key: "TestFoo", Ref: "run-1", Details: "failed", Tags: []string{"kind/flake"}}
+		if err := s.Sync(ctx, source); (err != nil) != test.failed {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		}
+		open := tracker.OpenIssues("TestFoo")
+		if len(open) != test.open {
+			t.Errorf("%v: expected %v open issues, got %v", test.name, test.open, open)
+		}
+		for _, issue := range tracker.Issues() {
+			if test.existing || test.labels == nil {
+				break
+			}
+			labels := []string{}
+			for _, l := range issue.Labels {
+				labels = append(labels, *l.Name)
+			}
+			if !reflect.DeepEqual(labels, test.labels) {
+				t.Errorf("%v: expected labels %v, got %v", test.name, test.labels, labels)
+			}
+		}
+		tracker.Close()
+	}
+}
+
+func TestStageSeesDecision(t *testing.T) {
+	tracker := NewTracker()
+	defer tracker.Close()
+	tracker.AddDuplicates("TestFoo", "failed", 2)
+	s := newSyncer(tracker)
+	var st *sync.SyncState
+	s.Stages = []sync.Stage{{
+		Name:   "record",
+		Before: sync.PhaseMutate,
+		Run: func(ctx context.Context, state *sync.SyncState) error {
+			st = state
+			return nil
+		},
+	}}
+	if err := s.Sync(context.Background(), &sync.JSONSource{Key: "TestFoo", Ref: "run-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st == nil {
+		t.Fatalf("stage didn't run")
+	}
+	if st.Decision != sync.DecisionUpdate || len(st.Open) != 1 || st.Issue != st.Open[0] {
+		t.Errorf("expected to update the oldest of the dups, got %v about %v", st.Decision, st.Open)
+	}
+}
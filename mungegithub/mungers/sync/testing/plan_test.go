@@ -0,0 +1,83 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/mungers/sync"
+)
+
+func TestPlan(t *testing.T) {
+	tracker := NewTracker()
+	defer tracker.Close()
+	dups := tracker.AddDuplicates("TestFoo", "failed", 3)
+	recorded := tracker.AddIssue("TestBaz", "failed in run-0")
+	s := newSyncer(tracker)
+	s.Stages = []sync.Stage{sync.SkipTitles(regexp.MustCompile("Skipped"))}
+	sources := []sync.IssueSource{
+		&sync.JSONSource{Key: "TestFoo", Ref: "run-1"},
+		&sync.JSONSource{Key: "TestBar", Ref: "run-1", Tags: []string{"kind/flake"}},
+		&sync.JSONSource{Key: "TestBar", Ref: "run-2"},
+		&sync.JSONSource{Key: "TestBaz", Ref: "run-0"},
+		&sync.JSONSource{Key: "TestSkipped", Ref: "run-1"},
+	}
+	before := tracker.Issues()
+
+	plan, err := s.Plan(context.Background(), sources)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if after := tracker.Issues(); !reflect.DeepEqual(before, after) {
+		t.Errorf("planning changed issues: %v", after)
+	}
+	for _, n := range append(dups, recorded) {
+		if comments := tracker.Comments(n); len(comments) != 0 {
+			t.Errorf("planning commented on #%d: %q", n, comments)
+		}
+	}
+	if s.Synced("run-1") {
+		t.Errorf("planning marked sources synced")
+	}
+
+	expected := []sync.PlannedAction{
+		{Title: "TestFoo", ID: "run-1", Decision: sync.DecisionUpdate, Issue: dups[0], Dups: dups[1:]},
+		{Title: "TestBar", ID: "run-1", Decision: sync.DecisionCreate, Labels: []string{"kind/flake"}},
+		{Title: "TestBar", ID: "run-2", Decision: sync.DecisionUpdate},
+		{Title: "TestBaz", ID: "run-0", Decision: sync.DecisionNone, Issue: recorded},
+		{Title: "TestSkipped", ID: "run-1", Skipped: "title matches Skipped"},
+	}
+	if !reflect.DeepEqual(plan.Actions, expected) {
+		t.Errorf("expected actions %+v, got %+v", expected, plan.Actions)
+	}
+	report := plan.String()
+	for _, line := range []string{
+		"- #2: close as a duplicate of #1",
+		"~ #1 \"TestFoo\" (run-1): update",
+		"+ \"TestBar\" (run-1) [kind/flake]",
+		"~ new issue \"TestBar\" (run-2): update",
+		"1 to create, 2 to update, 2 to close as duplicates",
+	} {
+		if !strings.Contains(report, line) {
+			t.Errorf("expected %q in the report:\n%v", line, report)
+		}
+	}
+}
@@ -0,0 +1,756 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testing provides a fake github repo, for testing IssueSources (and
+// anything else built on the syncer) without talking to github.
+package testing
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	githubapi "github.com/google/go-github/github"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+const (
+	// Org and Project are the repo the Tracker serves.
+	Org     = "o"
+	Project = "r"
+)
+
+var (
+	issuePath        = regexp.MustCompile(`^/repos/o/r/issues/(\d+)$`)
+	commentsPath     = regexp.MustCompile(`^/repos/o/r/issues/(\d+)/comments$`)
+	commentPath      = regexp.MustCompile(`^/repos/o/r/issues/comments/(\d+)$`)
+	issueLabelsPath  = regexp.MustCompile(`^/repos/o/r/issues/(\d+)/labels$`)
+	issueLabelPath   = regexp.MustCompile(`^/repos/o/r/issues/(\d+)/labels/(.+)$`)
+	reactionsPath    = regexp.MustCompile(`^/repos/o/r/issues/(\d+)/reactions$`)
+	lockPath         = regexp.MustCompile(`^/repos/o/r/issues/(\d+)/lock$`)
+	searchQueryTerms = regexp.MustCompile(`(\w+:)?("[^"]*"|\S+)`)
+)
+
+// failure is a scenario in which github fails requests, see FailNext.
+type failure struct {
+	method, path string
+	status       int
+	times        int
+}
+
+// Tracker is an in-memory github repo (Org/Project) with issues, comments
+// and labels, served over http the way github's API serves them, so that the
+// syncer can be pointed at it with Config. Scenario helpers set up what a
+// source integration has to cope with: issues filed before (AddIssue,
+// AddDuplicates), rate limiting (RateLimit) and failing calls (FailNext).
+// Requests the Tracker doesn't know are answered with 404.
+type Tracker struct {
+	// Login is who issues and comments made through the API are by.
+	Login string
+	// Now is the Tracker's clock.
+	Now func() time.Time
+
+	server *httptest.Server
+
+	lock      sync.Mutex
+	issues    map[int]*githubapi.Issue
+	comments  map[int][]githubapi.IssueComment
+	labels    map[string]githubapi.Label
+	reactions map[int][]string
+	locked    map[int]string
+	commits   []githubapi.RepositoryCommit
+	paths     map[string]string
+	lastID    int
+	lastTime  time.Time
+	limited   int
+	failures  []*failure
+	requests  map[string]int
+}
+
+// NewTracker starts a Tracker. Close it when done.
+func NewTracker() *Tracker {
+	t := &Tracker{
+		Login:     "bot",
+		Now:       time.Now,
+		issues:    map[int]*githubapi.Issue{},
+		comments:  map[int][]githubapi.IssueComment{},
+		labels:    map[string]githubapi.Label{},
+		reactions: map[int][]string{},
+		locked:    map[int]string{},
+		paths:     map[string]string{},
+		requests:  map[string]int{},
+	}
+	t.server = httptest.NewServer(t)
+	return t
+}
+
+// Close stops the Tracker's server.
+func (t *Tracker) Close() {
+	t.server.Close()
+}
+
+// Config returns a config for talking to the Tracker.
+func (t *Tracker) Config() *github.Config {
+	client := githubapi.NewClient(nil)
+	u, _ := url.Parse(t.server.URL + "/")
+	client.BaseURL = u
+	client.UploadURL = u
+	config := &github.Config{Org: Org, Project: Project, MaxPRNumber: math.MaxInt32}
+	config.SetClient(client)
+	return config
+}
+
+// tick returns the time of a change. Every change gets a later time than
+// the one before, since the syncer relies on updated_at moving.
+func (t *Tracker) tick() time.Time {
+	now := t.Now()
+	if !now.After(t.lastTime) {
+		now = t.lastTime.Add(time.Second)
+	}
+	t.lastTime = now
+	return now
+}
+
+func (t *Tracker) nextID() int {
+	t.lastID++
+	return t.lastID
+}
+
+// AddIssue files an issue, as if someone had before the test, and returns
+// its number.
+func (t *Tracker) AddIssue(title, body string, labels ...string) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return *t.create(title, body, labels).Number
+}
+
+// AddDuplicates files `n` open issues with the same title, e.g. left over
+// from a syncer which crashed, and returns their numbers.
+func (t *Tracker) AddDuplicates(title, body string, n int, labels ...string) []int {
+	numbers := []int{}
+	for i := 0; i < n; i++ {
+		numbers = append(numbers, t.AddIssue(title, body, labels...))
+	}
+	return numbers
+}
+
+// AddComment comments on issue `number`, as `login`.
+func (t *Tracker) AddComment(number int, login, body string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.comment(number, login, body)
+}
+
+// EditComment replaces the body of the `i`th comment on issue `number`, as
+// if a human had, e.g. to check a box of a task list.
+func (t *Tracker) EditComment(number, i int, body string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if comments := t.comments[number]; i < len(comments) {
+		t.editComment(&comments[i], body)
+	}
+}
+
+// EditBody replaces the body of issue `number`, as if a human had.
+func (t *Tracker) EditBody(number int, body string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if issue, ok := t.issues[number]; ok {
+		issue.Body = &body
+		now := t.tick()
+		issue.UpdatedAt = &now
+	}
+}
+
+// AddLabel labels issue `number`, as if a human had.
+func (t *Tracker) AddLabel(number int, label string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if issue, ok := t.issues[number]; ok {
+		t.addLabels(issue, []string{label})
+	}
+}
+
+// RemoveLabel removes `label` from issue `number`, as if a human had.
+func (t *Tracker) RemoveLabel(number int, label string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if issue, ok := t.issues[number]; ok {
+		t.removeLabel(issue, label)
+	}
+}
+
+// CloseIssue closes issue `number`, as if a human had.
+func (t *Tracker) CloseIssue(number int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if issue, ok := t.issues[number]; ok {
+		t.setState(issue, "closed")
+	}
+}
+
+// RateLimit has github refuse the next `requests` requests because the
+// rate limit is exhausted, as it does with a 403 and no remaining calls.
+func (t *Tracker) RateLimit(requests int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.limited = requests
+}
+
+// FailNext has github fail the next `times` requests with `method` for
+// `path` (e.g. "POST", "/repos/o/r/issues" for creating issues) with
+// `status`.
+func (t *Tracker) FailNext(method, path string, status, times int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.failures = append(t.failures, &failure{method: method, path: path, status: status, times: times})
+}
+
+// AddMerge merges pull request `pr` titled `title` into master at `at`, the
+// way github does with a merge commit, and returns the commit's SHA.
+func (t *Tracker) AddMerge(pr int, title string, at time.Time) string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	sha := fmt.Sprintf("%040x", t.nextID())
+	message := fmt.Sprintf("Merge pull request #%d from someone/branch\n\n%v", pr, title)
+	parent, other := fmt.Sprintf("%040x", 0), fmt.Sprintf("%040x", pr)
+	if len(t.commits) > 0 {
+		parent = *t.commits[len(t.commits)-1].SHA
+	}
+	t.commits = append(t.commits, githubapi.RepositoryCommit{
+		SHA: &sha,
+		Commit: &githubapi.Commit{
+			Message:   &message,
+			Committer: &githubapi.CommitAuthor{Date: &at},
+		},
+		Parents: []githubapi.Commit{{SHA: &parent}, {SHA: &other}},
+	})
+	return sha
+}
+
+// AddCommit commits a change to `path` by `author` to master at `at`, and
+// returns the commit's SHA.
+func (t *Tracker) AddCommit(path, author string, at time.Time) string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	sha := fmt.Sprintf("%040x", t.nextID())
+	message := "Change " + path
+	parent := fmt.Sprintf("%040x", 0)
+	if len(t.commits) > 0 {
+		parent = *t.commits[len(t.commits)-1].SHA
+	}
+	t.commits = append(t.commits, githubapi.RepositoryCommit{
+		SHA: &sha,
+		Commit: &githubapi.Commit{
+			Message:   &message,
+			Committer: &githubapi.CommitAuthor{Date: &at},
+		},
+		Author:  &githubapi.User{Login: &author},
+		Parents: []githubapi.Commit{{SHA: &parent}},
+	})
+	t.paths[sha] = path
+	return sha
+}
+
+// Issues returns copies of all issues, by number.
+func (t *Tracker) Issues() []githubapi.Issue {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	numbers := []int{}
+	for n := range t.issues {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	out := []githubapi.Issue{}
+	for _, n := range numbers {
+		out = append(out, *t.issues[n])
+	}
+	return out
+}
+
+// OpenIssues returns the numbers of all open issues titled `title`.
+func (t *Tracker) OpenIssues(title string) []int {
+	numbers := []int{}
+	for _, issue := range t.Issues() {
+		if *issue.Title == title && *issue.State == "open" {
+			numbers = append(numbers, *issue.Number)
+		}
+	}
+	return numbers
+}
+
+// Comments returns the bodies of the comments on issue `number`.
+func (t *Tracker) Comments(number int) []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	bodies := []string{}
+	for _, c := range t.comments[number] {
+		bodies = append(bodies, *c.Body)
+	}
+	return bodies
+}
+
+// Reactions returns the reactions to issue `number`.
+func (t *Tracker) Reactions(number int) []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]string{}, t.reactions[number]...)
+}
+
+// Locked returns whether issue `number` is locked, and why.
+func (t *Tracker) Locked(number int) (bool, string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	reason, ok := t.locked[number]
+	return ok, reason
+}
+
+// Requests returns how many requests were made with `method` for `path`,
+// including the ones which failed.
+func (t *Tracker) Requests(method, path string) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.requests[method+" "+path]
+}
+
+func (t *Tracker) create(title, body string, labels []string) *githubapi.Issue {
+	number := t.nextID()
+	now := t.tick()
+	state := "open"
+	issue := &githubapi.Issue{
+		Number:    &number,
+		Title:     &title,
+		Body:      &body,
+		State:     &state,
+		User:      &githubapi.User{Login: &t.Login},
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+	t.issues[number] = issue
+	t.addLabels(issue, labels)
+	return issue
+}
+
+func (t *Tracker) comment(number int, login, body string) *githubapi.IssueComment {
+	issue, ok := t.issues[number]
+	if !ok {
+		return nil
+	}
+	id := t.nextID()
+	now := t.tick()
+	issueURL := fmt.Sprintf("%v/repos/%v/%v/issues/%d", t.server.URL, Org, Project, number)
+	c := githubapi.IssueComment{
+		ID:        &id,
+		IssueURL:  &issueURL,
+		Body:      &body,
+		User:      &githubapi.User{Login: &login},
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+	t.comments[number] = append(t.comments[number], c)
+	issue.UpdatedAt = &now
+	return &c
+}
+
+func (t *Tracker) editComment(c *githubapi.IssueComment, body string) {
+	now := t.tick()
+	c.Body = &body
+	c.UpdatedAt = &now
+}
+
+func (t *Tracker) setState(issue *githubapi.Issue, state string) {
+	now := t.tick()
+	issue.State = &state
+	issue.UpdatedAt = &now
+	if state == "closed" {
+		issue.ClosedAt = &now
+	} else {
+		issue.ClosedAt = nil
+	}
+}
+
+func (t *Tracker) addLabels(issue *githubapi.Issue, labels []string) {
+	for _, l := range labels {
+		if hasLabel(issue, l) {
+			continue
+		}
+		if _, ok := t.labels[l]; !ok {
+			name := l
+			t.labels[l] = githubapi.Label{Name: &name}
+		}
+		label := t.labels[l]
+		issue.Labels = append(issue.Labels, label)
+	}
+	now := t.tick()
+	issue.UpdatedAt = &now
+}
+
+func (t *Tracker) removeLabel(issue *githubapi.Issue, label string) {
+	labels := []githubapi.Label{}
+	for _, l := range issue.Labels {
+		if *l.Name != label {
+			labels = append(labels, l)
+		}
+	}
+	issue.Labels = labels
+	now := t.tick()
+	issue.UpdatedAt = &now
+}
+
+func hasLabel(issue *githubapi.Issue, label string) bool {
+	for _, l := range issue.Labels {
+		if l.Name != nil && *l.Name == label {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP answers a github API request.
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.requests[r.Method+" "+r.URL.Path]++
+
+	w.Header().Set("Content-Type", "application/json")
+	if t.limited > 0 {
+		t.limited--
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(t.Now().Unix(), 10))
+		t.error(w, http.StatusForbidden, "API rate limit exceeded")
+		return
+	}
+	for _, f := range t.failures {
+		if f.times > 0 && f.method == r.Method && f.path == r.URL.Path {
+			f.times--
+			t.error(w, f.status, "failure injected by the test")
+			return
+		}
+	}
+	w.Header().Set("X-RateLimit-Remaining", "5000")
+
+	path := r.URL.Path
+	switch {
+	case path == "/repos/o/r/issues" && r.Method == "GET":
+		t.listIssues(w, r)
+	case path == "/repos/o/r/issues" && r.Method == "POST":
+		req := githubapi.IssueRequest{}
+		if !t.decode(w, r, &req) || req.Title == nil {
+			return
+		}
+		body, labels := "", []string{}
+		if req.Body != nil {
+			body = *req.Body
+		}
+		if req.Labels != nil {
+			labels = *req.Labels
+		}
+		t.reply(w, http.StatusCreated, t.create(*req.Title, body, labels))
+	case path == "/repos/o/r/issues/comments" && r.Method == "GET":
+		t.listComments(w, r)
+	case path == "/repos/o/r/commits" && r.Method == "GET":
+		t.listCommits(w, r)
+	case path == "/search/issues" && r.Method == "GET":
+		t.search(w, r)
+	case path == "/repos/o/r/labels" && r.Method == "GET":
+		labels := []githubapi.Label{}
+		for _, l := range t.labels {
+			labels = append(labels, l)
+		}
+		t.reply(w, http.StatusOK, labels)
+	case path == "/repos/o/r/labels" && r.Method == "POST":
+		label := githubapi.Label{}
+		if !t.decode(w, r, &label) || label.Name == nil {
+			return
+		}
+		t.labels[*label.Name] = label
+		t.reply(w, http.StatusCreated, label)
+	case commentPath.MatchString(path) && r.Method == "PATCH":
+		id, _ := strconv.Atoi(commentPath.FindStringSubmatch(path)[1])
+		req := githubapi.IssueComment{}
+		if !t.decode(w, r, &req) || req.Body == nil {
+			return
+		}
+		for _, comments := range t.comments {
+			for i := range comments {
+				if c := &comments[i]; *c.ID == id {
+					t.editComment(c, *req.Body)
+					t.reply(w, http.StatusOK, c)
+					return
+				}
+			}
+		}
+		t.error(w, http.StatusNotFound, "Not Found")
+	case commentPath.MatchString(path) && r.Method == "DELETE":
+		id, _ := strconv.Atoi(commentPath.FindStringSubmatch(path)[1])
+		for n, comments := range t.comments {
+			for i, c := range comments {
+				if *c.ID == id {
+					t.comments[n] = append(comments[:i:i], comments[i+1:]...)
+					break
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		t.serveIssue(w, r)
+	}
+}
+
+// serveIssue answers requests about a single issue.
+func (t *Tracker) serveIssue(w http.ResponseWriter, r *http.Request) {
+	var m []string
+	for _, re := range []*regexp.Regexp{issuePath, commentsPath, issueLabelsPath, issueLabelPath, reactionsPath, lockPath} {
+		if m = re.FindStringSubmatch(r.URL.Path); m != nil {
+			break
+		}
+	}
+	if m == nil {
+		t.error(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	number, _ := strconv.Atoi(m[1])
+	issue, ok := t.issues[number]
+	if !ok {
+		t.error(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	switch {
+	case issuePath.MatchString(r.URL.Path) && r.Method == "GET":
+		t.reply(w, http.StatusOK, issue)
+	case issuePath.MatchString(r.URL.Path) && r.Method == "PATCH":
+		req := githubapi.IssueRequest{}
+		if !t.decode(w, r, &req) {
+			return
+		}
+		now := t.tick()
+		issue.UpdatedAt = &now
+		if req.Title != nil {
+			issue.Title = req.Title
+		}
+		if req.Body != nil {
+			issue.Body = req.Body
+		}
+		if req.State != nil && *req.State != *issue.State {
+			t.setState(issue, *req.State)
+		}
+		t.reply(w, http.StatusOK, issue)
+	case commentsPath.MatchString(r.URL.Path) && r.Method == "GET":
+		since, _ := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+		comments := []githubapi.IssueComment{}
+		for _, c := range t.comments[number] {
+			if !c.UpdatedAt.Before(since) {
+				comments = append(comments, c)
+			}
+		}
+		t.reply(w, http.StatusOK, comments)
+	case commentsPath.MatchString(r.URL.Path) && r.Method == "POST":
+		c := githubapi.IssueComment{}
+		if !t.decode(w, r, &c) || c.Body == nil {
+			return
+		}
+		t.reply(w, http.StatusCreated, t.comment(number, t.Login, *c.Body))
+	case issueLabelsPath.MatchString(r.URL.Path) && r.Method == "POST":
+		labels := []string{}
+		if !t.decode(w, r, &labels) {
+			return
+		}
+		t.addLabels(issue, labels)
+		t.reply(w, http.StatusOK, issue.Labels)
+	case issueLabelPath.MatchString(r.URL.Path) && r.Method == "DELETE":
+		name, _ := url.QueryUnescape(m[2])
+		t.removeLabel(issue, name)
+		w.WriteHeader(http.StatusNoContent)
+	case reactionsPath.MatchString(r.URL.Path) && r.Method == "POST":
+		reaction := map[string]string{}
+		if !t.decode(w, r, &reaction) {
+			return
+		}
+		t.reactions[number] = append(t.reactions[number], reaction["content"])
+		t.reply(w, http.StatusCreated, reaction)
+	case lockPath.MatchString(r.URL.Path) && r.Method == "PUT":
+		req := map[string]string{}
+		if !t.decode(w, r, &req) {
+			return
+		}
+		t.locked[number] = req["lock_reason"]
+		w.WriteHeader(http.StatusNoContent)
+	case lockPath.MatchString(r.URL.Path) && r.Method == "DELETE":
+		delete(t.locked, number)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		t.error(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+// listIssues lists issues like github does, minus pagination.
+func (t *Tracker) listIssues(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	state := q.Get("state")
+	if state == "" {
+		state = "open"
+	}
+	since, _ := time.Parse(time.RFC3339, q.Get("since"))
+	labels := []string{}
+	if l := q.Get("labels"); l != "" {
+		labels = strings.Split(l, ",")
+	}
+	issues := []*githubapi.Issue{}
+	for _, issue := range t.issues {
+		if state != "all" && *issue.State != state {
+			continue
+		}
+		if issue.UpdatedAt.Before(since) || !hasLabels(issue, labels) {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	sort.Sort(byNumber(issues))
+	t.reply(w, http.StatusOK, issues)
+}
+
+// listComments lists the comments on all issues like github does, oldest
+// first and minus pagination.
+func (t *Tracker) listComments(w http.ResponseWriter, r *http.Request) {
+	since, _ := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+	comments := []githubapi.IssueComment{}
+	for _, list := range t.comments {
+		for _, c := range list {
+			if !c.UpdatedAt.Before(since) {
+				comments = append(comments, c)
+			}
+		}
+	}
+	sort.Sort(byUpdated(comments))
+	t.reply(w, http.StatusOK, comments)
+}
+
+// listCommits lists the commits made with AddMerge and AddCommit like github
+// lists commits, newest first and minus pagination.
+func (t *Tracker) listCommits(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	since, _ := time.Parse(time.RFC3339, q.Get("since"))
+	until, _ := time.Parse(time.RFC3339, q.Get("until"))
+	path := q.Get("path")
+	commits := []githubapi.RepositoryCommit{}
+	for i := len(t.commits) - 1; i >= 0; i-- {
+		at := *t.commits[i].Commit.Committer.Date
+		if at.Before(since) || (!until.IsZero() && at.After(until)) {
+			continue
+		}
+		if path != "" && t.paths[*t.commits[i].SHA] != path {
+			continue
+		}
+		commits = append(commits, t.commits[i])
+	}
+	t.reply(w, http.StatusOK, commits)
+}
+
+// search finds issues like github does for the queries the syncer makes:
+// every term has to be in the title (or the body, with "in:body"), and the
+// issue has to have every "label:" qualifier.
+func (t *Tracker) search(w http.ResponseWriter, r *http.Request) {
+	inBody := false
+	terms, labels := []string{}, []string{}
+	for _, m := range searchQueryTerms.FindAllStringSubmatch(r.URL.Query().Get("q"), -1) {
+		value := strings.Trim(m[2], `"`)
+		switch m[1] {
+		case "":
+			terms = append(terms, strings.ToLower(value))
+		case "in:":
+			inBody = value == "body"
+		case "label:":
+			labels = append(labels, value)
+		}
+	}
+	result := githubapi.IssuesSearchResult{Issues: []githubapi.Issue{}}
+	matches := []*githubapi.Issue{}
+	for _, issue := range t.issues {
+		text := *issue.Title
+		if inBody {
+			text = *issue.Body
+		}
+		text = strings.ToLower(text)
+		found := hasLabels(issue, labels)
+		for _, term := range terms {
+			found = found && strings.Contains(text, term)
+		}
+		if found {
+			matches = append(matches, issue)
+		}
+	}
+	sort.Sort(byNumber(matches))
+	for _, issue := range matches {
+		result.Issues = append(result.Issues, *issue)
+	}
+	total := len(result.Issues)
+	result.Total = &total
+	t.reply(w, http.StatusOK, result)
+}
+
+func hasLabels(issue *githubapi.Issue, labels []string) bool {
+	for _, l := range labels {
+		if !hasLabel(issue, l) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Tracker) decode(w http.ResponseWriter, r *http.Request, into interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(into); err != nil {
+		t.error(w, http.StatusBadRequest, fmt.Sprintf("Problems parsing JSON: %v", err))
+		return false
+	}
+	return true
+}
+
+func (t *Tracker) reply(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func (t *Tracker) error(w http.ResponseWriter, status int, message string) {
+	data, _ := json.Marshal(map[string]string{"message": message})
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+type byNumber []*githubapi.Issue
+
+func (b byNumber) Len() int           { return len(b) }
+func (b byNumber) Less(i, j int) bool { return *b[i].Number < *b[j].Number }
+func (b byNumber) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+type byUpdated []githubapi.IssueComment
+
+func (b byUpdated) Len() int           { return len(b) }
+func (b byUpdated) Less(i, j int) bool { return b[i].UpdatedAt.Before(*b[j].UpdatedAt) }
+func (b byUpdated) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
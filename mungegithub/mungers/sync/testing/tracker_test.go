@@ -0,0 +1,108 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/mungers/sync"
+)
+
+func newSyncer(t *Tracker) *sync.IssueSyncer {
+	config := t.Config()
+	finder := sync.NewSearchFinder(config, nil)
+	finder.MinInterval = 0
+	finder.TTL = 0
+	s := sync.NewIssueSyncer(config, finder)
+	s.Backoff = sync.Backoff{Steps: 3, Initial: time.Millisecond, Factor: 1}
+	return s
+}
+
+func TestTracker(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		scenario func(t *Tracker)
+		// succeeds is how many of the two syncs succeed.
+		succeeds int
+		open     int
+		comments int
+	}{
+		{
+			name:     "new issue",
+			scenario: func(t *Tracker) {},
+			succeeds: 2,
+			open:     1,
+			comments: 1,
+		},
+		{
+			name: "existing duplicates",
+			scenario: func(t *Tracker) {
+				t.AddDuplicates("TestFoo", "failed", 3)
+			},
+			succeeds: 2,
+			open:     1,
+			comments: 2,
+		},
+		{
+			// Looking the source up isn't retried, the source is
+			// skipped until the next pass.
+			name: "rate limited",
+			scenario: func(t *Tracker) {
+				t.RateLimit(1)
+			},
+			succeeds: 1,
+			open:     1,
+			comments: 0,
+		},
+		{
+			name: "creating fails",
+			scenario: func(t *Tracker) {
+				t.FailNext("POST", "/repos/o/r/issues", http.StatusBadGateway, 3)
+			},
+			succeeds: 1,
+			open:     1,
+			comments: 0,
+		},
+	}
+	for _, test := range tests {
+		tracker := NewTracker()
+		test.scenario(tracker)
+		s := newSyncer(tracker)
+		succeeds := 0
+		for _, ref := range []string{"run-1", "run-2"} {
+			if err := s.Sync(ctx, &sync.JSONSource{Key: "TestFoo", Ref: ref, Details: "failed in " + ref}); err == nil {
+				succeeds++
+			} else if !sync.IsRetryable(err) {
+				t.Errorf("%v: expected only retryable errors, got %v", test.name, err)
+			}
+		}
+		if succeeds != test.succeeds {
+			t.Errorf("%v: expected %v syncs to succeed, got %v", test.name, test.succeeds, succeeds)
+		}
+		open := tracker.OpenIssues("TestFoo")
+		if len(open) != test.open {
+			t.Errorf("%v: expected %v open issues, got %v", test.name, test.open, open)
+		} else if comments := tracker.Comments(open[0]); len(comments) != test.comments {
+			t.Errorf("%v: expected %v comments, got %q", test.name, test.comments, comments)
+		}
+		tracker.Close()
+	}
+}
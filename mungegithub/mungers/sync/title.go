@@ -0,0 +1,81 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// MaxTitleLength is the most characters github accepts in an issue title.
+// Longer titles are cut, and end with a hash of the full title so that
+// sources which only differ past the cut still get an issue each.
+const MaxTitleLength = 256
+
+var truncatedTitleRE = regexp.MustCompile(`… \(([0-9a-f]{10})\)$`)
+
+// titleHash returns the hash of the full title which a cut title ends with.
+func titleHash(title string) string {
+	if m := truncatedTitleRE.FindStringSubmatch(title); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// truncateTitle cuts `title` to MaxTitleLength characters, if it's longer.
+func truncateTitle(title string) string {
+	if utf8.RuneCountInString(title) <= MaxTitleLength {
+		return title
+	}
+	suffix := fmt.Sprintf("… (%v)", fmt.Sprintf("%x", sha1.Sum([]byte(title)))[:10])
+	runes := []rune(title)[:MaxTitleLength-utf8.RuneCountInString(suffix)]
+	return strings.TrimSpace(string(runes)) + suffix
+}
+
+// fullTitleMarker records the hash of the full title in the body of issues
+// whose title was cut.
+func fullTitleMarker(title string) string {
+	return fmt.Sprintf("<!-- sync-full-title: %x -->", sha1.Sum([]byte(title)))
+}
+
+var fullTitleRE = regexp.MustCompile(`<!-- sync-full-title: ([0-9a-f]+) -->`)
+
+// fullTitleText is added to the body of new issues whose title was cut.
+func fullTitleText(title string) string {
+	return fmt.Sprintf("The title is too long for github, it is:\n\n`%v`\n\n%v", sectionID(title), fullTitleMarker(title))
+}
+
+// collides returns true if `obj` has the (cut) title of the source but was
+// filed for another source, whose title only differed past the cut.
+func (s *IssueSyncer) collides(obj *github.MungeObject, source IssueSource) bool {
+	full := Namespaced(s.Namespace, source.Title())
+	if obj.Issue.Body == nil || s.title(source) == full {
+		return false
+	}
+	m := fullTitleRE.FindStringSubmatch(*obj.Issue.Body)
+	if m == nil || m[0] == fullTitleMarker(full) {
+		return false
+	}
+	metrics.Add("titleCollisions", 1)
+	s.logger().With("issue", *obj.Issue.Number).Warningf("Not syncing to the issue, it has the same cut title but was filed for another source")
+	return true
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestTruncateTitle(t *testing.T) {
+	long := strings.Repeat("é", MaxTitleLength)
+	tests := []struct {
+		title string
+		cut   bool
+	}{
+		{title: "TestFoo"},
+		{title: strings.Repeat("a", MaxTitleLength)},
+		{title: long + "a", cut: true},
+		{title: long + "b", cut: true},
+	}
+	seen := map[string]bool{}
+	for _, test := range tests {
+		got := truncateTitle(test.title)
+		if !test.cut {
+			if got != test.title {
+				t.Errorf("expected %q to be left alone, got %q", test.title, got)
+			}
+			continue
+		}
+		if n := utf8.RuneCountInString(got); n > MaxTitleLength {
+			t.Errorf("expected at most %d characters, got %d: %q", MaxTitleLength, n, got)
+		}
+		if titleHash(got) == "" {
+			t.Errorf("expected a hash at the end of %q", got)
+		}
+		if seen[got] {
+			t.Errorf("distinct titles were cut to the same %q", got)
+		}
+		seen[got] = true
+	}
+}
+
+func TestLongTitles(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	// A normalizer which forgets the hash makes the cut titles collide.
+	finder.Normalizer = &TitleNormalizer{Rules: []NormalizeRule{{Pattern: `… \([0-9a-f]+\)$`, Replacement: ""}}}
+	if err := finder.Normalizer.compile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := NewIssueSyncer(tracker.Config(), finder)
+	s.Normalizer = finder.Normalizer
+	long := strings.Repeat("x", MaxTitleLength)
+	for _, source := range []*JSONSource{
+		{Key: long + " in TestFoo", Ref: "run-1"},
+		{Key: long + " in TestBar", Ref: "run-2"},
+		{Key: long + " in TestFoo", Ref: "run-3"},
+	} {
+		if err := s.Sync(context.Background(), source); err != nil {
+			t.Fatalf("unexpected error syncing %v: %v", source.Ref, err)
+		}
+	}
+	issues := tracker.Issues()
+	if len(issues) != 2 {
+		t.Fatalf("expected an issue for each title, got %d", len(issues))
+	}
+	for _, issue := range issues {
+		if utf8.RuneCountInString(*issue.Title) > MaxTitleLength {
+			t.Errorf("title too long: %q", *issue.Title)
+		}
+		if !strings.Contains(*issue.Body, "x in Test") {
+			t.Errorf("expected the full title in the body, got %q", *issue.Body)
+		}
+	}
+	if comments := tracker.Comments(*issues[0].Number); len(comments) != 1 || !strings.Contains(comments[0], "run-3") {
+		t.Errorf("expected run-3 to be synced to the first issue, got %q", comments)
+	}
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"golang.org/x/net/context"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// IssueTracker is an issue tracker other than github which a TrackerSyncer
+// files issues in, see GerritTracker and BugzillaTracker. Issues are
+// identified by number.
+type IssueTracker interface {
+	// Find returns the issues, open or not, which may be titled
+	// `title`. Trackers may return more; they are matched by key.
+	Find(ctx context.Context, title string) ([]TrackedIssue, error)
+	// Comments returns the text of the issue's comments.
+	Comments(ctx context.Context, number int) ([]string, error)
+	// Create files an issue and returns its number.
+	Create(ctx context.Context, title, body string, labels []string) (int, error)
+	// Comment adds a comment to the issue.
+	Comment(ctx context.Context, number int, body string) error
+	// CloseDuplicate closes the issue as a duplicate of issue `of`,
+	// saying so with `text`.
+	CloseDuplicate(ctx context.Context, number, of int, text string) error
+}
+
+// TrackedIssue is an issue in an IssueTracker.
+type TrackedIssue struct {
+	Number int
+	Title  string
+	Body   string
+	Open   bool
+}
+
+// TrackerError is returned by IssueTrackers when the tracker answers a
+// request with an error status. Server errors and rate limiting are
+// retried, see IssueSyncer.Backoff.
+type TrackerError struct {
+	Op         string
+	StatusCode int
+	Message    string
+}
+
+func (e *TrackerError) Error() string {
+	return fmt.Sprintf("%v: %v %v", e.Op, e.StatusCode, e.Message)
+}
+
+func (e *TrackerError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// TrackerSyncer syncs sources to an IssueTracker the way an IssueSyncer
+// does to github: issues are found by key, sources already recorded on
+// one are skipped, the others are commented on the oldest open issue,
+// newer open duplicates are closed, and otherwise an issue is filed. The
+// records of the issues are kept in its Store, as they are for an
+// IssueSyncer. It is a Syncer, so it can be used with an Ingester or a
+// MultiSyncer.
+type TrackerSyncer struct {
+	tracker IssueTracker
+	// format is how issues are titled, written and recorded; only the
+	// settings it shares with an IssueSyncer are used.
+	format *IssueSyncer
+	synced sets.String
+}
+
+// NewTrackerSyncer returns a TrackerSyncer which files issues in
+// `tracker`. Options other than WithRepo, which isn't needed, set how
+// issues are titled and written, and where they are recorded; those only
+// about github are ignored.
+func NewTrackerSyncer(tracker IssueTracker, opts ...Option) (*TrackerSyncer, error) {
+	o := &options{syncer: NewIssueSyncer(nil, nil)}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	if o.clock != nil {
+		o.syncer.SetClock(o.clock)
+	}
+	return &TrackerSyncer{
+		tracker: tracker,
+		format:  o.syncer,
+		synced:  sets.NewString(),
+	}, nil
+}
+
+// Store returns where the syncer records the issues it synced to.
+func (t *TrackerSyncer) Store() *MetadataStore {
+	return t.format.Store
+}
+
+// Synced returns true once the source with `id` was synced.
+func (t *TrackerSyncer) Synced(id string) bool {
+	return t.synced.Has(id)
+}
+
+// SyncAll syncs every source. Like IssueSyncer.SyncAll, there is a result
+// for every source, and the returned error only says how many failed.
+func (t *TrackerSyncer) SyncAll(ctx context.Context, sources []IssueSource) ([]SyncResult, error) {
+	s := t.format
+	defer func() {
+		if err := s.Store.Flush(); err != nil {
+			s.logger().Errorf("Unable to persist the metadata: %v", err)
+		}
+	}()
+	results := []SyncResult{}
+	failed := 0
+	for _, source := range sources {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result := t.sync(ctx, source)
+		if result.Err != nil {
+			failed++
+			s.logger().With("source", result.Source).Errorf("Unable to sync: %v", result.Err)
+		}
+		results = append(results, result)
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("failed to sync %d of %d sources", failed, len(sources))
+	}
+	return results, nil
+}
+
+func (t *TrackerSyncer) sync(ctx context.Context, source IssueSource) SyncResult {
+	s := t.format
+	id := source.ID()
+	result := SyncResult{Source: s.sourceID(source), Action: DecisionNone}
+	if t.synced.Has(id) {
+		return result
+	}
+	title := s.title(source)
+	var found []TrackedIssue
+	err := s.retry(ctx, fmt.Sprintf("finding issues for %v", result.Source), func() (err error) {
+		found, err = t.tracker.Find(ctx, title)
+		return err
+	})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	key := s.key(source)
+	open := []TrackedIssue{}
+	for _, issue := range found {
+		if s.Normalizer.Normalize(issue.Title) != key {
+			continue
+		}
+		recorded, err := t.isRecorded(ctx, issue, id)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if recorded {
+			t.synced.Insert(id)
+			result.Issue = issue.Number
+			return result
+		}
+		if issue.Open {
+			open = append(open, issue)
+		}
+	}
+	if len(open) == 0 {
+		n, err := t.create(ctx, source)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		t.synced.Insert(id)
+		result.Action, result.Issue = DecisionCreate, n
+		return result
+	}
+	sort.Sort(byTrackedNumber(open))
+	n := open[0].Number
+	if err := t.closeDuplicates(ctx, open[1:], n); err != nil {
+		result.Err = err
+		return result
+	}
+	body := s.text(s.sourceBody(source, false))
+	err = s.retry(ctx, fmt.Sprintf("commenting on %v for %v", n, result.Source), func() error {
+		return t.tracker.Comment(ctx, n, body)
+	})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	s.recordOccurrence(n, func(r *IssueRecord) {
+		r.LastUpdate = s.now()
+	})
+	t.synced.Insert(id)
+	result.Action, result.Issue = DecisionUpdate, n
+	return result
+}
+
+// isRecorded returns true if the source with `id` is recorded in the body
+// or the comments of `issue`.
+func (t *TrackerSyncer) isRecorded(ctx context.Context, issue TrackedIssue, id string) (bool, error) {
+	s := t.format
+	if s.recorded(issue.Body, id) {
+		return true, nil
+	}
+	var comments []string
+	err := s.retry(ctx, fmt.Sprintf("listing comments of %v", issue.Number), func() (err error) {
+		comments, err = t.tracker.Comments(ctx, issue.Number)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, c := range comments {
+		if s.recorded(c, id) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (t *TrackerSyncer) create(ctx context.Context, source IssueSource) (int, error) {
+	s := t.format
+	title := s.title(source)
+	body := s.text(s.sourceBody(source, true)) + "\n\n" + SyncKeyMarker(s.syncKey(source))
+	labels := s.labels(source)
+	var n int
+	err := s.retry(ctx, fmt.Sprintf("making issue for %v", s.sourceID(source)), func() (err error) {
+		n, err = t.tracker.Create(ctx, title, body, labels)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.logger().With("issue", n).Infof("Created issue, no open issue was found for %q", title)
+	s.recordOccurrence(n, func(r *IssueRecord) {
+		r.Title = title
+		r.Labels = labels
+		r.Created = s.now()
+		r.LastUpdate = r.Created
+	})
+	return n, nil
+}
+
+// closeDuplicates closes the `dups` of issue `of`, like
+// IssueSyncer.markAsDups.
+func (t *TrackerSyncer) closeDuplicates(ctx context.Context, dups []TrackedIssue, of int) error {
+	s := t.format
+	for _, dup := range dups {
+		n := dup.Number
+		text := s.text(s.duplicateText(n, of))
+		s.logger().With("issue", n).Infof("Closing as a duplicate of %v", of)
+		err := s.retry(ctx, fmt.Sprintf("closing %v as a dup of %v", n, of), func() error {
+			return t.tracker.CloseDuplicate(ctx, n, of, text)
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.closedAs(n, ClosedDuplicate, of); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type byTrackedNumber []TrackedIssue
+
+func (b byTrackedNumber) Len() int           { return len(b) }
+func (b byTrackedNumber) Less(i, j int) bool { return b[i].Number < b[j].Number }
+func (b byTrackedNumber) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
@@ -0,0 +1,201 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// fakeIssueTracker is an IssueTracker in memory.
+type fakeIssueTracker struct {
+	issues   map[int]*TrackedIssue
+	labels   map[int][]string
+	comments map[int][]string
+	dupOf    map[int]int
+	next     int
+	// failures are how many calls fail with a server error first.
+	failures int
+}
+
+func newFakeIssueTracker() *fakeIssueTracker {
+	return &fakeIssueTracker{
+		issues:   map[int]*TrackedIssue{},
+		labels:   map[int][]string{},
+		comments: map[int][]string{},
+		dupOf:    map[int]int{},
+		next:     1,
+	}
+}
+
+func (f *fakeIssueTracker) fail(op string) error {
+	if f.failures == 0 {
+		return nil
+	}
+	f.failures--
+	return &TrackerError{Op: op, StatusCode: http.StatusServiceUnavailable}
+}
+
+func (f *fakeIssueTracker) Find(ctx context.Context, title string) ([]TrackedIssue, error) {
+	if err := f.fail("find"); err != nil {
+		return nil, err
+	}
+	issues := []TrackedIssue{}
+	for n := 1; n < f.next; n++ {
+		if i := f.issues[n]; strings.EqualFold(i.Title, title) {
+			issues = append(issues, *i)
+		}
+	}
+	return issues, nil
+}
+
+func (f *fakeIssueTracker) Comments(ctx context.Context, number int) ([]string, error) {
+	return f.comments[number], nil
+}
+
+func (f *fakeIssueTracker) Create(ctx context.Context, title, body string, labels []string) (int, error) {
+	n := f.next
+	f.next++
+	f.issues[n] = &TrackedIssue{Number: n, Title: title, Body: body, Open: true}
+	f.labels[n] = labels
+	return n, nil
+}
+
+func (f *fakeIssueTracker) Comment(ctx context.Context, number int, body string) error {
+	if err := f.fail("comment"); err != nil {
+		return err
+	}
+	f.comments[number] = append(f.comments[number], body)
+	return nil
+}
+
+func (f *fakeIssueTracker) CloseDuplicate(ctx context.Context, number, of int, text string) error {
+	f.issues[number].Open = false
+	f.dupOf[number] = of
+	f.comments[number] = append(f.comments[number], text)
+	return nil
+}
+
+func TestTrackerSyncer(t *testing.T) {
+	tracker := newFakeIssueTracker()
+	ctx := context.Background()
+	s, err := NewTrackerSyncer(tracker, WithNamespace("exp-"), WithLabels("flake"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := &testSource{title: "TestFoo", id: "http://build/1"}
+	results, err := s.SyncAll(ctx, []IssueSource{first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != DecisionCreate || results[0].Issue != 1 {
+		t.Fatalf("expected issue 1 to be created, got %+v", results[0])
+	}
+	issue := tracker.issues[1]
+	if issue.Title != "exp-TestFoo" {
+		t.Errorf("unexpected title %q", issue.Title)
+	}
+	if !strings.Contains(issue.Body, IDMarker(first.id)) || !strings.HasPrefix(issue.Body, "[exp-]") {
+		t.Errorf("unexpected body %q", issue.Body)
+	}
+	if e, a := []string{"exp-kind/bug", "exp-flake"}, tracker.labels[1]; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected labels %v, got %v", e, a)
+	}
+	if !s.Synced(first.id) {
+		t.Errorf("expected %v to be synced", first.id)
+	}
+
+	// A new syncer finds the source recorded, and the next source is
+	// commented on the issue.
+	s, err = NewTrackerSyncer(tracker, WithNamespace("exp-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := &testSource{title: "TestFoo", id: "http://build/2"}
+	results, err = s.SyncAll(ctx, []IssueSource{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != DecisionNone || results[0].Issue != 1 {
+		t.Errorf("expected %v to be found on issue 1, got %+v", first.id, results[0])
+	}
+	if results[1].Action != DecisionUpdate || results[1].Issue != 1 {
+		t.Errorf("expected %v to be commented on issue 1, got %+v", second.id, results[1])
+	}
+	if len(tracker.comments[1]) != 1 || !strings.Contains(tracker.comments[1][0], IDMarker(second.id)) {
+		t.Errorf("unexpected comments %q", tracker.comments[1])
+	}
+	if r, ok := s.Store().Get(1); !ok || r.Occurrences != 1 {
+		t.Errorf("expected the occurrence to be recorded, got %+v", r)
+	}
+}
+
+func TestTrackerSyncerDuplicates(t *testing.T) {
+	tracker := newFakeIssueTracker()
+	ctx := context.Background()
+	tracker.Create(ctx, "TestFoo", "first", nil)
+	tracker.Create(ctx, "testfoo", "second", nil)
+	tracker.Create(ctx, "TestFoo", "third", nil)
+	tracker.issues[1].Open = false
+	s, err := NewTrackerSyncer(tracker, WithNormalizer(&TitleNormalizer{Lowercase: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.SyncAll(ctx, []IssueSource{&testSource{title: "TestFoo", id: "http://build/1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != DecisionUpdate || results[0].Issue != 2 {
+		t.Fatalf("expected the oldest open issue to be commented on, got %+v", results[0])
+	}
+	if tracker.issues[3].Open || tracker.dupOf[3] != 2 {
+		t.Errorf("expected 3 to be closed as a duplicate of 2")
+	}
+	if _, ok := tracker.dupOf[1]; ok {
+		t.Errorf("closed issue 1 was closed again")
+	}
+	if r, _ := s.Store().Get(3); r.ClosedAs != ClosedDuplicate || r.DuplicateOf != 2 {
+		t.Errorf("expected 3 to be recorded as a duplicate of 2, got %+v", r)
+	}
+}
+
+func TestTrackerSyncerRetries(t *testing.T) {
+	tracker := newFakeIssueTracker()
+	tracker.failures = 2
+	s, err := NewTrackerSyncer(tracker, WithBackoff(Backoff{Steps: 3, Initial: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.SyncAll(context.Background(), []IssueSource{&testSource{title: "TestFoo", id: "http://build/1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != DecisionCreate {
+		t.Errorf("expected the issue to be created after retrying, got %+v", results[0])
+	}
+
+	tracker.failures = 3
+	results, err = s.SyncAll(context.Background(), []IssueSource{&testSource{title: "TestFoo", id: "http://build/2"}})
+	if err == nil || !IsRetryable(results[0].Err) {
+		t.Errorf("expected a retryable error once out of attempts, got %v", results[0].Err)
+	}
+}
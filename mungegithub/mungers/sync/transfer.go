@@ -0,0 +1,173 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// TransferLabelPrefix is what humans label an issue we filed with, followed
+// by a repo ("org/project", or "project" in the same org), to have it moved
+// there. The syncer files the issue again in that repo, with a summary of its
+// history, closes the original and from then on records occurrences on the
+// new issue.
+const TransferLabelPrefix = "transfer-to:"
+
+// transferTarget returns the repo `obj` is labeled to be transferred to.
+func (s *IssueSyncer) transferTarget(obj *github.MungeObject) (org, project string, ok bool) {
+	for _, l := range github.GetLabelsWithPrefix(obj.Issue.Labels, TransferLabelPrefix) {
+		repo := strings.TrimSpace(strings.TrimPrefix(l, TransferLabelPrefix))
+		parts := strings.Split(repo, "/")
+		switch {
+		case len(parts) == 1 && parts[0] != "":
+			return s.config.Org, parts[0], true
+		case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+			return parts[0], parts[1], true
+		}
+		s.logger().With("issue", *obj.Issue.Number).Warningf("Ignoring invalid transfer label %q", l)
+	}
+	return "", "", false
+}
+
+// transfer files `obj` again in org/project and closes it, pointing to the
+// new issue.
+func (s *IssueSyncer) transfer(ctx context.Context, obj *github.MungeObject, org, project string) error {
+	n := *obj.Issue.Number
+	record, _ := s.Store.Get(n)
+	title, body := "", ""
+	if obj.Issue.Title != nil {
+		title = *obj.Issue.Title
+	}
+	if obj.Issue.Body != nil {
+		body = *obj.Issue.Body
+	}
+	lines := []string{fmt.Sprintf("Moved here from %v/%v#%d.", s.config.Org, s.config.Project, n)}
+	if record.Occurrences > 0 {
+		lines = append(lines, fmt.Sprintf("It was seen %d times, last on %v.", record.Occurrences, record.LastOccurrence.UTC().Format(dateFormat)))
+	}
+	if a := obj.Issue.Assignee; a != nil && a.Login != nil {
+		lines = append(lines, fmt.Sprintf("It was assigned to @%v.", *a.Login))
+	}
+	body = s.text(strings.Join(lines, " ")) + "\n\n" + body
+	labels := []string{}
+	for _, l := range obj.Issue.Labels {
+		if l.Name != nil && !strings.HasPrefix(*l.Name, TransferLabelPrefix) {
+			labels = append(labels, *l.Name)
+		}
+	}
+
+	// Writes to the other repo aren't audited: the audit log (and Undo)
+	// only knows about issues in ours.
+	target := s.client(ctx).ForRepo(org, project)
+	var created *github.MungeObject
+	s.logger().With("issue", n).Infof("Transferring to %v/%v", org, project)
+	if err := s.retry(ctx, fmt.Sprintf("transferring %v to %v/%v", n, org, project), func() (err error) {
+		created, err = target.NewIssue(title, body, labels)
+		return err
+	}); err != nil {
+		return err
+	}
+	moved := *created.Issue.Number
+	if err := s.Store.Update(n, func(r *IssueRecord) {
+		r.TransferredTo = fmt.Sprintf("%v/%v", org, project)
+		r.TransferredAs = moved
+	}); err != nil {
+		return err
+	}
+	msg := s.text(fmt.Sprintf("Moved to %v/%v#%d.", org, project, moved))
+	if err := s.writeComment(ctx, fmt.Sprintf("commenting on transferred %v", n), obj, msg); err != nil {
+		return err
+	}
+	if err := s.closeIssue(ctx, fmt.Sprintf("closing transferred %v", n), obj); err != nil {
+		return err
+	}
+	return s.noticeClosed(ctx, n)
+}
+
+// transferred returns the newest of `issues` which was transferred, and its
+// record.
+func (s *IssueSyncer) transferred(issues []*github.MungeObject) (IssueRecord, bool) {
+	for i := len(issues) - 1; i >= 0; i-- {
+		if r, ok := s.Store.Get(*issues[i].Issue.Number); ok && r.TransferredTo != "" {
+			return r, true
+		}
+	}
+	return IssueRecord{}, false
+}
+
+// syncTransferred records an occurrence of `source` on the issue `r` was
+// transferred to.
+func (s *IssueSyncer) syncTransferred(ctx context.Context, source IssueSource, r IssueRecord) error {
+	parts := strings.SplitN(r.TransferredTo, "/", 2)
+	target := s.client(ctx).ForRepo(parts[0], parts[1])
+	var obj *github.MungeObject
+	if err := s.retry(ctx, fmt.Sprintf("getting %v#%d", r.TransferredTo, r.TransferredAs), func() (err error) {
+		obj, err = target.GetObject(r.TransferredAs)
+		return err
+	}); err != nil {
+		return err
+	}
+	id := source.ID()
+	recorded, err := s.recordedRemote(ctx, r.TransferredTo, obj, id)
+	if err != nil {
+		return err
+	}
+	if recorded {
+		s.logger().Debugf("Already recorded on %v#%d", r.TransferredTo, r.TransferredAs)
+		return nil
+	}
+	s.logger().With("issue", r.Number).Infof("Transferred, updating %v#%d", r.TransferredTo, r.TransferredAs)
+	body := s.text(s.recurrenceText(r.TransferredAs, s.sourceBody(source, false)))
+	if err := s.retry(ctx, fmt.Sprintf("updating %v#%d for %v", r.TransferredTo, r.TransferredAs, id), func() error {
+		return obj.WriteComment(body)
+	}); err != nil {
+		return err
+	}
+	s.recordOccurrence(r.Number, func(r *IssueRecord) { r.LastUpdate = s.now() })
+	return nil
+}
+
+// recordedRemote returns true if source `id` is recorded in `obj`, an issue
+// in another repo, `repo`.
+func (s *IssueSyncer) recordedRemote(ctx context.Context, repo string, obj *github.MungeObject, id string) (bool, error) {
+	// Not through the comment cache, which is only for our repo.
+	var comments []string
+	if err := s.retry(ctx, fmt.Sprintf("getting comments for %v#%d", repo, *obj.Issue.Number), func() error {
+		list, err := obj.ListComments()
+		for _, c := range list {
+			if c.Body != nil {
+				comments = append(comments, *c.Body)
+			}
+		}
+		return err
+	}); err != nil {
+		return false, err
+	}
+	if obj.Issue.Body != nil {
+		comments = append(comments, *obj.Issue.Body)
+	}
+	for _, c := range comments {
+		if s.recorded(c, id) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func TestTransfer(t *testing.T) {
+	issue := github_test.Issue("bot", 1, []string{"kind/flake", "transfer-to:other"}, false)
+	title, body, state := "TestFoo", "TestFoo failed in run-1", "open"
+	issue.Title, issue.Body, issue.State = &title, &body, &state
+	client, server, mux := github_test.InitServer(t, issue, nil, nil, nil, nil, nil)
+	defer server.Close()
+	config := &github.Config{Org: "o", Project: "r"}
+	config.SetClient(client)
+
+	comments := map[string][]string{}
+	commentsOn := func(path string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				c := githubapi.IssueComment{}
+				json.NewDecoder(r.Body).Decode(&c)
+				comments[path] = append(comments[path], *c.Body)
+				w.Write([]byte("{}"))
+				return
+			}
+			w.Write([]byte("[]"))
+		})
+	}
+	commentsOn("/repos/o/r/issues/1/comments")
+	commentsOn("/repos/o/other/issues/7/comments")
+	var moved *githubapi.Issue
+	mux.HandleFunc("/repos/o/other/issues", func(w http.ResponseWriter, r *http.Request) {
+		req := githubapi.IssueRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		number := 7
+		moved = &githubapi.Issue{Number: &number, Title: req.Title, Body: req.Body, State: &state}
+		for _, l := range *req.Labels {
+			name := l
+			moved.Labels = append(moved.Labels, githubapi.Label{Name: &name})
+		}
+		data, _ := json.Marshal(moved)
+		w.Write(data)
+	})
+	mux.HandleFunc("/repos/o/other/issues/7", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(moved)
+		w.Write(data)
+	})
+
+	s := NewIssueSyncer(config, &keyFinder{titles: map[string][]int{"TestFoo": {1}}})
+	s.Store.Update(1, func(r *IssueRecord) { r.Occurrences = 1 })
+	if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: "run-2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if moved == nil {
+		t.Fatalf("expected the issue to be filed in o/other")
+	}
+	if got := github.GetLabelsWithPrefix(moved.Labels, ""); !reflect.DeepEqual(got, []string{"kind/flake"}) {
+		t.Errorf("expected the labels but the transfer label to be kept, got %v", got)
+	}
+	if r, _ := s.Store.Get(1); r.TransferredTo != "o/other" || r.TransferredAs != 7 || !r.Closed {
+		t.Errorf("expected #1 to be recorded as moved to o/other#7 and closed, got %+v", r)
+	}
+	if got := comments["/repos/o/r/issues/1/comments"]; len(got) != 1 || got[0] != "Moved to o/other#7." {
+		t.Errorf("expected a comment pointing to the new issue, got %q", got)
+	}
+
+	// The original is closed now, later occurrences go to the new issue.
+	closed := "closed"
+	issue.State = &closed
+	if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: "run-3"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := comments["/repos/o/other/issues/7/comments"]; len(got) != 2 {
+		t.Errorf("expected both occurrences on the new issue, got %q", got)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// TriageSignals tell when humans are working on an issue: it has an
+// assignee or Label, or a maintainer commented after our last comment. While
+// they are, new occurrences are only recorded in the sync section of the
+// issue body (see updateSection), so that nobody gets notified about them,
+// until the issue has been quiet for QuietAfter.
+type TriageSignals struct {
+	// Label marks an issue as triaged.
+	Label string
+	// Maintainers are the logins whose comments are triage. If empty,
+	// comments by anyone but Bots are.
+	Maintainers sets.String
+	// Bots are the logins whose comments are not triage, including the
+	// syncer's own.
+	Bots sets.String
+	// QuietAfter is how long (in business time, see IssueSyncer.Calendar)
+	// after the last triage signal we go back to commenting. A label or
+	// assignee only counts as a signal when we first notice it.
+	QuietAfter time.Duration
+}
+
+// NewTriageSignals returns TriageSignals for the "triaged" label, which
+// stay in effect for 5 business days.
+func NewTriageSignals() *TriageSignals {
+	return &TriageSignals{
+		Label:       "triaged",
+		Maintainers: sets.NewString(),
+		Bots:        sets.NewString(),
+		QuietAfter:  5 * businessDay,
+	}
+}
+
+// lastTriage returns when a maintainer last commented after the last comment
+// by a bot, if they did.
+func (t *TriageSignals) lastTriage(comments []commentInfo) time.Time {
+	var last time.Time
+	for _, c := range comments {
+		switch {
+		case t.Bots.Has(c.login):
+			last = time.Time{}
+		case t.Maintainers.Len() == 0 || t.Maintainers.Has(c.login):
+			last = c.at
+		}
+	}
+	return last
+}
+
+type commentInfo struct {
+	login string
+	at    time.Time
+}
+
+// triaged returns true if humans are working on the issue according to
+// Triage, and records when they last showed it in the store.
+func (s *IssueSyncer) triaged(ctx context.Context, obj *github.MungeObject) (bool, error) {
+	if s.Triage == nil {
+		return false, nil
+	}
+	n := *obj.Issue.Number
+	r, _ := s.Store.Get(n)
+	last := r.TriagedAt
+
+	labeled := s.Triage.Label != "" && obj.HasLabel(Namespaced(s.Namespace, s.Triage.Label))
+	if (obj.Issue.Assignee != nil || labeled) && r.TriagedAt.IsZero() {
+		last = s.now()
+	}
+	var comments []commentInfo
+	err := s.retry(ctx, fmt.Sprintf("getting comments for %v", n), func() error {
+		list, err := obj.ListComments()
+		if err != nil {
+			return err
+		}
+		comments = nil
+		for _, c := range list {
+			if c.User != nil && c.User.Login != nil && c.CreatedAt != nil {
+				comments = append(comments, commentInfo{*c.User.Login, *c.CreatedAt})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if t := s.Triage.lastTriage(comments); t.After(last) {
+		last = t
+	}
+	if last.IsZero() {
+		return false, nil
+	}
+	if !last.Equal(r.TriagedAt) {
+		if err := s.Store.Update(n, func(r *IssueRecord) {
+			r.Triaged = true
+			r.TriagedAt = last
+		}); err != nil {
+			return false, err
+		}
+	}
+	return s.Triage.QuietAfter <= 0 || s.Calendar.Elapsed(last, s.now()) < s.Triage.QuietAfter, nil
+}
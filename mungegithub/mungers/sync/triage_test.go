@@ -0,0 +1,121 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	github_test "k8s.io/contrib/mungegithub/github/testing"
+)
+
+func TestTriaged(t *testing.T) {
+	type comment struct {
+		login string
+		at    string
+	}
+	tests := []struct {
+		name        string
+		labels      []string
+		maintainers []string
+		comments    []comment
+		triagedAt   string
+		now         string
+		expected    bool
+	}{
+		{
+			name:     "untouched",
+			comments: []comment{{"bot", "2016-07-01 12:00"}},
+			now:      "2016-07-01 13:00",
+		},
+		{
+			name:     "human after the bot",
+			comments: []comment{{"bot", "2016-07-01 12:00"}, {"alice", "2016-07-01 12:30"}},
+			now:      "2016-07-01 13:00",
+			expected: true,
+		},
+		{
+			name:     "bot after the human",
+			comments: []comment{{"alice", "2016-07-01 12:00"}, {"bot", "2016-07-01 12:30"}},
+			now:      "2016-07-01 13:00",
+		},
+		{
+			name:        "not a maintainer",
+			maintainers: []string{"bob"},
+			comments:    []comment{{"bot", "2016-07-01 12:00"}, {"alice", "2016-07-01 12:30"}},
+			now:         "2016-07-01 13:00",
+		},
+		{
+			name:     "quiet again",
+			comments: []comment{{"bot", "2016-07-01 12:00"}, {"alice", "2016-07-01 12:30"}},
+			now:      "2016-07-03 13:00",
+		},
+		{
+			name:     "label",
+			labels:   []string{"triaged"},
+			now:      "2016-07-01 13:00",
+			expected: true,
+		},
+		{
+			name:      "label noticed long ago",
+			labels:    []string{"triaged"},
+			triagedAt: "2016-06-01 12:00",
+			now:       "2016-07-01 13:00",
+		},
+	}
+	for _, test := range tests {
+		client, server, mux := github_test.InitServer(t, github_test.Issue("bot", 1, test.labels, false), nil, nil, nil, nil, nil)
+		config := &github.Config{Org: "o", Project: "r"}
+		config.SetClient(client)
+		mux.HandleFunc("/repos/o/r/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+			comments := []interface{}{}
+			for i, c := range test.comments {
+				comments = append(comments, github_test.Comment(i+1, c.login, date(c.at), "hi"))
+			}
+			data, _ := json.Marshal(comments)
+			w.Write(data)
+		})
+
+		s := NewIssueSyncer(config, nil)
+		s.now = func() time.Time { return date(test.now) }
+		s.Triage = NewTriageSignals()
+		s.Triage.QuietAfter = 24 * time.Hour
+		s.Triage.Bots.Insert("bot")
+		s.Triage.Maintainers.Insert(test.maintainers...)
+		if test.triagedAt != "" {
+			s.Store.Update(1, func(r *IssueRecord) { r.Triaged, r.TriagedAt = true, date(test.triagedAt) })
+		}
+		obj, err := config.GetObject(1)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		triaged, err := s.triaged(context.Background(), obj)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		} else if triaged != test.expected {
+			t.Errorf("%v: expected triaged %v, got %v", test.name, test.expected, triaged)
+		}
+		if r, _ := s.Store.Get(1); test.expected && !r.Triaged {
+			t.Errorf("%v: expected the issue to be recorded as triaged", test.name)
+		}
+		server.Close()
+	}
+}
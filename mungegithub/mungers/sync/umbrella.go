@@ -0,0 +1,122 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Umbrella issues start with a summary of their members between these
+// markers.
+const (
+	umbrellaStart = "<!-- umbrella-summary-start -->"
+	umbrellaEnd   = "<!-- umbrella-summary-end -->"
+)
+
+// GroupedSource is an IssueSource which belongs to a group, e.g. all failures
+// of the tests in a package during a bad day for the infrastructure. Sources
+// of a group are synced to a single umbrella issue, titled with the group
+// key, instead of an issue each.
+type GroupedSource interface {
+	IssueSource
+	GroupKey() string
+}
+
+func groupKey(source IssueSource) string {
+	if g, ok := source.(GroupedSource); ok {
+		return g.GroupKey()
+	}
+	return ""
+}
+
+// umbrellaSource is a member of a group, synced to the group's issue.
+type umbrellaSource struct {
+	IssueSource
+	key string
+}
+
+func (u *umbrellaSource) Title() string {
+	return u.key
+}
+
+func (u *umbrellaSource) Body(newIssue bool) string {
+	return fmt.Sprintf("**%v**\n\n%v", u.IssueSource.Title(), u.IssueSource.Body(newIssue))
+}
+
+// umbrellaSummary lists the members of an umbrella issue, by when they were
+// first seen.
+func umbrellaSummary(members map[string]time.Time) string {
+	titles := []string{}
+	for t := range members {
+		titles = append(titles, t)
+	}
+	sort.Sort(byFirstSeen{titles, members})
+	lines := []string{
+		umbrellaStart,
+		fmt.Sprintf("This issue groups %d related problems:", len(titles)),
+		"",
+		"| Problem | First seen |",
+		"| --- | --- |",
+	}
+	for _, t := range titles {
+		lines = append(lines, fmt.Sprintf("| %v | %v |", strings.Replace(t, "|", `\|`, -1), members[t].UTC().Format("2006-01-02 15:04 MST")))
+	}
+	lines = append(lines, umbrellaEnd)
+	return strings.Join(lines, "\n")
+}
+
+type byFirstSeen struct {
+	titles  []string
+	members map[string]time.Time
+}
+
+func (b byFirstSeen) Len() int      { return len(b.titles) }
+func (b byFirstSeen) Swap(i, j int) { b.titles[i], b.titles[j] = b.titles[j], b.titles[i] }
+func (b byFirstSeen) Less(i, j int) bool {
+	ti, tj := b.members[b.titles[i]], b.members[b.titles[j]]
+	if !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	return b.titles[i] < b.titles[j]
+}
+
+// updateUmbrella rewrites the summary of umbrella issue `n` from the store.
+func (s *IssueSyncer) updateUmbrella(ctx context.Context, n int) error {
+	r, ok := s.Store.Get(n)
+	if !ok || len(r.Members) == 0 {
+		return nil
+	}
+	obj, err := s.getObject(ctx, n)
+	if err != nil {
+		return err
+	}
+	old := ""
+	if obj.Issue.Body != nil {
+		old = *obj.Issue.Body
+	}
+	body := replaceSection(old, umbrellaStart, umbrellaEnd, umbrellaSummary(r.Members), false)
+	if body == old {
+		return nil
+	}
+	s.logger().With("issue", n).Infof("Updating the summary of the umbrella issue, it has %d members", len(r.Members))
+	return s.editBody(ctx, fmt.Sprintf("editing umbrella issue %v", n), obj, old, body)
+}
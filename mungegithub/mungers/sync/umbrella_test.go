@@ -0,0 +1,76 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type groupedSource struct {
+	testSource
+	group string
+}
+
+func (g *groupedSource) GroupKey() string { return g.group }
+
+func TestUmbrellaSource(t *testing.T) {
+	var source IssueSource = &groupedSource{testSource{title: "TestFoo failed", id: "gs://job/1"}, "Failures in pkg/foo"}
+	key := groupKey(source)
+	if key != "Failures in pkg/foo" || groupKey(&testSource{}) != "" {
+		t.Fatalf("unexpected group key %q", key)
+	}
+	u := &umbrellaSource{source, key}
+	if u.Title() != key || u.ID() != "gs://job/1" {
+		t.Errorf("unexpected umbrella source %q %q", u.Title(), u.ID())
+	}
+	if !strings.HasPrefix(u.Body(true), "**TestFoo failed**\n\n") {
+		t.Errorf("expected the member's title in the body:\n%v", u.Body(true))
+	}
+}
+
+func TestWithUmbrellaSummary(t *testing.T) {
+	members := map[string]time.Time{
+		"TestBar failed":   date("2016-07-01 13:00"),
+		"TestFoo failed":   date("2016-07-01 12:00"),
+		"Test|Pipe failed": date("2016-07-01 13:00"),
+	}
+	summary := umbrellaSummary(members)
+	expected := umbrellaStart + `
+This issue groups 3 related problems:
+
+| Problem | First seen |
+| --- | --- |
+| TestFoo failed | 2016-07-01 12:00 UTC |
+| TestBar failed | 2016-07-01 13:00 UTC |
+| Test\|Pipe failed | 2016-07-01 13:00 UTC |
+` + umbrellaEnd
+	if summary != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, summary)
+	}
+
+	body := replaceSection("Failed: TestFoo", umbrellaStart, umbrellaEnd, summary, false)
+	if body != summary+"\n\nFailed: TestFoo" {
+		t.Errorf("expected the summary first:\n%v", body)
+	}
+	members["TestBaz failed"] = date("2016-07-01 14:00")
+	body = replaceSection(body, umbrellaStart, umbrellaEnd, umbrellaSummary(members), false)
+	if strings.Count(body, umbrellaStart) != 1 || !strings.Contains(body, "groups 4 related") || !strings.HasSuffix(body, "\n\nFailed: TestFoo") {
+		t.Errorf("summary was not replaced in place:\n%v", body)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+)
+
+// UpstreamRepo is the repo issues are mirrored from, for syncers filing in
+// a downstream fork of it. Before filing an issue, the syncer looks for an
+// open issue upstream with the source's sync key (see KeyedSource), and
+// records the source there, linking to the upstream issue, instead of
+// filing a duplicate downstream.
+type UpstreamRepo struct {
+	Config *github.Config
+	// Finder finds upstream issues by sync key, see NewUpstreamRepo.
+	Finder SyncKeyFinder
+}
+
+// NewUpstreamRepo constructs an UpstreamRepo which searches the repo
+// `config` talks to.
+func NewUpstreamRepo(config *github.Config) *UpstreamRepo {
+	return &UpstreamRepo{Config: config, Finder: NewSearchFinder(config, nil)}
+}
+
+func (u *UpstreamRepo) String() string {
+	return fmt.Sprintf("%v/%v", u.Config.Org, u.Config.Project)
+}
+
+// findUpstream has st.Source recorded upstream instead of in a new issue,
+// if it has an open issue there.
+func (s *IssueSyncer) findUpstream(ctx context.Context, st *SyncState) error {
+	if s.Upstream == nil || st.Decision != DecisionCreate || internal(st.Source) {
+		return nil
+	}
+	key := s.syncKey(st.Source)
+	var numbers []int
+	if err := s.retry(ctx, fmt.Sprintf("searching %v for %v", s.Upstream, st.Source.ID()), func() (err error) {
+		numbers, err = s.Upstream.Finder.IssuesForSyncKey(key)
+		return err
+	}); err != nil {
+		return err
+	}
+	upstream := s.Upstream.Config.WithContext(ctx, s.CallTimeout)
+	for _, n := range numbers {
+		var obj *github.MungeObject
+		if err := s.retry(ctx, fmt.Sprintf("getting %v#%d", s.Upstream, n), func() (err error) {
+			obj, err = upstream.GetObject(n)
+			return err
+		}); err != nil {
+			return err
+		}
+		if obj.Issue.State != nil && *obj.Issue.State == "open" {
+			st.Decision, st.upstream = DecisionUpstream, obj
+			return nil
+		}
+	}
+	return nil
+}
+
+// syncUpstream records st.Source on its upstream issue, unless it already
+// is.
+func (s *IssueSyncer) syncUpstream(ctx context.Context, st *SyncState) error {
+	obj, source := st.upstream, st.Source
+	n := *obj.Issue.Number
+	repo := s.Upstream.String()
+	recorded, err := s.recordedRemote(ctx, repo, obj, source.ID())
+	if err != nil {
+		return err
+	}
+	if recorded {
+		s.logger().Debugf("Already recorded on %v#%d", repo, n)
+		return nil
+	}
+	s.logger().Infof("Open upstream as %v#%d, recording it there instead of filing", repo, n)
+	body := fmt.Sprintf("Also seen in %v/%v.\n\n%v", s.config.Org, s.config.Project, s.recurrenceText(n, s.sourceBody(source, false)))
+	if err := s.retry(ctx, fmt.Sprintf("updating %v#%d for %v", repo, n, source.ID()), func() error {
+		return obj.WriteComment(s.text(body))
+	}); err != nil {
+		return err
+	}
+	metrics.Add("linkedUpstream", 1)
+	return nil
+}
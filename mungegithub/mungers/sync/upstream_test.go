@@ -0,0 +1,76 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestUpstream(t *testing.T) {
+	upstream := synctesting.NewTracker()
+	defer upstream.Close()
+	downstream := synctesting.NewTracker()
+	defer downstream.Close()
+
+	newSyncer := func(tracker *synctesting.Tracker) *IssueSyncer {
+		finder := NewSearchFinder(tracker.Config(), nil)
+		finder.MinInterval = 0
+		return NewIssueSyncer(tracker.Config(), finder)
+	}
+	if err := newSyncer(upstream).Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: "up-1", Stable: "foo"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n := upstream.OpenIssues("TestFoo")[0]
+
+	newDownstream := func() *IssueSyncer {
+		s := newSyncer(downstream)
+		finder := NewSearchFinder(upstream.Config(), nil)
+		finder.MinInterval = 0
+		s.Upstream = &UpstreamRepo{Config: upstream.Config(), Finder: finder}
+		return s
+	}
+	// Renamed downstream, but with the same sync key.
+	source := &JSONSource{Key: "TestFooRenamed", Ref: "down-1", Stable: "foo"}
+	for i := 0; i < 2; i++ {
+		results, err := newDownstream().SyncAll(context.Background(), []IssueSource{source})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 1 || results[0].Action != DecisionUpstream {
+			t.Errorf("expected the source to be recorded upstream, got %+v", results)
+		}
+	}
+	if issues := downstream.Issues(); len(issues) != 0 {
+		t.Errorf("expected nothing to be filed downstream, got %d issues", len(issues))
+	}
+	comments := upstream.Comments(n)
+	if len(comments) != 1 || !strings.Contains(comments[0], "down-1") || !strings.Contains(comments[0], "Also seen in") {
+		t.Errorf("expected one comment upstream about down-1, got %q", comments)
+	}
+
+	upstream.CloseIssue(n)
+	if err := newDownstream().Sync(context.Background(), &JSONSource{Key: "TestFooRenamed", Ref: "down-2", Stable: "foo"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if issues := downstream.OpenIssues("TestFooRenamed"); len(issues) != 1 {
+		t.Errorf("expected an issue downstream once the upstream one is closed, got %v", issues)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+	"k8s.io/contrib/mungegithub/github"
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// DefaultMaxWarmUpAge is the default IssueSyncer.MaxWarmUpAge.
+const DefaultMaxWarmUpAge = 7 * 24 * time.Hour
+
+// issueURLRE gets the issue number out of a comment's issue_url.
+var issueURLRE = regexp.MustCompile(`/issues/(\d+)$`)
+
+// IssueUpdater is an IssueFinder which brings itself up to date with a bulk
+// listing, like IssueIndex.
+type IssueUpdater interface {
+	IssueFinder
+	Update() error
+}
+
+// WarmUp gets what the first cycle after a restart needs in bulk, instead
+// of with a few calls for every issue: the finder is brought up to date if
+// it is an IssueUpdater (an IssueIndex lists what changed since the time it
+// persisted), and the open issues in the store are listed, with their
+// comments, into the comment cache. The first time a source needs one of
+// those issues, it isn't fetched again.
+//
+// Only the comments since the last warm-up recorded in Store are listed, or
+// since MaxWarmUpAge ago if that is later, so only the issues filed since
+// are warmed up: we need all the comments of an issue to cache them, and
+// the others are fetched when they are needed, like without WarmUp.
+func (s *IssueSyncer) WarmUp(ctx context.Context) error {
+	finders := []IssueFinder{s.finder}
+	if f, ok := s.finder.(*FallbackFinder); ok {
+		finders = append(finders, f.Primary)
+	}
+	for _, f := range finders {
+		if u, ok := f.(IssueUpdater); ok {
+			if err := s.retry(ctx, "updating the finder", u.Update); err != nil {
+				return err
+			}
+		}
+	}
+
+	now := s.now()
+	since := s.Store.WarmedUp()
+	if bound := now.Add(-s.MaxWarmUpAge); since.Before(bound) {
+		since = bound
+	}
+	open := sets.NewInt()
+	for _, r := range s.Store.List() {
+		if !r.Closed && !r.Created.Before(since) {
+			open.Insert(r.Number)
+		}
+	}
+	if open.Len() == 0 {
+		return s.Store.SetWarmedUp(now)
+	}
+	// Issues filed since were updated since.
+	var issues []*githubapi.Issue
+	err := s.retry(ctx, fmt.Sprintf("listing the open issues since %v", since), func() (err error) {
+		issues, err = s.client(ctx).ListAllIssues(&githubapi.IssueListByRepoOptions{State: "open", Sort: "updated", Since: since})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	warm := []*githubapi.Issue{}
+	for _, issue := range issues {
+		if !open.Has(*issue.Number) || issue.CreatedAt == nil || issue.UpdatedAt == nil || issue.CreatedAt.Before(since) {
+			continue
+		}
+		warm = append(warm, issue)
+	}
+	if len(warm) == 0 {
+		return s.Store.SetWarmedUp(now)
+	}
+	var comments []githubapi.IssueComment
+	err = s.retry(ctx, fmt.Sprintf("listing the comments since %v", since), func() (err error) {
+		comments, err = s.client(ctx).ListRepoComments(since)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	byIssue := map[int][]githubapi.IssueComment{}
+	for _, c := range comments {
+		if c.IssueURL == nil {
+			continue
+		}
+		if m := issueURLRE.FindStringSubmatch(*c.IssueURL); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			byIssue[n] = append(byIssue[n], c)
+		}
+	}
+	s.warm = map[int]*github.MungeObject{}
+	for _, issue := range warm {
+		n := *issue.Number
+		s.warm[n] = s.config.IssueObject(issue)
+		s.comments.prime(n, *issue.UpdatedAt, byIssue[n])
+	}
+	s.logger().Infof("Warmed up with %d of %d open issues filed since %v and %d comments", len(s.warm), open.Len(), since, len(comments))
+	metrics.Add("warmedUpIssues", int64(len(s.warm)))
+	return s.Store.SetWarmedUp(now)
+}
+
+// warmObject returns the object WarmUp listed for issue `n`, once: after
+// that the issue may have changed, and is fetched again.
+func (s *IssueSyncer) warmObject(n int) (*github.MungeObject, bool) {
+	obj, ok := s.warm[n]
+	delete(s.warm, n)
+	return obj, ok
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func TestWarmUp(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	newSyncer := func() *IssueSyncer {
+		finder := NewSearchFinder(tracker.Config(), nil)
+		finder.MinInterval = 0
+		return NewIssueSyncer(tracker.Config(), finder)
+	}
+	before := newSyncer()
+	for _, ref := range []string{"foo-1", "foo-2"} {
+		if err := before.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref}); err != nil {
+			t.Fatalf("unexpected error syncing %v: %v", ref, err)
+		}
+	}
+	if err := before.Sync(context.Background(), &JSONSource{Key: "TestBar", Ref: "bar-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	foo, bar := tracker.OpenIssues("TestFoo")[0], tracker.OpenIssues("TestBar")[0]
+	tracker.CloseIssue(bar)
+	before.Store.Update(bar, func(r *IssueRecord) { r.Closed = true })
+	tracker.AddComment(foo, "someone", "Looking into it")
+
+	// A restart, with the records persisted.
+	s := newSyncer()
+	s.Store = before.Store
+	if err := s.WarmUp(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.warm) != 1 || s.warm[foo] == nil {
+		t.Errorf("expected only the open issue to be warmed up, got %v", s.warm)
+	}
+	issuePath := fmt.Sprintf("/repos/o/r/issues/%d", foo)
+	commentsPath := issuePath + "/comments"
+	gets, lists := tracker.Requests("GET", issuePath), tracker.Requests("GET", commentsPath)
+	for _, ref := range []string{"foo-2", "foo-3"} {
+		if err := s.Sync(context.Background(), &JSONSource{Key: "TestFoo", Ref: ref}); err != nil {
+			t.Fatalf("unexpected error syncing %v: %v", ref, err)
+		}
+	}
+	if got := tracker.Requests("GET", issuePath) - gets; got != 1 {
+		t.Errorf("expected the issue only fetched again after its first use, got %d requests", got)
+	}
+	if got := tracker.Requests("GET", commentsPath) - lists; got != 0 {
+		t.Errorf("expected the comments to be cached, got %d requests", got)
+	}
+	if comments := tracker.Comments(foo); len(comments) != 3 {
+		t.Errorf("expected one new comment, got %q", comments)
+	}
+	if s.Store.WarmedUp().IsZero() {
+		t.Errorf("expected the warm-up to be recorded")
+	}
+
+	// Another restart: the issue was filed before the last warm-up, so its
+	// comments weren't listed.
+	again := newSyncer()
+	again.Store = s.Store
+	again.Store.SetWarmedUp(time.Now().Add(time.Minute))
+	lists = tracker.Requests("GET", "/repos/o/r/issues/comments")
+	if err := again.WarmUp(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(again.warm) != 0 {
+		t.Errorf("expected no issues to be warmed up, got %v", again.warm)
+	}
+	if got := tracker.Requests("GET", "/repos/o/r/issues/comments") - lists; got != 0 {
+		t.Errorf("expected no comments listed, got %d requests", got)
+	}
+}
+
+func TestMetadataStoreWarmedUp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metadata.json")
+	// Written before the warm-up was recorded.
+	if err := ioutil.WriteFile(path, []byte(`[{"Number": 5, "Title": "TestFoo"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMetadataStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r, ok := m.Get(5); !ok || r.Title != "TestFoo" || !m.WarmedUp().IsZero() {
+		t.Fatalf("expected the old store to be loaded, got %v, %v", r, m.WarmedUp())
+	}
+	warmedUp := date("2016-07-01 12:00")
+	m.SetWarmedUp(warmedUp)
+	if err := m.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err = NewMetadataStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r, ok := m.Get(5); !ok || r.Title != "TestFoo" || !m.WarmedUp().Equal(warmedUp) {
+		t.Errorf("expected the store and the warm-up to be loaded, got %v, %v", r, m.WarmedUp())
+	}
+}
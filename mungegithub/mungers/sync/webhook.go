@@ -0,0 +1,219 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/net/context"
+)
+
+// maxWebhookBody is the most bytes of payload accepted in one delivery.
+const maxWebhookBody = 5 << 20
+
+// IssueObserver is an IssueFinder which can be told about issues one at a
+// time, as they change, see WebhookReceiver.
+type IssueObserver interface {
+	Observe(issue *githubapi.Issue)
+}
+
+// WebhookReceiver accepts github's `issues` and `issue_comment` webhook
+// deliveries, and passes the issues in them on to an IssueObserver, so that
+// the finder knows about new, edited and closed issues right away instead of
+// after its next listing. Deliveries must be signed with the webhook's
+// secret.
+type WebhookReceiver struct {
+	secret   []byte
+	observer IssueObserver
+
+	// Syncer, if set, is told when issues it filed are closed, reopened or
+	// labeled, see IssueChanged and SourceFeedback.
+	Syncer *IssueSyncer
+	// Logger is where deliveries are logged.
+	Logger Logger
+}
+
+// NewWebhookReceiver constructs a WebhookReceiver for deliveries signed with
+// `secret`, which must not be empty.
+func NewWebhookReceiver(secret []byte, observer IssueObserver) *WebhookReceiver {
+	return &WebhookReceiver{
+		secret:   secret,
+		observer: observer,
+		Logger:   &textLogger{},
+	}
+}
+
+// webhookPayload is the part of `issues` and `issue_comment` payloads we
+// use.
+type webhookPayload struct {
+	Action string           `json:"action"`
+	Issue  *githubapi.Issue `json:"issue"`
+}
+
+// signed returns true if `signature` ("sha1=<hex>") is the HMAC of `body`.
+func (w *WebhookReceiver) signed(body []byte, signature string) bool {
+	if len(w.secret) == 0 || !strings.HasPrefix(signature, "sha1=") {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha1="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, w.secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// ServeHTTP handles a webhook delivery.
+func (w *WebhookReceiver) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(res, "deliveries must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(res, req.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(res, "unable to read the payload", http.StatusBadRequest)
+		return
+	}
+	if !w.signed(body, req.Header.Get("X-Hub-Signature")) {
+		http.Error(res, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	event := req.Header.Get("X-GitHub-Event")
+	if event != "issues" && event != "issue_comment" {
+		// e.g. "ping", when the webhook is set up.
+		res.WriteHeader(http.StatusNoContent)
+		return
+	}
+	payload := webhookPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Issue == nil || payload.Issue.Number == nil {
+		http.Error(res, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	w.handle(req.Context(), event, payload)
+	res.WriteHeader(http.StatusAccepted)
+}
+
+func (w *WebhookReceiver) handle(ctx context.Context, event string, payload webhookPayload) {
+	issue := payload.Issue
+	if issue.PullRequestLinks != nil {
+		return
+	}
+	log := w.Logger.With("issue", *issue.Number)
+	log.Debugf("Received %v %v", event, payload.Action)
+	w.observer.Observe(issue)
+	if w.Syncer == nil || event != "issues" {
+		return
+	}
+	if err := w.Syncer.IssueChanged(ctx, payload.Action, issue); err != nil {
+		log.Errorf("Unable to queue that the issue was %v: %v", payload.Action, err)
+	}
+}
+
+// issueChange is a change to an issue, queued by IssueChanged.
+type issueChange struct {
+	action string
+	issue  *githubapi.Issue
+}
+
+// IssueChanged tells the syncer that `issue` was closed, reopened, labeled
+// or unlabeled (`action`) on github. Issues it didn't file and other
+// actions are ignored. Since this changes the syncer's state, the change is
+// queued and applied before the next sync cycle, or when the syncer is
+// stopped, instead of waiting for the cycle which is running, if any.
+func (s *IssueSyncer) IssueChanged(ctx context.Context, action string, issue *githubapi.Issue) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.isStopped() {
+		return ErrStopped
+	}
+	switch action {
+	case "closed", "reopened", "labeled", "unlabeled":
+	default:
+		return nil
+	}
+	if _, ok := s.Store.Get(*issue.Number); !ok {
+		return nil
+	}
+	s.changesLock.Lock()
+	defer s.changesLock.Unlock()
+	s.changes = append(s.changes, issueChange{action: action, issue: issue})
+	return nil
+}
+
+// applyChanges applies the changes IssueChanged queued, in the order they
+// were delivered. Changes which fail are logged and dropped, like the
+// deliveries were before they were queued; the ones left when ctx is done
+// stay queued.
+func (s *IssueSyncer) applyChanges(ctx context.Context) {
+	s.changesLock.Lock()
+	changes := s.changes
+	s.changes = nil
+	s.changesLock.Unlock()
+	for i, change := range changes {
+		if ctx.Err() != nil {
+			s.changesLock.Lock()
+			s.changes = append(changes[i:], s.changes...)
+			s.changesLock.Unlock()
+			return
+		}
+		if err := s.applyChange(ctx, change); err != nil {
+			s.logger().With("issue", *change.issue.Number).Errorf("Unable to record that the issue was %v: %v", change.action, err)
+		}
+	}
+}
+
+// applyChange applies one change queued by IssueChanged.
+func (s *IssueSyncer) applyChange(ctx context.Context, change issueChange) error {
+	number := *change.issue.Number
+	switch change.action {
+	case "closed":
+		return s.noticeClosed(ctx, number)
+	case "reopened":
+		return s.Store.Update(number, func(r *IssueRecord) { r.Closed = false })
+	case "labeled", "unlabeled":
+		if s.Feedback != nil {
+			return s.feedback(ctx, change.issue)
+		}
+	}
+	return nil
+}
+
+// hasLabels returns true if `issue` has all of `labels`.
+func hasLabels(issue *githubapi.Issue, labels []string) bool {
+	for _, l := range labels {
+		found := false
+		for _, label := range issue.Labels {
+			if label.Name != nil && *label.Name == l {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
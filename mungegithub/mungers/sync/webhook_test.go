@@ -0,0 +1,146 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	synctesting "k8s.io/contrib/mungegithub/mungers/sync/testing"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookReceiver(t *testing.T) {
+	index := NewIssueIndex(nil, nil, "")
+	s := NewIssueSyncer(nil, index)
+	s.Store.Update(5, func(r *IssueRecord) { r.Title = "TestFoo" })
+	w := NewWebhookReceiver([]byte("secret"), index)
+	w.Syncer = s
+
+	closed := `{"action": "closed", "issue": {"number": 5, "title": "TestFoo", "state": "closed"}}`
+	commented := `{"action": "created", "issue": {"number": 6, "title": "TestBar", "state": "open"}, "comment": {"body": "LGTM"}}`
+	tests := []struct {
+		name      string
+		event     string
+		body      string
+		signature string
+		expected  int
+	}{
+		{name: "unsigned", event: "issues", body: closed, expected: http.StatusUnauthorized},
+		{name: "wrong signature", event: "issues", body: closed, signature: sign("other", closed), expected: http.StatusUnauthorized},
+		{name: "ping", event: "ping", body: `{"zen": "hi"}`, signature: sign("secret", `{"zen": "hi"}`), expected: http.StatusNoContent},
+		{name: "invalid", event: "issues", body: `{}`, signature: sign("secret", `{}`), expected: http.StatusBadRequest},
+		{name: "closed", event: "issues", body: closed, signature: sign("secret", closed), expected: http.StatusAccepted},
+		{name: "comment", event: "issue_comment", body: commented, signature: sign("secret", commented), expected: http.StatusAccepted},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("POST", "/webhook", strings.NewReader(test.body))
+		req.Header.Set("X-GitHub-Event", test.event)
+		if test.signature != "" {
+			req.Header.Set("X-Hub-Signature", test.signature)
+		}
+		res := httptest.NewRecorder()
+		w.ServeHTTP(res, req)
+		if res.Code != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, res.Code)
+		}
+	}
+
+	if r, _ := s.Store.Get(5); r.Closed {
+		t.Errorf("expected #5 to be noticed as closed only before the next cycle")
+	}
+	s.applyChanges(context.Background())
+	if r, _ := s.Store.Get(5); !r.Closed {
+		t.Errorf("expected #5 to be noticed as closed")
+	}
+	for key, expected := range map[string][]int{"TestFoo": {5}, "TestBar": {6}} {
+		if got := index.AllIssuesForKey(key); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q: expected %v, got %v", key, expected, got)
+		}
+	}
+}
+
+// Run with -race: webhook deliveries come in while sources are synced, and
+// are answered without waiting for the cycle.
+func TestWebhookDuringSyncAll(t *testing.T) {
+	tracker := synctesting.NewTracker()
+	defer tracker.Close()
+	finder := NewSearchFinder(tracker.Config(), nil)
+	finder.MinInterval = 0
+	s := NewIssueSyncer(tracker.Config(), finder)
+	// The tracker has no project boards, so moving the card fails and is
+	// logged, like syncing does.
+	s.Board = &ProjectBoard{Project: "Flakes", ClosedColumn: "Done"}
+	n := tracker.AddIssue("TestFoo", "")
+	s.Store.Update(n, func(r *IssueRecord) {
+		r.Title = "TestFoo"
+		r.ProjectCard = 5
+	})
+	w := NewWebhookReceiver([]byte("secret"), finder)
+	w.Syncer = s
+	deliver := func(action string) int {
+		body := fmt.Sprintf(`{"action": %q, "issue": {"number": %d, "title": "TestFoo", "state": "open"}}`, action, n)
+		req, _ := http.NewRequest("POST", "/webhook", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "issues")
+		req.Header.Set("X-Hub-Signature", sign("secret", body))
+		res := httptest.NewRecorder()
+		w.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	// A cycle which takes forever.
+	s.running.Lock()
+	if code := deliver("reopened"); code != http.StatusAccepted {
+		t.Errorf("expected %v, got %v", http.StatusAccepted, code)
+	}
+	s.running.Unlock()
+
+	sources := []IssueSource{}
+	for i := 0; i < 5; i++ {
+		sources = append(sources, &JSONSource{Key: fmt.Sprintf("TestBar%d", i), Ref: "bar"})
+	}
+	done := make(chan error)
+	go func() {
+		_, err := s.SyncAll(context.Background(), sources)
+		done <- err
+	}()
+	if code := deliver("closed"); code != http.StatusAccepted {
+		t.Errorf("expected %v, got %v", http.StatusAccepted, code)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.SyncAll(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r, _ := s.Store.Get(n); !r.Closed {
+		t.Errorf("expected #%d to be noticed as closed", n)
+	}
+}